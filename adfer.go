@@ -1,21 +1,62 @@
 package adfer
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"runtime"
 	"runtime/debug"
+	"sync"
+	"text/template"
 	"time"
 )
 
 // CrashReport represents a single crash report
 type CrashReport struct {
+	ID         string            `json:"id,omitempty"`
 	Timestamp  time.Time         `json:"timestamp"`
 	Error      string            `json:"error"`
+	ErrorType  string            `json:"error_type,omitempty"`
 	Stack      string            `json:"stack"`
 	SystemInfo SystemInfo        `json:"system_info,omitempty"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	// Scopes is the scope stack of the context the panic was recovered with, outermost first, see PushScope
+	Scopes []string `json:"scopes,omitempty"`
+	// StackDiff is set if the stack was stored as a diff against an earlier report, see WithStackDiffs
+	StackDiff *StackDiff `json:"stack_diff,omitempty"`
+	// Flags holds the flags set on the command line, if captured with WithFlagSnapshot
+	Flags map[string]string `json:"flags,omitempty"`
+	// Config holds the flattened config struct, if captured with WithConfigSnapshot
+	Config map[string]string `json:"config,omitempty"`
+	// Deliveries holds the delivery receipt for each reporter, keyed by reporter name
+	Deliveries map[string]Delivery `json:"deliveries,omitempty"`
+	// Category is the category of the panic value, e.g. "runtime"
+	Category string `json:"category,omitempty"`
+	// Fingerprint groups the reports of the same bug, see Fingerprint
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Severity is the severity of the crash, see WithClassifier
+	Severity Severity `json:"severity,omitempty"`
+	// HandlerErrors holds the failures of the error handlers that panicked or timed out handling the report
+	HandlerErrors []string `json:"handler_errors,omitempty"`
+	// TraceFile is the path of the execution trace captured with the report, if any
+	TraceFile string `json:"trace_file,omitempty"`
+	// Skipped lists the enrichment steps skipped to stay within the performance budget
+	Skipped []string `json:"skipped,omitempty"`
+	// Uptime is the time since the process started, if system information is included
+	Uptime time.Duration `json:"uptime,omitempty"`
+	// SinceDeploy is the time since the running build was deployed, if set with WithDeployTime
+	SinceDeploy time.Duration `json:"since_deploy,omitempty"`
+	// Handled is true for reports submitted with PanicHandler.Report rather than recovered from a panic
+	Handled bool `json:"handled,omitempty"`
+	// Replayed is true for stored reports passed through the handler again with PanicHandler.Replay
+	Replayed bool `json:"replayed,omitempty"`
+	// Encrypted holds the report encrypted with WithEncryption. Only the ID, timestamp and
+	// delivery receipts are kept in the clear, see DecryptCrashReport
+	Encrypted string `json:"encrypted,omitempty"`
 }
 
 // SystemInfo represents system information
@@ -23,6 +64,8 @@ type SystemInfo struct {
 	OS           string `json:"os"`
 	Architecture string `json:"architecture"`
 	GoVersion    string `json:"go_version"`
+	Hostname     string `json:"hostname,omitempty"`
+	GOMAXPROCS   int    `json:"gomaxprocs,omitempty"`
 }
 
 // ErrorHandler is a function type for custom error handling
@@ -32,43 +75,247 @@ type ErrorHandler func(error, []byte)
 type Options struct {
 	// ErrorHandler is a custom error handling function
 	ErrorHandler ErrorHandler
+	// ErrorHandlerV2 is a custom error handling function that receives the full crash report and
+	// may modify it before it's stored and delivered. It's called instead of ErrorHandler
+	ErrorHandlerV2 ErrorHandlerV2
+	// ErrorHandlers are called in order after ErrorHandlerV2 or ErrorHandler. A handler that panics
+	// is skipped and reported as a diagnostic
+	ErrorHandlers []ErrorHandlerV2
 	// DumpToFile enables dumping errors to a file
 	DumpToFile bool
-	// FilePath is the path to the file to dump errors to
+	// FilePath is the path to the file to dump errors to. Missing parent directories are created.
+	// The {app}, {pid}, {hostname}, {date}, {time} and {timestamp} placeholders are replaced when
+	// the PanicHandler is created, e.g. "crashes/{app}-{pid}.json"
 	FilePath string
 	// ExitOnPanic enables exiting the program after handling a panic
 	ExitOnPanic bool
+	// Repanic re-raises the original panic value after handling a panic. It takes precedence over ExitOnPanic
+	Repanic bool
+	// IgnorePanics are panic values that are re-raised untouched instead of being handled, see WithIgnorePanics
+	IgnorePanics []any
+	// IgnoreFuncs return true for panic values that are re-raised untouched instead of being handled
+	IgnoreFuncs []func(value any) bool
+	// ExitFunc exits the program when a panic's action is Exit, see WithExitFunc. Defaults to os.Exit
+	ExitFunc func(code int)
+	// ExitCode is the exit code used when a panic's action is Exit. Defaults to 1
+	ExitCode int
+	// ExitCodeFunc, if set, maps the crash report of a panic to the exit code, see WithExitCodeFunc
+	ExitCodeFunc func(report CrashReport) int
+	// OnExit callbacks are called with the crash report before the program exits after a panic, see WithOnExit
+	OnExit []func(report CrashReport)
+	// OnExitTimeout is how long the OnExit callbacks may run before the program exits anyway. Defaults to 5 seconds
+	OnExitTimeout time.Duration
+	// HandlerTimeout limits how long each error handler and reporter may take per crash report. Disabled if zero
+	HandlerTimeout time.Duration
+	// CleanupTimeout is how long each cleanup registered with RegisterCleanup may run. Defaults to 5 seconds
+	CleanupTimeout time.Duration
 	// IncludeSystemInfo enables including system information in crash reports
 	IncludeSystemInfo bool
-	// Metadata is custom metadata to include in crash reports
+	// PanicValueFormatter, if set, formats panic values that aren't errors, see WithPanicValueFormatter
+	PanicValueFormatter func(value any) string
+	// Fingerprinter, if set, computes the fingerprint of crash reports, see WithFingerprinter
+	Fingerprinter func(err error, stack []byte) string
+	// Classifier, if set, classifies the severity of crash reports, see WithClassifier
+	Classifier func(err error, stack []byte) Severity
+	// DeployTime, if set, returns the time the running build was deployed, see WithDeployTime
+	DeployTime func() time.Time
+	// Metadata is custom metadata to include in crash reports. Values may contain
+	// templates, e.g. "{{.Hostname}}" or "{{env \"REGION\"}}", resolved when a report is created
 	Metadata map[string]string
+	// Flags, if set, captures the flags set on the command line into crash reports
+	Flags *flag.FlagSet
+	// Config, if set, captures a config struct into crash reports. Fields tagged `adfer:"redact"` are redacted
+	Config any
+	// StackDiffs stores recurring panics in the crash file as a diff against the first-seen stack
+	StackDiffs bool
+	// Storage, if set, stores crash reports instead of the crash file. DumpToFile, FilePath,
+	// Index and StackDiffs only apply to the crash file
+	Storage Storage
+	// FileMode is the mode of the files written by adfer, see WithFileMode. Defaults to 0644
+	FileMode os.FileMode
+	// DirMode is the mode of the directories created by adfer, see WithDirMode. Defaults to 0755
+	DirMode os.FileMode
+	// FileOwner, if set, is the owner of the files and directories written by adfer, see WithFileOwner
+	FileOwner *FileOwner
+	// MaxFileSize, if set, rotates the crash file once it reaches this size in bytes
+	MaxFileSize int64
+	// MaxFileAge, if set, rotates the crash file once its first report is older than this
+	MaxFileAge time.Duration
+	// MaxFileBackups is the number of rotated crash files kept. Defaults to 5
+	MaxFileBackups int
+	// MaxReports, if set, is the number of newest reports kept in the crash file or crash directory
+	MaxReports int
+	// CrashDir, if set, writes each crash report to its own file in this directory instead of the crash file
+	CrashDir string
+	// FileFormat is the format of the crash file. Defaults to a JSON array
+	FileFormat FileFormat
+	// FileSystem, if set, is the file system the crash file is stored in, see WithFileSystem
+	FileSystem FileSystem
+	// Compress gzip compresses the crash file, see WithCompression
+	Compress bool
+	// EncryptionKey, if set, is the X25519 public key crash reports are encrypted with at rest, see WithEncryption
+	EncryptionKey []byte
+	// FileHooks, if set, are called on lifecycle events of the crash file, see WithFileHooks
+	FileHooks *FileHooks
+	// SnapshotDir, if set, receives a snapshot of the crash reports before they are wiped, see WithWipeSnapshots
+	SnapshotDir string
+	// ImportLegacyLog converts a plain-text panic log found at FilePath into crash reports on
+	// initialization, see WithLegacyLogImport
+	ImportLegacyLog bool
 	// WipeFile enables wiping the crash file on initialization
 	WipeFile bool
+	// Index maintains a sidecar index next to the crash file, so changes to the file are
+	// detected on startup and GetLastNCrashReports doesn't decode the whole file
+	Index bool
+	// TraceCapture, if set, keeps a moving window of the execution trace and writes it out with each crash report
+	TraceCapture *TraceOptions
+	// PerformanceBudget, if set, is the maximum time spent creating a crash report before optional enrichment is skipped
+	PerformanceBudget time.Duration
+	// Policies sets the action taken after handling a panic, per category.
+	// Categories without a policy re-panic if Repanic is set, exit if ExitOnPanic is set and are absorbed otherwise
+	Policies map[Category]Action
+	// Reporters receive every crash report after it has been handled
+	Reporters []Reporter
+	// BeforeReport hooks are called with each crash report before it is handled, stored and delivered,
+	// and may modify or veto it, see WithBeforeReport
+	BeforeReport []func(report *CrashReport) bool
+	// AfterReport hooks are called with each crash report once it has been stored and delivered, see WithAfterReport
+	AfterReport []func(report CrashReport, err error)
+	// Async, if set, delivers crash reports to the reporters on a background worker
+	Async *AsyncOptions
+	// IDGenerator generates crash report IDs. Defaults to UUIDv4
+	IDGenerator func() string
+	// Spool, if set, writes crash reports that failed to reach a reporter to disk and retries them
+	Spool *SpoolOptions
+	// Consent, if set, gates crash reporting on the consent level set with PanicHandler.SetConsent
+	Consent *ConsentOptions
+	// Language is the language tag of user-facing messages, e.g. "de" or "pt-BR".
+	// Defaults to the language of the LC_ALL, LC_MESSAGES or LANG environment variables
+	Language string
+	// Messages holds the user-facing messages per language tag. English is used for missing languages and messages
+	Messages map[string]Messages
+	// Prompt, if set, asks the user on the terminal before a crash report is sent to the reporters
+	Prompt *PromptOptions
+	// CircuitBreaker, if set, wraps each reporter in its own CircuitBreaker
+	CircuitBreaker *CircuitBreakerOptions
+	// PersistentStats keeps lifetime counters of the stored crash reports in the storage, see WithPersistentStats
+	PersistentStats bool
+	// Statsd, if set, sends metrics to a statsd or DogStatsD server
+	Statsd *StatsdOptions
+	// OnDiagnostic receives operational failures of the crash reporter itself
+	OnDiagnostic DiagnosticHandler
+	// Logger receives operational failures of the crash reporter itself.
+	// If neither Logger nor OnDiagnostic is set, failures are printed to stdout
+	Logger *log.Logger
 }
 
+// Option is a function that modifies Options. Options are applied in order by New
+type Option func(*Options)
+
 type PanicHandler struct {
-	options  Options
-	exitFunc func(int)
-}
+	reportStore
+
+	options Options
+
+	console       consoleReporter
+	errorHandlers []ErrorHandlerV2
+	cleanups      []cleanup
+	reporters     []Reporter
+	reporterNames []string
+	templates     map[string]*template.Template
+	tracer        traceRecorder
+	prompter      *prompter
+	messages      Messages
+	budget        *budget
+	pipeline      *pipeline
+	statsd        *statsd
+
+	mu      sync.Mutex
+	stats   Stats
+	consent ConsentLevel
 
-// defaultErrorHandler is the default error handling function
-func defaultErrorHandler(err error, stack []byte) {
-	fmt.Printf("Recovered from panic:\nError: %v\nStack Trace:\n%s\n", err, stack)
+	goroutines goroutineRegistry
+	contexts   contextRegistry
+
+	// spoolMu serialises retries of the spool directory
+	spoolMu   sync.Mutex
+	spoolStop chan struct{}
+	spoolDone chan struct{}
+	spoolOnce sync.Once
 }
 
 // New initializes a new PanicHandler with optional configurations
-func New(options Options) *PanicHandler {
-	if options.ErrorHandler == nil {
-		options.ErrorHandler = defaultErrorHandler
+func New(options Options, opts ...Option) *PanicHandler {
+	for _, opt := range opts {
+		opt(&options)
+	}
+	now := time.Now()
+	options.FilePath = expandFilePath(options.FilePath, now)
+	options.CrashDir = expandFilePath(options.CrashDir, now)
+	if options.CircuitBreaker != nil {
+		reporters := make([]Reporter, len(options.Reporters))
+		for i, reporter := range options.Reporters {
+			// Wrap inside sinks, so an open circuit triggers the sink's failure policy
+			if sink, ok := reporter.(*Sink); ok {
+				reporters[i] = sink.withReporter(NewCircuitBreaker(sink.reporter, *options.CircuitBreaker))
+				continue
+			}
+			reporters[i] = NewCircuitBreaker(reporter, *options.CircuitBreaker)
+		}
+		options.Reporters = reporters
 	}
 	ph := &PanicHandler{
-		options:  options,
-		exitFunc: os.Exit,
+		options: options,
+	}
+	ph.messages = ph.resolveMessages()
+	ph.budget = newBudget(ph.options.PerformanceBudget)
+	ph.errorHandlers = ph.configuredErrorHandlers()
+	ph.console = consoleReporter{handler: ph.handleError}
+	if ph.options.ErrorHandler == nil {
+		ph.options.ErrorHandler = consoleErrorHandler(ph.messages)
+	}
+	if ph.options.ExitFunc == nil {
+		ph.options.ExitFunc = os.Exit
+	}
+	ph.perms = permsFromOptions(ph.options)
+	ph.storage = ph.options.Storage
+	if ph.storage == nil && ph.options.CrashDir != "" {
+		ph.storage = &dirStorage{dir: ph.options.CrashDir, maxReports: ph.options.MaxReports, perms: ph.perms, hooks: ph.fileHooks(), diagnose: ph.diagnose}
+	}
+	if ph.storage == nil && (ph.options.DumpToFile || ph.options.FilePath != "") {
+		ph.storage = newFileStorage(ph.options, ph.diagnose)
+	}
+	if ph.options.Statsd != nil {
+		ph.statsd = newStatsd(*ph.options.Statsd, ph.diagnose)
+	}
+	if ph.stores() {
+		ph.reporters = append(ph.reporters, storageReporter{
+			storage:       ph.storage,
+			statsd:        ph.statsd,
+			encryptionKey: ph.options.EncryptionKey,
+			persistStats:  ph.options.PersistentStats,
+			diagnose:      ph.diagnose,
+		})
+	}
+	ph.reporterNames = uniqueReporterNames(ph.options.Reporters)
+	ph.loadConsent()
+	if ph.options.Prompt != nil {
+		ph.prompter = newPrompter(*ph.options.Prompt, ph.messages)
+	}
+	ph.parseMetadataTemplates()
+	ph.startTraceCapture()
+	ph.startPipeline()
+	ph.startSpool()
+	if ph.options.ImportLegacyLog {
+		ph.importLegacyLog()
+	}
+	if file, ok := ph.storage.(*fileStorage); ok && ph.options.Index && ph.options.DumpToFile {
+		file.checkIndex()
 	}
-	if ph.options.WipeFile && ph.options.DumpToFile {
+	if ph.options.WipeFile && ph.stores() {
 		err := ph.WipeCrashFile()
 		if err != nil {
-			fmt.Printf("Error wiping crash file: %v\n", err)
+			ph.diagnose(OpWipe, ph.options.FilePath, err)
 		}
 	}
 	return ph
@@ -77,56 +324,167 @@ func New(options Options) *PanicHandler {
 // Recover is the main function to recover from panics
 func (ph *PanicHandler) Recover() {
 	if r := recover(); r != nil {
-		err, ok := r.(error)
-		if !ok {
-			err = fmt.Errorf("%v", r)
-		}
-		stack := debug.Stack()
-		ph.options.ErrorHandler(err, stack)
+		ph.handlePanic(context.Background(), r)
+	}
+}
 
-		if ph.options.DumpToFile {
-			report := CrashReport{
-				Timestamp: time.Now(),
-				Error:     err.Error(),
-				Stack:     string(stack),
-				Metadata:  ph.options.Metadata,
-			}
+// RecoverCtx recovers from panics like Recover, adding the tags stored in ctx to the crash report
+func (ph *PanicHandler) RecoverCtx(ctx context.Context) {
+	if r := recover(); r != nil {
+		ph.handlePanic(ctx, r)
+	}
+}
 
-			if ph.options.IncludeSystemInfo {
-				report.SystemInfo = SystemInfo{
-					OS:           runtime.GOOS,
-					Architecture: runtime.GOARCH,
-					GoVersion:    runtime.Version(),
-				}
-			}
+// handlePanic handles a recovered panic value and returns its crash report
+func (ph *PanicHandler) handlePanic(ctx context.Context, r any) CrashReport {
+	return ph.handlePanicWith(ctx, r, nil)
+}
 
-			ph.appendCrashReport(report)
-		}
+// handlePanicWith handles a recovered panic value, adding metadata to its crash report.
+// Ignored panic values are re-raised untouched
+func (ph *PanicHandler) handlePanicWith(ctx context.Context, r any, metadata map[string]string) CrashReport {
+	if ph.ignores(r) {
+		panic(r)
+	}
+	err, ok := r.(error)
+	if !ok {
+		err = ph.panicValueError(r)
+	}
+	stack := debug.Stack()
+	ph.mu.Lock()
+	ph.stats.Panics++
+	ph.mu.Unlock()
 
-		if ph.options.ExitOnPanic {
-			ph.exitFunc(1)
-		}
+	category := categorize(r)
+	report := ph.newCrashReport(ctx, err, fmt.Sprintf("%T", r), stack)
+	report.Category = category.String()
+	addMetadata(&report, metadata)
+	report = ph.process(ctx, err, report)
+
+	switch ph.action(category) {
+	case Repanic:
+		ph.flushBeforeExit()
+		panic(r)
+	case Exit:
+		ph.exit(report)
 	}
+	return report
 }
 
-func (ph *PanicHandler) appendCrashReport(report CrashReport) {
-	var reports []CrashReport
+// newCrashReport creates a crash report for the given error and stack
+func (ph *PanicHandler) newCrashReport(ctx context.Context, err error, errorType string, stack []byte) CrashReport {
+	start := time.Now()
+	report := CrashReport{
+		ID:        ph.newID(),
+		Timestamp: time.Now(),
+		Error:     err.Error(),
+		ErrorType: errorType,
+		Stack:     string(stack),
+		Tags:      TagsFromContext(ctx),
+		Scopes:    ScopesFromContext(ctx),
+	}
+	report.Fingerprint = ph.fingerprint(err, stack, report)
+	if ph.Consent() == ConsentNone {
+		return report
+	}
 
-	data, err := os.ReadFile(ph.options.FilePath)
-	if err == nil {
-		err := json.Unmarshal(data, &reports)
-		if err != nil {
-			fmt.Printf("Error unmarshalling crash reports: %v\n", err)
-		}
+	if ph.options.IncludeSystemInfo {
+		ph.enrich(&report, start, EnrichSystemInfo, func() {
+			hostname, _ := os.Hostname()
+			report.SystemInfo = SystemInfo{
+				OS:           runtime.GOOS,
+				Architecture: runtime.GOARCH,
+				GoVersion:    runtime.Version(),
+				Hostname:     hostname,
+				GOMAXPROCS:   runtime.GOMAXPROCS(0),
+			}
+		})
 	}
+	ph.annotate(&report)
+	report.Metadata = ph.resolveMetadata(report)
+	if ph.options.Flags != nil || ph.options.Config != nil {
+		ph.enrich(&report, start, EnrichConfig, func() {
+			if ph.options.Flags != nil {
+				report.Flags = snapshotFlags(ph.options.Flags)
+			}
+			if ph.options.Config != nil {
+				report.Config = snapshotConfig(ph.options.Config)
+			}
+		})
+	}
+	if ph.tracing() {
+		ph.enrich(&report, start, EnrichTrace, func() { ph.captureTrace(&report) })
+	}
+	return report
+}
 
-	reports = append(reports, report)
+// process passes a crash report to the BeforeReport hooks and the error handlers, then to every reporter: the
+// crash file and any configured reporters. Changes the hooks and handlers make to the report are kept. If the
+// crash file is enabled, the delivery receipts of the configured reporters are stored with the report.
+// With asynchronous delivery, the configured reporters are called on the background worker.
+// The consent level limits which reporters are called. The AfterReport hooks are called with the outcome.
+// It returns the report as handled
+func (ph *PanicHandler) process(ctx context.Context, err error, report CrashReport) CrashReport {
+	ctx = contextWithError(ctx, err)
+	if report.Severity == "" {
+		report.Severity = ph.severity(err, report)
+	}
+	if !ph.beforeReport(&report) {
+		return report
+	}
+	consent := ph.Consent()
+	ph.console.handle(ctx, &report)
+	if consent == ConsentNone {
+		ph.afterReport(report, ErrNoConsent)
+		return report
+	}
+	if ph.statsd != nil {
+		ph.statsd.reportCount(report)
+	}
+	tracked := ph.tracksDeliveries()
+
+	stored := report
+	if tracked {
+		stored.Deliveries = ph.pendingDeliveries()
+	}
+	var storeErr error
+	for _, reporter := range ph.reporters {
+		if err := reporter.Report(ctx, stored); err != nil {
+			// The report isn't in the crash file, so there is nowhere to store receipts
+			tracked = false
+			storeErr = err
+		}
+	}
 
-	data, _ = json.MarshalIndent(reports, "", "  ")
-	err = os.WriteFile(ph.options.FilePath, data, 0644)
-	if err != nil {
-		fmt.Printf("Error writing crash report to file: %v\n", err)
+	if len(ph.options.Reporters) > 0 && (consent < ConsentFull || ph.prompter != nil && !ph.prompter.confirm(report)) {
+		report.Deliveries = ph.allDeliveries(Delivery{Status: DeliveryDeclined, UpdatedAt: time.Now()})
+		ph.storeDeliveries(tracked, report.ID, report.Deliveries)
+		ph.afterReport(report, errors.Join(storeErr, ErrNoConsent))
+		return report
+	}
+	if len(ph.options.Reporters) > 0 && ph.pipeline != nil {
+		queued, err := ph.pipeline.enqueue(deliveryJob{report: report, tracked: tracked, err: storeErr})
+		if err != nil {
+			ph.diagnose(OpReport, "", err)
+			report.Deliveries = ph.allDeliveries(Delivery{
+				Status:    DeliveryDropped,
+				Error:     err.Error(),
+				UpdatedAt: time.Now(),
+			})
+			ph.storeDeliveries(tracked, report.ID, report.Deliveries)
+			ph.afterReport(report, errors.Join(storeErr, err))
+		}
+		if queued {
+			return report
+		}
 	}
+	ph.completeDelivery(tracked, report, ph.deliverAll(ctx, report), storeErr)
+	return report
+}
+
+// stores returns true if crash reports are appended to the storage
+func (ph *PanicHandler) stores() bool {
+	return ph.options.Storage != nil || ph.options.CrashDir != "" || ph.options.DumpToFile
 }
 
 // SafeGo wraps a function to be executed in a goroutine with panic recovery
@@ -137,32 +495,35 @@ func (ph *PanicHandler) SafeGo(f func()) {
 	}()
 }
 
-// GetLastNCrashReports retrieves the last N crash reports from the log file
-func (ph *PanicHandler) GetLastNCrashReports(n int) ([]CrashReport, error) {
-	if ph.options.FilePath == "" {
-		return nil, fmt.Errorf("no file path set for crash reports")
-	}
-	data, err := os.ReadFile(ph.options.FilePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var reports []CrashReport
-	err = json.Unmarshal(data, &reports)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(reports) <= n {
-		return reports, nil
-	}
-	return reports[len(reports)-n:], nil
+// SafeGoCtx wraps a function to be executed in a goroutine with panic recovery.
+// Tags stored in ctx are added to the crash report. f receives a context derived from ctx that is
+// cancelled once f returns or its panic has been handled, and when the handler shuts down with Close
+// or exits after a panic, so goroutines started with it can tear down together
+func (ph *PanicHandler) SafeGoCtx(ctx context.Context, f func(ctx context.Context)) {
+	derived, release := ph.contexts.derive(ctx)
+	go func() {
+		defer release()
+		// Panics are handled with the caller's context, as the derived context may be cancelled
+		// by then and reporters would fail with it
+		defer ph.RecoverCtx(ctx)
+		f(derived)
+	}()
 }
 
-// WipeCrashFile clears all crash reports from the log file
+// WipeCrashFile clears all crash reports from the storage. If Options.SnapshotDir is set, the
+// reports are snapshotted first and nothing is wiped if that fails
 func (ph *PanicHandler) WipeCrashFile() error {
-	if ph.options.FilePath == "" {
+	if ph.storage == nil {
 		return fmt.Errorf("no file path set for crash reports")
 	}
-	return os.WriteFile(ph.options.FilePath, []byte("[]"), 0644)
+	if err := ph.snapshotBeforeWipe(); err != nil {
+		return fmt.Errorf("snapshot before wipe: %w", err)
+	}
+	if err := ph.storage.Wipe(); err != nil {
+		return err
+	}
+	if hooks := ph.fileHooks(); hooks.OnWipe != nil {
+		hooks.OnWipe()
+	}
+	return nil
 }
@@ -27,10 +27,11 @@ func TestNew(t *testing.T) {
 
 	t.Run("Custom options", func(t *testing.T) {
 		customHandler := func(error, []byte) {}
+		filePath := filepath.Join(t.TempDir(), "test.json")
 		ph := New(Options{
 			ErrorHandler:      customHandler,
 			DumpToFile:        true,
-			FilePath:          "test.json",
+			FilePath:          filePath,
 			ExitOnPanic:       true,
 			IncludeSystemInfo: true,
 			Metadata:          map[string]string{"test": "value"},
@@ -45,8 +46,8 @@ func TestNew(t *testing.T) {
 		if !ph.options.DumpToFile {
 			t.Error("Expected DumpToFile to be true")
 		}
-		if ph.options.FilePath != "test.json" {
-			t.Errorf("Expected FilePath to be 'test.json', got '%s'", ph.options.FilePath)
+		if ph.options.FilePath != filePath {
+			t.Errorf("Expected FilePath to be '%s', got '%s'", filePath, ph.options.FilePath)
 		}
 		if !ph.options.ExitOnPanic {
 			t.Error("Expected ExitOnPanic to be true")
@@ -95,19 +96,22 @@ func TestRecover(t *testing.T) {
 }
 
 func TestSafeGo(t *testing.T) {
-	ph := New(Options{})
-	done := make(chan bool)
+	recovered := make(chan error, 1)
+	ph := New(Options{ErrorHandler: func(err error, _ []byte) {
+		recovered <- err
+	}})
 
 	ph.SafeGo(func() {
 		panic("test panic")
-		done <- true
 	})
 
 	select {
-	case <-done:
-		t.Fatal("Goroutine should have panicked")
-	case <-time.After(100 * time.Millisecond):
-		// Success: goroutine panicked and was recovered
+	case err := <-recovered:
+		if !strings.Contains(err.Error(), "test panic") {
+			t.Errorf("Expected the panic to be handled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the panic to be recovered")
 	}
 }
 
@@ -362,11 +366,12 @@ func TestNewErrorOnWipeCrashFile(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	// Create a file that can't be written to
+	// Create a path that can't be written to. A directory is used rather
+	// than a read-only file so the test also holds when running as root.
 	filePath := filepath.Join(tempDir, "crash_report.json")
-	err = os.WriteFile(filePath, []byte("[]"), 0444) // Read-only file
+	err = os.Mkdir(filePath, 0755)
 	if err != nil {
-		t.Fatalf("Failed to create read-only file: %v", err)
+		t.Fatalf("Failed to create unwritable path: %v", err)
 	}
 
 	// Redirect stdout to capture the error message
@@ -401,13 +406,12 @@ func TestRecoverWithExitOnPanic(t *testing.T) {
 	exitCalled := false
 	ph := New(Options{
 		ExitOnPanic: true,
-	})
-	ph.exitFunc = func(code int) {
+	}, WithExitFunc(func(code int) {
 		exitCalled = true
 		if code != 1 {
 			t.Errorf("Expected exit code 1, got %d", code)
 		}
-	}
+	}))
 
 	func() {
 		defer ph.Recover()
@@ -427,14 +431,15 @@ func TestAppendCrashReportWriteError(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	// Create a read-only file
+	// Create a path that can't be written to. A directory is used rather
+	// than a read-only file so the test also holds when running as root.
 	filePath := filepath.Join(tempDir, "crash_report.json")
-	err = os.WriteFile(filePath, []byte("[]"), 0444) // Read-only file
+	err = os.Mkdir(filePath, 0755)
 	if err != nil {
-		t.Fatalf("Failed to create read-only file: %v", err)
+		t.Fatalf("Failed to create unwritable path: %v", err)
 	}
 
-	// Create a PanicHandler with the read-only file
+	// Create a PanicHandler with the unwritable path
 	ph := New(Options{
 		DumpToFile: true,
 		FilePath:   filePath,
@@ -446,7 +451,7 @@ func TestAppendCrashReportWriteError(t *testing.T) {
 	os.Stdout = w
 
 	// Attempt to append a crash report
-	ph.appendCrashReport(CrashReport{
+	ph.storage.Append(CrashReport{
 		Timestamp: time.Now(),
 		Error:     "test error",
 		Stack:     "test stack",
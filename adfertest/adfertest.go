@@ -0,0 +1,178 @@
+// Package adfertest generates realistic, deterministic crash reports, so code that reads adfer
+// crash stores, such as dashboards and queries, can be tested without provoking real panics.
+//
+// The same seed always generates the same report. Reports are drawn from a fixed set of crashes,
+// so reports generated from different seeds share fingerprints the way real crashes do, and their
+// timestamps increase with the seed.
+//
+//	store := adfer.NewMemoryStore(100)
+//	reports, err := adfertest.PopulateStore(store, 50)
+//	...
+//	ph := adfer.New(adfer.Options{}, adfer.WithStorage(store))
+package adfertest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+// Epoch is the timestamp of the report generated from seed 0
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reportInterval is the mean time between the timestamps of reports generated from consecutive seeds
+const reportInterval = 10 * time.Minute
+
+// crash is a crash that fake reports are generated from
+type crash struct {
+	errorType string
+	category  string
+	// message is the error, with %d replaced by a random number
+	message string
+	frames  []string
+}
+
+// crashes are the crashes fake reports are generated from. Each has its own fingerprint
+var crashes = []crash{
+	{
+		errorType: "runtime.boundsError",
+		category:  "runtime",
+		message:   "runtime error: index out of range [%d] with length 3",
+		frames:    []string{"example.com/shop/cart.(*Cart).Item", "example.com/shop/api.(*Server).getCart", "net/http.HandlerFunc.ServeHTTP"},
+	},
+	{
+		errorType: "runtime.errorString",
+		category:  "runtime",
+		message:   "runtime error: invalid memory address or nil pointer dereference",
+		frames:    []string{"example.com/shop/payments.(*Client).Charge", "example.com/shop/api.(*Server).checkout", "net/http.HandlerFunc.ServeHTTP"},
+	},
+	{
+		errorType: "*errors.errorString",
+		category:  "error",
+		message:   "connection reset by peer after %d bytes",
+		frames:    []string{"example.com/shop/db.(*Pool).Query", "example.com/shop/inventory.Reserve", "example.com/shop/worker.(*Queue).process"},
+	},
+	{
+		errorType: "*fmt.wrapError",
+		category:  "error",
+		message:   "decode order %d: unexpected end of JSON input",
+		frames:    []string{"example.com/shop/orders.Decode", "example.com/shop/worker.(*Queue).process", "example.com/shop/worker.(*Queue).Run"},
+	},
+	{
+		errorType: "string",
+		category:  "value",
+		message:   "unreachable: unknown order state %d",
+		frames:    []string{"example.com/shop/orders.(*Order).Advance", "example.com/shop/worker.(*Queue).process"},
+	},
+	{
+		errorType: "runtime.plainError",
+		category:  "runtime",
+		message:   "assignment to entry in nil map",
+		frames:    []string{"example.com/shop/cache.(*LRU).Set", "example.com/shop/api.(*Server).getProduct", "net/http.HandlerFunc.ServeHTTP"},
+	},
+}
+
+var (
+	versions = []string{"1.4.0", "1.4.1", "1.5.0"}
+	regions  = []string{"eu-west-1", "us-east-1", "ap-southeast-2"}
+	hosts    = []string{"web-1", "web-2", "worker-1"}
+	tags     = []string{"checkout", "background", "canary", "api"}
+	systems  = []adfer.SystemInfo{
+		{OS: "linux", Architecture: "amd64", GoVersion: "go1.22.3"},
+		{OS: "linux", Architecture: "arm64", GoVersion: "go1.22.3"},
+		{OS: "darwin", Architecture: "arm64", GoVersion: "go1.21.9"},
+	}
+)
+
+// FakeReport returns a crash report generated from seed, with a stack, system information,
+// metadata and tags. The same seed always returns the same report
+func FakeReport(seed int64) adfer.CrashReport {
+	random := rand.New(rand.NewSource(seed))
+	c := crashes[random.Intn(len(crashes))]
+	message := c.message
+	if strings.Contains(message, "%d") {
+		message = fmt.Sprintf(message, random.Intn(1000))
+	}
+	system := systems[random.Intn(len(systems))]
+	system.Hostname = hosts[random.Intn(len(hosts))]
+	system.GOMAXPROCS = 2 << random.Intn(4)
+
+	report := adfer.CrashReport{
+		ID:         fakeID(random),
+		Timestamp:  Epoch.Add(time.Duration(seed)*reportInterval + time.Duration(random.Int63n(int64(reportInterval)))),
+		Error:      message,
+		ErrorType:  c.errorType,
+		Category:   c.category,
+		Stack:      fakeStack(random, c.frames),
+		SystemInfo: system,
+		Metadata: map[string]string{
+			"version": versions[random.Intn(len(versions))],
+			"region":  regions[random.Intn(len(regions))],
+		},
+		Uptime: time.Duration(random.Int63n(int64(72 * time.Hour))).Round(time.Second),
+	}
+	if random.Intn(4) == 0 {
+		report.Metadata["user_id"] = fmt.Sprintf("user-%d", random.Intn(10000))
+	}
+	for _, tag := range tags {
+		if random.Intn(3) == 0 {
+			report.Tags = append(report.Tags, tag)
+		}
+	}
+	report.Fingerprint = adfer.Fingerprint(report)
+	return report
+}
+
+// PopulateStore appends n fake reports, generated from the seeds 0 to n-1, to store and returns them,
+// oldest first
+func PopulateStore(store adfer.Storage, n int) ([]adfer.CrashReport, error) {
+	reports := make([]adfer.CrashReport, 0, n)
+	for seed := 0; seed < n; seed++ {
+		report := FakeReport(int64(seed))
+		if err := store.Append(report); err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// fakeID returns a version 4 UUID drawn from random
+func fakeID(random *rand.Rand) string {
+	var id [16]byte
+	random.Read(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// fakeStack returns the stack of a panicking goroutine calling frames, innermost first.
+// Line numbers vary, as they would between builds, without changing the fingerprint
+func fakeStack(random *rand.Rand, frames []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "goroutine %d [running]:\n", 1+random.Intn(200))
+	sb.WriteString("runtime/debug.Stack()\n\t/usr/local/go/src/runtime/debug/stack.go:24 +0x5e\n")
+	sb.WriteString("panic({0x6a8e40?, 0xc000012345?})\n\t/usr/local/go/src/runtime/panic.go:770 +0x132\n")
+	for _, frame := range frames {
+		fmt.Fprintf(&sb, "%s(...)\n\t%s:%d +0x%x\n", frame, frameFile(frame), 10+random.Intn(300), random.Intn(0x200))
+	}
+	return sb.String()
+}
+
+// frameFile returns a plausible source file for a function, e.g. "/src/shop/cart/cart.go"
+// for "example.com/shop/cart.(*Cart).Item"
+func frameFile(function string) string {
+	pkg := function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	}
+	if strings.HasPrefix(pkg, "net/") {
+		return "/usr/local/go/src/" + pkg + "/server.go"
+	}
+	return "/src/" + strings.TrimPrefix(pkg, "example.com/") + "/" + pkg[strings.LastIndex(pkg, "/")+1:] + ".go"
+}
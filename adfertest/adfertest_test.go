@@ -0,0 +1,55 @@
+package adfertest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leaanthony/adfer"
+)
+
+func TestFakeReport(t *testing.T) {
+	report := FakeReport(42)
+	if !reflect.DeepEqual(report, FakeReport(42)) {
+		t.Error("Expected the same seed to generate the same report")
+	}
+	if reflect.DeepEqual(report, FakeReport(43)) {
+		t.Error("Expected different seeds to generate different reports")
+	}
+	if report.ID == "" || report.Error == "" || report.ErrorType == "" || report.SystemInfo.OS == "" || report.Metadata["version"] == "" {
+		t.Errorf("Expected a fully populated report, got %+v", report)
+	}
+	if frames := adfer.ParseStack(report.Stack); len(frames) < 4 {
+		t.Errorf("Expected a parseable stack, got %q", report.Stack)
+	}
+
+	fingerprints := make(map[string]int)
+	for seed := int64(0); seed < 100; seed++ {
+		fingerprints[adfer.Fingerprint(FakeReport(seed))]++
+	}
+	if len(fingerprints) != len(crashes) {
+		t.Errorf("Expected %d fingerprints, got %d", len(crashes), len(fingerprints))
+	}
+}
+
+func TestPopulateStore(t *testing.T) {
+	store := adfer.NewMemoryStore(100)
+	reports, err := PopulateStore(store, 20)
+	if err != nil || len(reports) != 20 {
+		t.Fatalf("Expected 20 reports, got %d, error %v", len(reports), err)
+	}
+	stored, _ := store.LastN(-1)
+	if !reflect.DeepEqual(stored, reports) {
+		t.Error("Expected the stored reports to be returned")
+	}
+	for i, report := range reports {
+		if !reflect.DeepEqual(report, FakeReport(int64(i))) {
+			t.Errorf("Expected report %d to be generated from seed %d", i, i)
+		}
+		if i > 0 && !report.Timestamp.After(reports[i-1].Timestamp) {
+			t.Errorf("Expected report %d to be newer than report %d", i, i-1)
+		}
+	}
+	if !reports[0].Timestamp.Before(Epoch.Add(reportInterval)) || reports[0].Timestamp.Before(Epoch) {
+		t.Errorf("Expected the first report shortly after the epoch, got %v", reports[0].Timestamp)
+	}
+}
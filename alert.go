@@ -0,0 +1,292 @@
+package adfer
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SeverityFunc maps a crash report to a severity understood by an alerting service
+type SeverityFunc func(CrashReport) string
+
+// PagerDutyOptions configures a PagerDutyReporter
+type PagerDutyOptions struct {
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string
+	// Source is the affected system. Defaults to the host the crash happened on
+	Source string
+	// Component is the component of the source machine responsible for the event
+	Component string
+	// Group is a logical grouping of components
+	Group string
+	// Severity maps a report to one of "critical", "error", "warning" or "info". Defaults to the report's
+	// Severity, with fatal crashes and reports without a severity as "critical"
+	Severity SeverityFunc
+	// AutoResolveAfter resolves the alert if the same panic has not recurred for this long. Disabled if zero
+	AutoResolveAfter time.Duration
+	// URL is the Events API v2 endpoint. Defaults to "https://events.pagerduty.com/v2/enqueue"
+	URL string
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// PagerDutyReporter triggers PagerDuty alerts for crash reports, deduplicated by panic fingerprint
+type PagerDutyReporter struct {
+	options  PagerDutyOptions
+	resolver *autoResolver
+}
+
+// NewPagerDutyReporter creates a PagerDutyReporter from the given options
+func NewPagerDutyReporter(options PagerDutyOptions) *PagerDutyReporter {
+	if options.URL == "" {
+		options.URL = "https://events.pagerduty.com/v2/enqueue"
+	}
+	if options.Severity == nil {
+		options.Severity = pagerDutySeverity
+	}
+	p := &PagerDutyReporter{options: options}
+	p.resolver = newAutoResolver(options.AutoResolveAfter, func(dedupKey string) {
+		_ = p.Resolve(context.Background(), dedupKey)
+	})
+	return p
+}
+
+// pagerDutySeverity is the default severity of PagerDuty alerts
+func pagerDutySeverity(report CrashReport) string {
+	return severityOf(report, map[Severity]string{
+		SeverityFatal:   "critical",
+		SeverityError:   "error",
+		SeverityWarning: "warning",
+		SeverityInfo:    "info",
+	}, "critical")
+}
+
+// WithPagerDuty triggers a PagerDuty alert for every recovered panic
+func WithPagerDuty(options PagerDutyOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewPagerDutyReporter(options))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (p *PagerDutyReporter) Name() string {
+	return "pagerduty"
+}
+
+// Report triggers an alert for the crash report
+func (p *PagerDutyReporter) Report(ctx context.Context, report CrashReport) error {
+	dedupKey := Fingerprint(report)
+	source := p.options.Source
+	if source == "" {
+		source = reportHost(report)
+	}
+	details := map[string]any{
+		"error": report.Error,
+		"stack": report.Stack,
+	}
+	if report.ID != "" {
+		details["crash_id"] = report.ID
+	}
+	for key, value := range report.Metadata {
+		details[key] = value
+	}
+	if len(report.Tags) > 0 {
+		details["tags"] = report.Tags
+	}
+	err := postJSON(ctx, p.options.HTTPClient, p.options.URL, map[string]any{
+		"routing_key":  p.options.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    dedupKey,
+		"payload": map[string]any{
+			"summary":        truncate("Panic: "+firstLine(report.Error), 1024),
+			"source":         source,
+			"severity":       p.options.Severity(report),
+			"timestamp":      report.Timestamp.UTC().Format(time.RFC3339),
+			"component":      p.options.Component,
+			"group":          p.options.Group,
+			"class":          report.ErrorType,
+			"custom_details": details,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	p.resolver.schedule(dedupKey)
+	return nil
+}
+
+// Resolve resolves the alert with the given deduplication key
+func (p *PagerDutyReporter) Resolve(ctx context.Context, dedupKey string) error {
+	return postJSON(ctx, p.options.HTTPClient, p.options.URL, map[string]any{
+		"routing_key":  p.options.RoutingKey,
+		"event_action": "resolve",
+		"dedup_key":    dedupKey,
+	}, nil)
+}
+
+// OpsgenieOptions configures an OpsgenieReporter
+type OpsgenieOptions struct {
+	// APIKey is the API integration key
+	APIKey string
+	// Priority maps a report to one of "P1" to "P5". Defaults to "P1" for fatal crashes and reports without
+	// a severity, "P2" for errors, "P3" for warnings and "P5" for info
+	Priority SeverityFunc
+	// Responders are the teams, users or schedules the alert is routed to, e.g. {"type": "team", "name": "ops"}
+	Responders []map[string]string
+	// Tags are added to every alert
+	Tags []string
+	// AutoResolveAfter closes the alert if the same panic has not recurred for this long. Disabled if zero
+	AutoResolveAfter time.Duration
+	// URL is the API base URL. Defaults to "https://api.opsgenie.com". Use "https://api.eu.opsgenie.com" for the EU instance
+	URL string
+	// HTTPClient is the client used to send alerts. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// OpsgenieReporter creates Opsgenie alerts for crash reports, deduplicated by panic fingerprint
+type OpsgenieReporter struct {
+	options  OpsgenieOptions
+	resolver *autoResolver
+}
+
+// NewOpsgenieReporter creates an OpsgenieReporter from the given options
+func NewOpsgenieReporter(options OpsgenieOptions) *OpsgenieReporter {
+	if options.URL == "" {
+		options.URL = "https://api.opsgenie.com"
+	}
+	if options.Priority == nil {
+		options.Priority = opsgeniePriority
+	}
+	o := &OpsgenieReporter{options: options}
+	o.resolver = newAutoResolver(options.AutoResolveAfter, func(alias string) {
+		_ = o.Resolve(context.Background(), alias)
+	})
+	return o
+}
+
+// opsgeniePriority is the default priority of Opsgenie alerts
+func opsgeniePriority(report CrashReport) string {
+	return severityOf(report, map[Severity]string{
+		SeverityFatal:   "P1",
+		SeverityError:   "P2",
+		SeverityWarning: "P3",
+		SeverityInfo:    "P5",
+	}, "P1")
+}
+
+// WithOpsgenie creates an Opsgenie alert for every recovered panic
+func WithOpsgenie(options OpsgenieOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewOpsgenieReporter(options))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (o *OpsgenieReporter) Name() string {
+	return "opsgenie"
+}
+
+// Report creates an alert for the crash report
+func (o *OpsgenieReporter) Report(ctx context.Context, report CrashReport) error {
+	alias := Fingerprint(report)
+	details := map[string]string{
+		"host": reportHost(report),
+	}
+	if report.ID != "" {
+		details["crash_id"] = report.ID
+	}
+	for key, value := range report.Metadata {
+		details[key] = value
+	}
+	tags := append(append([]string{}, o.options.Tags...), report.Tags...)
+	payload := map[string]any{
+		"message":     truncate("Panic: "+firstLine(report.Error), 130),
+		"alias":       alias,
+		"description": truncate(report.Error+"\n\n"+report.Stack, 15000),
+		"priority":    o.options.Priority(report),
+		"source":      "adfer",
+		"details":     details,
+	}
+	if len(tags) > 0 {
+		payload["tags"] = tags
+	}
+	if len(o.options.Responders) > 0 {
+		payload["responders"] = o.options.Responders
+	}
+	err := postJSON(ctx, o.options.HTTPClient, o.options.URL+"/v2/alerts", payload, o.headers())
+	if err != nil {
+		return err
+	}
+	o.resolver.schedule(alias)
+	return nil
+}
+
+// Resolve closes the alert with the given alias
+func (o *OpsgenieReporter) Resolve(ctx context.Context, alias string) error {
+	endpoint := o.options.URL + "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+	return postJSON(ctx, o.options.HTTPClient, endpoint, map[string]string{
+		"source": "adfer",
+		"note":   "Auto-resolved: panic has not recurred",
+	}, o.headers())
+}
+
+func (o *OpsgenieReporter) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.options.APIKey}
+}
+
+// autoResolver resolves alerts after a quiet period. Each recurrence restarts the period
+type autoResolver struct {
+	after   time.Duration
+	resolve func(key string)
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newAutoResolver(after time.Duration, resolve func(key string)) *autoResolver {
+	return &autoResolver{
+		after:   after,
+		resolve: resolve,
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// schedule (re)starts the quiet period for the given key
+func (a *autoResolver) schedule(key string) {
+	if a.after <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if timer, ok := a.timers[key]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(a.after, func() {
+		a.mu.Lock()
+		current := a.timers[key] == timer
+		if current {
+			delete(a.timers, key)
+		}
+		a.mu.Unlock()
+		if current {
+			a.resolve(key)
+		}
+	})
+	a.timers[key] = timer
+}
+
+// truncate shortens s to at most max bytes, cutting on a rune boundary
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - 3
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,162 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	Path    string
+	Query   string
+	Headers http.Header
+	Body    map[string]any
+}
+
+// newRecordingServer returns a server that records the JSON body of every request
+func newRecordingServer(t *testing.T) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Headers: r.Header, Body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest{}, requests...)
+	}
+}
+
+func TestPagerDutyReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewPagerDutyReporter(PagerDutyOptions{
+		RoutingKey: "routing",
+		URL:        server.URL,
+		Severity: func(r CrashReport) string {
+			if strings.Contains(r.Error, "minor") {
+				return "warning"
+			}
+			return "critical"
+		},
+		AutoResolveAfter: 50 * time.Millisecond,
+	})
+
+	report := CrashReport{Timestamp: time.Now(), Error: "minor problem", ErrorType: "string", Stack: testStack}
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Same panic again should reuse the dedup key
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(requests()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("Expected 2 triggers and 1 resolve, got %d requests", len(got))
+	}
+	trigger := got[0].Body
+	payload := trigger["payload"].(map[string]any)
+	if trigger["event_action"] != "trigger" || trigger["routing_key"] != "routing" {
+		t.Errorf("Unexpected trigger: %+v", trigger)
+	}
+	if payload["severity"] != "warning" || payload["summary"] != "Panic: minor problem" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+	if got[1].Body["dedup_key"] != trigger["dedup_key"] {
+		t.Error("Expected identical panics to share a dedup key")
+	}
+	if got[2].Body["event_action"] != "resolve" || got[2].Body["dedup_key"] != trigger["dedup_key"] {
+		t.Errorf("Expected auto-resolve, got %+v", got[2].Body)
+	}
+
+	// The default severity is the report's
+	severity := NewPagerDutyReporter(PagerDutyOptions{}).options.Severity
+	for _, tc := range []struct {
+		report   CrashReport
+		expected string
+	}{
+		{CrashReport{}, "critical"},
+		{CrashReport{Severity: SeverityFatal}, "critical"},
+		{CrashReport{Severity: SeverityError, Handled: true}, "error"},
+		{CrashReport{Severity: SeverityWarning}, "warning"},
+		{CrashReport{Severity: SeverityInfo}, "info"},
+	} {
+		if got := severity(tc.report); got != tc.expected {
+			t.Errorf("Expected severity %s for %q, got %s", tc.expected, tc.report.Severity, got)
+		}
+	}
+}
+
+func TestOpsgenieReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewOpsgenieReporter(OpsgenieOptions{
+		APIKey: "key",
+		URL:    server.URL,
+		Tags:   []string{"backend"},
+	})
+
+	report := CrashReport{Timestamp: time.Now(), Error: "boom", Stack: testStack, Tags: []string{"queue=email"}}
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := reporter.Resolve(context.Background(), Fingerprint(report)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(got))
+	}
+	if got[0].Path != "/v2/alerts" || got[0].Headers.Get("Authorization") != "GenieKey key" {
+		t.Errorf("Unexpected alert request: %+v", got[0])
+	}
+	if got[0].Body["priority"] != "P1" || got[0].Body["alias"] != Fingerprint(report) {
+		t.Errorf("Unexpected alert body: %+v", got[0].Body)
+	}
+	tags := got[0].Body["tags"].([]any)
+	if len(tags) != 2 || tags[0] != "backend" || tags[1] != "queue=email" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if got[1].Path != "/v2/alerts/"+Fingerprint(report)+"/close" || got[1].Query != "identifierType=alias" {
+		t.Errorf("Unexpected close request: %+v", got[1])
+	}
+
+	report.Severity = SeverityWarning
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if priority := requests()[2].Body["priority"]; priority != "P3" {
+		t.Errorf("Expected priority P3 for a warning, got %v", priority)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := CrashReport{ErrorType: "string", Error: "a", Stack: testStack}
+	b := CrashReport{ErrorType: "string", Error: "b", Stack: strings.Replace(testStack, "main.go:5", "main.go:6", 1)}
+	c := CrashReport{ErrorType: "*errors.errorString", Error: "a", Stack: testStack}
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Error("Expected fingerprint to ignore error message and line numbers")
+	}
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Error("Expected fingerprint to depend on error type")
+	}
+	if Fingerprint(CrashReport{Error: "x"}) == Fingerprint(CrashReport{Error: "y"}) {
+		t.Error("Expected fingerprint without stack to depend on error message")
+	}
+}
@@ -0,0 +1,183 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when a crash report is dropped because the delivery queue is full
+var ErrQueueFull = errors.New("delivery queue is full, report dropped")
+
+// AsyncOptions configures background delivery of crash reports
+type AsyncOptions struct {
+	// QueueSize is the number of crash reports waiting for delivery before new reports are dropped. Defaults to 100
+	QueueSize int
+	// ExitTimeout is how long to wait for pending deliveries before the program exits
+	// or re-panics because of a policy. Defaults to 5 seconds
+	ExitTimeout time.Duration
+}
+
+// WithAsync delivers crash reports to the reporters on a background worker, so recovering
+// from a panic doesn't wait for slow remote services. The error handler and crash file are
+// still written synchronously. Call Flush or Close before the program exits
+func WithAsync(options AsyncOptions) Option {
+	return func(o *Options) {
+		o.Async = &options
+	}
+}
+
+// deliveryJob is a crash report waiting for delivery
+type deliveryJob struct {
+	report  CrashReport
+	tracked bool
+	// err is the error storing the report, passed to the AfterReport hooks
+	err error
+}
+
+// pipeline delivers crash reports on a background worker
+type pipeline struct {
+	jobs    chan deliveryJob
+	stopped chan struct{}
+
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{}
+	closed  bool
+}
+
+// startPipeline starts the background worker if asynchronous delivery is enabled
+func (ph *PanicHandler) startPipeline() {
+	options := ph.options.Async
+	if options == nil {
+		return
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 100
+	}
+	if options.ExitTimeout <= 0 {
+		options.ExitTimeout = 5 * time.Second
+	}
+	ph.pipeline = &pipeline{
+		jobs:    make(chan deliveryJob, options.QueueSize),
+		stopped: make(chan struct{}),
+	}
+	go ph.runPipeline()
+}
+
+// runPipeline delivers queued crash reports until the pipeline is closed
+func (ph *PanicHandler) runPipeline() {
+	p := ph.pipeline
+	defer close(p.stopped)
+	for job := range p.jobs {
+		ph.completeDelivery(job.tracked, job.report, ph.deliverAll(context.Background(), job.report), job.err)
+		p.done()
+	}
+}
+
+// enqueue queues a crash report for delivery. It returns false if the pipeline is closed,
+// in which case the report should be delivered synchronously, or ErrQueueFull
+func (p *pipeline) enqueue(job deliveryJob) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false, nil
+	}
+	select {
+	case p.jobs <- job:
+	default:
+		return true, ErrQueueFull
+	}
+	if p.pending == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.pending++
+	return true, nil
+}
+
+// done marks a queued crash report as delivered
+func (p *pipeline) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending--
+	if p.pending == 0 {
+		close(p.idle)
+	}
+}
+
+// wait blocks until every queued crash report has been delivered, or ctx is done
+func (p *pipeline) wait(ctx context.Context) error {
+	p.mu.Lock()
+	if p.pending == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush waits until every queued crash report has been delivered, or ctx is done.
+// It returns immediately if asynchronous delivery is not enabled
+func (ph *PanicHandler) Flush(ctx context.Context) error {
+	if ph.pipeline == nil {
+		return nil
+	}
+	return ph.pipeline.wait(ctx)
+}
+
+// Close cancels the contexts of the goroutines started with SafeGoCtx, delivers every queued crash report,
+// stops the background worker, the spool retry timer, trace capture and the statsd connection. Crash reports
+// handled after Close are delivered synchronously
+func (ph *PanicHandler) Close() error {
+	ph.contexts.cancelAll()
+	ph.StopTraceCapture()
+	ph.stopSpool()
+	if ph.statsd != nil {
+		defer ph.statsd.close()
+	}
+	p := ph.pipeline
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	<-p.stopped
+	return nil
+}
+
+// flusher is implemented by reporters that queue reports, such as Uploader and ClickHouseWriter
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// flushBeforeExit waits for queued deliveries and flushes reporters that queue reports,
+// up to the configured exit timeout
+func (ph *PanicHandler) flushBeforeExit() {
+	timeout := 5 * time.Second
+	if ph.options.Async != nil && ph.options.Async.ExitTimeout > 0 {
+		timeout = ph.options.Async.ExitTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := ph.Flush(ctx); err != nil {
+		ph.diagnose(OpReport, "", err)
+	}
+	for i, reporter := range ph.options.Reporters {
+		if f, ok := reporter.(flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				ph.diagnose(OpReport, ph.reporterNames[i], err)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// blockingReporter blocks every report until release is closed
+type blockingReporter struct {
+	release  chan struct{}
+	received chan CrashReport
+}
+
+func newBlockingReporter() *blockingReporter {
+	return &blockingReporter{
+		release:  make(chan struct{}),
+		received: make(chan CrashReport, 10),
+	}
+}
+
+func (b *blockingReporter) Name() string {
+	return "blocking"
+}
+
+func (b *blockingReporter) Report(_ context.Context, report CrashReport) error {
+	<-b.release
+	b.received <- report
+	return nil
+}
+
+func TestAsyncDelivery(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "crash_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	reporter := newBlockingReporter()
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     tempFile.Name(),
+		WipeFile:     true,
+	}, WithReporter(reporter), WithAsync(AsyncOptions{}))
+	defer ph.Close()
+
+	func() {
+		defer ph.Recover()
+		panic("async panic")
+	}()
+
+	// Recover returned while the reporter is still blocked
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports[0].Deliveries["blocking"].Status != DeliveryPending {
+		t.Errorf("Expected pending delivery, got %+v", reports[0].Deliveries)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ph.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected flush to time out, got %v", err)
+	}
+
+	close(reporter.release)
+	if err := ph.Flush(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reporter.received) != 1 {
+		t.Error("Expected the report to be delivered")
+	}
+	reports, _ = ph.GetLastNCrashReports(1)
+	if reports[0].Deliveries["blocking"].Status != DeliverySent {
+		t.Errorf("Expected sent delivery, got %+v", reports[0].Deliveries)
+	}
+}
+
+func TestAsyncQueueFull(t *testing.T) {
+	reporter := newBlockingReporter()
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		},
+	}, WithReporter(reporter), WithAsync(AsyncOptions{QueueSize: 1}))
+
+	// The first report is taken by the worker, the second fills the queue
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic("queued panic")
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(diagnostics) != 1 || !errors.Is(diagnostics[0], ErrQueueFull) {
+		t.Errorf("Expected a queue full diagnostic, got %v", diagnostics)
+	}
+	close(reporter.release)
+	if err := ph.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reporter.received) != 2 {
+		t.Errorf("Expected 2 delivered reports, got %d", len(reporter.received))
+	}
+
+	// Reports handled after Close are delivered synchronously
+	func() {
+		defer ph.Recover()
+		panic("closed panic")
+	}()
+	if len(reporter.received) != 3 {
+		t.Errorf("Expected 3 delivered reports, got %d", len(reporter.received))
+	}
+}
+
+func TestAsyncFlushBeforeExit(t *testing.T) {
+	reporter := newBlockingReporter()
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		ExitOnPanic:  true,
+	}, WithReporter(reporter), WithAsync(AsyncOptions{}), WithExitFunc(func(int) {
+		if len(reporter.received) != 1 {
+			t.Error("Expected pending reports to be delivered before exiting")
+		}
+	}))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(reporter.release)
+	}()
+	func() {
+		defer ph.Recover()
+		panic("exit panic")
+	}()
+	ph.Close()
+}
@@ -0,0 +1,180 @@
+package adfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotReports is the name of the file holding the reports of a snapshot
+const snapshotReports = "reports.json"
+
+// snapshotManifest is the name of the manifest file of a snapshot
+const snapshotManifest = "manifest.json"
+
+// ErrSnapshotCorrupt is returned by Restore when a snapshot doesn't match its manifest
+var ErrSnapshotCorrupt = errors.New("snapshot doesn't match its manifest")
+
+// SnapshotManifest describes the contents of a snapshot
+type SnapshotManifest struct {
+	// CreatedAt is the time the snapshot was taken
+	CreatedAt time.Time `json:"created_at"`
+	// Count is the number of crash reports in the snapshot
+	Count int `json:"count"`
+	// Checksums holds the SHA-256 checksum of each file of the snapshot
+	Checksums map[string]string `json:"checksums"`
+}
+
+// WithWipeSnapshots takes a snapshot of the crash reports in a new timestamped directory
+// inside dir before they are wiped, so an accidental wipe can be undone with Restore
+func WithWipeSnapshots(dir string) Option {
+	return func(o *Options) {
+		o.SnapshotDir = dir
+	}
+}
+
+// Snapshot copies every crash report to dir, with a manifest holding checksums. The snapshot is
+// written to a temporary directory that is renamed to dir, so dir is either complete or missing.
+// It fails if dir already exists
+func (s *reportStore) Snapshot(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("snapshot %s: %w", dir, fs.ErrExist)
+	}
+	reports, err := s.readCrashReports()
+	if err != nil {
+		return err
+	}
+	return writeSnapshot(dir, reports, s.perms)
+}
+
+// writeSnapshot writes reports and their manifest to a temporary directory and renames it to dir
+func writeSnapshot(dir string, reports []CrashReport, perms filePerms) error {
+	if reports == nil {
+		reports = []CrashReport{}
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	manifest, err := json.MarshalIndent(SnapshotManifest{
+		CreatedAt: time.Now(),
+		Count:     len(reports),
+		Checksums: map[string]string{snapshotReports: hex.EncodeToString(sum[:])},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := perms.mkdirAll(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	if err := perms.apply(tmp, perms.dir); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	for name, contents := range map[string][]byte{snapshotReports: data, snapshotManifest: manifest} {
+		if err := writeSynced(filepath.Join(tmp, name), contents, perms); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeSynced writes a file and flushes it to disk
+func writeSynced(path string, data []byte, perms filePerms) error {
+	file, err := perms.create(path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadSnapshot reads the crash reports of a snapshot, returning an error wrapping
+// ErrSnapshotCorrupt if they don't match the manifest
+func ReadSnapshot(dir string) ([]CrashReport, *SnapshotManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotManifest))
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, snapshotReports))
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.Checksums[snapshotReports] {
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+	var reports []CrashReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if len(reports) != manifest.Count {
+		return nil, nil, fmt.Errorf("%w: %d reports, expected %d", ErrSnapshotCorrupt, len(reports), manifest.Count)
+	}
+	return reports, &manifest, nil
+}
+
+// Restore replaces the stored crash reports with those of a snapshot taken by Snapshot.
+// The snapshot is verified against its manifest before anything is wiped
+func (ph *PanicHandler) Restore(dir string) error {
+	reports, _, err := ReadSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	if ph.storage == nil {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	if err := ph.storage.Wipe(); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if err := ph.storage.Append(report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotBeforeWipe takes a snapshot in a new directory of Options.SnapshotDir, if set.
+// Nothing is taken if there are no reports
+func (ph *PanicHandler) snapshotBeforeWipe() error {
+	if ph.options.SnapshotDir == "" {
+		return nil
+	}
+	reports, err := ph.readCrashReports()
+	if errors.Is(err, fs.ErrNotExist) || err == nil && len(reports) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(ph.options.SnapshotDir, time.Now().UTC().Format("20060102T150405.000000000Z"))
+	return writeSnapshot(dir, reports, ph.perms)
+}
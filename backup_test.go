@@ -0,0 +1,89 @@
+package adfer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(dir, "crash.json"),
+	})
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	snapshot := filepath.Join(dir, "snapshots", "before")
+	if err := ph.Snapshot(snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ph.Snapshot(snapshot); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected an existing snapshot not to be overwritten, got %v", err)
+	}
+	reports, manifest, err := ReadSnapshot(snapshot)
+	if err != nil || len(reports) != 3 || manifest.Count != 3 {
+		t.Fatalf("Unexpected snapshot %+v, %+v, error %v", reports, manifest, err)
+	}
+
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ph.Restore(snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	restored, _ := ph.GetLastNCrashReports(10)
+	if len(restored) != 3 || restored[0].Error != "0" || restored[2].ID != reports[2].ID {
+		t.Errorf("Expected the snapshot to be restored, got %+v", restored)
+	}
+
+	// A corrupted snapshot is not restored
+	os.WriteFile(filepath.Join(snapshot, snapshotReports), []byte("[]"), 0644)
+	if err := ph.Restore(snapshot); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("Expected ErrSnapshotCorrupt, got %v", err)
+	}
+	if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 3 {
+		t.Errorf("Expected the reports to be kept, got %d", len(reports))
+	}
+}
+
+func TestWipeSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	snapshots := filepath.Join(dir, "snapshots")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(dir, "crash.json"),
+	}, WithWipeSnapshots(snapshots))
+
+	// Nothing to snapshot
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, _ := os.ReadDir(snapshots)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(entries))
+	}
+	if err := ph.Restore(filepath.Join(snapshots, entries[0].Name())); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 1 || reports[0].Error != "boom" {
+		t.Errorf("Expected the wiped report to be restored, got %+v", reports)
+	}
+}
@@ -0,0 +1,197 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker while it is rejecting reports
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed passes every report through to the reporter
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects every report without calling the reporter
+	CircuitOpen
+	// CircuitHalfOpen lets probe reports through to test if the reporter has recovered
+	CircuitHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit. Defaults to 5
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before allowing a probe. Defaults to 30 seconds
+	OpenDuration time.Duration
+	// SuccessThreshold is the number of successful probes that closes the circuit again. Defaults to 1
+	SuccessThreshold int
+	// OnStateChange is called whenever the circuit changes state, without the circuit's lock held
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker wraps a Reporter and stops calling it after repeated failures,
+// so a dead endpoint doesn't add latency to every recovered panic
+type CircuitBreaker struct {
+	reporter Reporter
+	options  CircuitBreakerOptions
+	now      func() time.Time
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+	changes   []stateChange
+}
+
+// stateChange is a state transition of a CircuitBreaker
+type stateChange struct {
+	from, to CircuitState
+}
+
+// NewCircuitBreaker wraps the given reporter in a circuit breaker
+func NewCircuitBreaker(reporter Reporter, options CircuitBreakerOptions) *CircuitBreaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 5
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = 30 * time.Second
+	}
+	if options.SuccessThreshold <= 0 {
+		options.SuccessThreshold = 1
+	}
+	return &CircuitBreaker{
+		reporter: reporter,
+		options:  options,
+		now:      time.Now,
+	}
+}
+
+// State returns the current state of the circuit
+func (c *CircuitBreaker) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == CircuitOpen && c.now().Sub(c.openedAt) >= c.options.OpenDuration {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Unwrap returns the wrapped reporter
+func (c *CircuitBreaker) Unwrap() Reporter {
+	return c.reporter
+}
+
+// Name returns the name of the wrapped reporter
+func (c *CircuitBreaker) Name() string {
+	return reporterName(c.reporter)
+}
+
+// Report passes the report to the wrapped reporter unless the circuit is open. A reporter that
+// panics counts as a failure
+func (c *CircuitBreaker) Report(ctx context.Context, report CrashReport) error {
+	if !c.allow() {
+		return ErrCircuitOpen
+	}
+	success := false
+	// Deferred so a panicking probe doesn't leave the circuit half-open
+	defer func() { c.record(success) }()
+	err := c.reporter.Report(ctx, report)
+	success = err == nil
+	return err
+}
+
+// allow decides whether a report may be passed to the reporter
+func (c *CircuitBreaker) allow() bool {
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case CircuitOpen:
+		if c.now().Sub(c.openedAt) < c.options.OpenDuration {
+			return false
+		}
+		c.setState(CircuitHalfOpen)
+		c.probing = true
+		return true
+	case CircuitHalfOpen:
+		// Only one probe at a time
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the circuit with the outcome of a report
+func (c *CircuitBreaker) record(success bool) {
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probing = false
+	if success {
+		c.failures = 0
+		if c.state == CircuitHalfOpen {
+			c.successes++
+			if c.successes >= c.options.SuccessThreshold {
+				c.setState(CircuitClosed)
+			}
+		}
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= c.options.FailureThreshold {
+		c.openedAt = c.now()
+		c.setState(CircuitOpen)
+	}
+}
+
+// setState changes the state, resetting counters, and queues the transition for notify. Must be
+// called with the lock held
+func (c *CircuitBreaker) setState(state CircuitState) {
+	if c.state == state {
+		return
+	}
+	from := c.state
+	c.state = state
+	c.successes = 0
+	if state == CircuitClosed {
+		c.failures = 0
+	}
+	if c.options.OnStateChange != nil {
+		c.changes = append(c.changes, stateChange{from: from, to: state})
+	}
+}
+
+// notify calls OnStateChange with the transitions queued while the lock was held
+func (c *CircuitBreaker) notify() {
+	c.mu.Lock()
+	changes := c.changes
+	c.changes = nil
+	c.mu.Unlock()
+	for _, change := range changes {
+		c.options.OnStateChange(change.from, change.to)
+	}
+}
@@ -0,0 +1,166 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type countingReporter struct {
+	calls int
+	err   error
+}
+
+func (c *countingReporter) Report(context.Context, CrashReport) error {
+	c.calls++
+	return c.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	reporter := &countingReporter{err: errors.New("down")}
+	now := time.Now()
+	var transitions []string
+	breaker := NewCircuitBreaker(reporter, CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenDuration:     time.Minute,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.Report(ctx, CrashReport{}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected reporter error, got %v", err)
+		}
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected open circuit, got %s", breaker.State())
+	}
+
+	if err := breaker.Report(ctx, CrashReport{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if reporter.calls != 2 {
+		t.Errorf("Expected reporter to be skipped while open, got %d calls", reporter.calls)
+	}
+
+	// Failed probe re-opens the circuit
+	now = now.Add(time.Minute)
+	if breaker.State() != CircuitHalfOpen {
+		t.Errorf("Expected half-open circuit, got %s", breaker.State())
+	}
+	_ = breaker.Report(ctx, CrashReport{})
+	if reporter.calls != 3 || breaker.State() != CircuitOpen {
+		t.Errorf("Expected failed probe to re-open circuit, calls=%d state=%s", reporter.calls, breaker.State())
+	}
+
+	// Successful probe closes the circuit
+	now = now.Add(time.Minute)
+	reporter.err = nil
+	if err := breaker.Report(ctx, CrashReport{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected closed circuit, got %s", breaker.State())
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transitions %v, got %v", expected, transitions)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerPanickingProbe(t *testing.T) {
+	panics := true
+	reporter := ReporterFunc(func(context.Context, CrashReport) error {
+		if panics {
+			panic("broken reporter")
+		}
+		return nil
+	})
+	now := time.Now()
+	breaker := NewCircuitBreaker(reporter, CircuitBreakerOptions{FailureThreshold: 1, OpenDuration: time.Minute})
+	breaker.now = func() time.Time { return now }
+	report := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panicked: %v", p)
+			}
+		}()
+		return breaker.Report(context.Background(), CrashReport{})
+	}
+
+	report()
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected a panic to open the circuit, got %s", breaker.State())
+	}
+	now = now.Add(time.Minute)
+	if err := report(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the probe to panic, got %v", err)
+	}
+	if breaker.State() != CircuitOpen {
+		t.Errorf("Expected the panicking probe to re-open the circuit, got %s", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	panics = false
+	if err := report(); err != nil || breaker.State() != CircuitClosed {
+		t.Errorf("Expected the next probe to close the circuit, got %v, state %s", err, breaker.State())
+	}
+}
+
+func TestCircuitBreakerStateChangeUnlocked(t *testing.T) {
+	var breaker *CircuitBreaker
+	var states []CircuitState
+	breaker = NewCircuitBreaker(&countingReporter{err: errors.New("down")}, CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OnStateChange: func(from, to CircuitState) {
+			// The circuit is unlocked, so the callback can inspect it
+			states = append(states, breaker.State())
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		breaker.Report(context.Background(), CrashReport{})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected OnStateChange to be called without the lock held")
+	}
+	if len(states) != 1 || states[0] != CircuitOpen {
+		t.Errorf("Expected the circuit to be open in the callback, got %v", states)
+	}
+}
+
+func TestCircuitBreakerOption(t *testing.T) {
+	reporter := &countingReporter{err: errors.New("down")}
+	ph := New(Options{
+		ErrorHandler:   func(error, []byte) {},
+		Reporters:      []Reporter{reporter},
+		CircuitBreaker: &CircuitBreakerOptions{FailureThreshold: 1},
+		OnDiagnostic:   func(Diagnostic) {},
+	})
+
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic("test panic")
+		}()
+	}
+	if reporter.calls != 1 {
+		t.Errorf("Expected 1 call before the circuit opened, got %d", reporter.calls)
+	}
+}
@@ -0,0 +1,73 @@
+package adfer
+
+import (
+	"sync"
+	"time"
+)
+
+// Enrichment steps that can be skipped to stay within the performance budget
+const (
+	// EnrichSystemInfo adds system information to the report
+	EnrichSystemInfo = "system_info"
+	// EnrichConfig adds the flag and config snapshots to the report
+	EnrichConfig = "config"
+	// EnrichTrace writes the execution trace captured with the report
+	EnrichTrace = "trace"
+)
+
+// WithPerformanceBudget limits the time spent creating a crash report. Optional enrichment,
+// such as system information and execution traces, is skipped when the time spent so far plus
+// the step's last observed duration would exceed max. Skipped steps are listed in CrashReport.Skipped
+func WithPerformanceBudget(max time.Duration) Option {
+	return func(o *Options) {
+		o.PerformanceBudget = max
+	}
+}
+
+// budget tracks the observed duration of enrichment steps
+type budget struct {
+	max time.Duration
+
+	mu        sync.Mutex
+	estimates map[string]time.Duration
+}
+
+// newBudget creates a budget, or returns nil if max is not positive
+func newBudget(max time.Duration) *budget {
+	if max <= 0 {
+		return nil
+	}
+	return &budget{
+		max:       max,
+		estimates: make(map[string]time.Duration),
+	}
+}
+
+// enrich runs an optional enrichment step, unless it is expected to exceed the budget
+// of a report started at start. Skipped steps are recorded in the report
+func (ph *PanicHandler) enrich(report *CrashReport, start time.Time, step string, fn func()) {
+	b := ph.budget
+	if b == nil {
+		fn()
+		return
+	}
+	b.mu.Lock()
+	estimate := b.estimates[step]
+	b.mu.Unlock()
+	if time.Since(start)+estimate > b.max {
+		report.Skipped = append(report.Skipped, step)
+		return
+	}
+
+	stepStart := time.Now()
+	fn()
+	elapsed := time.Since(stepStart)
+
+	b.mu.Lock()
+	// Weight the latest observation, so estimates follow changes such as a slow DNS server
+	if previous, ok := b.estimates[step]; ok {
+		elapsed = (previous + elapsed) / 2
+	}
+	b.estimates[step] = elapsed
+	b.mu.Unlock()
+}
@@ -0,0 +1,99 @@
+package adfer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPerformanceBudget(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler:      func(error, []byte) {},
+		IncludeSystemInfo: true,
+	}, WithReporter(channelReporter(reports)), WithPerformanceBudget(time.Second))
+
+	crash := func() CrashReport {
+		func() {
+			defer ph.Recover()
+			panic("budget panic")
+		}()
+		return <-reports
+	}
+
+	report := crash()
+	if len(report.Skipped) != 0 || report.SystemInfo.OS == "" {
+		t.Fatalf("Expected nothing to be skipped within the budget, got %v", report.Skipped)
+	}
+	if _, ok := ph.budget.estimates[EnrichSystemInfo]; !ok {
+		t.Error("Expected the duration of the step to be recorded")
+	}
+
+	// A step expected to take longer than the budget is skipped
+	ph.budget.estimates[EnrichSystemInfo] = time.Hour
+	report = crash()
+	if len(report.Skipped) != 1 || report.Skipped[0] != EnrichSystemInfo || report.SystemInfo.OS != "" {
+		t.Errorf("Expected system info to be skipped, got %v", report.Skipped)
+	}
+}
+
+func benchmarkRecover(b *testing.B, options Options, opts ...Option) {
+	options.ErrorHandler = func(error, []byte) {}
+	ph := New(options, opts...)
+	b.Cleanup(func() { ph.Close() })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		func() {
+			defer ph.Recover()
+			panic("benchmark panic")
+		}()
+	}
+}
+
+func BenchmarkRecover(b *testing.B) {
+	benchmarkRecover(b, Options{})
+}
+
+func BenchmarkRecoverSystemInfo(b *testing.B) {
+	benchmarkRecover(b, Options{IncludeSystemInfo: true})
+}
+
+func BenchmarkRecoverMetadataTemplates(b *testing.B) {
+	benchmarkRecover(b, Options{Metadata: map[string]string{
+		"host":   "{{.Hostname}}",
+		"report": "{{.ReportID}}",
+	}})
+}
+
+func BenchmarkRecoverReporters(b *testing.B) {
+	benchmarkRecover(b, Options{}, WithReporters(&countingReporter{}, &countingReporter{}))
+}
+
+func BenchmarkRecoverAsync(b *testing.B) {
+	benchmarkRecover(b, Options{}, WithReporter(&countingReporter{}), WithAsync(AsyncOptions{QueueSize: 1 << 20}))
+}
+
+func BenchmarkRecoverToFile(b *testing.B) {
+	// The whole file is rewritten for each report, so keep it small
+	filePath := filepath.Join(b.TempDir(), "crash.json")
+	options := Options{DumpToFile: true, FilePath: filePath, ErrorHandler: func(error, []byte) {}}
+	ph := New(options)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%100 == 0 {
+			b.StopTimer()
+			ph.WipeCrashFile()
+			b.StartTimer()
+		}
+		func() {
+			defer ph.Recover()
+			panic("benchmark panic")
+		}()
+	}
+}
+
+func BenchmarkRecoverBudget(b *testing.B) {
+	benchmarkRecover(b, Options{IncludeSystemInfo: true}, WithPerformanceBudget(time.Millisecond))
+}
@@ -0,0 +1,150 @@
+package adfer
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// BugsnagOptions configures a BugsnagReporter
+type BugsnagOptions struct {
+	// APIKey is the project's notifier API key
+	APIKey string
+	// ReleaseStage is the stage of the release process, e.g. "production". Defaults to "production"
+	ReleaseStage string
+	// AppVersion is the version of the program
+	AppVersion string
+	// URL is the notify endpoint. Defaults to "https://notify.bugsnag.com"
+	URL string
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// BugsnagReporter sends crash reports to Bugsnag using the Error Reporting API
+type BugsnagReporter struct {
+	options BugsnagOptions
+}
+
+// NewBugsnagReporter creates a BugsnagReporter from the given options
+func NewBugsnagReporter(options BugsnagOptions) *BugsnagReporter {
+	if options.ReleaseStage == "" {
+		options.ReleaseStage = "production"
+	}
+	if options.URL == "" {
+		options.URL = "https://notify.bugsnag.com"
+	}
+	return &BugsnagReporter{options: options}
+}
+
+// WithBugsnag sends every crash report to Bugsnag
+func WithBugsnag(options BugsnagOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewBugsnagReporter(options))
+	}
+}
+
+// Payload converts a crash report into a Bugsnag payload containing a single event
+func (b *BugsnagReporter) Payload(report CrashReport) map[string]any {
+	errorClass := report.ErrorType
+	if errorClass == "" {
+		errorClass = "panic"
+	}
+	frames := ParseStack(report.Stack)
+	stacktrace := make([]map[string]any, len(frames))
+	for i, frame := range frames {
+		stacktrace[i] = map[string]any{
+			"file":       frame.File,
+			"lineNumber": frame.Line,
+			"method":     frame.Function,
+			"inProject":  !frame.isRuntime(),
+		}
+	}
+
+	severityReason := "unhandledPanic"
+	fallback := "error"
+	if report.Handled {
+		severityReason = "handledError"
+		fallback = "warning"
+	}
+	severity := severityOf(report, map[Severity]string{
+		SeverityFatal:   "error",
+		SeverityError:   "error",
+		SeverityWarning: "warning",
+		SeverityInfo:    "info",
+	}, fallback)
+
+	device := map[string]any{
+		"hostname": reportHost(report),
+		"time":     report.Timestamp.UTC().Format(time.RFC3339),
+	}
+	if report.SystemInfo.OS != "" {
+		device["osName"] = report.SystemInfo.OS
+		device["runtimeVersions"] = map[string]string{"go": report.SystemInfo.GoVersion}
+	}
+
+	metadata := map[string]any{}
+	if len(report.Metadata) > 0 {
+		custom := map[string]any{}
+		for key, value := range report.Metadata {
+			custom[key] = value
+		}
+		metadata["custom"] = custom
+	}
+	crash := map[string]any{}
+	if report.ID != "" {
+		crash["id"] = report.ID
+	}
+	if len(report.Tags) > 0 {
+		crash["tags"] = report.Tags
+	}
+	if report.Category != "" {
+		crash["category"] = report.Category
+	}
+	if len(crash) > 0 {
+		metadata["crash"] = crash
+	}
+
+	app := map[string]any{"releaseStage": b.options.ReleaseStage}
+	if b.options.AppVersion != "" {
+		app["version"] = b.options.AppVersion
+	}
+
+	return map[string]any{
+		"apiKey":         b.options.APIKey,
+		"payloadVersion": "5",
+		"notifier": map[string]any{
+			"name":    "adfer",
+			"version": "1.0.0",
+			"url":     "https://github.com/leaanthony/adfer",
+		},
+		"events": []map[string]any{{
+			"exceptions": []map[string]any{{
+				"errorClass": errorClass,
+				"message":    report.Error,
+				"stacktrace": stacktrace,
+				"type":       "go",
+			}},
+			"severity":       severity,
+			"unhandled":      !report.Handled,
+			"severityReason": map[string]any{"type": severityReason},
+			"groupingHash":   Fingerprint(report),
+			"app":            app,
+			"device":         device,
+			"metaData":       metadata,
+		}},
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (b *BugsnagReporter) Name() string {
+	return "bugsnag"
+}
+
+// Report sends the crash report to Bugsnag
+func (b *BugsnagReporter) Report(ctx context.Context, report CrashReport) error {
+	return postJSON(ctx, b.options.HTTPClient, b.options.URL, b.Payload(report), map[string]string{
+		"Bugsnag-Api-Key":         b.options.APIKey,
+		"Bugsnag-Payload-Version": "5",
+		"Bugsnag-Sent-At":         time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,66 @@
+package adfer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBugsnagReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewBugsnagReporter(BugsnagOptions{
+		APIKey:     "key",
+		AppVersion: "1.2.3",
+		URL:        server.URL,
+	})
+	report := CrashReport{
+		ID:       "crash-1",
+		Error:    "nil map",
+		Stack:    testStack,
+		Metadata: map[string]string{"region": "eu"},
+		Tags:     []string{"queue=email"},
+	}
+
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	request := requests()[0]
+	if request.Headers.Get("Bugsnag-Api-Key") != "key" || request.Headers.Get("Bugsnag-Payload-Version") != "5" {
+		t.Errorf("Unexpected headers: %v", request.Headers)
+	}
+	event := request.Body["events"].([]any)[0].(map[string]any)
+	if event["unhandled"] != true || event["severity"] != "error" {
+		t.Errorf("Unexpected severity: %v", event)
+	}
+	if event["severityReason"].(map[string]any)["type"] != "unhandledPanic" {
+		t.Errorf("Unexpected severity reason: %v", event["severityReason"])
+	}
+	exception := event["exceptions"].([]any)[0].(map[string]any)
+	if exception["errorClass"] != "panic" || exception["message"] != "nil map" {
+		t.Errorf("Unexpected exception: %v", exception)
+	}
+	stacktrace := exception["stacktrace"].([]any)
+	first := stacktrace[0].(map[string]any)
+	inner := stacktrace[1].(map[string]any)
+	if first["method"] != "runtime/debug.Stack" || first["inProject"] != false {
+		t.Errorf("Expected the innermost runtime frame first, got %v", first)
+	}
+	if inner["method"] != "main.inner" || inner["inProject"] != true || inner["lineNumber"] != float64(5) {
+		t.Errorf("Unexpected frame: %v", inner)
+	}
+	if event["app"].(map[string]any)["version"] != "1.2.3" {
+		t.Errorf("Unexpected app: %v", event["app"])
+	}
+	metadata := event["metaData"].(map[string]any)
+	if metadata["custom"].(map[string]any)["region"] != "eu" || metadata["crash"].(map[string]any)["id"] != "crash-1" {
+		t.Errorf("Unexpected metadata: %v", metadata)
+	}
+
+	handled := reporter.Payload(CrashReport{Error: "boom", Handled: true})["events"].([]map[string]any)[0]
+	if handled["unhandled"] != false || handled["severity"] != "warning" {
+		t.Errorf("Unexpected handled event: %v", handled)
+	}
+	info := reporter.Payload(CrashReport{Error: "boom", Handled: true, Severity: SeverityInfo})["events"].([]map[string]any)[0]
+	if info["severity"] != "info" {
+		t.Errorf("Expected the report's severity, got %v", info["severity"])
+	}
+}
@@ -0,0 +1,71 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultCleanupTimeout is how long each cleanup may run before the next one is started
+const defaultCleanupTimeout = 5 * time.Second
+
+// cleanup is a function registered with RegisterCleanup
+type cleanup struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// WithCleanupTimeout sets how long each cleanup registered with RegisterCleanup may run. Defaults to 5 seconds
+func WithCleanupTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.CleanupTimeout = timeout
+	}
+}
+
+// RegisterCleanup registers a function that closes a resource, such as a database connection, a file
+// or a server, when a panic makes the program exit. Cleanups run after the OnExit callbacks, in the
+// reverse order of registration like deferred calls, so resources opened first are closed last. Each
+// gets a context that is done after CleanupTimeout. Cleanups that fail, panic or time out are reported
+// as OpCleanup diagnostics with their name, and the next one is started
+func (ph *PanicHandler) RegisterCleanup(name string, fn func(ctx context.Context) error) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.cleanups = append(ph.cleanups, cleanup{name: name, fn: fn})
+}
+
+// runCleanups runs the registered cleanups, newest first
+func (ph *PanicHandler) runCleanups() {
+	ph.mu.Lock()
+	cleanups := ph.cleanups
+	ph.mu.Unlock()
+	timeout := ph.options.CleanupTimeout
+	if timeout <= 0 {
+		timeout = defaultCleanupTimeout
+	}
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if err := runCleanup(cleanups[i], timeout); err != nil {
+			ph.diagnose(OpCleanup, cleanups[i].name, err)
+		}
+	}
+}
+
+// runCleanup runs a cleanup, returning once it returns or its timeout has passed
+func runCleanup(c cleanup, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("cleanup panicked: %v", p)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("cleanup did not return within %v", timeout)
+	}
+}
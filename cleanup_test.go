@@ -0,0 +1,72 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterCleanup(t *testing.T) {
+	var order []string
+	var diagnostics []Diagnostic
+	exited := false
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		ExitOnPanic:  true,
+	}, WithCleanupTimeout(20*time.Millisecond), WithExitFunc(func(int) {
+		order = append(order, "exit")
+		exited = true
+	}))
+
+	ph.RegisterCleanup("database", func(context.Context) error {
+		order = append(order, "database")
+		return errors.New("connection busy")
+	})
+	ph.RegisterCleanup("hung", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	ph.RegisterCleanup("server", func(context.Context) error {
+		order = append(order, "server")
+		panic("server bug")
+	})
+
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	if !exited || strings.Join(order, ",") != "server,database,exit" {
+		t.Errorf("Expected the cleanups in reverse order before the exit, got %v", order)
+	}
+	names := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		if d.Op != OpCleanup {
+			t.Errorf("Expected cleanup diagnostics, got %+v", d)
+		}
+		names[i] = d.Path
+	}
+	if strings.Join(names, ",") != "server,hung,database" {
+		t.Errorf("Expected a diagnostic for each failed cleanup, got %v", names)
+	}
+}
+
+func TestCleanupsOnlyOnExit(t *testing.T) {
+	ran := false
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	ph.RegisterCleanup("database", func(context.Context) error {
+		ran = true
+		return nil
+	})
+	func() {
+		defer ph.Recover()
+		panic("absorbed")
+	}()
+	if ran {
+		t.Error("Expected cleanups not to run for absorbed panics")
+	}
+}
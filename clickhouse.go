@@ -0,0 +1,230 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClickHouseOptions configures a ClickHouseWriter
+type ClickHouseOptions struct {
+	// URL is the HTTP interface of the server, e.g. "http://localhost:8123"
+	URL string
+	// Database is the database of the table. Defaults to the user's default database
+	Database string
+	// Table is the table the rows are inserted into. Defaults to "crash_reports"
+	Table string
+	// Username and Password authenticate the inserts
+	Username string
+	Password string
+	// Row converts a crash report into the columns of a row, for tables with their own schema.
+	// Defaults to ClickHouseRow, matching ClickHouseSchema
+	Row func(report CrashReport) map[string]any
+	// BatchSize is the number of rows inserted at once. Defaults to 100
+	BatchSize int
+	// FlushInterval is the longest a row waits for its batch to fill up. Defaults to 10 seconds
+	FlushInterval time.Duration
+	// QueueSize is the number of rows kept while the server is unreachable. The oldest
+	// row is dropped when the queue is full. Defaults to 10000
+	QueueSize int
+	// HTTPClient is the client used to send inserts. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// ClickHouseWriter is a Reporter that inserts crash reports into a ClickHouse table in batches,
+// using the JSONEachRow format. Rows are inserted when a batch is full, after FlushInterval, and
+// on Flush or Close. A report counts as delivered once it has been queued
+type ClickHouseWriter struct {
+	options ClickHouseOptions
+
+	// flushMu serialises flushes, so a batch isn't inserted twice
+	flushMu sync.Mutex
+
+	mu      sync.Mutex
+	rows    [][]byte
+	dropped int
+	timer   *time.Timer
+}
+
+// NewClickHouseWriter creates a ClickHouseWriter from the given options
+func NewClickHouseWriter(options ClickHouseOptions) *ClickHouseWriter {
+	if options.Table == "" {
+		options.Table = "crash_reports"
+	}
+	if options.Row == nil {
+		options.Row = ClickHouseRow
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 10 * time.Second
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 10000
+	}
+	options.URL = strings.TrimRight(options.URL, "/")
+	return &ClickHouseWriter{options: options}
+}
+
+// WithClickHouse inserts every crash report into a ClickHouse table
+func WithClickHouse(options ClickHouseOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewClickHouseWriter(options))
+	}
+}
+
+// ClickHouseSchema returns the CREATE TABLE statement of the default table layout
+func ClickHouseSchema(table string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+    timestamp DateTime64(3, 'UTC'),
+    id String,
+    error String,
+    error_type LowCardinality(String),
+    category LowCardinality(String),
+    handled Bool,
+    fingerprint String,
+    culprit String,
+    host String,
+    os LowCardinality(String),
+    go_version LowCardinality(String),
+    tags Array(String),
+    metadata Map(String, String),
+    stack String CODEC(ZSTD)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (fingerprint, timestamp)`
+}
+
+// ClickHouseRow converts a crash report into a row of the table created with ClickHouseSchema
+func ClickHouseRow(report CrashReport) map[string]any {
+	culprit := ""
+	if app := appFrames(ParseStack(report.Stack)); len(app) > 0 {
+		culprit = app[0].Function
+	}
+	tags := report.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	metadata := report.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return map[string]any{
+		"timestamp":   report.Timestamp.UTC().Format("2006-01-02 15:04:05.000"),
+		"id":          report.ID,
+		"error":       report.Error,
+		"error_type":  report.ErrorType,
+		"category":    report.Category,
+		"handled":     report.Handled,
+		"fingerprint": Fingerprint(report),
+		"culprit":     culprit,
+		"host":        reportHost(report),
+		"os":          report.SystemInfo.OS,
+		"go_version":  report.SystemInfo.GoVersion,
+		"tags":        tags,
+		"metadata":    metadata,
+		"stack":       report.Stack,
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (c *ClickHouseWriter) Name() string {
+	return "clickhouse"
+}
+
+// Report queues the crash report, inserting the batch if it is full
+func (c *ClickHouseWriter) Report(ctx context.Context, report CrashReport) error {
+	row, err := json.Marshal(c.options.Row(report))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if len(c.rows) >= c.options.QueueSize {
+		c.rows = c.rows[1:]
+		c.dropped++
+	}
+	c.rows = append(c.rows, row)
+	full := len(c.rows) >= c.options.BatchSize
+	if !full && c.timer == nil {
+		c.timer = time.AfterFunc(c.options.FlushInterval, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), c.options.FlushInterval)
+			defer cancel()
+			_ = c.Flush(ctx)
+		})
+	}
+	c.mu.Unlock()
+	if full {
+		return c.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush inserts the queued rows in batches, stopping at the first failure. Rows that
+// couldn't be inserted stay queued for the next attempt
+func (c *ClickHouseWriter) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	for {
+		c.mu.Lock()
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+		n := len(c.rows)
+		if n > c.options.BatchSize {
+			n = c.options.BatchSize
+		}
+		batch := c.rows[:n:n]
+		dropped := c.dropped
+		c.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		if err := c.insert(ctx, batch); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		// Rows of the batch may have been dropped from a full queue in the meantime
+		if n -= c.dropped - dropped; n > 0 {
+			c.rows = c.rows[n:]
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Pending returns the number of queued rows
+func (c *ClickHouseWriter) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.rows)
+}
+
+// Close inserts the queued rows and stops the flush timer
+func (c *ClickHouseWriter) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.FlushInterval)
+	defer cancel()
+	return c.Flush(ctx)
+}
+
+// insert sends a batch of rows in a single INSERT statement
+func (c *ClickHouseWriter) insert(ctx context.Context, batch [][]byte) error {
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", c.options.Table))
+	if c.options.Database != "" {
+		query.Set("database", c.options.Database)
+	}
+	body := append(bytes.Join(batch, []byte("\n")), '\n')
+	headers := map[string]string{}
+	if c.options.Username != "" {
+		headers["X-ClickHouse-User"] = c.options.Username
+		headers["X-ClickHouse-Key"] = c.options.Password
+	}
+	return post(ctx, c.options.HTTPClient, c.options.URL+"/?"+query.Encode(), "application/x-ndjson", body, headers)
+}
@@ -0,0 +1,109 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clickHouseServer records the query and rows of every insert, failing while fail is set
+type clickHouseServer struct {
+	mu      sync.Mutex
+	fail    bool
+	queries []string
+	batches [][]map[string]any
+}
+
+func (s *clickHouseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.queries = append(s.queries, r.URL.Query().Get("query"))
+	body, _ := io.ReadAll(r.Body)
+	var rows []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		var row map[string]any
+		json.Unmarshal([]byte(line), &row)
+		rows = append(rows, row)
+	}
+	s.batches = append(s.batches, rows)
+}
+
+func TestClickHouseWriter(t *testing.T) {
+	recorder := &clickHouseServer{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	writer := NewClickHouseWriter(ClickHouseOptions{URL: server.URL, Table: "crashes", BatchSize: 2, FlushInterval: time.Hour})
+	report := CrashReport{ID: "crash-1", Error: "nil map", Stack: testStack, Timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	if err := writer.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(recorder.batches) != 0 || writer.Pending() != 1 {
+		t.Fatalf("Expected the row to be queued until the batch is full")
+	}
+	if err := writer.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(recorder.batches) != 1 || len(recorder.batches[0]) != 2 || writer.Pending() != 0 {
+		t.Fatalf("Expected a batch of 2 rows, got %v", recorder.batches)
+	}
+	if recorder.queries[0] != "INSERT INTO crashes FORMAT JSONEachRow" {
+		t.Errorf("Unexpected query: %s", recorder.queries[0])
+	}
+	row := recorder.batches[0][0]
+	if row["timestamp"] != "2024-06-01 12:00:00.000" || row["fingerprint"] != Fingerprint(report) || row["culprit"] != "main.inner" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+
+	recorder.fail = true
+	writer.Report(context.Background(), report)
+	if err := writer.Flush(context.Background()); err == nil || writer.Pending() != 1 {
+		t.Fatalf("Expected the row to stay queued after a failed insert, got %d pending, error %v", writer.Pending(), err)
+	}
+	recorder.fail = false
+	if err := writer.Close(); err != nil || writer.Pending() != 0 || len(recorder.batches) != 2 {
+		t.Errorf("Expected Close to insert the queued row, got %d pending, error %v", writer.Pending(), err)
+	}
+}
+
+func TestClickHouseFlushInterval(t *testing.T) {
+	recorder := &clickHouseServer{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	writer := NewClickHouseWriter(ClickHouseOptions{URL: server.URL, FlushInterval: 10 * time.Millisecond})
+	writer.Report(context.Background(), CrashReport{Error: "boom"})
+
+	deadline := time.Now().Add(time.Second)
+	for writer.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if writer.Pending() != 0 {
+		t.Errorf("Expected the row to be inserted after the flush interval")
+	}
+}
+
+func TestClickHouseFlushBeforeExit(t *testing.T) {
+	recorder := &clickHouseServer{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	writer := NewClickHouseWriter(ClickHouseOptions{URL: server.URL, FlushInterval: time.Hour})
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, ExitOnPanic: true}, WithReporter(writer), WithExitFunc(func(int) {}))
+
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if writer.Pending() != 0 || len(recorder.batches) != 1 {
+		t.Errorf("Expected the row to be inserted before exiting, got %d pending", writer.Pending())
+	}
+}
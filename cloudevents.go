@@ -0,0 +1,132 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CloudEvents types of crash reports
+const (
+	// CloudEventTypePanic is the type of events for recovered panics
+	CloudEventTypePanic = "dev.adfer.panic"
+	// CloudEventTypeError is the type of events for handled errors reported with PanicHandler.Report
+	CloudEventTypeError = "dev.adfer.error"
+)
+
+// CloudEvent is a crash report in the CloudEvents v1.0 JSON format
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time,omitempty"`
+	DataContentType string `json:"datacontenttype,omitempty"`
+	// Fingerprint is an extension attribute holding the grouping key of the panic
+	Fingerprint string      `json:"fingerprint,omitempty"`
+	Data        CrashReport `json:"data"`
+}
+
+// NewCloudEvent converts a crash report to a CloudEvent. Source identifies the producer, e.g.
+// "/myapp"; it defaults to "/" followed by the executable name
+func NewCloudEvent(report CrashReport, source string) CloudEvent {
+	if source == "" {
+		source = "/" + filepath.Base(os.Args[0])
+	}
+	eventType := CloudEventTypePanic
+	if report.Handled {
+		eventType = CloudEventTypeError
+	}
+	id := report.ID
+	if id == "" {
+		id = newReportID()
+	}
+	event := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         report.ErrorType,
+		DataContentType: "application/json",
+		Fingerprint:     Fingerprint(report),
+		Data:            report,
+	}
+	if !report.Timestamp.IsZero() {
+		event.Time = report.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	return event
+}
+
+// CloudEventsOptions configures a CloudEventsSender
+type CloudEventsOptions struct {
+	// URL is the endpoint events are sent to, e.g. a Knative broker or sink
+	URL string
+	// Source is the source attribute of the events. Defaults to "/" followed by the executable name
+	Source string
+	// Binary sends events in binary content mode, with the attributes as ce- headers and the
+	// crash report as the body. Defaults to structured content mode
+	Binary bool
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// CloudEventsSender sends crash reports as CloudEvents using the HTTP protocol binding
+type CloudEventsSender struct {
+	options CloudEventsOptions
+}
+
+// NewCloudEventsSender creates a CloudEventsSender from the given options
+func NewCloudEventsSender(options CloudEventsOptions) *CloudEventsSender {
+	return &CloudEventsSender{options: options}
+}
+
+// WithCloudEvents sends every crash report as a CloudEvent
+func WithCloudEvents(options CloudEventsOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewCloudEventsSender(options))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (c *CloudEventsSender) Name() string {
+	return "cloudevents"
+}
+
+// Report sends the crash report as a CloudEvent
+func (c *CloudEventsSender) Report(ctx context.Context, report CrashReport) error {
+	event := NewCloudEvent(report, c.options.Source)
+	headers := make(map[string]string, len(c.options.Headers)+8)
+	for key, value := range c.options.Headers {
+		headers[key] = value
+	}
+	if !c.options.Binary {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return post(ctx, c.options.HTTPClient, c.options.URL, "application/cloudevents+json; charset=UTF-8", body, headers)
+	}
+
+	headers["ce-specversion"] = event.SpecVersion
+	headers["ce-id"] = event.ID
+	headers["ce-source"] = event.Source
+	headers["ce-type"] = event.Type
+	headers["ce-fingerprint"] = event.Fingerprint
+	if event.Subject != "" {
+		headers["ce-subject"] = event.Subject
+	}
+	if event.Time != "" {
+		headers["ce-time"] = event.Time
+	}
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	return post(ctx, c.options.HTTPClient, c.options.URL, event.DataContentType, body, headers)
+}
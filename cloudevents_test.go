@@ -0,0 +1,58 @@
+package adfer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewCloudEvent(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	report := CrashReport{ID: "abc", Timestamp: timestamp, Error: "boom", ErrorType: "string", Stack: testStack}
+
+	event := NewCloudEvent(report, "/billing")
+	if event.SpecVersion != "1.0" || event.ID != "abc" || event.Source != "/billing" || event.Type != CloudEventTypePanic {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if event.Time != "2024-05-01T12:00:00Z" || event.Fingerprint != Fingerprint(report) || event.Data.Error != "boom" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	report.Handled = true
+	if event := NewCloudEvent(report, ""); event.Type != CloudEventTypeError || event.Source == "" {
+		t.Errorf("Unexpected event for a handled error %+v", event)
+	}
+}
+
+func TestCloudEventsSender(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	report := CrashReport{ID: "abc", Timestamp: time.Now(), Error: "boom", ErrorType: "string"}
+
+	structured := NewCloudEventsSender(CloudEventsOptions{URL: server.URL, Source: "/billing"})
+	if err := structured.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	binary := NewCloudEventsSender(CloudEventsOptions{URL: server.URL, Source: "/billing", Binary: true})
+	if err := binary.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	recorded := requests()
+	if len(recorded) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(recorded))
+	}
+	if contentType := recorded[0].Headers.Get("Content-Type"); contentType != "application/cloudevents+json; charset=UTF-8" {
+		t.Errorf("Unexpected structured content type '%s'", contentType)
+	}
+	if body := recorded[0].Body; body["specversion"] != "1.0" || body["type"] != CloudEventTypePanic || body["data"].(map[string]any)["error"] != "boom" {
+		t.Errorf("Unexpected structured event %v", body)
+	}
+
+	headers := recorded[1].Headers
+	if headers.Get("Content-Type") != "application/json" || headers.Get("Ce-Id") != "abc" || headers.Get("Ce-Source") != "/billing" || headers.Get("Ce-Specversion") != "1.0" {
+		t.Errorf("Unexpected binary headers %v", headers)
+	}
+	if recorded[1].Body["error"] != "boom" {
+		t.Errorf("Expected the crash report as binary body, got %v", recorded[1].Body)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+// loadConfig reads a config file and creates the reporters of its sinks
+func loadConfig(path string) ([]adfer.Reporter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(string(data))
+}
+
+// parseConfig creates the reporters of the sinks in a config file:
+//
+//	sinks:
+//	  - type: webhook
+//	    url: https://crashes.example.com/ingest
+//	    secret: ${ADFER_WEBHOOK_SECRET}
+//	  - type: slack
+//	    url: https://hooks.slack.com/services/...
+//
+// Environment variables in values are expanded, so secrets don't have to be stored in the file
+func parseConfig(data string) ([]adfer.Reporter, error) {
+	document, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := document.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("config must be a mapping with a 'sinks' key")
+	}
+	for key := range root {
+		if key != "sinks" {
+			return nil, fmt.Errorf("unknown config key '%s'", key)
+		}
+	}
+	sinks, ok := root["sinks"].([]any)
+	if !ok || len(sinks) == 0 {
+		return nil, fmt.Errorf("config has no sinks")
+	}
+	reporters := make([]adfer.Reporter, 0, len(sinks))
+	for i, sink := range sinks {
+		values, ok := sink.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("sink %d: expected a mapping", i+1)
+		}
+		reporter, err := newReporter(&sinkConfig{values: values, used: map[string]bool{}})
+		if err != nil {
+			return nil, fmt.Errorf("sink %d: %w", i+1, err)
+		}
+		reporters = append(reporters, reporter)
+	}
+	return reporters, nil
+}
+
+// sinkConfig is the configuration of a sink, tracking the keys that were read
+type sinkConfig struct {
+	values map[string]any
+	used   map[string]bool
+	err    error
+}
+
+// string returns the value of key with environment variables expanded
+func (c *sinkConfig) string(key string) string {
+	c.used[key] = true
+	value, ok := c.values[key].(string)
+	if !ok && c.values[key] != nil && c.err == nil {
+		c.err = fmt.Errorf("'%s' must be a string", key)
+	}
+	return os.ExpandEnv(value)
+}
+
+// required returns the value of key, recording an error if it is empty
+func (c *sinkConfig) required(key string) string {
+	value := c.string(key)
+	if value == "" && c.err == nil {
+		c.err = fmt.Errorf("'%s' is required", key)
+	}
+	return value
+}
+
+// int returns the value of key as an integer
+func (c *sinkConfig) int(key string) int {
+	value := c.string(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("'%s' must be an integer", key)
+	}
+	return n
+}
+
+// duration returns the value of key as a duration, e.g. "10m"
+func (c *sinkConfig) duration(key string) time.Duration {
+	value := c.string(key)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("'%s' must be a duration", key)
+	}
+	return d
+}
+
+// bool returns the value of key as a boolean
+func (c *sinkConfig) bool(key string) bool {
+	value := c.string(key)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("'%s' must be true or false", key)
+	}
+	return b
+}
+
+// list returns the value of key as a list of strings. A single string is a list of one
+func (c *sinkConfig) list(key string) []string {
+	c.used[key] = true
+	switch value := c.values[key].(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{os.ExpandEnv(value)}
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok && c.err == nil {
+				c.err = fmt.Errorf("'%s' must be a list of strings", key)
+			}
+			list = append(list, os.ExpandEnv(s))
+		}
+		return list
+	}
+	if c.err == nil {
+		c.err = fmt.Errorf("'%s' must be a list of strings", key)
+	}
+	return nil
+}
+
+// mapping returns the value of key as a mapping of strings, e.g. HTTP headers
+func (c *sinkConfig) mapping(key string) map[string]string {
+	c.used[key] = true
+	if c.values[key] == nil {
+		return nil
+	}
+	values, ok := c.values[key].(map[string]any)
+	if !ok {
+		if c.err == nil {
+			c.err = fmt.Errorf("'%s' must be a mapping", key)
+		}
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for name, value := range values {
+		s, _ := value.(string)
+		result[name] = os.ExpandEnv(s)
+	}
+	return result
+}
+
+// check returns the first error, or an error listing keys that weren't used by the sink
+func (c *sinkConfig) check() error {
+	if c.err != nil {
+		return c.err
+	}
+	var unknown []string
+	for key := range c.values {
+		if !c.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// newReporter creates the reporter of a sink
+func newReporter(c *sinkConfig) (adfer.Reporter, error) {
+	var reporter adfer.Reporter
+	switch sinkType := c.required("type"); sinkType {
+	case "webhook":
+		reporter = adfer.NewWebhookReporter(adfer.WebhookOptions{
+			URL:            c.required("url"),
+			Headers:        c.mapping("headers"),
+			Secret:         c.string("secret"),
+			Compression:    c.string("compression"),
+			MaxPayloadSize: c.int("max_payload_size"),
+		})
+	case "cloudevents":
+		reporter = adfer.NewCloudEventsSender(adfer.CloudEventsOptions{
+			URL:     c.required("url"),
+			Source:  c.string("source"),
+			Binary:  c.bool("binary"),
+			Headers: c.mapping("headers"),
+		})
+	case "slack":
+		reporter = adfer.NewSlackNotifier(c.required("url"))
+	case "discord":
+		reporter = adfer.NewDiscordNotifier(c.required("url"))
+	case "teams":
+		reporter = adfer.NewTeamsNotifier(c.required("url"))
+	case "telegram":
+		reporter = adfer.NewTelegramNotifier(c.required("token"), c.required("chat_id"))
+	case "sentry":
+		sentry, err := adfer.NewSentryReporter(adfer.SentryOptions{
+			DSN:         c.required("dsn"),
+			Release:     c.string("release"),
+			Environment: c.string("environment"),
+		})
+		if err != nil && c.err == nil {
+			return nil, err
+		}
+		reporter = sentry
+	case "rollbar":
+		reporter = adfer.NewRollbarReporter(adfer.RollbarOptions{
+			AccessToken: c.required("access_token"),
+			Environment: c.string("environment"),
+			CodeVersion: c.string("code_version"),
+		})
+	case "bugsnag":
+		reporter = adfer.NewBugsnagReporter(adfer.BugsnagOptions{
+			APIKey:       c.required("api_key"),
+			ReleaseStage: c.string("release_stage"),
+			AppVersion:   c.string("app_version"),
+		})
+	case "gcp-error-reporting":
+		reporter = adfer.NewGCPErrorReporter(adfer.GCPErrorReportingOptions{
+			ProjectID: c.required("project_id"),
+			Service:   c.string("service"),
+			Version:   c.string("version"),
+			APIKey:    c.required("api_key"),
+		})
+	case "honeycomb":
+		reporter = adfer.NewHoneycombReporter(adfer.HoneycombOptions{
+			APIKey:  c.required("api_key"),
+			Dataset: c.required("dataset"),
+			URL:     c.string("url"),
+		})
+	case "elasticsearch", "opensearch":
+		reporter = adfer.NewElasticsearchReporter(adfer.ElasticsearchOptions{
+			URL:      c.required("url"),
+			Index:    c.string("index"),
+			Username: c.string("username"),
+			Password: c.string("password"),
+			APIKey:   c.string("api_key"),
+		})
+	case "loki":
+		reporter = adfer.NewLokiReporter(adfer.LokiOptions{
+			URL:          c.required("url"),
+			Labels:       c.mapping("labels"),
+			ComponentKey: c.string("component_key"),
+			TenantID:     c.string("tenant_id"),
+			Username:     c.string("username"),
+			Password:     c.string("password"),
+		})
+	case "kafka":
+		reporter = adfer.NewKafkaPublisher(adfer.KafkaOptions{
+			URL:     c.required("url"),
+			Topic:   c.required("topic"),
+			Headers: c.mapping("headers"),
+		})
+	case "nats":
+		reporter = adfer.NewNATSPublisher(adfer.NATSOptions{
+			URL:     c.string("url"),
+			Subject: c.required("subject"),
+			Token:   c.string("token"),
+		})
+	case "mqtt":
+		reporter = adfer.NewMQTTPublisher(adfer.MQTTOptions{
+			Broker:        c.string("broker"),
+			DeviceID:      c.string("device_id"),
+			TopicTemplate: c.string("topic"),
+			QoS:           byte(c.int("qos")),
+			Retain:        c.bool("retain"),
+		})
+	case "github":
+		reporter = adfer.NewGitHubIssueReporter(adfer.GitHubIssueOptions{
+			Repository:          c.required("repository"),
+			Token:               c.required("token"),
+			Labels:              c.list("labels"),
+			LabelMetadata:       c.list("label_metadata"),
+			CommentOnRecurrence: c.bool("comment_on_recurrence"),
+			URL:                 c.string("url"),
+		})
+	case "pagerduty":
+		reporter = adfer.NewPagerDutyReporter(adfer.PagerDutyOptions{
+			RoutingKey: c.required("routing_key"),
+			Source:     c.string("source"),
+			Component:  c.string("component"),
+			Group:      c.string("group"),
+		})
+	case "opsgenie":
+		reporter = adfer.NewOpsgenieReporter(adfer.OpsgenieOptions{
+			APIKey: c.required("api_key"),
+			Tags:   c.list("tags"),
+		})
+	case "email":
+		reporter = adfer.NewEmailNotifier(adfer.SMTPConfig{
+			Host:          c.required("host"),
+			Port:          c.int("port"),
+			Username:      c.string("username"),
+			Password:      c.string("password"),
+			From:          c.required("from"),
+			To:            c.list("to"),
+			SubjectPrefix: c.string("subject_prefix"),
+			AttachJSON:    c.bool("attach_json"),
+			MaxEmails:     c.int("max_emails"),
+			Interval:      c.duration("interval"),
+		})
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown sink type '%s'", sinkType)
+	}
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return reporter, nil
+}
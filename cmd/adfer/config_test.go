@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leaanthony/adfer"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("ADFER_TEST_SECRET", "s3cret")
+	reporters, err := parseConfig(`
+sinks:
+  - type: webhook
+    url: https://crashes.example.com/ingest
+    secret: ${ADFER_TEST_SECRET}
+    compression: gzip
+    max_payload_size: 65536
+  - type: telegram
+    token: "123:abc"
+    chat_id: "-10042"
+  - type: honeycomb
+    api_key: key
+    dataset: crashes
+  - type: email
+    host: smtp.example.com
+    port: 587
+    from: crashes@example.com
+    to: [ops@example.com]
+    interval: 1h
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reporters) != 4 {
+		t.Fatalf("Expected 4 reporters, got %d", len(reporters))
+	}
+	if _, ok := reporters[0].(*adfer.WebhookReporter); !ok {
+		t.Errorf("Expected a webhook reporter, got %T", reporters[0])
+	}
+	if _, ok := reporters[2].(*adfer.HoneycombReporter); !ok {
+		t.Errorf("Expected a Honeycomb reporter, got %T", reporters[2])
+	}
+	if _, ok := reporters[3].(*adfer.EmailNotifier); !ok {
+		t.Errorf("Expected an email notifier, got %T", reporters[3])
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		config   string
+		expected string
+	}{
+		{config: "sinks: []\n", expected: "no sinks"},
+		{config: "reporters:\n  - type: slack\n", expected: "unknown config key 'reporters'"},
+		{config: "sinks:\n  - type: carrier-pigeon\n", expected: "unknown sink type 'carrier-pigeon'"},
+		{config: "sinks:\n  - type: slack\n", expected: "sink 1: 'url' is required"},
+		{config: "sinks:\n  - type: slack\n    url: x\n    channel: ops\n", expected: "unknown keys channel"},
+		{config: "sinks:\n  - type: email\n    host: h\n    from: f\n    port: smtp\n", expected: "'port' must be an integer"},
+	}
+	for _, test := range tests {
+		_, err := parseConfig(test.config)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing '%s' for %q, got %v", test.expected, test.config, err)
+		}
+	}
+}
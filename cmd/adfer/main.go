@@ -0,0 +1,235 @@
+// Command adfer works with crash files written by the adfer package.
+//
+// The push command delivers the reports of a crash file that haven't been sent yet through
+// the sinks described in a config file, e.g. to ship crashes collected on an air-gapped
+// machine from an operator workstation:
+//
+//	adfer push --config adfer.yaml --path crashes.json
+//
+// JSON, JSON Lines and gzip compressed crash files are detected from their contents. Delivery
+// receipts in the crash file are updated, so pushing the same file again only retries the
+// reports that failed.
+//
+// The collect command runs a collector daemon receiving the reports of every process on the
+// host that uses adfer.WithSocket, so they share one crash file and one set of sinks:
+//
+//	adfer collect --socket /run/adfer.sock --config adfer.yaml --path /var/lib/adfer/crashes.json
+//
+// The decrypt command prints the reports of a crash file encrypted with adfer.WithEncryption, given
+// a file holding the base64 encoded private key:
+//
+//	adfer decrypt --key private.key --path crashes.json
+//
+// The merge command combines crash files collected from several machines into one, ordered by
+// timestamp and without duplicate reports:
+//
+//	adfer merge --out merged.json host1.json host2.json
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+const usage = `Usage: adfer <command> [flags]
+
+Commands:
+  push       Deliver unsent crash reports through the sinks in a config file
+  collect    Receive crash reports from other processes on a Unix domain socket
+  decrypt    Print the reports of an encrypted crash file as JSON
+  merge      Combine crash files into one
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command given by args and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "push":
+		return push(ctx, args[1:], stdout, stderr)
+	case "collect":
+		return collect(ctx, args[1:], stdout, stderr)
+	case "decrypt":
+		return decrypt(args[1:], stdout, stderr)
+	case "merge":
+		return merge(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "Unknown command '%s'\n\n%s", args[0], usage)
+	return 2
+}
+
+// push delivers the unsent reports of a crash file
+func push(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("push", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "adfer.yaml", "config file describing the sinks")
+	path := flags.String("path", "crash_reports.json", "crash file to push")
+	timeout := flags.Duration("timeout", 5*time.Minute, "maximum time to spend delivering reports")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	reporters, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading config %s: %v\n", *configPath, err)
+		return 1
+	}
+	if _, err := os.Stat(*path); err != nil {
+		fmt.Fprintf(stderr, "Error reading crash file: %v\n", err)
+		return 1
+	}
+
+	// The receipts are written back in the format and compression the crash file was written with
+	format, compressed := adfer.DetectFileFormat(*path)
+	ph := adfer.New(adfer.Options{
+		DumpToFile: true,
+		FilePath:   *path,
+		FileFormat: format,
+		Compress:   compressed,
+		OnDiagnostic: func(d adfer.Diagnostic) {
+			fmt.Fprintln(stderr, d.Error())
+		},
+	}, adfer.WithReporters(reporters...))
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	delivered, err := ph.ResendUnsent(ctx)
+	fmt.Fprintf(stdout, "Delivered %d crash report(s)\n", delivered)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", *timeout)
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// collect stores and delivers the reports forwarded to a Unix domain socket until ctx is done
+func collect(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("collect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	socket := flags.String("socket", "/run/adfer.sock", "Unix domain socket to listen on")
+	configPath := flags.String("config", "", "config file describing the sinks, if reports should be delivered")
+	path := flags.String("path", "crash_reports.json", "crash file to store reports in")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var reporters []adfer.Reporter
+	if *configPath != "" {
+		var err error
+		if reporters, err = loadConfig(*configPath); err != nil {
+			fmt.Fprintf(stderr, "Error reading config %s: %v\n", *configPath, err)
+			return 1
+		}
+	}
+
+	ph := adfer.New(adfer.Options{
+		DumpToFile: true,
+		FilePath:   *path,
+		OnDiagnostic: func(d adfer.Diagnostic) {
+			fmt.Fprintln(stderr, d.Error())
+		},
+	}, adfer.WithReporters(reporters...))
+	defer ph.Close()
+
+	fmt.Fprintf(stdout, "Collecting crash reports on %s\n", *socket)
+	if err := adfer.NewCollector(ph).ListenAndServe(ctx, *socket); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// decrypt prints the decrypted reports of a crash file
+func decrypt(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyPath := flags.String("key", "", "file holding the base64 encoded private key")
+	path := flags.String("path", "crash_reports.json", "crash file to decrypt")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *keyPath == "" {
+		fmt.Fprintln(stderr, "Error: --key is required")
+		return 2
+	}
+
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading key: %v\n", err)
+		return 1
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error decoding key: %v\n", err)
+		return 1
+	}
+	reader, err := adfer.OpenReadOnly(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading crash file: %v\n", err)
+		return 1
+	}
+	reports, err := reader.GetCrashReportsWithTags()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading crash file: %v\n", err)
+		return 1
+	}
+	for i, report := range reports {
+		if reports[i], err = adfer.DecryptCrashReport(report, key); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reports); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// merge combines crash files into one
+func merge(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "merged.json", "crash file to merge into, written as JSON Lines if it ends in .jsonl")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "Error: no crash files to merge")
+		return 2
+	}
+
+	if err := adfer.MergeCrashFiles(*out, flags.Args()...); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Merged %d crash file(s) into %s\n", flags.NArg(), *out)
+	return 0
+}
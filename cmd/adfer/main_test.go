@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+func TestPush(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "adfer.yaml")
+	config := "sinks:\n  - type: webhook\n    url: " + server.URL + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	crashPath := filepath.Join(dir, "crashes.json")
+	data, _ := json.Marshal([]adfer.CrashReport{{ID: "one", Error: "boom"}, {ID: "two", Error: "bang"}})
+	if err := os.WriteFile(crashPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write crash file: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"push", "--config", configPath, "--path", crashPath}
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if received.Load() != 2 || !strings.Contains(stdout.String(), "Delivered 2 crash report(s)") {
+		t.Errorf("Expected 2 deliveries, got %d: %s", received.Load(), stdout.String())
+	}
+
+	// Pushing again doesn't resend delivered reports
+	stdout.Reset()
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 || received.Load() != 2 {
+		t.Errorf("Expected no new deliveries, got exit code %d and %d deliveries", code, received.Load())
+	}
+}
+
+func TestPushCompressedJSONLines(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "adfer.yaml")
+	config := "sinks:\n  - type: webhook\n    url: " + server.URL + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	for _, report := range []adfer.CrashReport{{ID: "one", Error: "boom"}, {ID: "two", Error: "bang"}} {
+		line, _ := json.Marshal(report)
+		writer.Write(append(line, '\n'))
+	}
+	writer.Close()
+	crashPath := filepath.Join(dir, "crashes.jsonl.gz")
+	if err := os.WriteFile(crashPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write crash file: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"push", "--config", configPath, "--path", crashPath}
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if received.Load() != 2 {
+		t.Errorf("Expected 2 deliveries, got %d: %s", received.Load(), stdout.String())
+	}
+	if format, compressed := adfer.DetectFileFormat(crashPath); format != adfer.FormatJSONLines || !compressed {
+		t.Errorf("Expected the crash file to stay compressed JSON Lines, got format %v, compressed %v", format, compressed)
+	}
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 || received.Load() != 2 {
+		t.Errorf("Expected no new deliveries, got exit code %d and %d deliveries", code, received.Load())
+	}
+}
+
+func TestPushErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected usage error without a command, got %d", code)
+	}
+	if code := run(context.Background(), []string{"pull"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected usage error for an unknown command, got %d", code)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	if code := run(context.Background(), []string{"push", "--config", missing}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected an error for a missing config, got %d", code)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "adfer")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "adfer.sock")
+	crashPath := filepath.Join(dir, "crashes.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout, stderr bytes.Buffer
+	done := make(chan int, 1)
+	go func() {
+		done <- run(ctx, []string{"collect", "--socket", socket, "--path", crashPath}, &stdout, &stderr)
+	}()
+
+	reporter := adfer.NewSocketReporter(socket)
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := reporter.Report(context.Background(), adfer.CrashReport{ID: "forwarded", Error: "boom"})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Failed to forward the report: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	reader, err := adfer.OpenReadOnly(crashPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, _ := reader.GetLastNCrashReports(10); len(reports) != 1 || reports[0].ID != "forwarded" {
+		t.Errorf("Expected the forwarded report to be stored, got %+v", reports)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	publicKey, privateKey, err := adfer.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	dir := t.TempDir()
+	crashPath := filepath.Join(dir, "crashes.json")
+	ph := adfer.New(adfer.Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: crashPath}, adfer.WithEncryption(publicKey))
+	ph.Report(errors.New("secret failure"))
+	if data, _ := os.ReadFile(crashPath); strings.Contains(string(data), "secret failure") {
+		t.Fatalf("Expected the crash file to be encrypted")
+	}
+
+	keyPath := filepath.Join(dir, "private.key")
+	os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(privateKey)+"\n"), 0600)
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"decrypt", "--key", keyPath, "--path", crashPath}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var reports []adfer.CrashReport
+	if err := json.Unmarshal(stdout.Bytes(), &reports); err != nil || len(reports) != 1 || reports[0].Error != "secret failure" {
+		t.Errorf("Expected the decrypted report, got %s, error %v", stdout.String(), err)
+	}
+
+	if code := run(context.Background(), []string{"decrypt", "--path", crashPath}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected a usage error without a key, got %d", code)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, host := range []string{"host1", "host2"} {
+		path := filepath.Join(dir, host+".json")
+		ph := adfer.New(adfer.Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: path})
+		ph.Report(errors.New(host))
+		paths = append(paths, path)
+	}
+
+	out := filepath.Join(dir, "merged.json")
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), append([]string{"merge", "--out", out}, paths...), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	reader, err := adfer.OpenReadOnly(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, err := reader.GetLastNCrashReports(10); err != nil || len(reports) != 2 || reports[0].Error != "host1" {
+		t.Errorf("Expected the reports of both hosts, got %+v, error %v", reports, err)
+	}
+
+	if code := run(context.Background(), []string{"merge", "--out", out}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected a usage error without crash files, got %d", code)
+	}
+}
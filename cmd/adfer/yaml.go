@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a significant line of a YAML document
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+// parseYAML parses the subset of YAML used by config files: block mappings, block sequences,
+// flow sequences of scalars, quoted and plain scalars, and comments. Scalars are returned as strings
+func parseYAML(data string) (any, error) {
+	var lines []yamlLine
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{indent: len(line) - len(text), text: text, number: i + 1})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	value, next, err := parseNode(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	return value, nil
+}
+
+// stripComment removes a trailing comment from a line, ignoring # inside quotes
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseNode parses the mapping or sequence starting at lines[i]
+func parseNode(lines []yamlLine, i, indent int) (any, int, error) {
+	if isSequenceItem(lines[i].text) {
+		return parseSequence(lines, i, indent)
+	}
+	return parseMapping(lines, i, indent)
+}
+
+// isSequenceItem reports whether text is an item of a block sequence
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitMappingEntry splits "key: value" into its key and value
+func splitMappingEntry(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		return "", "", false
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSuffix(text, ":"), "", true
+	}
+	key, value, ok := strings.Cut(text, ": ")
+	return key, strings.TrimSpace(value), ok
+}
+
+// parseSequence parses a block sequence
+func parseSequence(lines []yamlLine, i, indent int) ([]any, int, error) {
+	var items []any
+	for i < len(lines) && lines[i].indent == indent && isSequenceItem(lines[i].text) {
+		rest := strings.TrimLeft(strings.TrimPrefix(lines[i].text, "-"), " ")
+		var item any
+		var err error
+		switch {
+		case rest == "":
+			if i+1 < len(lines) && lines[i+1].indent > indent {
+				item, i, err = parseNode(lines, i+1, lines[i+1].indent)
+			} else {
+				i++
+			}
+		case isSequenceItem(rest):
+			return nil, i, fmt.Errorf("line %d: nested sequences must start on a new line", lines[i].number)
+		default:
+			if _, _, ok := splitMappingEntry(rest); ok {
+				// A mapping starting on the item's line continues at the indentation of its first key
+				childIndent := indent + len(lines[i].text) - len(rest)
+				lines[i] = yamlLine{indent: childIndent, text: rest, number: lines[i].number}
+				item, i, err = parseMapping(lines, i, childIndent)
+			} else {
+				item, err = parseScalar(rest, lines[i].number)
+				i++
+			}
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		items = append(items, item)
+	}
+	return items, i, nil
+}
+
+// parseMapping parses a block mapping
+func parseMapping(lines []yamlLine, i, indent int) (map[string]any, int, error) {
+	mapping := map[string]any{}
+	for i < len(lines) && lines[i].indent == indent && !isSequenceItem(lines[i].text) {
+		line := lines[i]
+		key, rest, ok := splitMappingEntry(line.text)
+		if !ok {
+			return nil, i, fmt.Errorf("line %d: expected 'key: value'", line.number)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, i, fmt.Errorf("line %d: duplicate key '%s'", line.number, key)
+		}
+		i++
+		var value any
+		var err error
+		switch {
+		case rest != "":
+			value, err = parseScalar(rest, line.number)
+		case i < len(lines) && lines[i].indent > indent:
+			value, i, err = parseNode(lines, i, lines[i].indent)
+		case i < len(lines) && lines[i].indent == indent && isSequenceItem(lines[i].text):
+			value, i, err = parseSequence(lines, i, indent)
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		mapping[key] = value
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("line %d: unexpected indentation", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+// parseScalar parses a quoted or plain scalar, or a flow sequence of scalars
+func parseScalar(text string, number int) (any, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated flow sequence", number)
+		}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		items := []any{}
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range strings.Split(inner, ",") {
+			item, err := parseScalar(strings.TrimSpace(part), number)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", number, text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", number, text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case text == "~" || text == "null":
+		return nil, nil
+	}
+	return text, nil
+}
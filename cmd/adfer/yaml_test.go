@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	document := `
+# Sinks for the ops workstation
+sinks:
+  - type: webhook   # signed
+    url: "https://example.com/hook#fragment"
+    headers:
+      Authorization: Bearer ${TOKEN}
+  - type: email
+    to: [ops@example.com, 'dev''s@example.com']
+    cc:
+    - a@example.com
+    - b@example.com
+  -
+    type: slack
+empty:
+`
+	value, err := parseYAML(document)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"sinks": []any{
+			map[string]any{
+				"type":    "webhook",
+				"url":     "https://example.com/hook#fragment",
+				"headers": map[string]any{"Authorization": "Bearer ${TOKEN}"},
+			},
+			map[string]any{
+				"type": "email",
+				"to":   []any{"ops@example.com", "dev's@example.com"},
+				"cc":   []any{"a@example.com", "b@example.com"},
+			},
+			map[string]any{"type": "slack"},
+		},
+		"empty": nil,
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, value)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, document := range []string{
+		"sinks:\n  - type: slack\n      url: x\n",
+		"key: value\nkey: again\n",
+		"just a string\n",
+		"list: [a, b\n",
+		"key:\n\t- tab\n",
+	} {
+		if _, err := parseYAML(document); err == nil {
+			t.Errorf("Expected an error for %q", document)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// socketTimeout is the time allowed to forward a report when the context has no deadline
+const socketTimeout = 10 * time.Second
+
+// collectorReply acknowledges a report received by a Collector
+type collectorReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// SocketReporter forwards crash reports over a Unix domain socket to a Collector, so many
+// processes on a host can share one crash store and remote delivery
+type SocketReporter struct {
+	path string
+}
+
+// NewSocketReporter creates a SocketReporter forwarding to the collector listening on path
+func NewSocketReporter(path string) *SocketReporter {
+	return &SocketReporter{path: path}
+}
+
+// WithSocket forwards every crash report to the collector listening on path, e.g. "/run/adfer.sock"
+func WithSocket(path string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewSocketReporter(path))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (s *SocketReporter) Name() string {
+	return "socket"
+}
+
+// Report sends the crash report to the collector and waits for it to be acknowledged
+func (s *SocketReporter) Report(ctx context.Context, report CrashReport) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", s.path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(socketTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	if err := json.NewEncoder(conn).Encode(report); err != nil {
+		return err
+	}
+	var reply collectorReply
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+// Collector receives crash reports forwarded by SocketReporters and handles them with its
+// PanicHandler, which stores them and delivers them to its reporters. Forwarded reports keep
+// their ID and aren't subject to the handler's policies, as the panic happened in another process
+type Collector struct {
+	ph *PanicHandler
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewCollector creates a Collector handling reports with ph
+func NewCollector(ph *PanicHandler) *Collector {
+	return &Collector{ph: ph, conns: make(map[net.Conn]struct{})}
+}
+
+// ListenAndServe listens on the Unix domain socket at path and serves until ctx is done. A stale
+// socket left by a previous collector is removed. The socket gets the handler's file mode and
+// owner, so use WithFileMode(0660) or 0666 to accept reports from processes of other users
+func (c *Collector) ListenAndServe(ctx context.Context, path string) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return errors.New("a collector is already listening on " + path)
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := c.ph.perms.apply(path, c.ph.perms.file); err != nil {
+		listener.Close()
+		return err
+	}
+	return c.Serve(ctx, listener)
+}
+
+// Serve accepts connections on listener until ctx is done, then closes the listener and
+// waits for the reports being received to be handled
+func (c *Collector) Serve(ctx context.Context, listener net.Listener) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close()
+		case <-stop:
+		}
+	}()
+	defer c.wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			listener.Close()
+			c.closeConns()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c.mu.Lock()
+		c.conns[conn] = struct{}{}
+		c.mu.Unlock()
+		c.wg.Add(1)
+		go c.handle(conn)
+	}
+}
+
+// handle receives the reports sent on a connection until it is closed
+func (c *Collector) handle(conn net.Conn) {
+	defer c.wg.Done()
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+		conn.Close()
+	}()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var report CrashReport
+		if err := decoder.Decode(&report); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.ph.diagnose(OpCollect, conn.LocalAddr().String(), err)
+				encoder.Encode(collectorReply{Error: "invalid crash report: " + err.Error()})
+			}
+			return
+		}
+		// Reports already received are handled even if the collector is stopping
+		c.ph.process(context.Background(), errors.New(report.Error), report)
+		if err := encoder.Encode(collectorReply{}); err != nil {
+			return
+		}
+	}
+}
+
+// closeConns closes the connections that are idle or still being read
+func (c *Collector) closeConns() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for conn := range c.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,109 @@
+package adfer
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socketPath returns a path for a Unix domain socket, short enough for the platform's limit
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "adfer")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "adfer.sock")
+}
+
+func TestCollector(t *testing.T) {
+	path := socketPath(t)
+	received := make(chan CrashReport, 1)
+	collectorPath := filepath.Join(t.TempDir(), "crash.json")
+	collector := NewCollector(New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     collectorPath,
+	}, WithReporter(channelReporter(received))))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- collector.ListenAndServe(ctx, path) }()
+	waitForSocket(t, path)
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithSocket(path))
+	func() {
+		defer ph.Recover()
+		panic("forwarded")
+	}()
+
+	select {
+	case report := <-received:
+		if report.Error != "forwarded" || report.ID == "" {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the collector to deliver the report")
+	}
+	reader, _ := OpenReadOnly(collectorPath)
+	if reports, _ := reader.GetLastNCrashReports(10); len(reports) != 1 {
+		t.Errorf("Expected the collector to store the report, got %d", len(reports))
+	}
+
+	if err := NewCollector(New(Options{})).ListenAndServe(context.Background(), path); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("Expected an error for a second collector, got %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := NewSocketReporter(path).Report(context.Background(), CrashReport{Error: "late"}); err == nil {
+		t.Errorf("Expected an error once the collector is stopped")
+	}
+}
+
+func TestCollectorInvalidReport(t *testing.T) {
+	path := socketPath(t)
+	var diagnostics []Diagnostic
+	collector := NewCollector(New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go collector.ListenAndServe(ctx, path)
+	waitForSocket(t, path)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("{\"error\": 42}\n"))
+	reply := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _ := conn.Read(reply)
+	if !strings.Contains(string(reply[:n]), "invalid crash report") {
+		t.Errorf("Expected an error reply, got %q", reply[:n])
+	}
+}
+
+// waitForSocket waits until a collector listens on path
+func waitForSocket(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Collector didn't listen on %s", path)
+}
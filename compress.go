@@ -0,0 +1,115 @@
+package adfer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// WithCompression gzip compresses the crash file. Stacks with full goroutine dumps compress
+// well, often tenfold. JSON Lines crash files stay append-only, as each report is appended as its
+// own gzip member. Compressed crash files are detected and decompressed when they are read,
+// whether compression is enabled or not, and aren't indexed
+func WithCompression() Option {
+	return func(o *Options) {
+		o.Compress = true
+	}
+}
+
+// isGzip returns true if data starts with the gzip magic number
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic)
+}
+
+// decompress returns the decompressed contents of a crash file, or data itself if it isn't
+// compressed. A member torn by a crash while it was written is dropped, like a torn line
+func decompress(data []byte) ([]byte, error) {
+	if !isGzip(data) {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := io.ReadAll(reader)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return decompressed, err
+}
+
+// compressData compresses data if compression is enabled
+func (f *fileStorage) compressData(data []byte) []byte {
+	if !f.compressed || len(data) == 0 {
+		return data
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// readFile reads and decompresses the crash file
+func (f *fileStorage) readFile() ([]byte, error) {
+	data, err := f.files().ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(data)
+}
+
+// openCrashFile opens a crash file for reading, decompressing it if needed
+func openCrashFile(files FileSystem, path string) (io.ReadCloser, error) {
+	file, err := files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := decompressReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+}
+
+// decompressReader returns a reader of the decompressed contents of r if it is gzip compressed
+func decompressReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(len(gzipMagic)); !isGzip(magic) {
+		return buffered, nil
+	}
+	return gzip.NewReader(buffered)
+}
+
+// convertLines rewrites a JSON Lines crash file whose compression doesn't match the configuration,
+// so appended reports don't mix compressed and uncompressed data
+func (f *fileStorage) convertLines() error {
+	file, err := f.files().Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	magic := make([]byte, len(gzipMagic))
+	n, _ := io.ReadFull(file, magic)
+	file.Close()
+	if n == 0 || isGzip(magic[:n]) == f.compressed {
+		return nil
+	}
+	data, err := f.readFile()
+	if err != nil {
+		return err
+	}
+	return f.writeFile(f.path, f.compressData(data))
+}
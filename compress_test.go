@@ -0,0 +1,147 @@
+package adfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCompression(t *testing.T) {
+	for name, format := range map[string]FileFormat{"json": FormatJSON, "jsonl": FormatJSONLines} {
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "crash")
+			ph := New(Options{
+				ErrorHandler: func(error, []byte) {},
+				DumpToFile:   true,
+				FilePath:     filePath,
+				MaxReports:   3,
+				Index:        true,
+			}, WithFileFormat(format), WithCompression())
+			// JSON Lines crash files are trimmed once they hold twice MaxReports
+			for i := 0; i < 6; i++ {
+				func() {
+					defer ph.Recover()
+					panic(i)
+				}()
+			}
+
+			data, _ := os.ReadFile(filePath)
+			if !isGzip(data) {
+				t.Fatalf("Expected a gzip compressed crash file, got %q", data)
+			}
+			if _, err := os.Stat(filePath + ".idx"); !os.IsNotExist(err) {
+				t.Errorf("Expected no index for a compressed crash file, got %v", err)
+			}
+			reports, err := ph.GetLastNCrashReports(2)
+			if err != nil || len(reports) != 2 || reports[0].Error != "4" || reports[1].Error != "5" {
+				t.Fatalf("Expected the last 2 reports, got %+v, error %v", reports, err)
+			}
+			if all, _ := ph.GetLastNCrashReports(10); len(all) != 3 || all[0].Error != "3" {
+				t.Errorf("Expected the last 3 reports to be kept, got %d", len(all))
+			}
+
+			reader, err := OpenReadOnly(filePath)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if last, _ := reader.GetLastNCrashReports(1); len(last) != 1 || last[0].Error != "5" {
+				t.Errorf("Expected the reader to decompress the crash file, got %+v", last)
+			}
+
+			if _, err := ph.DeleteReportsOlderThan(time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if all, _ := ph.GetLastNCrashReports(10); len(all) != 0 {
+				t.Errorf("Expected no reports, got %+v", all)
+			}
+		})
+	}
+}
+
+func TestCompressionExistingFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	plain := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithFileFormat(FormatJSONLines))
+	for i := 0; i < 2; i++ {
+		func() {
+			defer plain.Recover()
+			panic(i)
+		}()
+	}
+
+	compressed := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithFileFormat(FormatJSONLines), WithCompression())
+	func() {
+		defer compressed.Recover()
+		panic(2)
+	}()
+	if data, _ := os.ReadFile(filePath); !isGzip(data) {
+		t.Fatalf("Expected the crash file to be compressed, got %q", data)
+	}
+
+	// Reports are read back whether compression is enabled or not
+	for _, ph := range []*PanicHandler{compressed, plain} {
+		reports, err := ph.GetLastNCrashReports(10)
+		if err != nil || len(reports) != 3 {
+			t.Fatalf("Expected 3 reports, got %+v, error %v", reports, err)
+		}
+		for i, report := range reports {
+			if report.Error != strconv.Itoa(i) {
+				t.Errorf("Expected report %d, got %q", i, report.Error)
+			}
+		}
+	}
+
+	// Appending without compression decompresses the crash file
+	func() {
+		defer plain.Recover()
+		panic(3)
+	}()
+	if data, _ := os.ReadFile(filePath); isGzip(data) {
+		t.Error("Expected the crash file to be decompressed")
+	}
+	if reports, _ := plain.GetLastNCrashReports(10); len(reports) != 4 {
+		t.Errorf("Expected 4 reports, got %d", len(reports))
+	}
+}
+
+func TestCompressionRotation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithCompression(), WithMaxFileSize(1))
+	for i := 0; i < 2; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	file, err := openCrashFile(osFileSystem{}, filePath+".1.gz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+	var rotated []CrashReport
+	if err := json.NewDecoder(file).Decode(&rotated); err != nil || len(rotated) != 1 || rotated[0].Error != "0" {
+		t.Errorf("Expected the first report in the rotated file, got %+v, error %v", rotated, err)
+	}
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, _ := reader.GetLastNCrashReports(10); len(reports) != 1 || reports[0].Error != "1" {
+		t.Errorf("Expected the second report after rotation, got %+v", reports)
+	}
+}
@@ -0,0 +1,132 @@
+package adfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrNoConsent is returned when an operation requires consent the user has not given
+var ErrNoConsent = errors.New("user has not consented to sending crash reports")
+
+// ConsentLevel is how much crash reporting the user has agreed to
+type ConsentLevel int
+
+const (
+	// ConsentNone only passes crashes to the error handler. Reports don't include
+	// system information, metadata or execution traces, and aren't written to the crash file
+	ConsentNone ConsentLevel = iota
+	// ConsentLocal captures full crash reports and writes them to the crash file,
+	// but doesn't send them to any reporter
+	ConsentLocal
+	// ConsentFull captures full crash reports and sends them to the reporters
+	ConsentFull
+)
+
+// String returns the name of the consent level
+func (c ConsentLevel) String() string {
+	switch c {
+	case ConsentNone:
+		return "none"
+	case ConsentLocal:
+		return "local"
+	case ConsentFull:
+		return "full"
+	}
+	return fmt.Sprintf("ConsentLevel(%d)", int(c))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c ConsentLevel) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (c *ConsentLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "none":
+		*c = ConsentNone
+	case "local":
+		*c = ConsentLocal
+	case "full":
+		*c = ConsentFull
+	default:
+		return fmt.Errorf("unknown consent level %q", text)
+	}
+	return nil
+}
+
+// ConsentOptions configures the consent gate
+type ConsentOptions struct {
+	// File is where the user's choice is persisted across runs. If empty, the choice only lasts for the process
+	File string
+	// Default is the level used until the user has made a choice. Defaults to ConsentNone
+	Default ConsentLevel
+}
+
+// consentRecord is the persisted consent choice
+type consentRecord struct {
+	Level     ConsentLevel `json:"level"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// WithConsent gates crash reporting on the user's consent, set with PanicHandler.SetConsent.
+// Without this option, crash reporting behaves as if full consent was given
+func WithConsent(options ConsentOptions) Option {
+	return func(o *Options) {
+		o.Consent = &options
+	}
+}
+
+// loadConsent sets the consent level from the consent file, falling back to the default
+func (ph *PanicHandler) loadConsent() {
+	ph.consent = ConsentFull
+	options := ph.options.Consent
+	if options == nil {
+		return
+	}
+	ph.consent = options.Default
+	if options.File == "" {
+		return
+	}
+	data, err := os.ReadFile(options.File)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		ph.diagnose(OpConsent, options.File, err)
+		return
+	}
+	var record consentRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		ph.diagnose(OpConsent, options.File, err)
+		return
+	}
+	ph.consent = record.Level
+}
+
+// Consent returns the current consent level
+func (ph *PanicHandler) Consent() ConsentLevel {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return ph.consent
+}
+
+// SetConsent sets the consent level and persists it to the consent file, if configured.
+// The level takes effect even if it could not be persisted
+func (ph *PanicHandler) SetConsent(level ConsentLevel) error {
+	ph.mu.Lock()
+	ph.consent = level
+	ph.mu.Unlock()
+
+	if ph.options.Consent == nil || ph.options.Consent.File == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(consentRecord{Level: level, UpdatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ph.perms.writeFile(ph.options.Consent.File, data)
+}
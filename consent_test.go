@@ -0,0 +1,107 @@
+package adfer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConsentLevels(t *testing.T) {
+	dir := t.TempDir()
+	crashFile := filepath.Join(dir, "crash.json")
+	consentFile := filepath.Join(dir, "consent.json")
+
+	handled := 0
+	reports := make(chan CrashReport, 10)
+	newHandler := func() *PanicHandler {
+		return New(Options{
+			ErrorHandler:      func(error, []byte) { handled++ },
+			DumpToFile:        true,
+			FilePath:          crashFile,
+			IncludeSystemInfo: true,
+			Metadata:          map[string]string{"user": "alice"},
+		},
+			WithReporter(channelReporter(reports)),
+			WithConsent(ConsentOptions{File: consentFile}),
+		)
+	}
+	crash := func(ph *PanicHandler) {
+		defer ph.Recover()
+		panic("consent panic")
+	}
+
+	ph := newHandler()
+	if ph.Consent() != ConsentNone {
+		t.Fatalf("Expected default consent to be none, got %s", ph.Consent())
+	}
+	crash(ph)
+	if handled != 1 {
+		t.Error("Expected the error handler to be called without consent")
+	}
+	if _, err := os.Stat(crashFile); !os.IsNotExist(err) {
+		t.Error("Expected no crash file without consent")
+	}
+	if len(reports) != 0 {
+		t.Error("Expected no reports to be sent without consent")
+	}
+
+	if err := ph.SetConsent(ConsentLocal); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	crash(ph)
+	stored, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored[0].SystemInfo.OS == "" || stored[0].Metadata["user"] != "alice" {
+		t.Errorf("Expected full local report, got %+v", stored[0])
+	}
+	if stored[0].Deliveries["adfer.channelReporter"].Status != DeliveryDeclined {
+		t.Errorf("Expected declined delivery, got %+v", stored[0].Deliveries)
+	}
+	if len(reports) != 0 {
+		t.Error("Expected no reports to be sent with local consent")
+	}
+	if err := ph.Resend(stored[0].ID); !errors.Is(err, ErrNoConsent) {
+		t.Errorf("Expected ErrNoConsent, got %v", err)
+	}
+
+	// The choice is persisted across runs
+	if err := ph.SetConsent(ConsentFull); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ph = newHandler()
+	if ph.Consent() != ConsentFull {
+		t.Fatalf("Expected persisted consent to be full, got %s", ph.Consent())
+	}
+	crash(ph)
+	if len(reports) != 1 {
+		t.Error("Expected the report to be sent with full consent")
+	}
+}
+
+func TestConsentWithoutOption(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	if ph.Consent() != ConsentFull {
+		t.Errorf("Expected full consent without the option, got %s", ph.Consent())
+	}
+}
+
+func TestConsentFileError(t *testing.T) {
+	consentFile := filepath.Join(t.TempDir(), "consent.json")
+	if err := os.WriteFile(consentFile, []byte(`{"level":"everything"}`), 0644); err != nil {
+		t.Fatalf("Failed to write consent file: %v", err)
+	}
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithConsent(ConsentOptions{File: consentFile, Default: ConsentLocal}))
+
+	if ph.Consent() != ConsentLocal {
+		t.Errorf("Expected the default consent, got %s", ph.Consent())
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpConsent {
+		t.Errorf("Expected a consent diagnostic, got %v", diagnostics)
+	}
+}
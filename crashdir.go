@@ -0,0 +1,267 @@
+package adfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crashFileTime is the layout of the timestamp at the start of the name of a crash file in a crash directory
+const crashFileTime = "2006-01-02T15-04-05"
+
+// WithCrashDir writes each crash report to its own file in dir, named after its timestamp and
+// ID, e.g. crashes/2024-06-01T12-00-00_<id>.json. Files are written to a temporary file and renamed,
+// so concurrent processes can share the directory and log shippers never see a partial report
+func WithCrashDir(dir string) Option {
+	return func(o *Options) {
+		o.CrashDir = dir
+	}
+}
+
+// dirStorage stores each crash report in its own file in a directory
+type dirStorage struct {
+	dir        string
+	maxReports int
+	perms      filePerms
+	hooks      FileHooks
+	diagnose   func(op string, path string, err error)
+
+	// mu serialises updates of a report
+	mu sync.Mutex
+}
+
+// crashFile is a crash file in a crash directory
+type crashFile struct {
+	name    string
+	modTime time.Time
+}
+
+// Append writes a report to a new file in the crash directory
+func (d *dirStorage) Append(report CrashReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		d.diagnose(OpEncode, d.dir, err)
+		return err
+	}
+	if err := d.perms.mkdirAll(d.dir); err != nil {
+		d.diagnose(OpWrite, d.dir, err)
+		return err
+	}
+	timestamp := report.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	path := filepath.Join(d.dir, timestamp.UTC().Format(crashFileTime)+"_"+crashFileID(report.ID)+".json")
+	if err := d.write(path, data); err != nil {
+		d.diagnose(OpWrite, path, err)
+		return err
+	}
+	if d.maxReports > 0 {
+		d.trim()
+	}
+	return nil
+}
+
+// trim removes the oldest crash files beyond maxReports
+func (d *dirStorage) trim() {
+	files, err := d.files()
+	if err != nil || len(files) <= d.maxReports {
+		return
+	}
+	removed := 0
+	for _, file := range files[:len(files)-d.maxReports] {
+		path := filepath.Join(d.dir, file.name)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			d.diagnose(OpWipe, path, err)
+			continue
+		}
+		removed++
+	}
+	if d.hooks.OnPrune != nil && removed > 0 {
+		d.hooks.OnPrune(removed)
+	}
+}
+
+// write writes data to a temporary file in the crash directory and renames it to path
+func (d *dirStorage) write(path string, data []byte) error {
+	file, err := os.CreateTemp(d.dir, ".crash-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.perms.apply(file.Name(), d.perms.file)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// LastN returns the last n reports of the crash directory. Files that can't be read
+// or decoded are skipped and reported as diagnostics
+func (d *dirStorage) LastN(n int) ([]CrashReport, error) {
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	if n >= 0 && len(files) > n {
+		files = files[len(files)-n:]
+	}
+	reports := make([]CrashReport, 0, len(files))
+	for _, file := range files {
+		path := filepath.Join(d.dir, file.name)
+		report, err := readCrashFile(path)
+		if os.IsNotExist(err) {
+			// Wiped by another process
+			continue
+		}
+		if err != nil {
+			d.diagnose(OpDecode, path, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// files lists the crash files of the directory, oldest first. Files written in the same
+// second are ordered by modification time
+func (d *dirStorage) files() ([]crashFile, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []crashFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isCrashFile(entry.Name()) {
+			continue
+		}
+		file := crashFile{name: entry.Name()}
+		if info, err := entry.Info(); err == nil {
+			file.modTime = info.ModTime()
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i].name[:len(crashFileTime)], files[j].name[:len(crashFileTime)]
+		if a != b {
+			return a < b
+		}
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, nil
+}
+
+// Wipe removes every crash file from the directory. Other files are left in place
+func (d *dirStorage) Wipe() error {
+	files, err := d.files()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(filepath.Join(d.dir, file.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the crash files of the reports for which fn returns true
+func (d *dirStorage) Delete(fn func(report CrashReport) bool) (int, error) {
+	files, err := d.files()
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, file := range files {
+		path := filepath.Join(d.dir, file.name)
+		report, err := readCrashFile(path)
+		if err != nil || !fn(report) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Update rewrites the crash file of the report with the given ID
+func (d *dirStorage) Update(id string, fn func(report *CrashReport)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	matches, err := filepath.Glob(filepath.Join(d.dir, "*_"+crashFileID(id)+".json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		if !isCrashFile(filepath.Base(path)) {
+			continue
+		}
+		report, err := readCrashFile(path)
+		if err != nil {
+			return err
+		}
+		if report.ID != id {
+			continue
+		}
+		fn(&report)
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		return d.write(path, data)
+	}
+	return ErrReportNotFound
+}
+
+// readCrashFile reads the report of a crash file
+func readCrashFile(path string) (CrashReport, error) {
+	var report CrashReport
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &report)
+	}
+	return report, err
+}
+
+// isCrashFile returns true if name is the name of a crash file written by dirStorage
+func isCrashFile(name string) bool {
+	if !strings.HasSuffix(name, ".json") || len(name) <= len(crashFileTime) || name[len(crashFileTime)] != '_' {
+		return false
+	}
+	_, err := time.Parse(crashFileTime, name[:len(crashFileTime)])
+	return err == nil
+}
+
+// crashFileID returns the ID used in the name of a crash file. Characters that aren't safe
+// in file names are replaced, and reports without an ID get a random one
+func crashFileID(id string) string {
+	if id == "" {
+		return UUIDv4()
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, id)
+}
@@ -0,0 +1,72 @@
+package adfer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// defaultCrashFile is the name of the crash file in the default crash directory
+const defaultCrashFile = "crash_reports.json"
+
+// DefaultCrashDir returns the platform's directory for the crash files of an application:
+// $XDG_STATE_HOME/<app> (~/.local/state/<app>) on Linux and other Unix systems,
+// ~/Library/Logs/<app> on macOS and %LOCALAPPDATA%\<app> on Windows
+func DefaultCrashDir(appName string) (string, error) {
+	return defaultCrashDir(runtime.GOOS, os.Getenv, os.UserHomeDir, appName)
+}
+
+// defaultCrashDir resolves the default crash directory for the given OS
+func defaultCrashDir(goos string, getenv func(string) string, home func() (string, error), appName string) (string, error) {
+	if appName == "" {
+		return "", errors.New("no application name set for the crash directory")
+	}
+	var base string
+	switch goos {
+	case "windows":
+		base = getenv("LOCALAPPDATA")
+		if base == "" {
+			dir, err := home()
+			if err != nil {
+				return "", err
+			}
+			base = filepath.Join(dir, "AppData", "Local")
+		}
+	case "darwin", "ios":
+		dir, err := home()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(dir, "Library", "Logs")
+	default:
+		// Relative paths are invalid in XDG variables and must be ignored
+		base = getenv("XDG_STATE_HOME")
+		if !filepath.IsAbs(base) {
+			dir, err := home()
+			if err != nil {
+				return "", err
+			}
+			base = filepath.Join(dir, ".local", "state")
+		}
+	}
+	return filepath.Join(base, appName), nil
+}
+
+// WithDefaultCrashDir writes the crash file to the platform's directory for the application, see
+// DefaultCrashDir, instead of the working directory. The file is named after FilePath if it is set,
+// or crash_reports.json. If the directory can't be resolved, FilePath is left unchanged
+func WithDefaultCrashDir(appName string) Option {
+	return func(o *Options) {
+		dir, err := DefaultCrashDir(appName)
+		if err != nil {
+			return
+		}
+		name := defaultCrashFile
+		if o.FilePath != "" {
+			name = filepath.Base(o.FilePath)
+		}
+		o.DumpToFile = true
+		o.FilePath = filepath.Join(dir, name)
+	}
+}
@@ -0,0 +1,59 @@
+package adfer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultCrashDir(t *testing.T) {
+	home := func() (string, error) { return filepath.Join("/home", "ada"), nil }
+	state := t.TempDir()
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	tests := []struct {
+		goos string
+		env  map[string]string
+		want string
+	}{
+		{"linux", nil, filepath.Join("/home", "ada", ".local", "state", "myapp")},
+		{"linux", map[string]string{"XDG_STATE_HOME": state}, filepath.Join(state, "myapp")},
+		{"freebsd", map[string]string{"XDG_STATE_HOME": "relative"}, filepath.Join("/home", "ada", ".local", "state", "myapp")},
+		{"darwin", nil, filepath.Join("/home", "ada", "Library", "Logs", "myapp")},
+		{"windows", map[string]string{"LOCALAPPDATA": filepath.Join("/appdata")}, filepath.Join("/appdata", "myapp")},
+		{"windows", nil, filepath.Join("/home", "ada", "AppData", "Local", "myapp")},
+	}
+	for _, test := range tests {
+		env = test.env
+		dir, err := defaultCrashDir(test.goos, getenv, home, "myapp")
+		if err != nil || dir != test.want {
+			t.Errorf("%s %v: expected %s, got %s, error %v", test.goos, test.env, test.want, dir, err)
+		}
+	}
+
+	noHome := func() (string, error) { return "", errors.New("no home") }
+	if _, err := defaultCrashDir("darwin", getenv, noHome, "myapp"); err == nil {
+		t.Errorf("Expected an error without a home directory")
+	}
+	if _, err := defaultCrashDir("linux", getenv, home, ""); err == nil {
+		t.Errorf("Expected an error without an application name")
+	}
+}
+
+func TestWithDefaultCrashDir(t *testing.T) {
+	dir, err := DefaultCrashDir("myapp")
+	if err != nil {
+		t.Skipf("No default crash directory: %v", err)
+	}
+	options := Options{FilePath: "panic.json"}
+	WithDefaultCrashDir("myapp")(&options)
+	if !options.DumpToFile || options.FilePath != filepath.Join(dir, "panic.json") {
+		t.Errorf("Unexpected options %+v", options)
+	}
+	options = Options{}
+	WithDefaultCrashDir("myapp")(&options)
+	if options.FilePath != filepath.Join(dir, "crash_reports.json") {
+		t.Errorf("Expected the default file name, got %s", options.FilePath)
+	}
+}
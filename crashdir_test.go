@@ -0,0 +1,98 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCrashDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+	}, WithCrashDir(dir), WithReporter(ReporterFunc(func(context.Context, CrashReport) error {
+		return errors.New("unreachable")
+	})))
+	var ids []string
+	for i := 0; i < 3; i++ {
+		func() {
+			defer func() { ids = append(ids, ph.handlePanic(context.Background(), recover()).ID) }()
+			panic(i)
+		}()
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 crash files, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if !isCrashFile(entry.Name()) {
+			t.Errorf("Unexpected file name %s", entry.Name())
+		}
+	}
+
+	reports, err := ph.GetLastNCrashReports(2)
+	if err != nil || len(reports) != 2 || reports[0].ID != ids[1] || reports[1].ID != ids[2] {
+		t.Fatalf("Expected the last 2 reports, got %+v, error %v", reports, err)
+	}
+	pending, err := ph.PendingReports()
+	if err != nil || len(pending) != 3 {
+		t.Errorf("Expected delivery receipts in the crash files, got %d pending, error %v", len(pending), err)
+	}
+
+	reader, err := OpenReadOnly(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if all, _ := reader.GetLastNCrashReports(10); len(all) != 3 {
+		t.Errorf("Expected the reader to read 3 reports, got %d", len(all))
+	}
+
+	os.WriteFile(filepath.Join(dir, "notes.json"), []byte("{}"), 0644)
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "notes.json" {
+		t.Errorf("Expected only other files to remain, got %v", entries)
+	}
+}
+
+func TestCrashDirFiles(t *testing.T) {
+	dir := t.TempDir()
+	var diagnostics []Diagnostic
+	storage := &dirStorage{dir: dir, perms: permsFromOptions(Options{}), diagnose: func(op, path string, err error) {
+		diagnostics = append(diagnostics, Diagnostic{Op: op, Path: path, Err: err})
+	}}
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	storage.Append(CrashReport{ID: "a/b", Timestamp: timestamp})
+	os.WriteFile(filepath.Join(dir, "2024-06-01T12-00-01_torn.json"), []byte("{"), 0644)
+
+	if _, err := os.Stat(filepath.Join(dir, "2024-06-01T12-00-00_a_b.json")); err != nil {
+		t.Fatalf("Expected the ID to be made safe for the file name: %v", err)
+	}
+
+	if err := storage.Update("a/b", func(report *CrashReport) { report.Error = "updated" }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, err := storage.LastN(-1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Error != "updated" {
+		t.Errorf("Expected the updated report, got %+v", reports)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpDecode {
+		t.Errorf("Expected a decode diagnostic for the torn file, got %v", diagnostics)
+	}
+	if err := storage.Update("missing", func(*CrashReport) {}); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrReportNotFound is returned when no crash report has the requested ID
+var ErrReportNotFound = errors.New("crash report not found")
+
+// DeliveryStatus is the delivery status of a crash report to a reporter
+type DeliveryStatus string
+
+const (
+	// DeliveryPending means the report has not been delivered yet
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySent means the report was delivered
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryFailed means the reporter returned an error
+	DeliveryFailed DeliveryStatus = "failed"
+	// DeliveryDropped means the reporter deliberately skipped the report, e.g. because of rate limiting
+	DeliveryDropped DeliveryStatus = "dropped"
+	// DeliveryFallback means the reporter failed and the report was delivered to its sink's fallback reporter
+	DeliveryFallback DeliveryStatus = "fallback"
+	// DeliveryDeclined means the user declined to submit the report at the consent prompt,
+	// or has not consented to reports being sent
+	DeliveryDeclined DeliveryStatus = "declined"
+)
+
+// Delivery is the receipt of a crash report's delivery to a single reporter
+type Delivery struct {
+	Status    DeliveryStatus `json:"status"`
+	Attempts  int            `json:"attempts"`
+	Error     string         `json:"error,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// reporterName returns the name of a reporter used in delivery receipts.
+// Reporters can choose their name by implementing Name() string
+func reporterName(reporter Reporter) string {
+	if named, ok := reporter.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", reporter), "*")
+}
+
+// findReporter returns the reporter of type T, looking through wrappers such as Sink and CircuitBreaker
+func findReporter[T Reporter](reporter Reporter) (T, bool) {
+	for {
+		if found, ok := reporter.(T); ok {
+			return found, true
+		}
+		wrapper, ok := reporter.(interface{ Unwrap() Reporter })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		reporter = wrapper.Unwrap()
+	}
+}
+
+// uniqueReporterNames returns a unique name for each reporter, suffixing duplicates with a number
+func uniqueReporterNames(reporters []Reporter) []string {
+	names := make([]string, len(reporters))
+	seen := make(map[string]int)
+	for i, reporter := range reporters {
+		name := reporterName(reporter)
+		seen[name]++
+		if seen[name] > 1 {
+			name += "-" + strconv.Itoa(seen[name])
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// tracksDeliveries returns true if delivery receipts are stored with the crash reports
+func (ph *PanicHandler) tracksDeliveries() bool {
+	if !ph.stores() || len(ph.options.Reporters) == 0 {
+		return false
+	}
+	if file, ok := ph.storage.(*fileStorage); ok && file.path == "" {
+		return false
+	}
+	_, ok := ph.storage.(ReportUpdater)
+	return ok
+}
+
+// pendingDeliveries returns a pending delivery for every configured reporter
+func (ph *PanicHandler) pendingDeliveries() map[string]Delivery {
+	return ph.allDeliveries(Delivery{Status: DeliveryPending, UpdatedAt: time.Now()})
+}
+
+// allDeliveries returns the same delivery for every configured reporter
+func (ph *PanicHandler) allDeliveries(delivery Delivery) map[string]Delivery {
+	deliveries := make(map[string]Delivery, len(ph.reporterNames))
+	for _, name := range ph.reporterNames {
+		deliveries[name] = delivery
+	}
+	return deliveries
+}
+
+// deliverAll sends a report to every configured reporter and returns the receipts
+func (ph *PanicHandler) deliverAll(ctx context.Context, report CrashReport) map[string]Delivery {
+	deliveries := make(map[string]Delivery, len(ph.options.Reporters))
+	for i := range ph.options.Reporters {
+		deliveries[ph.reporterNames[i]] = ph.deliver(ctx, i, report, Delivery{})
+	}
+	return deliveries
+}
+
+// storeDeliveries stores the receipts of a report in the crash file, if it is tracked
+func (ph *PanicHandler) storeDeliveries(tracked bool, id string, deliveries map[string]Delivery) {
+	if !tracked {
+		return
+	}
+	if err := ph.updateDeliveries(id, deliveries); err != nil {
+		ph.diagnose(OpWrite, ph.options.FilePath, err)
+	}
+}
+
+// recordDeliveries stores the receipts of a delivered report and spools it if any delivery failed
+func (ph *PanicHandler) recordDeliveries(tracked bool, report CrashReport, deliveries map[string]Delivery) {
+	ph.storeDeliveries(tracked, report.ID, deliveries)
+	ph.spool(report, deliveries)
+}
+
+// completeDelivery records the receipts of a delivered report and calls the AfterReport hooks with the
+// error storing it and the errors of the failed deliveries
+func (ph *PanicHandler) completeDelivery(tracked bool, report CrashReport, deliveries map[string]Delivery, storeErr error) {
+	ph.recordDeliveries(tracked, report, deliveries)
+	if len(ph.options.AfterReport) == 0 {
+		return
+	}
+	if len(deliveries) > 0 {
+		report.Deliveries = deliveries
+	}
+	ph.afterReport(report, errors.Join(storeErr, deliveryErrors(deliveries)))
+}
+
+// deliver sends a report to a configured reporter under HandlerTimeout and returns the updated receipt.
+// A reporter that panics fails the delivery
+func (ph *PanicHandler) deliver(ctx context.Context, index int, report CrashReport, previous Delivery) Delivery {
+	reporter := ph.options.Reporters[index]
+	err := isolate(ctx, ph.options.HandlerTimeout, func(ctx context.Context) error {
+		return reporter.Report(ctx, report)
+	})
+	delivery := Delivery{
+		Status:    DeliverySent,
+		Attempts:  previous.Attempts + 1,
+		UpdatedAt: time.Now(),
+	}
+	if err != nil {
+		delivery.Status = DeliveryFailed
+		delivery.Error = err.Error()
+		var handled *sinkError
+		switch {
+		case errors.As(err, &handled) && handled.policy == DropFailure:
+			delivery.Status = DeliveryDropped
+			return delivery
+		case errors.As(err, &handled) && handled.policy == FallbackOnFailure:
+			delivery.Status = DeliveryFallback
+		case errors.Is(err, ErrRateLimited) || errors.Is(err, ErrCircuitOpen):
+			delivery.Status = DeliveryDropped
+		}
+		ph.diagnose(OpReport, ph.reporterNames[index], err)
+	}
+	return delivery
+}
+
+// updateDeliveries stores the delivery receipts of a report
+func (ph *PanicHandler) updateDeliveries(id string, deliveries map[string]Delivery) error {
+	updater, ok := ph.storage.(ReportUpdater)
+	if !ok {
+		return fmt.Errorf("storage can't store delivery receipts")
+	}
+	return updater.Update(id, func(report *CrashReport) {
+		report.Deliveries = deliveries
+	})
+}
+
+// Resend delivers the crash report with the given ID to every configured reporter it
+// has not yet been sent to, and stores the updated receipts. It returns ErrNoConsent
+// unless the consent level is ConsentFull
+func (ph *PanicHandler) Resend(id string) error {
+	if ph.Consent() < ConsentFull {
+		return ErrNoConsent
+	}
+	reports, err := ph.readCrashReports()
+	if err != nil {
+		return err
+	}
+	var report *CrashReport
+	for i := range reports {
+		if reports[i].ID == id {
+			report = &reports[i]
+			break
+		}
+	}
+	if report == nil {
+		return ErrReportNotFound
+	}
+
+	deliveries, failed := ph.redeliver(context.Background(), *report)
+	if err := ph.updateDeliveries(id, deliveries); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to deliver crash report %s to %s", id, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ResendUnsent delivers every stored crash report to the reporters it hasn't reached yet,
+// including reports recorded without any reporters, e.g. on an air-gapped machine. Reports
+// the user declined to send are skipped. It returns the number of reports that were
+// delivered to every remaining reporter
+func (ph *PanicHandler) ResendUnsent(ctx context.Context) (int, error) {
+	if ph.Consent() < ConsentFull {
+		return 0, ErrNoConsent
+	}
+	reports, err := ph.readCrashReports()
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	var failedIDs []string
+	for _, report := range reports {
+		if !ph.unsent(report) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+		deliveries, failed := ph.redeliver(ctx, report)
+		if err := ph.updateDeliveries(report.ID, deliveries); err != nil {
+			return delivered, err
+		}
+		if len(failed) > 0 {
+			failedIDs = append(failedIDs, report.ID)
+			continue
+		}
+		delivered++
+	}
+	if len(failedIDs) > 0 {
+		return delivered, fmt.Errorf("failed to deliver crash reports %s", strings.Join(failedIDs, ", "))
+	}
+	return delivered, nil
+}
+
+// unsent reports whether a crash report hasn't reached every reporter and the user didn't decline to send it
+func (ph *PanicHandler) unsent(report CrashReport) bool {
+	for _, delivery := range report.Deliveries {
+		if delivery.Status == DeliveryDeclined {
+			return false
+		}
+	}
+	for _, name := range ph.reporterNames {
+		if report.Deliveries[name].Status != DeliverySent {
+			return true
+		}
+	}
+	return false
+}
+
+// redeliver sends a stored report to every configured reporter it has not yet been sent to.
+// It returns the updated receipts and the names of the reporters that failed again
+func (ph *PanicHandler) redeliver(ctx context.Context, report CrashReport) (map[string]Delivery, []string) {
+	deliveries := make(map[string]Delivery, len(ph.reporterNames))
+	for name, delivery := range report.Deliveries {
+		deliveries[name] = delivery
+	}
+	report.Deliveries = nil
+
+	var failed []string
+	for i, name := range ph.reporterNames {
+		previous, ok := deliveries[name]
+		if ok && previous.Status == DeliverySent {
+			continue
+		}
+		delivery := ph.deliver(ctx, i, report, previous)
+		deliveries[name] = delivery
+		if delivery.Status != DeliverySent {
+			failed = append(failed, name)
+		}
+	}
+	return deliveries, failed
+}
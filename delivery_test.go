@@ -0,0 +1,148 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type namedReporter struct {
+	name string
+	err  error
+}
+
+func (n *namedReporter) Name() string {
+	return n.name
+}
+
+func (n *namedReporter) Report(context.Context, CrashReport) error {
+	return n.err
+}
+
+func TestDeliveryReceipts(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "crash_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	webhook := &namedReporter{name: "webhook", err: errors.New("endpoint down")}
+	email := &namedReporter{name: "email", err: ErrRateLimited}
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     tempFile.Name(),
+		OnDiagnostic: func(Diagnostic) {},
+	},
+		WithReporter(&namedReporter{name: "sentry"}),
+		WithReporter(webhook),
+		WithReporter(email),
+		WithReporter(&namedReporter{name: "sentry"}),
+	)
+
+	func() {
+		defer ph.Recover()
+		panic("delivery panic")
+	}()
+
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deliveries := reports[0].Deliveries
+	expected := map[string]DeliveryStatus{
+		"sentry":   DeliverySent,
+		"sentry-2": DeliverySent,
+		"webhook":  DeliveryFailed,
+		"email":    DeliveryDropped,
+	}
+	for name, status := range expected {
+		if deliveries[name].Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", name, status, deliveries[name])
+		}
+	}
+	if deliveries["webhook"].Error != "endpoint down" || deliveries["webhook"].Attempts != 1 {
+		t.Errorf("Unexpected webhook receipt: %+v", deliveries["webhook"])
+	}
+
+	pending, err := ph.PendingReports()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != reports[0].ID {
+		t.Fatalf("Expected the report to be pending, got %+v", pending)
+	}
+
+	// Resend only retries failed deliveries
+	webhook.err = nil
+	if err := ph.Resend(reports[0].ID); err == nil {
+		t.Error("Expected error as email is still rate limited")
+	}
+	email.err = nil
+	if err := ph.Resend(reports[0].ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, _ = ph.GetLastNCrashReports(1)
+	deliveries = reports[0].Deliveries
+	if deliveries["webhook"].Attempts != 2 || deliveries["email"].Attempts != 3 || deliveries["sentry"].Attempts != 1 {
+		t.Errorf("Unexpected attempts: %+v", deliveries)
+	}
+	pending, _ = ph.PendingReports()
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending reports, got %d", len(pending))
+	}
+
+	if err := ph.Resend("unknown"); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
+
+func TestResendUnsent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crashes.json")
+	reports := []CrashReport{
+		{ID: "offline", Error: "recorded without reporters"},
+		{ID: "sent", Error: "already sent", Deliveries: map[string]Delivery{"webhook": {Status: DeliverySent, Attempts: 1}}},
+		{ID: "declined", Error: "user declined", Deliveries: map[string]Delivery{"webhook": {Status: DeliveryDeclined}}},
+	}
+	data, _ := json.Marshal(reports)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write crash file: %v", err)
+	}
+
+	webhook := &namedReporter{name: "webhook"}
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     path,
+		OnDiagnostic: func(Diagnostic) {},
+	}, WithReporter(webhook))
+	delivered, err := ph.ResendUnsent(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("Expected 1 delivered report, got %d (%v)", delivered, err)
+	}
+
+	stored, err := ph.readCrashReports()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if delivery := stored[0].Deliveries["webhook"]; delivery.Status != DeliverySent || delivery.Attempts != 1 {
+		t.Errorf("Expected the offline report to be sent, got %+v", delivery)
+	}
+	if delivery := stored[1].Deliveries["webhook"]; delivery.Attempts != 1 {
+		t.Errorf("Expected the sent report not to be resent, got %+v", delivery)
+	}
+	if delivery := stored[2].Deliveries["webhook"]; delivery.Status != DeliveryDeclined {
+		t.Errorf("Expected the declined report to be skipped, got %+v", delivery)
+	}
+
+	webhook.err = errors.New("offline")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write crash file: %v", err)
+	}
+	if delivered, err := ph.ResendUnsent(context.Background()); err == nil || delivered != 0 {
+		t.Errorf("Expected an error for the failed delivery, got %d (%v)", delivered, err)
+	}
+}
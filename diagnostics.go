@@ -0,0 +1,132 @@
+package adfer
+
+import (
+	"fmt"
+	"time"
+)
+
+// Diagnostic operations
+const (
+	// OpWipe is reported when the crash file could not be wiped
+	OpWipe = "wipe"
+	// OpRead is reported when the crash file could not be read
+	OpRead = "read"
+	// OpDecode is reported when the crash file could not be unmarshalled
+	OpDecode = "decode"
+	// OpEncode is reported when a crash report could not be marshalled
+	OpEncode = "encode"
+	// OpWrite is reported when a crash report could not be written to the crash file
+	OpWrite = "write"
+	// OpReport is reported when a reporter failed to deliver a crash report
+	OpReport = "report"
+	// OpTemplate is reported when a metadata template could not be parsed or executed
+	OpTemplate = "template"
+	// OpTrace is reported when the execution trace could not be captured
+	OpTrace = "trace"
+	// OpConsent is reported when the persisted consent choice could not be read
+	OpConsent = "consent"
+	// OpSpool is reported when the spool directory could not be read or written
+	OpSpool = "spool"
+	// OpIndex is reported when the crash file doesn't match its index, or the index could not be written
+	OpIndex = "index"
+	// OpLock is reported when the lock file of the crash file could not be locked
+	OpLock = "lock"
+	// OpRotate is reported when the crash file could not be rotated
+	OpRotate = "rotate"
+	// OpRepair is reported when a corrupt crash file was backed up and rewritten with the reports that could be recovered
+	OpRepair = "repair"
+	// OpImport is reported when a legacy panic log could not be imported
+	OpImport = "import"
+	// OpCollect is reported when a collector or plugin host received an invalid crash report
+	OpCollect = "collect"
+	// OpMetrics is reported when metrics could not be sent
+	OpMetrics = "metrics"
+	// OpHandler is reported when an error handler panicked or timed out
+	OpHandler = "handler"
+	// OpHook is reported when a BeforeReport, AfterReport or OnExit hook, a classifier or a fingerprinter
+	// panicked, or OnExit hooks timed out
+	OpHook = "hook"
+	// OpCleanup is reported when a cleanup registered with RegisterCleanup failed, panicked or timed out
+	OpCleanup = "cleanup"
+)
+
+// Diagnostic describes an operational failure of the crash reporter itself
+type Diagnostic struct {
+	// Op is the operation that failed, e.g. OpWrite
+	Op string `json:"op"`
+	// Path is the file or destination the operation was acting on, if any
+	Path string `json:"path,omitempty"`
+	// Err is the underlying error
+	Err error `json:"-"`
+	// Timestamp is when the failure happened
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DiagnosticHandler is a function type for receiving diagnostics
+type DiagnosticHandler func(Diagnostic)
+
+var diagnosticDescriptions = map[string]string{
+	OpWipe:     "wiping crash file",
+	OpRead:     "reading crash file",
+	OpDecode:   "unmarshalling crash reports",
+	OpEncode:   "marshalling crash reports",
+	OpWrite:    "writing crash report to file",
+	OpReport:   "sending crash report",
+	OpTemplate: "resolving metadata template",
+	OpTrace:    "capturing execution trace",
+	OpConsent:  "reading consent file",
+	OpSpool:    "spooling crash report",
+	OpIndex:    "indexing crash file",
+	OpLock:     "locking crash file",
+	OpRotate:   "rotating crash file",
+	OpRepair:   "repairing crash file",
+	OpImport:   "importing panic log",
+	OpCollect:  "collecting crash report",
+	OpMetrics:  "sending metrics",
+	OpHandler:  "running error handler",
+	OpHook:     "running report hook",
+	OpCleanup:  "running cleanup",
+}
+
+// Error implements the error interface
+func (d Diagnostic) Error() string {
+	description, ok := diagnosticDescriptions[d.Op]
+	if !ok {
+		description = d.Op
+	}
+	return fmt.Sprintf("Error %s: %v", description, d.Err)
+}
+
+// Unwrap returns the underlying error
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// diagnose records an operational failure, delivers it to the registered
+// DiagnosticHandler and logs it
+func (ph *PanicHandler) diagnose(op string, path string, err error) {
+	diagnostic := Diagnostic{
+		Op:        op,
+		Path:      path,
+		Err:       err,
+		Timestamp: time.Now(),
+	}
+
+	ph.mu.Lock()
+	ph.stats.Diagnostics++
+	if ph.stats.DiagnosticsByOp == nil {
+		ph.stats.DiagnosticsByOp = make(map[string]int)
+	}
+	ph.stats.DiagnosticsByOp[op]++
+	ph.mu.Unlock()
+
+	if ph.options.OnDiagnostic != nil {
+		ph.options.OnDiagnostic(diagnostic)
+	}
+	switch {
+	case ph.options.Logger != nil:
+		ph.options.Logger.Println(diagnostic.Error())
+	case ph.options.OnDiagnostic == nil:
+		fmt.Println(diagnostic.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingReporter struct {
+	err error
+}
+
+func (f failingReporter) Report(context.Context, CrashReport) error {
+	return f.err
+}
+
+func TestDiagnosticCallback(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "crash_report.json")
+	if err := os.Mkdir(filePath, 0755); err != nil {
+		t.Fatalf("Failed to create unwritable path: %v", err)
+	}
+
+	var diagnostics []Diagnostic
+	reportErr := errors.New("endpoint down")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+		Reporters:    []Reporter{failingReporter{err: reportErr}},
+		OnDiagnostic: func(d Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		},
+	})
+
+	func() {
+		defer ph.Recover()
+		panic("test panic")
+	}()
+
+	ops := map[string]Diagnostic{}
+	for _, d := range diagnostics {
+		ops[d.Op] = d
+	}
+	write, ok := ops[OpWrite]
+	if !ok {
+		t.Fatalf("Expected write diagnostic, got %+v", diagnostics)
+	}
+	if write.Path != filePath || write.Err == nil || write.Timestamp.IsZero() {
+		t.Errorf("Unexpected write diagnostic: %+v", write)
+	}
+	if !errors.Is(ops[OpReport], reportErr) {
+		t.Errorf("Expected report diagnostic to wrap reporter error, got %v", ops[OpReport])
+	}
+
+	stats := ph.Stats()
+	if stats.Panics != 1 {
+		t.Errorf("Expected 1 panic, got %d", stats.Panics)
+	}
+	if stats.Diagnostics != len(diagnostics) {
+		t.Errorf("Expected %d diagnostics, got %d", len(diagnostics), stats.Diagnostics)
+	}
+	if stats.DiagnosticsByOp[OpWrite] != 1 || stats.DiagnosticsByOp[OpReport] != 1 {
+		t.Errorf("Unexpected diagnostics by op: %+v", stats.DiagnosticsByOp)
+	}
+}
+
+func TestDiagnosticLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ph := New(Options{
+		Logger: log.New(&buf, "", 0),
+	})
+	ph.diagnose(OpWipe, "crash.json", errors.New("permission denied"))
+
+	expected := "Error wiping crash file: permission denied\n"
+	if buf.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, buf.String())
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	d := Diagnostic{Op: "custom", Err: errors.New("boom"), Timestamp: time.Now()}
+	if !strings.Contains(d.Error(), "Error custom: boom") {
+		t.Errorf("Unexpected error string '%s'", d.Error())
+	}
+
+	// Every exported Op has a description
+	file, err := parser.ParseFile(token.NewFileSet(), "diagnostics.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse diagnostics.go: %v", err)
+	}
+	ops := 0
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok || !strings.HasPrefix(spec.Names[0].Name, "Op") || len(spec.Values) != 1 {
+			return true
+		}
+		op, _ := strconv.Unquote(spec.Values[0].(*ast.BasicLit).Value)
+		if _, ok := diagnosticDescriptions[op]; !ok {
+			t.Errorf("Expected a description of %s", spec.Names[0].Name)
+		}
+		ops++
+		return true
+	})
+	if ops != len(diagnosticDescriptions) {
+		t.Errorf("Expected a description for each of the %d ops, got %d", ops, len(diagnosticDescriptions))
+	}
+}
@@ -0,0 +1,139 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownMethod is returned by Dispatcher.Call for methods the receiver doesn't have
+var ErrUnknownMethod = errors.New("unknown method")
+
+// contextType is the type of context.Context
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// errorType is the type of error
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Dispatcher calls the exported methods of a receiver by name, as RPC routers do, containing panics
+// from arguments that don't fit the method and from bugs in the methods
+type Dispatcher struct {
+	ph      *PanicHandler
+	methods map[string]reflect.Value
+}
+
+// DispatchError is returned by Dispatcher.Call when a method is unknown or panicked, so the
+// transport can answer with a structured error
+type DispatchError struct {
+	// Method is the name of the method called
+	Method string `json:"method"`
+	// ArgTypes are the types of the arguments the method was called with
+	ArgTypes []string `json:"arg_types,omitempty"`
+	// Err is ErrUnknownMethod or a *PanicError
+	Err error `json:"-"`
+}
+
+// Error returns the method, the argument types and the cause
+func (e *DispatchError) Error() string {
+	return fmt.Sprintf("calling %s(%s): %v", e.Method, strings.Join(e.ArgTypes, ", "), e.Err)
+}
+
+// Unwrap returns the cause
+func (e *DispatchError) Unwrap() error {
+	return e.Err
+}
+
+// NewDispatcher creates a Dispatcher for the exported methods of receiver, whose panics are reported through ph
+func NewDispatcher(ph *PanicHandler, receiver any) *Dispatcher {
+	value := reflect.ValueOf(receiver)
+	methods := make(map[string]reflect.Value, value.NumMethod())
+	for i := 0; i < value.NumMethod(); i++ {
+		methods[value.Type().Method(i).Name] = value.Method(i)
+	}
+	return &Dispatcher{ph: ph, methods: methods}
+}
+
+// Call calls the method with the given name. ctx is passed as the first argument if the method takes a
+// context.Context first. Arguments are converted to the parameter types where possible, e.g. the float64
+// numbers of decoded JSON to ints, and nil becomes the zero value. The results are returned, except for
+// a trailing error, which is returned as the error. A panic, including one from arguments that don't fit
+// the method, is reported with the "method" and "arg_types" metadata and returned as a *DispatchError
+// wrapping a *PanicError
+func (d *Dispatcher) Call(ctx context.Context, method string, args ...any) (results []any, err error) {
+	argTypes := make([]string, len(args))
+	for i, arg := range args {
+		argTypes[i] = fmt.Sprintf("%T", arg)
+	}
+	fn, ok := d.methods[method]
+	if !ok {
+		return nil, &DispatchError{Method: method, ArgTypes: argTypes, Err: ErrUnknownMethod}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := &PanicError{Value: r}
+			err = &DispatchError{Method: method, ArgTypes: argTypes, Err: panicErr}
+			results = nil
+			metadata := map[string]string{"method": method, "arg_types": strings.Join(argTypes, ", ")}
+			panicErr.ReportID = d.ph.handlePanicWith(ctx, r, metadata).ID
+		}
+	}()
+	out := fn.Call(callArgs(ctx, fn.Type(), args))
+	if n := len(out); n > 0 && fn.Type().Out(n-1) == errorType {
+		if !out[n-1].IsNil() {
+			err = out[n-1].Interface().(error)
+		}
+		out = out[:n-1]
+	}
+	results = make([]any, len(out))
+	for i, value := range out {
+		results[i] = value.Interface()
+	}
+	return results, err
+}
+
+// convertible returns true if an argument of type from is converted to a parameter of type to: between
+// numbers, and between types of the same kind such as a string and a named string type. Other conversions,
+// such as from an int to a string, would change the meaning of the argument
+func convertible(from, to reflect.Type) bool {
+	if from == to || from.AssignableTo(to) || !from.ConvertibleTo(to) {
+		return false
+	}
+	return from.Kind() == to.Kind() || isNumber(from.Kind()) && isNumber(to.Kind())
+}
+
+// isNumber returns true for the kinds of integers and floats
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+// callArgs converts args to the parameters of a method of type fn, prepending ctx if it takes a
+// context first. Arguments that can't be converted are passed as they are, so the call panics
+func callArgs(ctx context.Context, fn reflect.Type, args []any) []reflect.Value {
+	var values []reflect.Value
+	if fn.NumIn() > 0 && fn.In(0) == contextType {
+		values = append(values, reflect.ValueOf(&ctx).Elem())
+	}
+	for _, arg := range args {
+		i := len(values)
+		var param reflect.Type
+		switch {
+		case fn.IsVariadic() && i >= fn.NumIn()-1:
+			param = fn.In(fn.NumIn() - 1).Elem()
+		case i < fn.NumIn():
+			param = fn.In(i)
+		}
+		value := reflect.ValueOf(arg)
+		switch {
+		case param == nil:
+		case arg == nil:
+			value = reflect.Zero(param)
+		case convertible(value.Type(), param):
+			value = value.Convert(param)
+		}
+		values = append(values, value)
+	}
+	return values
+}
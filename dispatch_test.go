@@ -0,0 +1,81 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testService struct{}
+
+func (testService) Add(a, b int) int {
+	return a + b
+}
+
+func (testService) Greet(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", errors.New("no name")
+	}
+	return fmt.Sprintf("hello %s from %v", name, ctx.Value(testContextKey{})), nil
+}
+
+func (testService) Sum(values ...int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+func (testService) Index(values []string, i int) string {
+	return values[i]
+}
+
+type testContextKey struct{}
+
+func TestDispatcher(t *testing.T) {
+	reports := make(chan CrashReport, 2)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, Reporters: []Reporter{channelReporter(reports)}})
+	dispatcher := NewDispatcher(ph, testService{})
+	ctx := context.WithValue(context.Background(), testContextKey{}, "test")
+
+	// Decoded JSON numbers are float64
+	if results, err := dispatcher.Call(ctx, "Add", 2.0, 3.0); err != nil || len(results) != 1 || results[0] != 5 {
+		t.Errorf("Expected 5, got %v, error %v", results, err)
+	}
+	if results, err := dispatcher.Call(ctx, "Greet", "bob"); err != nil || results[0] != "hello bob from test" {
+		t.Errorf("Expected a greeting with the context value, got %v, error %v", results, err)
+	}
+	if _, err := dispatcher.Call(ctx, "Greet", ""); err == nil || err.Error() != "no name" {
+		t.Errorf("Expected the method's error, got %v", err)
+	}
+	if results, err := dispatcher.Call(ctx, "Sum", 1, 2, 3); err != nil || results[0] != 6 {
+		t.Errorf("Expected 6, got %v, error %v", results, err)
+	}
+
+	var dispatchErr *DispatchError
+	if _, err := dispatcher.Call(ctx, "Missing"); !errors.As(err, &dispatchErr) || !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("Expected an unknown method error, got %v", err)
+	}
+
+	// A bad argument and a bug in the method are both contained and reported
+	for _, call := range []struct {
+		method string
+		args   []any
+	}{
+		{"Add", []any{"two", 3}},
+		{"Index", []any{[]string{"a"}, 5}},
+	} {
+		method, args := call.method, call.args
+		_, err := dispatcher.Call(ctx, method, args...)
+		var panicErr *PanicError
+		if !errors.As(err, &dispatchErr) || !errors.As(err, &panicErr) || dispatchErr.Method != method {
+			t.Fatalf("Expected a dispatch error wrapping a panic, got %v", err)
+		}
+		report := <-reports
+		if report.ID != panicErr.ReportID || report.Metadata["method"] != method || report.Metadata["arg_types"] != dispatchErr.ArgTypes[0]+", int" {
+			t.Errorf("Unexpected report %+v for %v", report.Metadata, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ElasticsearchOptions configures an ElasticsearchReporter
+type ElasticsearchOptions struct {
+	// URL is the base URL of the cluster, e.g. "https://localhost:9200"
+	URL string
+	// Index is the index the crash reports are written to. Defaults to "crash-reports"
+	Index string
+	// Username and Password authenticate with basic authentication
+	Username string
+	Password string
+	// APIKey authenticates with an encoded API key instead of basic authentication
+	APIKey string
+	// HTTPClient is the client used to send requests. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// ElasticsearchReporter indexes crash reports into an Elasticsearch or OpenSearch index. The index is
+// created with ElasticsearchMapping before the first report is written, unless it already exists
+type ElasticsearchReporter struct {
+	options ElasticsearchOptions
+
+	mu      sync.Mutex
+	created bool
+}
+
+// NewElasticsearchReporter creates an ElasticsearchReporter from the given options
+func NewElasticsearchReporter(options ElasticsearchOptions) *ElasticsearchReporter {
+	if options.Index == "" {
+		options.Index = "crash-reports"
+	}
+	options.URL = strings.TrimRight(options.URL, "/")
+	return &ElasticsearchReporter{options: options}
+}
+
+// WithElasticsearch indexes every crash report into Elasticsearch or OpenSearch
+func WithElasticsearch(options ElasticsearchOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewElasticsearchReporter(options))
+	}
+}
+
+// ElasticsearchMapping returns the mapping of the crash report index: keyword fields for
+// identifiers, the fingerprint, tags and metadata, so they can be aggregated, and text for
+// the error message and stack
+func ElasticsearchMapping() map[string]any {
+	keyword := map[string]any{"type": "keyword"}
+	return map[string]any{
+		"mappings": map[string]any{
+			"dynamic_templates": []map[string]any{{
+				"strings_as_keywords": map[string]any{
+					"match_mapping_type": "string",
+					"mapping":            keyword,
+				},
+			}},
+			"properties": map[string]any{
+				"@timestamp": map[string]any{"type": "date"},
+				"id":         keyword,
+				"error": map[string]any{
+					"type":   "text",
+					"fields": map[string]any{"keyword": map[string]any{"type": "keyword", "ignore_above": 1024}},
+				},
+				"error_type":  keyword,
+				"stack":       map[string]any{"type": "text"},
+				"fingerprint": keyword,
+				"culprit":     keyword,
+				"category":    keyword,
+				"handled":     map[string]any{"type": "boolean"},
+				"host":        keyword,
+				"tags":        keyword,
+				"metadata":    map[string]any{"type": "object", "dynamic": true},
+				"system_info": map[string]any{"type": "object", "dynamic": true},
+			},
+		},
+	}
+}
+
+// Document converts a crash report into the document indexed for it
+func (e *ElasticsearchReporter) Document(report CrashReport) map[string]any {
+	document := map[string]any{
+		"@timestamp":  report.Timestamp,
+		"error":       report.Error,
+		"error_type":  report.ErrorType,
+		"stack":       report.Stack,
+		"fingerprint": Fingerprint(report),
+		"handled":     report.Handled,
+		"host":        reportHost(report),
+	}
+	if report.ID != "" {
+		document["id"] = report.ID
+	}
+	if app := appFrames(ParseStack(report.Stack)); len(app) > 0 {
+		document["culprit"] = app[0].Function
+	}
+	if report.Category != "" {
+		document["category"] = report.Category
+	}
+	if len(report.Tags) > 0 {
+		document["tags"] = report.Tags
+	}
+	if len(report.Metadata) > 0 {
+		document["metadata"] = report.Metadata
+	}
+	if report.SystemInfo.OS != "" {
+		document["system_info"] = report.SystemInfo
+	}
+	return document
+}
+
+// Name returns the name used in delivery receipts
+func (e *ElasticsearchReporter) Name() string {
+	return "elasticsearch"
+}
+
+// Report indexes the crash report. Reports with an ID are indexed under it, so
+// redelivering a report doesn't create a duplicate document
+func (e *ElasticsearchReporter) Report(ctx context.Context, report CrashReport) error {
+	if err := e.createIndex(ctx); err != nil {
+		return err
+	}
+	path := "/" + url.PathEscape(e.options.Index) + "/_doc"
+	method := http.MethodPost
+	if report.ID != "" {
+		path += "/" + url.PathEscape(report.ID)
+		method = http.MethodPut
+	}
+	return e.call(ctx, method, path, e.Document(report))
+}
+
+// createIndex creates the index with its mapping, once. An existing index is left unchanged
+func (e *ElasticsearchReporter) createIndex(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.created {
+		return nil
+	}
+	err := e.call(ctx, http.MethodPut, "/"+url.PathEscape(e.options.Index), ElasticsearchMapping())
+	if err != nil && !strings.Contains(err.Error(), "resource_already_exists_exception") {
+		return err
+	}
+	e.created = true
+	return nil
+}
+
+// call sends a JSON request to the cluster
+func (e *ElasticsearchReporter) call(ctx context.Context, method, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.options.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.options.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.options.APIKey)
+	} else if e.options.Username != "" {
+		req.SetBasicAuth(e.options.Username, e.options.Password)
+	}
+	return do(e.options.HTTPClient, req)
+}
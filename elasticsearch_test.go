@@ -0,0 +1,78 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticsearchReporter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	documents := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if user, password, _ := r.BasicAuth(); user != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/crashes" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+			return
+		}
+		documents[r.URL.Path] = body
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	reporter := NewElasticsearchReporter(ElasticsearchOptions{
+		URL:      server.URL + "/",
+		Index:    "crashes",
+		Username: "elastic",
+		Password: "secret",
+	})
+	report := CrashReport{
+		ID:        "crash-1",
+		Timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Error:     "nil map",
+		Stack:     testStack,
+		Metadata:  map[string]string{"region": "eu"},
+		Tags:      []string{"queue=email"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := reporter.Report(context.Background(), report); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []string{"PUT /crashes", "PUT /crashes/_doc/crash-1", "PUT /crashes/_doc/crash-1"}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected requests %v, got %v", expected, requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("Expected request %d to be %s, got %s", i, expected[i], requests[i])
+		}
+	}
+	document := documents["/crashes/_doc/crash-1"]
+	if document["@timestamp"] != "2024-06-01T12:00:00Z" || document["fingerprint"] != Fingerprint(report) || document["culprit"] != "main.inner" {
+		t.Errorf("Unexpected document: %v", document)
+	}
+	if document["metadata"].(map[string]any)["region"] != "eu" || document["tags"].([]any)[0] != "queue=email" {
+		t.Errorf("Unexpected metadata or tags: %v", document)
+	}
+
+	properties := ElasticsearchMapping()["mappings"].(map[string]any)["properties"].(map[string]any)
+	if properties["fingerprint"].(map[string]any)["type"] != "keyword" || properties["stack"].(map[string]any)["type"] != "text" {
+		t.Errorf("Unexpected mapping: %v", properties)
+	}
+}
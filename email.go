@@ -0,0 +1,266 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by notifiers that dropped a report to avoid flooding recipients
+var ErrRateLimited = errors.New("rate limit exceeded, report dropped")
+
+// SMTPConfig configures the email notifier
+type SMTPConfig struct {
+	// Host is the SMTP server host
+	Host string
+	// Port is the SMTP server port. Defaults to 587
+	Port int
+	// Username is used for PLAIN authentication. Authentication is skipped if empty
+	Username string
+	// Password is used for PLAIN authentication
+	Password string
+	// From is the sender address
+	From string
+	// To is the list of recipient addresses
+	To []string
+	// SubjectPrefix is prepended to the subject. Defaults to "[adfer]"
+	SubjectPrefix string
+	// AttachJSON attaches the crash report as a JSON file
+	AttachJSON bool
+	// MaxEmails is the maximum number of emails sent per Interval. Defaults to 5
+	MaxEmails int
+	// Interval is the rate limiting window. Defaults to 1 hour
+	Interval time.Duration
+	// Timeout limits how long connecting to the server and sending an email may take, so an
+	// unresponsive server can't hold up the crash. Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// EmailNotifier is a Reporter that emails crash reports
+type EmailNotifier struct {
+	config   SMTPConfig
+	sendMail func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now      func() time.Time
+
+	mu   sync.Mutex
+	sent []time.Time
+}
+
+// NewEmailNotifier creates an EmailNotifier from the given config
+func NewEmailNotifier(config SMTPConfig) *EmailNotifier {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.SubjectPrefix == "" {
+		config.SubjectPrefix = "[adfer]"
+	}
+	if config.MaxEmails <= 0 {
+		config.MaxEmails = 5
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	notifier := &EmailNotifier{
+		config: config,
+		now:    time.Now,
+	}
+	notifier.sendMail = notifier.send
+	return notifier
+}
+
+// WithEmailNotifier emails every recovered panic to the configured recipients
+func WithEmailNotifier(config SMTPConfig) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewEmailNotifier(config))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Report emails the crash report, unless the rate limit has been reached
+func (e *EmailNotifier) Report(ctx context.Context, report CrashReport) error {
+	if len(e.config.To) == 0 {
+		return fmt.Errorf("no email recipients configured")
+	}
+	if !e.allow() {
+		return ErrRateLimited
+	}
+	msg, err := e.Message(report)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	}
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	return e.sendMail(ctx, addr, auth, e.config.From, e.config.To, msg)
+}
+
+// send sends an email like smtp.SendMail, but gives up once ctx is done or Timeout has passed
+func (e *EmailNotifier) send(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// Unblock the conversation if ctx is cancelled before the deadline
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.config.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(msg); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// allow records a send if it is within the rate limit
+func (e *EmailNotifier) allow() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	recent := e.sent[:0]
+	for _, sent := range e.sent {
+		if now.Sub(sent) < e.config.Interval {
+			recent = append(recent, sent)
+		}
+	}
+	e.sent = recent
+	if len(e.sent) >= e.config.MaxEmails {
+		return false
+	}
+	e.sent = append(e.sent, now)
+	return true
+}
+
+// Message builds the MIME message for a crash report
+func (e *EmailNotifier) Message(report CrashReport) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	text, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(text, "%s\n\nTimestamp: %s\n", summarize(report, plainFormat), report.Timestamp.Format(time.RFC3339))
+	if report.ID != "" {
+		fmt.Fprintf(text, "Crash ID: %s\n", report.ID)
+	}
+	fmt.Fprintf(text, "\nFull stack trace:\n\n%s\n", report.Stack)
+
+	if e.config.AttachJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		filename := "crash.json"
+		if report.ID != "" {
+			filename = "crash-" + report.ID + ".json"
+		}
+		attachment, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"application/json"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, filename)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(attachment, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(attachment, "%s\r\n", encoded)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("%s Panic on %s: %s", e.config.SubjectPrefix, reportHost(report), firstLine(report.Error))
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", e.now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// firstLine returns the first line of s, truncated to 100 bytes
+func firstLine(s string) string {
+	if idx := strings.IndexAny(s, "\r\n"); idx >= 0 {
+		s = s[:idx]
+	}
+	return truncate(s, 100)
+}
@@ -0,0 +1,190 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailNotifier(t *testing.T) {
+	var sentAddr string
+	var sentTo []string
+	var sentMsg []byte
+	notifier := NewEmailNotifier(SMTPConfig{
+		Host:       "smtp.example.com",
+		Username:   "user",
+		Password:   "pass",
+		From:       "crashes@example.com",
+		To:         []string{"dev@example.com", "ops@example.com"},
+		AttachJSON: true,
+		MaxEmails:  2,
+		Interval:   time.Hour,
+	})
+	notifier.sendMail = func(_ context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sentAddr = addr
+		sentTo = to
+		sentMsg = msg
+		return nil
+	}
+	now := time.Now()
+	notifier.now = func() time.Time { return now }
+
+	report := CrashReport{
+		ID:         "1234",
+		Timestamp:  now,
+		Error:      "boom\nsecond line",
+		Stack:      testStack,
+		SystemInfo: SystemInfo{Hostname: "web-1"},
+	}
+	if err := notifier.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sentAddr != "smtp.example.com:587" {
+		t.Errorf("Unexpected address '%s'", sentAddr)
+	}
+	if len(sentTo) != 2 {
+		t.Errorf("Expected 2 recipients, got %v", sentTo)
+	}
+	msg := string(sentMsg)
+	for _, expected := range []string{
+		"Subject: [adfer] Panic on web-1: boom\r\n",
+		"To: dev@example.com, ops@example.com",
+		"Crash ID: 1234",
+		"main.inner",
+		`attachment; filename="crash-1234.json"`,
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected message to contain '%s'", expected)
+		}
+	}
+
+	// Rate limiting
+	if err := notifier.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := notifier.Report(context.Background(), report); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	now = now.Add(time.Hour)
+	if err := notifier.Report(context.Background(), report); err != nil {
+		t.Errorf("Expected rate limit to reset, got %v", err)
+	}
+}
+
+func TestEmailNotifierNoRecipients(t *testing.T) {
+	notifier := NewEmailNotifier(SMTPConfig{Host: "smtp.example.com"})
+	if err := notifier.Report(context.Background(), CrashReport{}); err == nil {
+		t.Error("Expected error for missing recipients")
+	}
+}
+
+// serveSMTP accepts one SMTP conversation on listener, recording the message
+func serveSMTP(t *testing.T, listener net.Listener, messages chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+		case "EHLO", "HELO", "MAIL", "RCPT":
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			messages <- string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			t.Errorf("Unexpected SMTP command %q", line)
+			text.PrintfLine("502 Unsupported")
+		}
+	}
+}
+
+func TestEmailNotifierSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	messages := make(chan string, 1)
+	go serveSMTP(t, listener, messages)
+
+	address := listener.Addr().(*net.TCPAddr)
+	notifier := NewEmailNotifier(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: address.Port,
+		From: "crashes@example.com",
+		To:   []string{"dev@example.com"},
+	})
+	report := CrashReport{Error: "überlauf: 日本語", Stack: testStack, SystemInfo: SystemInfo{Hostname: "web-1"}}
+	if err := notifier.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	message, err := mail.ReadMessage(strings.NewReader(<-messages))
+	if err != nil {
+		t.Fatalf("Expected a valid message, got %v", err)
+	}
+	subject := message.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err != nil || decoded != "[adfer] Panic on web-1: überlauf: 日本語" {
+		t.Errorf("Expected an encoded subject, got %q (%q, %v)", subject, decoded, err)
+	}
+	for _, r := range subject {
+		if r > 127 {
+			t.Errorf("Expected an ASCII subject header, got %q", subject)
+			break
+		}
+	}
+}
+
+func TestEmailNotifierTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	// The server accepts connections but never greets
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	notifier := NewEmailNotifier(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: listener.Addr().(*net.TCPAddr).Port,
+		To:   []string{"dev@example.com"},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := notifier.Report(ctx, CrashReport{Error: "boom"}); err == nil {
+		t.Error("Expected an error from an unresponsive server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the send to stop when the context is done, took %v", elapsed)
+	}
+}
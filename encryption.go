@@ -0,0 +1,142 @@
+package adfer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// encryptionVersion is the first byte of an encrypted crash report
+const encryptionVersion = 1
+
+// encryptionInfo binds the derived key to its use
+const encryptionInfo = "adfer crash report v1"
+
+// GenerateEncryptionKey generates an X25519 key pair for WithEncryption. The public key is
+// shipped with the application, the private key stays with whoever reads the crash reports
+func GenerateEncryptionKey() (publicKey, privateKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// WithEncryption encrypts crash reports at rest with an X25519 public key, see GenerateEncryptionKey,
+// so reports stored on end-user machines can only be read with the private key. Stored reports only
+// keep their ID, timestamp and delivery receipts in the clear. Reporters receive the report unencrypted
+func WithEncryption(publicKey []byte) Option {
+	return func(o *Options) {
+		o.EncryptionKey = publicKey
+	}
+}
+
+// EncryptCrashReport encrypts a crash report for the holder of the private key of publicKey, using an
+// ephemeral X25519 key exchange and AES-256-GCM. The result only keeps the ID and timestamp in the clear
+func EncryptCrashReport(report CrashReport, publicKey []byte) (CrashReport, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return CrashReport{}, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	plaintext, err := json.Marshal(report)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	gcm, err := reportCipher(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return CrashReport{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return CrashReport{}, err
+	}
+	data := append([]byte{encryptionVersion}, ephemeral.PublicKey().Bytes()...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, plaintext, []byte(report.ID))
+	return CrashReport{
+		ID:         report.ID,
+		Timestamp:  report.Timestamp,
+		Deliveries: report.Deliveries,
+		Encrypted:  base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// DecryptCrashReport decrypts a crash report encrypted with the public key of privateKey. The
+// delivery receipts stored with the encrypted report are kept. Reports that aren't encrypted
+// are returned unchanged
+func DecryptCrashReport(report CrashReport, privateKey []byte) (CrashReport, error) {
+	if report.Encrypted == "" {
+		return report, nil
+	}
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return CrashReport{}, fmt.Errorf("invalid decryption key: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(report.Encrypted)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	const keySize = 32
+	if len(data) < 1+keySize || data[0] != encryptionVersion {
+		return CrashReport{}, errors.New("unsupported encrypted crash report")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[1 : 1+keySize])
+	if err != nil {
+		return CrashReport{}, err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	gcm, err := reportCipher(shared, ephemeral.Bytes(), key.PublicKey().Bytes())
+	if err != nil {
+		return CrashReport{}, err
+	}
+	data = data[1+keySize:]
+	if len(data) < gcm.NonceSize() {
+		return CrashReport{}, errors.New("unsupported encrypted crash report")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(report.ID))
+	if err != nil {
+		return CrashReport{}, fmt.Errorf("decrypting crash report %s: %w", report.ID, err)
+	}
+	var decrypted CrashReport
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		return CrashReport{}, err
+	}
+	if report.Deliveries != nil {
+		decrypted.Deliveries = report.Deliveries
+	}
+	return decrypted, nil
+}
+
+// reportCipher derives the AES-256-GCM cipher of a report from the shared secret with HKDF-SHA256
+func reportCipher(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	extract := hmac.New(sha256.New, nil)
+	extract.Write(shared)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(encryptionInfo))
+	expand.Write(ephemeral)
+	expand.Write(recipient)
+	expand.Write([]byte{1})
+	block, err := aes.NewCipher(expand.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
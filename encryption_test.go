@@ -0,0 +1,85 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptCrashReport(t *testing.T) {
+	publicKey, privateKey, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report := CrashReport{ID: "crash-1", Timestamp: time.Now(), Error: "password=hunter2", Stack: testStack}
+	encrypted, err := EncryptCrashReport(report, publicKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if encrypted.ID != "crash-1" || encrypted.Error != "" || encrypted.Stack != "" || encrypted.Encrypted == "" {
+		t.Fatalf("Expected only the ID and timestamp in the clear, got %+v", encrypted)
+	}
+
+	encrypted.Deliveries = map[string]Delivery{"webhook": {Status: DeliverySent}}
+	decrypted, err := DecryptCrashReport(encrypted, privateKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decrypted.Error != report.Error || decrypted.Stack != report.Stack || decrypted.Deliveries["webhook"].Status != DeliverySent {
+		t.Errorf("Unexpected decrypted report %+v", decrypted)
+	}
+
+	_, otherKey, _ := GenerateEncryptionKey()
+	if _, err := DecryptCrashReport(encrypted, otherKey); err == nil {
+		t.Errorf("Expected an error for the wrong key")
+	}
+	// The ciphertext is bound to the report's ID
+	encrypted.ID = "crash-2"
+	if _, err := DecryptCrashReport(encrypted, privateKey); err == nil {
+		t.Errorf("Expected an error for a report with another ID")
+	}
+	if _, err := EncryptCrashReport(report, []byte("short")); err == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+	if plain, err := DecryptCrashReport(report, privateKey); err != nil || plain.Error != report.Error {
+		t.Errorf("Expected unencrypted reports to be returned unchanged")
+	}
+}
+
+func TestWithEncryption(t *testing.T) {
+	publicKey, privateKey, _ := GenerateEncryptionKey()
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	received := make(chan CrashReport, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filePath},
+		WithEncryption(publicKey), WithReporter(channelReporter(received)))
+	ph.Report(errors.New("password=hunter2"))
+
+	if data, _ := os.ReadFile(filePath); strings.Contains(string(data), "hunter2") {
+		t.Fatalf("Expected the crash file to be encrypted")
+	}
+	if report := <-received; report.Error != "password=hunter2" {
+		t.Errorf("Expected reporters to receive the report unencrypted, got %+v", report)
+	}
+	reports, _ := ph.GetLastNCrashReports(1)
+	if len(reports) != 1 || reports[0].Deliveries[ph.reporterNames[0]].Status != DeliverySent {
+		t.Fatalf("Expected delivery receipts to be stored in the clear, got %+v", reports)
+	}
+	if report, err := DecryptCrashReport(reports[0], privateKey); err != nil || report.Error != "password=hunter2" {
+		t.Errorf("Expected the stored report to decrypt, got %+v, error %v", report, err)
+	}
+
+	var diagnostics []Diagnostic
+	ph = New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		Storage:      NewMemoryStore(10),
+	}, WithEncryption([]byte("invalid")))
+	ph.process(context.Background(), errors.New("boom"), CrashReport{ID: "1", Error: "boom"})
+	if reports, _ := ph.GetLastNCrashReports(1); len(reports) != 0 || len(diagnostics) != 1 || diagnostics[0].Op != OpEncode {
+		t.Errorf("Expected nothing to be stored with an invalid key, got %+v, diagnostics %v", reports, diagnostics)
+	}
+}
@@ -0,0 +1,92 @@
+package adfer
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultOnExitTimeout is how long the OnExit callbacks may run before the program exits anyway
+const defaultOnExitTimeout = 5 * time.Second
+
+// WithExitCode sets the exit code used when a panic's action is Exit. Defaults to 1
+func WithExitCode(code int) Option {
+	return func(o *Options) {
+		o.ExitCode = code
+	}
+}
+
+// WithExitCodeFunc sets a function mapping the crash report of a panic to the exit code, e.g. so a
+// supervisor can tell out of memory conditions from bugs. If it returns 0 or panics, ExitCode is used
+func WithExitCodeFunc(exitCode func(report CrashReport) int) Option {
+	return func(o *Options) {
+		o.ExitCodeFunc = exitCode
+	}
+}
+
+// WithOnExit adds a callback called with the crash report before the program exits after a panic,
+// e.g. to release resources. Callbacks are called in order once pending deliveries are flushed.
+// The program exits once they return or OnExitTimeout has passed
+func WithOnExit(callback func(report CrashReport)) Option {
+	return func(o *Options) {
+		o.OnExit = append(o.OnExit, callback)
+	}
+}
+
+// WithOnExitTimeout sets how long the OnExit callbacks may run before the program exits anyway.
+// Defaults to 5 seconds
+func WithOnExitTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.OnExitTimeout = timeout
+	}
+}
+
+// exit flushes pending deliveries, cancels the contexts of the goroutines started with SafeGoCtx, calls
+// the OnExit callbacks, runs the registered cleanups and exits the program
+func (ph *PanicHandler) exit(report CrashReport) {
+	ph.flushBeforeExit()
+	ph.contexts.cancelAll()
+	ph.runOnExit(report)
+	ph.runCleanups()
+	ph.options.ExitFunc(ph.exitCode(report))
+}
+
+// exitCode returns the exit code for the crash report of a panic
+func (ph *PanicHandler) exitCode(report CrashReport) int {
+	code := ph.options.ExitCode
+	if code == 0 {
+		code = 1
+	}
+	if ph.options.ExitCodeFunc != nil {
+		mapped := 0
+		ph.callHook("exit code", 0, func() { mapped = ph.options.ExitCodeFunc(report) })
+		if mapped != 0 {
+			code = mapped
+		}
+	}
+	return code
+}
+
+// runOnExit calls the OnExit callbacks in order, waiting at most OnExitTimeout for them to return
+func (ph *PanicHandler) runOnExit(report CrashReport) {
+	if len(ph.options.OnExit) == 0 {
+		return
+	}
+	timeout := ph.options.OnExitTimeout
+	if timeout <= 0 {
+		timeout = defaultOnExitTimeout
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, callback := range ph.options.OnExit {
+			ph.callHook("on exit", i, func() { callback(report) })
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		ph.diagnose(OpHook, "", fmt.Errorf("on exit callbacks did not return within %v", timeout))
+	}
+}
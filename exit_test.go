@@ -0,0 +1,85 @@
+package adfer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	for name, test := range map[string]struct {
+		options []Option
+		want    int
+	}{
+		"default":        {nil, 1},
+		"code":           {[]Option{WithExitCode(3)}, 3},
+		"mapper":         {[]Option{WithExitCode(3), WithExitCodeFunc(func(report CrashReport) int { return len(report.Error) })}, 4},
+		"mapper default": {[]Option{WithExitCode(3), WithExitCodeFunc(func(CrashReport) int { return 0 })}, 3},
+		"mapper panics":  {[]Option{WithExitCodeFunc(func(CrashReport) int { panic("bug") })}, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code := -1
+			options := append([]Option{WithExitFunc(func(c int) { code = c })}, test.options...)
+			ph := New(Options{ErrorHandler: func(error, []byte) {}, OnDiagnostic: func(Diagnostic) {}, ExitOnPanic: true}, options...)
+			func() {
+				defer ph.Recover()
+				panic("boom")
+			}()
+			if code != test.want {
+				t.Errorf("Expected exit code %d, got %d", test.want, code)
+			}
+		})
+	}
+}
+
+func TestOnExit(t *testing.T) {
+	var calls []string
+	exited := false
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, ExitOnPanic: true},
+		WithOnExit(func(report CrashReport) {
+			if exited {
+				t.Error("Expected the callbacks to run before the exit")
+			}
+			calls = append(calls, "first:"+report.Error+":"+string(report.Severity))
+		}),
+		WithOnExit(func(CrashReport) { calls = append(calls, "second") }),
+		WithExitFunc(func(int) { exited = true }),
+	)
+	func() {
+		defer ph.Recover()
+		panic(errors.New("boom"))
+	}()
+	if len(calls) != 2 || calls[0] != "first:boom:fatal" || calls[1] != "second" {
+		t.Errorf("Expected the callbacks to run in order with the report, got %v", calls)
+	}
+	if !exited {
+		t.Error("Expected the program to exit")
+	}
+}
+
+func TestOnExitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var diagnostics []Diagnostic
+	exited := make(chan struct{})
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		ExitOnPanic:  true,
+	}, WithOnExit(func(CrashReport) { <-release }),
+		WithOnExitTimeout(10*time.Millisecond),
+		WithExitFunc(func(int) { close(exited) }))
+
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	select {
+	case <-exited:
+	default:
+		t.Fatal("Expected the program to exit despite the hung callback")
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpHook {
+		t.Errorf("Expected a timeout diagnostic, got %+v", diagnostics)
+	}
+}
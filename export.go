@@ -0,0 +1,169 @@
+package adfer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is the format crash reports are exported in, see ExportCrashReports
+type ExportFormat int
+
+const (
+	// ExportCSV exports one row per report, with a header row, for spreadsheets
+	ExportCSV ExportFormat = iota
+	// ExportHTML exports a self-contained HTML page with a table of the reports and expandable
+	// stack traces, which needs no scripts or external resources
+	ExportHTML
+)
+
+// exportColumns are the columns of a CSV export
+var exportColumns = []string{"id", "timestamp", "error", "error_type", "category", "fingerprint", "handled", "os", "architecture", "go_version", "hostname", "tags", "metadata", "stack"}
+
+// ExportCrashReports writes the stored crash reports, oldest first, to w in the given format, so
+// non-engineers can review crash data in a spreadsheet or a browser. The reports are read one at a time
+func (s *reportStore) ExportCrashReports(w io.Writer, format ExportFormat) error {
+	if s.storage == nil {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	switch format {
+	case ExportCSV:
+		return s.exportCSV(w)
+	case ExportHTML:
+		return s.exportHTML(w)
+	default:
+		return fmt.Errorf("unsupported export format %d", format)
+	}
+}
+
+// exportCSV writes the stored crash reports as CSV
+func (s *reportStore) exportCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportColumns); err != nil {
+		return err
+	}
+	var writeErr error
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		writeErr = writer.Write([]string{
+			report.ID,
+			report.Timestamp.Format(time.RFC3339),
+			csvCell(report.Error),
+			report.ErrorType,
+			report.Category,
+			Fingerprint(report),
+			strconv.FormatBool(report.Handled),
+			report.SystemInfo.OS,
+			report.SystemInfo.Architecture,
+			report.SystemInfo.GoVersion,
+			csvCell(report.SystemInfo.Hostname),
+			csvCell(strings.Join(report.Tags, ", ")),
+			csvCell(formatMetadata(report.Metadata, ", ")),
+			report.Stack,
+		})
+		return writeErr == nil
+	})
+	if err == nil {
+		err = writeErr
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	return err
+}
+
+// csvCell prefixes values that spreadsheets would evaluate as a formula with an apostrophe, so a
+// crafted error message can't run a formula when the export is opened
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatMetadata formats metadata as "key=value" pairs sorted by key
+func formatMetadata(metadata map[string]string, sep string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + metadata[key]
+	}
+	return strings.Join(pairs, sep)
+}
+
+// exportTemplate renders the HTML export. The header, each report and the footer are rendered
+// separately, so reports are written as they are read
+var exportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"metadata": func(metadata map[string]string) []string {
+		if len(metadata) == 0 {
+			return nil
+		}
+		return strings.Split(formatMetadata(metadata, "\n"), "\n")
+	},
+}).Parse(`{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crash reports</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.5em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+summary { cursor: pointer; color: #06c; }
+pre { background: #f8f8f8; padding: 0.5em; overflow-x: auto; font-size: 0.85em; }
+.handled { color: #888; }
+.tag { background: #eef; border-radius: 3px; padding: 0 0.3em; margin-right: 0.3em; }
+</style>
+</head>
+<body>
+<h1>Crash reports</h1>
+<p>Exported {{.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<thead><tr><th>Time</th><th>Error</th><th>Type</th><th>System</th><th>Tags and metadata</th></tr></thead>
+<tbody>
+{{end}}{{define "report"}}<tr id="{{.ID}}">
+<td>{{.Timestamp.Format "2006-01-02 15:04:05 MST"}}</td>
+<td><strong>{{.Error}}</strong>{{if .Handled}} <span class="handled">(handled)</span>{{end}}
+{{if .Stack}}<details><summary>Stack trace</summary><pre>{{.Stack}}</pre></details>{{end}}
+<small>ID {{.ID}}</small></td>
+<td>{{.ErrorType}}{{if .Category}}<br><small>{{.Category}}</small>{{end}}</td>
+<td>{{.SystemInfo.OS}}/{{.SystemInfo.Architecture}}<br><small>{{.SystemInfo.GoVersion}} {{.SystemInfo.Hostname}}</small></td>
+<td>{{range .Tags}}<span class="tag">{{.}}</span>{{end}}{{range metadata .Metadata}}<br><small>{{.}}</small>{{end}}</td>
+</tr>
+{{end}}{{define "footer"}}</tbody>
+</table>
+<p>{{.}} crash report(s)</p>
+</body>
+</html>
+{{end}}`))
+
+// exportHTML writes the stored crash reports as an HTML page
+func (s *reportStore) exportHTML(w io.Writer) error {
+	if err := exportTemplate.ExecuteTemplate(w, "header", time.Now()); err != nil {
+		return err
+	}
+	count := 0
+	var writeErr error
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		count++
+		writeErr = exportTemplate.ExecuteTemplate(w, "report", report)
+		return writeErr == nil
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return err
+	}
+	return exportTemplate.ExecuteTemplate(w, "footer", count)
+}
@@ -0,0 +1,79 @@
+package adfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportStore() *reportStore {
+	storage := NewMemoryStore(10)
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	storage.Append(CrashReport{
+		ID:         "a",
+		Timestamp:  timestamp,
+		Error:      "index out of range",
+		Stack:      "goroutine 1 [running]:\nmain.main()",
+		SystemInfo: SystemInfo{OS: "linux", Architecture: "amd64"},
+		Tags:       []string{"api", "canary"},
+		Metadata:   map[string]string{"region": "eu", "version": "1.2"},
+	})
+	storage.Append(CrashReport{ID: "b", Timestamp: timestamp.Add(time.Hour), Error: `=HYPERLINK("x") <script>`, Handled: true})
+	return &reportStore{storage: storage}
+}
+
+func TestExportCrashReportsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportStore().ExportCrashReports(&buf, ExportCSV); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d, error %v", len(records), err)
+	}
+	if strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+	row := records[1]
+	if row[0] != "a" || row[1] != "2024-06-01T12:00:00Z" || row[2] != "index out of range" || row[7] != "linux" {
+		t.Errorf("Unexpected row %v", row)
+	}
+	if row[11] != "api, canary" || row[12] != "region=eu, version=1.2" || row[13] != "goroutine 1 [running]:\nmain.main()" {
+		t.Errorf("Unexpected tags, metadata or stack in %v", row)
+	}
+	if records[2][2] != `'=HYPERLINK("x") <script>` || records[2][6] != "true" {
+		t.Errorf("Expected the formula to be escaped, got %v", records[2])
+	}
+}
+
+func TestExportCrashReportsHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportStore().ExportCrashReports(&buf, ExportHTML); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	page := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<details><summary>Stack trace</summary><pre>goroutine 1 [running]:\nmain.main()</pre></details>",
+		`<span class="tag">canary</span>`,
+		"region=eu",
+		"&lt;script&gt;",
+		"2 crash report(s)",
+		"</html>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<script>") || strings.Contains(page, "http") {
+		t.Error("Expected a self-contained page without scripts or external resources")
+	}
+}
+
+func TestExportCrashReportsFormat(t *testing.T) {
+	if err := exportStore().ExportCrashReports(&bytes.Buffer{}, ExportFormat(9)); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,253 @@
+package adfer
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileSystem is the file system the crash file is stored in, see WithFileSystem. Names are
+// paths as given in Options.FilePath, not the slash-separated paths of fs.FS. Errors for missing
+// files must satisfy errors.Is(err, fs.ErrNotExist)
+type FileSystem interface {
+	// Open opens a file for reading
+	Open(name string) (fs.File, error)
+	// ReadFile reads a whole file
+	ReadFile(name string) ([]byte, error)
+	// WriteFile creates or replaces a file
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Rename renames a file, replacing any file at newpath
+	Rename(oldpath, newpath string) error
+	// Remove removes a file
+	Remove(name string) error
+	// Stat returns information about a file
+	Stat(name string) (fs.FileInfo, error)
+	// MkdirAll creates a directory and its missing parents
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// WithFileSystem stores the crash file, its rotated backups and lifetime counters in fsys instead
+// of the OS file system, e.g. a MemoryFileSystem in unit tests or a custom file system in sandboxed
+// environments such as WASM or read-only containers. The index is disabled, and the crash file
+// isn't locked, so it can't be shared between processes
+func WithFileSystem(fsys FileSystem) Option {
+	return func(o *Options) {
+		o.FileSystem = fsys
+	}
+}
+
+// osFileSystem is the OS file system, applying the configured permissions and owner
+type osFileSystem struct {
+	perms filePerms
+}
+
+// Open opens a file for reading
+func (o osFileSystem) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// ReadFile reads a whole file
+func (o osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes a file with the configured mode and owner, ignoring perm
+func (o osFileSystem) WriteFile(name string, data []byte, _ fs.FileMode) error {
+	return o.perms.writeFile(name, data)
+}
+
+// Rename renames a file
+func (o osFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Remove removes a file
+func (o osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Stat returns information about a file
+func (o osFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// MkdirAll creates a directory with the configured mode and owner, ignoring perm
+func (o osFileSystem) MkdirAll(path string, _ fs.FileMode) error {
+	return o.perms.mkdirAll(path)
+}
+
+// MemoryFileSystem is a FileSystem that keeps files in memory, for tests and environments
+// without a writable disk. Directories are implied by the files in them
+type MemoryFileSystem struct {
+	mu    sync.Mutex
+	files map[string]memoryFile
+}
+
+// memoryFile is a file of a MemoryFileSystem
+type memoryFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemoryFileSystem creates an empty MemoryFileSystem
+func NewMemoryFileSystem() *MemoryFileSystem {
+	return &MemoryFileSystem{files: make(map[string]memoryFile)}
+}
+
+// Open opens a file for reading. Later writes to the file aren't seen by the returned file
+func (m *MemoryFileSystem) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &openMemoryFile{Reader: bytes.NewReader(file.data), info: memoryFileInfo{name, file}}, nil
+}
+
+// ReadFile reads a whole file
+func (m *MemoryFileSystem) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), file.data...), nil
+}
+
+// WriteFile creates or replaces a file
+func (m *MemoryFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filepath.Clean(name)] = memoryFile{data: append([]byte(nil), data...), mode: perm, modTime: time.Now()}
+	return nil
+}
+
+// Rename renames a file, replacing any file at newpath
+func (m *MemoryFileSystem) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(oldpath)]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(m.files, filepath.Clean(oldpath))
+	m.files[filepath.Clean(newpath)] = file
+	return nil
+}
+
+// Remove removes a file
+func (m *MemoryFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[filepath.Clean(name)]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, filepath.Clean(name))
+	return nil
+}
+
+// Stat returns information about a file
+func (m *MemoryFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memoryFileInfo{name, file}, nil
+}
+
+// MkdirAll does nothing, as directories are implied by the files in them
+func (m *MemoryFileSystem) MkdirAll(string, fs.FileMode) error {
+	return nil
+}
+
+// Names returns the names of the files, sorted
+func (m *MemoryFileSystem) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openMemoryFile is a file of a MemoryFileSystem opened for reading
+type openMemoryFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+// Stat returns information about the file
+func (f *openMemoryFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close does nothing
+func (f *openMemoryFile) Close() error {
+	return nil
+}
+
+// memoryFileInfo describes a file of a MemoryFileSystem
+type memoryFileInfo struct {
+	name string
+	file memoryFile
+}
+
+// Name returns the base name of the file
+func (i memoryFileInfo) Name() string {
+	return filepath.Base(i.name)
+}
+
+// Size returns the length of the file in bytes
+func (i memoryFileInfo) Size() int64 {
+	return int64(len(i.file.data))
+}
+
+// Mode returns the mode the file was written with
+func (i memoryFileInfo) Mode() fs.FileMode {
+	return i.file.mode
+}
+
+// ModTime returns the time the file was last written
+func (i memoryFileInfo) ModTime() time.Time {
+	return i.file.modTime
+}
+
+// IsDir returns false, as a MemoryFileSystem has no directories
+func (i memoryFileInfo) IsDir() bool {
+	return false
+}
+
+// Sys returns nil
+func (i memoryFileInfo) Sys() any {
+	return nil
+}
+
+// files returns the file system of the crash file
+func (f *fileStorage) files() FileSystem {
+	if f.fsys == nil {
+		return osFileSystem{perms: f.perms}
+	}
+	return f.fsys
+}
+
+// native returns true if the crash file is on the OS file system, so it can be locked,
+// appended to and read backwards
+func (f *fileStorage) native() bool {
+	return f.fsys == nil
+}
+
+// writeFile creates or replaces a file of the crash file's file system with the configured mode
+func (f *fileStorage) writeFile(path string, data []byte) error {
+	return f.files().WriteFile(path, data, f.perms.file)
+}
@@ -0,0 +1,84 @@
+package adfer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithFileSystem(t *testing.T) {
+	for name, format := range map[string]FileFormat{"json": FormatJSON, "jsonl": FormatJSONLines} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "crash")
+			fsys := NewMemoryFileSystem()
+			ph := New(Options{
+				ErrorHandler: func(error, []byte) {},
+				DumpToFile:   true,
+				FilePath:     filePath,
+				Index:        true,
+			}, WithFileFormat(format), WithFileSystem(fsys), WithMaxFileSize(1), WithPersistentStats())
+			for i := 0; i < 3; i++ {
+				func() {
+					defer ph.Recover()
+					panic(i)
+				}()
+			}
+
+			expected := []string{filePath, filePath + ".1.gz", filePath + ".2.gz", filePath + ".stats"}
+			if names := fsys.Names(); !reflect.DeepEqual(names, expected) {
+				t.Errorf("Expected files %v, got %v", expected, names)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Expected nothing on disk, got %d entries", len(entries))
+			}
+			reports, err := ph.GetLastNCrashReports(10)
+			if err != nil || len(reports) != 1 || reports[0].Error != "2" {
+				t.Fatalf("Expected the last report, got %+v, error %v", reports, err)
+			}
+			if stats := ph.Stats(); stats.Lifetime == nil || stats.Lifetime.Panics != 3 {
+				t.Errorf("Expected 3 panics in the lifetime counters, got %+v", stats.Lifetime)
+			}
+			if err := ph.WipeCrashFile(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 0 {
+				t.Errorf("Expected no reports after wiping, got %+v", reports)
+			}
+		})
+	}
+}
+
+func TestMemoryFileSystem(t *testing.T) {
+	fsys := NewMemoryFileSystem()
+	if _, err := fsys.ReadFile("missing"); !errors.Is(err, fs.ErrNotExist) || !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+	if err := fsys.WriteFile("a", []byte("data"), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fsys.Rename("a", "b"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := fsys.Stat("b")
+	if err != nil || info.Size() != 4 || info.Mode() != 0600 || info.Name() != "b" {
+		t.Errorf("Unexpected file info %+v, error %v", info, err)
+	}
+	file, err := fsys.Open("b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := make([]byte, 4)
+	if _, err := file.Read(data); err != nil || string(data) != "data" {
+		t.Errorf("Expected to read the file, got %q, error %v", data, err)
+	}
+	if err := fsys.Remove("b"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fsys.Remove("b"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
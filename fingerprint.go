@@ -0,0 +1,69 @@
+package adfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fingerprintFrames is the number of application frames used to compute a fingerprint
+const fingerprintFrames = 5
+
+// Fingerprint computes a stable grouping key for a crash report from the error
+// type and the top application frames. Line numbers are excluded so the
+// fingerprint survives unrelated edits to the same file. The fingerprint stored
+// in the report is returned if it is set.
+func Fingerprint(report CrashReport) string {
+	if report.Fingerprint != "" {
+		return report.Fingerprint
+	}
+	frames := appFrames(ParseStack(report.Stack))
+	if len(frames) > fingerprintFrames {
+		frames = frames[:fingerprintFrames]
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(report.ErrorType))
+	for _, frame := range frames {
+		hash.Write([]byte{0})
+		hash.Write([]byte(frame.Function))
+	}
+	if len(frames) == 0 {
+		// Without a usable stack, group by the error message instead
+		hash.Write([]byte{0})
+		hash.Write([]byte(strings.TrimSpace(report.Error)))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// WithFingerprinter sets a function computing the fingerprint of crash reports instead of Fingerprint,
+// e.g. to group by internal error codes rather than stack shape. It receives the error, or the
+// formatted panic value, and the stack. If it returns an empty string or panics, the default
+// fingerprint is used. Reporters that group or deduplicate reports use the fingerprint it returns
+func WithFingerprinter(fingerprint func(err error, stack []byte) string) Option {
+	return func(o *Options) {
+		o.Fingerprinter = fingerprint
+	}
+}
+
+// fingerprint returns the fingerprint of a new crash report
+func (ph *PanicHandler) fingerprint(err error, stack []byte, report CrashReport) string {
+	if ph.options.Fingerprinter != nil {
+		if fingerprint := ph.customFingerprint(err, stack); fingerprint != "" {
+			return fingerprint
+		}
+	}
+	return Fingerprint(report)
+}
+
+// customFingerprint calls the fingerprinter, recovering from its panics
+func (ph *PanicHandler) customFingerprint(err error, stack []byte) (fingerprint string) {
+	defer func() {
+		if p := recover(); p != nil {
+			ph.diagnose(OpHook, "", fmt.Errorf("computing fingerprint: %v", p))
+			fingerprint = ""
+		}
+	}()
+	return ph.options.Fingerprinter(err, stack)
+}
@@ -0,0 +1,61 @@
+package adfer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type codedError struct {
+	code string
+}
+
+func (e codedError) Error() string {
+	return "failed with " + e.code
+}
+
+func TestFingerprinter(t *testing.T) {
+	reports := make(chan CrashReport, 3)
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithFingerprinter(func(err error, stack []byte) string {
+		if len(stack) == 0 {
+			t.Error("Expected the stack to be passed to the fingerprinter")
+		}
+		var coded codedError
+		if errors.As(err, &coded) {
+			return "code:" + coded.code
+		}
+		if err.Error() == "bug" {
+			panic("fingerprinter bug")
+		}
+		return ""
+	}))
+
+	ph.Report(codedError{code: "E42"})
+	func() {
+		defer ph.Recover()
+		panic("plain")
+	}()
+	func() {
+		defer ph.Recover()
+		panic("bug")
+	}()
+
+	report := <-reports
+	if report.Fingerprint != "code:E42" || Fingerprint(report) != "code:E42" {
+		t.Errorf("Expected the custom fingerprint, got %q", report.Fingerprint)
+	}
+	for i := 0; i < 2; i++ {
+		report := <-reports
+		if expected := Fingerprint(CrashReport{ErrorType: report.ErrorType, Error: report.Error, Stack: report.Stack}); report.Fingerprint != expected {
+			t.Errorf("Expected the default fingerprint %q, got %q", expected, report.Fingerprint)
+		}
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpHook || !strings.Contains(diagnostics[0].Err.Error(), "fingerprinter bug") {
+		t.Errorf("Expected a diagnostic for the fingerprinter's panic, got %v", diagnostics)
+	}
+}
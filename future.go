@@ -0,0 +1,45 @@
+package adfer
+
+import "context"
+
+// Future is the result of a function run by SafeGoResult
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// SafeGoResult runs f in a goroutine with panic recovery and returns a Future for its result.
+// If f panics, the panic is reported through ph and the Future yields a *PanicError
+func SafeGoResult[T any](ph *PanicHandler, f func() (T, error)) *Future[T] {
+	future := &Future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(future.done)
+		ph.call(context.Background(), func() {
+			future.value, future.err = f()
+		}, &future.err)
+	}()
+	return future
+}
+
+// Done returns a channel that is closed when the function has returned or panicked
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the function and returns its result. It returns ctx.Err() if ctx is done first
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Get waits for the function and returns its result
+func (f *Future[T]) Get() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
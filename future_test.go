@@ -0,0 +1,58 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSafeGoResult(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+
+	future := SafeGoResult(ph, func() (int, error) { return 42, nil })
+	if value, err := future.Get(); value != 42 || err != nil {
+		t.Errorf("Expected 42, got %d (%v)", value, err)
+	}
+
+	cause := errors.New("not found")
+	failing := SafeGoResult(ph, func() (string, error) { return "", cause })
+	if _, err := failing.Wait(context.Background()); err != cause {
+		t.Errorf("Expected the function's error, got %v", err)
+	}
+}
+
+func TestSafeGoResultPanic(t *testing.T) {
+	reports := make(channelReporter, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(reports))
+
+	future := SafeGoResult(ph, func() ([]string, error) {
+		var items []string
+		return items[:1], nil
+	})
+	<-future.Done()
+	value, err := future.Get()
+	var panicErr *PanicError
+	if value != nil || !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a *PanicError, got %v (%v)", value, err)
+	}
+	if report := <-reports; report.ID != panicErr.ReportID {
+		t.Errorf("Expected the error to reference report %s, got %s", report.ID, panicErr.ReportID)
+	}
+}
+
+func TestFutureWaitTimeout(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	release := make(chan struct{})
+	defer close(release)
+	future := SafeGoResult(ph, func() (bool, error) {
+		<-release
+		return true, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := future.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GCPErrorReportingOptions configures a GCPErrorReporter
+type GCPErrorReportingOptions struct {
+	// ProjectID is the Google Cloud project the errors are reported to
+	ProjectID string
+	// Service identifies the reporting service. Defaults to the executable name
+	Service string
+	// Version is the version of the service, e.g. a release tag. Errors are grouped per version in the console
+	Version string
+	// TokenSource returns the OAuth2 access token of a service account with the "Error Reporting Writer" role
+	TokenSource TokenSource
+	// APIKey authenticates with an API key instead of a TokenSource
+	APIKey string
+	// User is the metadata key holding the affected user, if any
+	User string
+	// URL is the API base URL. Defaults to "https://clouderrorreporting.googleapis.com"
+	URL string
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// GCPReportedErrorEvent is an error event in the Error Reporting ReportedErrorEvent format
+type GCPReportedErrorEvent struct {
+	EventTime      string            `json:"eventTime"`
+	ServiceContext GCPServiceContext `json:"serviceContext"`
+	Message        string            `json:"message"`
+	Context        *GCPErrorContext  `json:"context,omitempty"`
+}
+
+// GCPServiceContext identifies the service an error event came from
+type GCPServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// GCPErrorContext is additional context of an error event
+type GCPErrorContext struct {
+	User           string             `json:"user,omitempty"`
+	ReportLocation *GCPSourceLocation `json:"reportLocation,omitempty"`
+}
+
+// GCPSourceLocation is the location in the source code where an error was reported
+type GCPSourceLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+// GCPErrorReporter sends crash reports to Google Cloud Error Reporting, where they are
+// grouped by stack trace in the console
+type GCPErrorReporter struct {
+	options GCPErrorReportingOptions
+}
+
+// NewGCPErrorReporter creates a GCPErrorReporter from the given options
+func NewGCPErrorReporter(options GCPErrorReportingOptions) *GCPErrorReporter {
+	if options.Service == "" {
+		options.Service = filepath.Base(os.Args[0])
+	}
+	if options.URL == "" {
+		options.URL = "https://clouderrorreporting.googleapis.com"
+	}
+	return &GCPErrorReporter{options: options}
+}
+
+// WithGCPErrorReporting sends every crash report to Google Cloud Error Reporting
+func WithGCPErrorReporting(options GCPErrorReportingOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewGCPErrorReporter(options))
+	}
+}
+
+// Event converts a crash report to an error event. Recovered panics are formatted like the
+// output of an unrecovered panic, which Error Reporting parses to group the events
+func (g *GCPErrorReporter) Event(report CrashReport) GCPReportedErrorEvent {
+	message := "panic: " + report.Error + "\n\n" + report.Stack
+	if report.Handled {
+		message = report.Error + "\n\n" + report.Stack
+	}
+	event := GCPReportedErrorEvent{
+		EventTime: report.Timestamp.UTC().Format(time.RFC3339Nano),
+		ServiceContext: GCPServiceContext{
+			Service: g.options.Service,
+			Version: g.options.Version,
+		},
+		Message: message,
+	}
+
+	errorContext := &GCPErrorContext{}
+	if g.options.User != "" {
+		errorContext.User = report.Metadata[g.options.User]
+	}
+	// Handled errors have no panic header, so the location must be given explicitly
+	if frames := appFrames(ParseStack(report.Stack)); report.Handled && len(frames) > 0 {
+		errorContext.ReportLocation = &GCPSourceLocation{
+			FilePath:     frames[0].File,
+			LineNumber:   frames[0].Line,
+			FunctionName: frames[0].Function,
+		}
+	}
+	if errorContext.User != "" || errorContext.ReportLocation != nil {
+		event.Context = errorContext
+	}
+	return event
+}
+
+// Name returns the name used in delivery receipts
+func (g *GCPErrorReporter) Name() string {
+	return "gcp-error-reporting"
+}
+
+// Report sends the crash report to Error Reporting
+func (g *GCPErrorReporter) Report(ctx context.Context, report CrashReport) error {
+	endpoint := fmt.Sprintf("%s/v1beta1/projects/%s/events:report",
+		strings.TrimSuffix(g.options.URL, "/"), url.PathEscape(g.options.ProjectID))
+	headers := map[string]string{}
+	if g.options.APIKey != "" {
+		endpoint += "?key=" + url.QueryEscape(g.options.APIKey)
+	}
+	if g.options.TokenSource != nil {
+		token, err := g.options.TokenSource(ctx)
+		if err != nil {
+			return err
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	return postJSON(ctx, g.options.HTTPClient, endpoint, g.Event(report), headers)
+}
@@ -0,0 +1,79 @@
+package adfer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGCPErrorReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewGCPErrorReporter(GCPErrorReportingOptions{
+		ProjectID: "my-project",
+		Service:   "api",
+		Version:   "1.2.3",
+		User:      "user",
+		TokenSource: func(context.Context) (string, error) {
+			return "token", nil
+		},
+		URL: server.URL,
+	})
+	report := CrashReport{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Error:     "nil map",
+		Stack:     testStack,
+		Metadata:  map[string]string{"user": "alice"},
+	}
+
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	recorded := requests()
+	if len(recorded) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(recorded))
+	}
+	request := recorded[0]
+	if request.Path != "/v1beta1/projects/my-project/events:report" {
+		t.Errorf("Unexpected path: %s", request.Path)
+	}
+	if request.Headers.Get("Authorization") != "Bearer token" {
+		t.Errorf("Unexpected authorization: %s", request.Headers.Get("Authorization"))
+	}
+	body := request.Body
+	if body["eventTime"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("Unexpected event time: %v", body["eventTime"])
+	}
+	if body["message"] != "panic: nil map\n\n"+testStack {
+		t.Errorf("Unexpected message: %v", body["message"])
+	}
+	service := body["serviceContext"].(map[string]any)
+	if service["service"] != "api" || service["version"] != "1.2.3" {
+		t.Errorf("Unexpected service context: %v", service)
+	}
+	errorContext := body["context"].(map[string]any)
+	if errorContext["user"] != "alice" || errorContext["reportLocation"] != nil {
+		t.Errorf("Unexpected context: %v", errorContext)
+	}
+}
+
+func TestGCPErrorReporterHandled(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewGCPErrorReporter(GCPErrorReportingOptions{ProjectID: "p", APIKey: "secret", URL: server.URL})
+
+	event := reporter.Event(CrashReport{Error: "payment failed", Stack: testStack, Handled: true})
+	if strings.HasPrefix(event.Message, "panic:") {
+		t.Errorf("Expected handled errors not to look like panics: %q", event.Message)
+	}
+	location := event.Context.ReportLocation
+	if location == nil || location.FunctionName != "main.inner" || location.FilePath != "/app/main.go" || location.LineNumber != 5 {
+		t.Errorf("Unexpected report location: %+v", location)
+	}
+
+	if err := reporter.Report(context.Background(), CrashReport{Error: "boom"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query := requests()[0].Query; query != "key=secret" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+}
@@ -0,0 +1,235 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GitHubIssueOptions configures a GitHubIssueReporter
+type GitHubIssueOptions struct {
+	// Repository is the repository issues are filed in, as "owner/name"
+	Repository string
+	// Token is a GitHub token with permission to create issues and comments
+	Token string
+	// Labels are added to every new issue
+	Labels []string
+	// LabelMetadata lists metadata keys whose values are added as "key:value" labels, e.g. "version"
+	LabelMetadata []string
+	// CommentOnRecurrence adds a comment to the open issue of a fingerprint each time the panic recurs.
+	// Otherwise recurrences are ignored
+	CommentOnRecurrence bool
+	// URL is the API base URL, e.g. for GitHub Enterprise Server. Defaults to "https://api.github.com"
+	URL string
+	// HTTPClient is the client used to call the API. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// GitHubIssueReporter opens a GitHub issue for each new panic fingerprint. The fingerprint is
+// written in the issue body, so open issues are found again after a restart
+type GitHubIssueReporter struct {
+	options GitHubIssueOptions
+
+	mu     sync.Mutex
+	issues map[string]int
+}
+
+// NewGitHubIssueReporter creates a GitHubIssueReporter from the given options
+func NewGitHubIssueReporter(options GitHubIssueOptions) *GitHubIssueReporter {
+	if options.URL == "" {
+		options.URL = "https://api.github.com"
+	}
+	options.URL = strings.TrimSuffix(options.URL, "/")
+	return &GitHubIssueReporter{options: options, issues: make(map[string]int)}
+}
+
+// WithGitHubIssues opens a GitHub issue for each new panic fingerprint
+func WithGitHubIssues(options GitHubIssueOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewGitHubIssueReporter(options))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (g *GitHubIssueReporter) Name() string {
+	return "github"
+}
+
+// Report opens an issue for the crash report's fingerprint, or comments on its open issue
+func (g *GitHubIssueReporter) Report(ctx context.Context, report CrashReport) error {
+	key := Fingerprint(report)
+	// Serialise reports, so concurrent panics with the same fingerprint don't open duplicate issues
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	number, ok := g.issues[key]
+	if !ok {
+		var err error
+		number, err = g.findIssue(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	if number == 0 {
+		created, err := g.createIssue(ctx, report, key)
+		if err != nil {
+			return err
+		}
+		g.issues[key] = created
+		return nil
+	}
+	g.issues[key] = number
+	if !g.options.CommentOnRecurrence {
+		return nil
+	}
+	return g.comment(ctx, number, report)
+}
+
+// findIssue returns the number of the open issue of a fingerprint, or 0 if there is none
+func (g *GitHubIssueReporter) findIssue(ctx context.Context, key string) (int, error) {
+	query := fmt.Sprintf(`repo:%s is:issue is:open in:body "%s"`, g.options.Repository, fingerprintLine(key))
+	var result struct {
+		Items []struct {
+			Number int    `json:"number"`
+			Body   string `json:"body"`
+		} `json:"items"`
+	}
+	if err := g.call(ctx, http.MethodGet, "/search/issues?q="+url.QueryEscape(query), nil, &result); err != nil {
+		return 0, err
+	}
+	// Search matches words, so check the body for the exact fingerprint
+	for _, item := range result.Items {
+		if strings.Contains(item.Body, fingerprintLine(key)) {
+			return item.Number, nil
+		}
+	}
+	return 0, nil
+}
+
+// createIssue opens an issue for a crash report and returns its number
+func (g *GitHubIssueReporter) createIssue(ctx context.Context, report CrashReport, key string) (int, error) {
+	title := "Panic: " + firstLine(report.Error)
+	if report.Handled {
+		title = "Error: " + firstLine(report.Error)
+	}
+	issue := map[string]any{
+		"title": title,
+		"body":  issueBody(report, key),
+	}
+	if labels := g.labels(report); len(labels) > 0 {
+		issue["labels"] = labels
+	}
+	var created struct {
+		Number int `json:"number"`
+	}
+	if err := g.call(ctx, http.MethodPost, "/repos/"+g.options.Repository+"/issues", issue, &created); err != nil {
+		return 0, err
+	}
+	return created.Number, nil
+}
+
+// comment adds a comment about a recurrence to an issue
+func (g *GitHubIssueReporter) comment(ctx context.Context, number int, report CrashReport) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Occurred again on `%s` at %s", reportHost(report), report.Timestamp.UTC().Format("2006-01-02 15:04:05 MST"))
+	if report.ID != "" {
+		fmt.Fprintf(&body, " (crash report `%s`)", report.ID)
+	}
+	body.WriteString(".\n")
+	writeIssueMetadata(&body, report)
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", g.options.Repository, number)
+	return g.call(ctx, http.MethodPost, path, map[string]string{"body": body.String()}, nil)
+}
+
+// labels returns the labels of a new issue
+func (g *GitHubIssueReporter) labels(report CrashReport) []string {
+	labels := append([]string{}, g.options.Labels...)
+	for _, key := range g.options.LabelMetadata {
+		if value := report.Metadata[key]; value != "" {
+			labels = append(labels, truncate(key+":"+value, 50))
+		}
+	}
+	return labels
+}
+
+// call calls the GitHub API, decoding the response into out if it is not nil
+func (g *GitHubIssueReporter) call(ctx context.Context, method, path string, payload, out any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.options.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.options.Token)
+	}
+	respBody, err := roundTrip(g.options.HTTPClient, req)
+	if err != nil || out == nil {
+		return err
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// fingerprintLine is the line of an issue body identifying its fingerprint
+func fingerprintLine(key string) string {
+	return "adfer-fingerprint-" + key
+}
+
+// issueBody formats a crash report as the body of a new issue, with the stack trace in a collapsible block
+func issueBody(report CrashReport, key string) string {
+	var sb strings.Builder
+	headline := "Panic recovered"
+	if report.Handled {
+		headline = "Error reported"
+	}
+	fmt.Fprintf(&sb, "**%s** on `%s` at %s\n\n", headline, reportHost(report), report.Timestamp.UTC().Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(&sb, "```\n%s\n```\n\n", truncate(report.Error, 10000))
+	fmt.Fprintf(&sb, "<details>\n<summary>Stack trace</summary>\n\n```\n%s\n```\n\n</details>\n\n", truncate(strings.TrimSpace(report.Stack), 50000))
+	if report.SystemInfo.OS != "" {
+		fmt.Fprintf(&sb, "%s/%s, %s\n\n", report.SystemInfo.OS, report.SystemInfo.Architecture, report.SystemInfo.GoVersion)
+	}
+	writeIssueMetadata(&sb, report)
+	if report.ID != "" {
+		fmt.Fprintf(&sb, "Crash report `%s`\n", report.ID)
+	}
+	fmt.Fprintf(&sb, "<sub>%s</sub>\n", fingerprintLine(key))
+	return sb.String()
+}
+
+// writeIssueMetadata writes the metadata of a report as a table
+func writeIssueMetadata(sb *strings.Builder, report CrashReport) {
+	if len(report.Metadata) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(report.Metadata))
+	for key := range report.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sb.WriteString("\n| Key | Value |\n| --- | --- |\n")
+	for _, key := range keys {
+		fmt.Fprintf(sb, "| %s | %s |\n", escapeTableCell(key), escapeTableCell(report.Metadata[key]))
+	}
+	sb.WriteString("\n")
+}
+
+// escapeTableCell escapes a value for a Markdown table cell
+func escapeTableCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,136 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub records issues and comments created through the API
+type fakeGitHub struct {
+	mu       sync.Mutex
+	issues   []map[string]any
+	comments map[string][]string
+	searches []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/search/issues":
+		query := r.URL.Query().Get("q")
+		f.searches = append(f.searches, query)
+		var items []map[string]any
+		for i, issue := range f.issues {
+			body := issue["body"].(string)
+			if line := body[strings.LastIndex(body, "adfer-fingerprint-"):]; strings.Contains(query, strings.TrimSuffix(line, "</sub>\n")) {
+				items = append(items, map[string]any{"number": i + 1, "body": body})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/app/issues":
+		var issue map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&issue)
+		f.issues = append(f.issues, issue)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"number": len(f.issues)})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+		var comment map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&comment)
+		f.comments[r.URL.Path] = append(f.comments[r.URL.Path], comment["body"])
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGitHubIssueReporter(t *testing.T) {
+	github := &fakeGitHub{comments: map[string][]string{}}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	options := GitHubIssueOptions{
+		Repository:          "acme/app",
+		Token:               "token",
+		Labels:              []string{"crash"},
+		LabelMetadata:       []string{"version"},
+		CommentOnRecurrence: true,
+		URL:                 server.URL,
+	}
+	reporter := NewGitHubIssueReporter(options)
+	report := CrashReport{
+		ID:         "abc",
+		Error:      "index out of range",
+		ErrorType:  "runtime.boundsError",
+		Stack:      testStack,
+		SystemInfo: SystemInfo{Hostname: "web-1"},
+		Metadata:   map[string]string{"version": "1.2.0"},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := reporter.Report(context.Background(), report); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(github.issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(github.issues))
+	}
+	issue := github.issues[0]
+	if issue["title"] != "Panic: index out of range" {
+		t.Errorf("Unexpected title '%v'", issue["title"])
+	}
+	body := issue["body"].(string)
+	for _, expected := range []string{"<details>\n<summary>Stack trace</summary>", "main.inner()", "| version | 1.2.0 |", fingerprintLine(Fingerprint(report))} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected body to contain '%s', got:\n%s", expected, body)
+		}
+	}
+	labels, _ := json.Marshal(issue["labels"])
+	if string(labels) != `["crash","version:1.2.0"]` {
+		t.Errorf("Unexpected labels %s", labels)
+	}
+	if comments := github.comments["/repos/acme/app/issues/1/comments"]; len(comments) != 1 || !strings.Contains(comments[0], "Occurred again on `web-1`") {
+		t.Errorf("Expected a comment on the recurrence, got %v", comments)
+	}
+	if len(github.searches) != 1 {
+		t.Errorf("Expected the issue number to be cached, got %d searches", len(github.searches))
+	}
+
+	// A new process finds the open issue by its fingerprint
+	restarted := NewGitHubIssueReporter(options)
+	if err := restarted.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(github.issues) != 1 || len(github.comments["/repos/acme/app/issues/1/comments"]) != 2 {
+		t.Errorf("Expected the open issue to be found, got %d issues", len(github.issues))
+	}
+
+	// A different panic opens a new issue
+	other := report
+	other.Error, other.ErrorType, other.Stack = "nil map", "runtime.plainError", ""
+	if err := restarted.Report(context.Background(), other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(github.issues) != 2 {
+		t.Errorf("Expected a new issue for a new fingerprint, got %d", len(github.issues))
+	}
+}
+
+func TestGitHubIssueReporterError(t *testing.T) {
+	server := httptest.NewServer(&fakeGitHub{comments: map[string][]string{}})
+	defer server.Close()
+
+	reporter := NewGitHubIssueReporter(GitHubIssueOptions{Repository: "acme/app", Token: "wrong", URL: server.URL})
+	if err := reporter.Report(context.Background(), CrashReport{Error: "boom"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an authorization error, got %v", err)
+	}
+}
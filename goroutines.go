@@ -0,0 +1,148 @@
+package adfer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxDeadGoroutines is the number of goroutines that died from panics kept by Goroutines
+const maxDeadGoroutines = 100
+
+// GoroutineInfo describes a goroutine started with SafeGoNamed
+type GoroutineInfo struct {
+	// Name is the name the goroutine was started with
+	Name string `json:"name"`
+	// Started is the time the goroutine was started
+	Started time.Time `json:"started"`
+	// Stopped is the time the goroutine died from a panic, zero while it is running
+	Stopped time.Time `json:"stopped,omitempty"`
+	// Panic is the error of the panic the goroutine died from
+	Panic string `json:"panic,omitempty"`
+}
+
+// goroutineRegistry tracks the goroutines started with SafeGoNamed
+type goroutineRegistry struct {
+	mu      sync.Mutex
+	next    uint64
+	running map[uint64]GoroutineInfo
+	dead    []GoroutineInfo
+}
+
+// SafeGoNamed runs f in a goroutine with panic recovery, like SafeGo. Its crash reports carry the
+// "goroutine" metadata entry, and it is listed by Goroutines while it runs and after it died from a panic
+func (ph *PanicHandler) SafeGoNamed(name string, f func()) {
+	id := ph.goroutines.start(name)
+	go func() {
+		defer func() {
+			r := recover()
+			if r == nil {
+				// Returned, or called runtime.Goexit
+				ph.goroutines.stop(id, "")
+				return
+			}
+			err, ok := r.(error)
+			if !ok {
+				err = ph.panicValueError(r)
+			}
+			// Recorded first, as the policy may re-panic or exit
+			ph.goroutines.stop(id, err.Error())
+			ph.handlePanicWith(context.Background(), r, map[string]string{"goroutine": name})
+		}()
+		f()
+	}()
+}
+
+// Goroutines returns the goroutines started with SafeGoNamed that are running, and the last ones
+// that died from a panic, ordered by start time
+func (ph *PanicHandler) Goroutines() []GoroutineInfo {
+	return ph.goroutines.list()
+}
+
+// start registers a goroutine and returns its ID
+func (g *goroutineRegistry) start(name string) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running == nil {
+		g.running = make(map[uint64]GoroutineInfo)
+	}
+	g.next++
+	g.running[g.next] = GoroutineInfo{Name: name, Started: time.Now()}
+	return g.next
+}
+
+// stop unregisters a goroutine, keeping it as dead if it panicked
+func (g *goroutineRegistry) stop(id uint64, panicErr string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	info := g.running[id]
+	delete(g.running, id)
+	if panicErr == "" {
+		return
+	}
+	info.Stopped = time.Now()
+	info.Panic = panicErr
+	g.dead = append(g.dead, info)
+	if len(g.dead) > maxDeadGoroutines {
+		g.dead = g.dead[len(g.dead)-maxDeadGoroutines:]
+	}
+}
+
+// list returns the running and dead goroutines, ordered by start time
+func (g *goroutineRegistry) list() []GoroutineInfo {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	infos := make([]GoroutineInfo, 0, len(g.running)+len(g.dead))
+	for _, info := range g.running {
+		infos = append(infos, info)
+	}
+	infos = append(infos, g.dead...)
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Started.Before(infos[j].Started)
+	})
+	return infos
+}
+
+// contextRegistry tracks the contexts of the goroutines started with SafeGoCtx, so they can be
+// cancelled when the handler shuts down
+type contextRegistry struct {
+	mu       sync.Mutex
+	next     uint64
+	cancels  map[uint64]context.CancelFunc
+	shutdown bool
+}
+
+// derive returns a context derived from parent, cancelled by release or cancelAll
+func (c *contextRegistry) derive(parent context.Context) (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancel(parent)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shutdown {
+		cancel()
+		return ctx, cancel
+	}
+	if c.cancels == nil {
+		c.cancels = make(map[uint64]context.CancelFunc)
+	}
+	c.next++
+	id := c.next
+	c.cancels[id] = cancel
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.cancels, id)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancelAll cancels every derived context, and the contexts derived from now on
+func (c *contextRegistry) cancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdown = true
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	c.cancels = nil
+}
@@ -0,0 +1,124 @@
+package adfer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSafeGoNamed(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	ph.SafeGoNamed("worker", func() {
+		<-release
+	})
+	ph.SafeGoNamed("returns", func() {
+		close(done)
+	})
+	ph.SafeGoNamed("crashes", func() {
+		panic("boom")
+	})
+
+	report := <-reports
+	if report.Metadata["goroutine"] != "crashes" {
+		t.Errorf("Expected the goroutine name in the report, got %v", report.Metadata)
+	}
+	<-done
+	var goroutines []GoroutineInfo
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if goroutines = ph.Goroutines(); len(goroutines) == 2 {
+			break
+		}
+	}
+	if len(goroutines) != 2 {
+		t.Fatalf("Expected the running and the crashed goroutine, got %+v", goroutines)
+	}
+	worker, crashed := goroutines[0], goroutines[1]
+	if worker.Name != "worker" || worker.Started.IsZero() || !worker.Stopped.IsZero() {
+		t.Errorf("Expected a running worker, got %+v", worker)
+	}
+	if crashed.Name != "crashes" || crashed.Stopped.IsZero() || crashed.Panic != "boom" {
+		t.Errorf("Expected a crashed goroutine, got %+v", crashed)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if goroutines = ph.Goroutines(); len(goroutines) == 1 {
+			break
+		}
+	}
+	if len(goroutines) != 1 || goroutines[0].Name != "crashes" {
+		t.Errorf("Expected only the crashed goroutine, got %+v", goroutines)
+	}
+}
+
+func TestSafeGoCtxCancellation(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+
+	// A panic cancels the context shared with the goroutines the panicking one started
+	stopped := make(chan struct{})
+	ph.SafeGoCtx(context.Background(), func(ctx context.Context) {
+		go func() {
+			<-ctx.Done()
+			close(stopped)
+		}()
+		panic("boom")
+	})
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the context to be cancelled after the panic")
+	}
+
+	// Close cancels the contexts of running goroutines, and of those started afterwards
+	running := make(chan context.Context)
+	ph.SafeGoCtx(context.Background(), func(ctx context.Context) {
+		running <- ctx
+		<-ctx.Done()
+	})
+	ctx := <-running
+	if ctx.Err() != nil {
+		t.Fatal("Expected the context to be live while the goroutine runs")
+	}
+	ph.Close()
+	if ctx.Err() == nil {
+		t.Error("Expected Close to cancel the context")
+	}
+	ph.SafeGoCtx(context.Background(), func(ctx context.Context) {
+		running <- ctx
+	})
+	if ctx := <-running; ctx.Err() == nil {
+		t.Error("Expected goroutines started after Close to get a cancelled context")
+	}
+}
+
+func TestSafeGoCtxPanicAfterClose(t *testing.T) {
+	errs := make(chan error, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(ReporterFunc(func(ctx context.Context, _ CrashReport) error {
+		errs <- ctx.Err()
+		return nil
+	})))
+
+	// The panic is reported with the caller's context, not the cancelled one passed to f
+	started := make(chan struct{})
+	ph.SafeGoCtx(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		panic("shutting down")
+	})
+	<-started
+	ph.Close()
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("Expected a live context for reporters, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the panic to be reported")
+	}
+}
@@ -0,0 +1,54 @@
+package adfer
+
+import (
+	"sort"
+	"time"
+)
+
+// CrashGroup holds the stored crash reports that share a fingerprint, i.e. the occurrences of the same bug
+type CrashGroup struct {
+	// Fingerprint is the fingerprint shared by the reports, see Fingerprint
+	Fingerprint string `json:"fingerprint"`
+	// Count is the number of reports with the fingerprint
+	Count int `json:"count"`
+	// First and Last are the times of the oldest and the newest report
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+	// Latest is the newest report, as an example of the group
+	Latest CrashReport `json:"latest"`
+}
+
+// GroupCrashReports groups the stored crash reports by fingerprint, most frequent first. Groups with
+// the same count are ordered by their newest report, newest first
+func (s *reportStore) GroupCrashReports() ([]CrashGroup, error) {
+	reports, err := s.readCrashReports()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	var groups []CrashGroup
+	for _, report := range reports {
+		fingerprint := Fingerprint(report)
+		i, ok := index[fingerprint]
+		if !ok {
+			i = len(groups)
+			index[fingerprint] = i
+			groups = append(groups, CrashGroup{Fingerprint: fingerprint, First: report.Timestamp, Last: report.Timestamp})
+		}
+		group := &groups[i]
+		group.Count++
+		if report.Timestamp.Before(group.First) {
+			group.First = report.Timestamp
+		}
+		if !report.Timestamp.Before(group.Last) {
+			group.Last, group.Latest = report.Timestamp, report
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Last.After(groups[j].Last)
+	})
+	return groups, nil
+}
@@ -0,0 +1,63 @@
+package adfer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGroupCrashReports(t *testing.T) {
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	})
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			var m map[string]int
+			m["x"] = i
+		}()
+	}
+	func() {
+		defer ph.Recover()
+		panic("other")
+	}()
+
+	reports, _ := ph.GetLastNCrashReports(10)
+	if len(reports) != 4 || reports[0].Fingerprint == "" || reports[0].Fingerprint != reports[2].Fingerprint {
+		t.Fatalf("Expected the same fingerprint to be stored for the same panic, got %+v", reports)
+	}
+	if reports[3].Fingerprint == reports[0].Fingerprint {
+		t.Error("Expected another fingerprint for another panic")
+	}
+	groups, err := ph.GroupCrashReports()
+	if err != nil || len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v, error %v", groups, err)
+	}
+	if groups[0].Fingerprint != reports[0].Fingerprint || groups[0].Count != 3 || groups[1].Count != 1 {
+		t.Errorf("Expected the most frequent group first, got %+v", groups)
+	}
+	if groups[0].Latest.ID != reports[2].ID || !groups[0].First.Equal(reports[0].Timestamp) || !groups[0].Last.Equal(reports[2].Timestamp) {
+		t.Errorf("Expected the group to span the first to the third report, got %+v", groups[0])
+	}
+}
+
+func TestGroupCrashReportsWithoutFingerprint(t *testing.T) {
+	// Reports written before fingerprints were stored are grouped by their computed fingerprint
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	now := time.Now()
+	writeCrashFile(t, filePath, FormatJSON,
+		CrashReport{ID: "a", Timestamp: now, Error: "boom", ErrorType: "string"},
+		CrashReport{ID: "b", Timestamp: now.Add(time.Second), Error: "boom", ErrorType: "string"},
+		CrashReport{ID: "c", Timestamp: now.Add(2 * time.Second), Error: "bang", ErrorType: "string"},
+	)
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	groups, err := reader.GroupCrashReports()
+	if err != nil || len(groups) != 2 || groups[0].Count != 2 || groups[0].Latest.ID != "b" || groups[1].Latest.ID != "c" {
+		t.Errorf("Expected a group of 2 and a group of 1, got %+v, error %v", groups, err)
+	}
+}
@@ -0,0 +1,136 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrorHandlerV2 is a function type for custom error handling that receives the full crash report,
+// including its timestamp, metadata, system information and fingerprint. Changes made to the report
+// are kept when it is stored and delivered
+type ErrorHandlerV2 func(ctx context.Context, report *CrashReport)
+
+// WithErrorHandlerV2 sets an error handler that receives the full crash report. It's called instead
+// of ErrorHandler
+func WithErrorHandlerV2(handler ErrorHandlerV2) Option {
+	return func(o *Options) {
+		o.ErrorHandlerV2 = handler
+	}
+}
+
+// AdaptErrorHandler converts an ErrorHandler to an ErrorHandlerV2. The handler receives the original
+// error of a crash, or an error with the report's error message for reports that weren't recovered
+// in this process, and the report's stack
+func AdaptErrorHandler(handler ErrorHandler) ErrorHandlerV2 {
+	return func(ctx context.Context, report *CrashReport) {
+		handler(errorFromContext(ctx, *report), []byte(report.Stack))
+	}
+}
+
+// consoleHandler returns the default error handler, printing the crash ID, error and stack
+// with the handler's messages
+func (ph *PanicHandler) consoleHandler() ErrorHandlerV2 {
+	return func(ctx context.Context, report *CrashReport) {
+		printCrash(ph.messages, report.ID, errorFromContext(ctx, *report), []byte(report.Stack))
+	}
+}
+
+// WithErrorHandlers adds error handlers, called in order after ErrorHandlerV2 or ErrorHandler
+func WithErrorHandlers(handlers ...ErrorHandlerV2) Option {
+	return func(o *Options) {
+		o.ErrorHandlers = append(o.ErrorHandlers, handlers...)
+	}
+}
+
+// AddErrorHandler adds an error handler to the end of the handler chain. If no error handlers were
+// configured, the chain holds the default handler, which keeps printing to the console
+func (ph *PanicHandler) AddErrorHandler(handler ErrorHandlerV2) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.errorHandlers = append(ph.errorHandlers, handler)
+}
+
+// configuredErrorHandlers returns the configured error handlers in order, falling back to the default console handler
+func (ph *PanicHandler) configuredErrorHandlers() []ErrorHandlerV2 {
+	var handlers []ErrorHandlerV2
+	switch {
+	case ph.options.ErrorHandlerV2 != nil:
+		handlers = append(handlers, ph.options.ErrorHandlerV2)
+	case ph.options.ErrorHandler != nil:
+		handlers = append(handlers, AdaptErrorHandler(ph.options.ErrorHandler))
+	}
+	handlers = append(handlers, ph.options.ErrorHandlers...)
+	if len(handlers) == 0 {
+		handlers = append(handlers, ph.consoleHandler())
+	}
+	return handlers
+}
+
+// handleError calls every error handler in order. Each handler sees the changes made by the previous ones.
+// Handlers that panic or time out are reported as diagnostics and recorded in the report's HandlerErrors
+func (ph *PanicHandler) handleError(ctx context.Context, report *CrashReport) {
+	ph.mu.Lock()
+	handlers := ph.errorHandlers
+	ph.mu.Unlock()
+	for i, handler := range handlers {
+		if err := ph.callErrorHandler(i, handler, ctx, report); err != nil {
+			ph.diagnose(OpHandler, "", err)
+			report.HandlerErrors = append(report.HandlerErrors, err.Error())
+		}
+	}
+}
+
+// callErrorHandler calls an error handler under HandlerTimeout, recovering from its panics so a broken
+// handler doesn't stop the report from being stored and delivered. The handler gets a deep copy of the
+// report, so the changes a failed handler made, including those a timed out handler keeps making in
+// the background, never reach the stored report
+func (ph *PanicHandler) callErrorHandler(i int, handler ErrorHandlerV2, ctx context.Context, report *CrashReport) error {
+	handled := report.clone()
+	err := isolate(ctx, ph.options.HandlerTimeout, func(ctx context.Context) error {
+		handler(ctx, &handled)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error handler %d: %w", i, err)
+	}
+	*report = handled
+	return nil
+}
+
+// clone returns a deep copy of the report, sharing none of its maps and slices
+func (r CrashReport) clone() CrashReport {
+	r.Metadata = cloneMap(r.Metadata)
+	r.Tags = cloneSlice(r.Tags)
+	r.Scopes = cloneSlice(r.Scopes)
+	r.Flags = cloneMap(r.Flags)
+	r.Config = cloneMap(r.Config)
+	r.Deliveries = cloneMap(r.Deliveries)
+	r.HandlerErrors = cloneSlice(r.HandlerErrors)
+	r.Skipped = cloneSlice(r.Skipped)
+	if r.StackDiff != nil {
+		diff := *r.StackDiff
+		diff.Changed = cloneMap(diff.Changed)
+		r.StackDiff = &diff
+	}
+	return r
+}
+
+// cloneMap returns a copy of m, or nil if m is nil
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[K]V, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
+}
+
+// cloneSlice returns a copy of s, or nil if s is nil
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
@@ -0,0 +1,86 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandlerV2(t *testing.T) {
+	var received CrashReport
+	ph := New(Options{
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+		Metadata:     map[string]string{"version": "1.2"},
+		ErrorHandler: func(error, []byte) { t.Error("Expected ErrorHandler not to be called") },
+	}, WithErrorHandlerV2(func(ctx context.Context, report *CrashReport) {
+		received = *report
+		report.Metadata = map[string]string{"ticket": "OPS-1"}
+	}))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	if received.ID == "" || received.Timestamp.IsZero() || received.Fingerprint == "" || received.Metadata["version"] != "1.2" {
+		t.Errorf("Expected the full report, got %+v", received)
+	}
+	stored, err := ph.GetLastNCrashReports(1)
+	if err != nil || len(stored) != 1 || stored[0].Metadata["ticket"] != "OPS-1" {
+		t.Errorf("Expected the modified report to be stored, got %+v, error %v", stored, err)
+	}
+}
+
+func TestAdaptErrorHandler(t *testing.T) {
+	original := errors.New("original")
+	var got error
+	var stack []byte
+	handler := AdaptErrorHandler(func(err error, s []byte) { got, stack = err, s })
+
+	handler(contextWithError(context.Background(), original), &CrashReport{Error: "message", Stack: "stack"})
+	if got != original || string(stack) != "stack" {
+		t.Errorf("Expected the original error and the stack, got %v, %q", got, stack)
+	}
+	handler(context.Background(), &CrashReport{Error: "message"})
+	if got == nil || got.Error() != "message" {
+		t.Errorf("Expected the report's error message, got %v", got)
+	}
+}
+
+func TestErrorHandlers(t *testing.T) {
+	var order []string
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) { order = append(order, "legacy") },
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithErrorHandlers(
+		func(ctx context.Context, report *CrashReport) {
+			order = append(order, "first")
+			report.Tags = append(report.Tags, "seen")
+		},
+		func(ctx context.Context, report *CrashReport) {
+			order = append(order, "broken")
+			panic("handler bug")
+		},
+	))
+	ph.AddErrorHandler(func(ctx context.Context, report *CrashReport) {
+		order = append(order, "added")
+		if len(report.Tags) != 1 || report.Tags[0] != "seen" {
+			t.Errorf("Expected the changes of earlier handlers, got %v", report.Tags)
+		}
+	})
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	if strings.Join(order, ",") != "legacy,first,broken,added" {
+		t.Errorf("Expected the handlers to run in order, got %v", order)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpHandler || !strings.Contains(diagnostics[0].Err.Error(), "handler bug") {
+		t.Errorf("Expected a handler diagnostic, got %+v", diagnostics)
+	}
+}
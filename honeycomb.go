@@ -0,0 +1,124 @@
+package adfer
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HoneycombOptions configures a HoneycombReporter
+type HoneycombOptions struct {
+	// APIKey is the Honeycomb API key, sent in the X-Honeycomb-Team header
+	APIKey string
+	// Dataset is the dataset the events are sent to
+	Dataset string
+	// URL is the API host. Defaults to "https://api.honeycomb.io", use "https://api.eu1.honeycomb.io" for the EU region
+	URL string
+	// Fields are added to every event, e.g. {"service.name": "api"}
+	Fields map[string]any
+	// MaxFrames is the maximum number of stack frames flattened into columns. Defaults to 20
+	MaxFrames int
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// HoneycombReporter sends crash reports to Honeycomb as wide events, with the metadata,
+// system information and stack frames flattened into columns
+type HoneycombReporter struct {
+	options HoneycombOptions
+}
+
+// NewHoneycombReporter creates a HoneycombReporter from the given options
+func NewHoneycombReporter(options HoneycombOptions) *HoneycombReporter {
+	if options.URL == "" {
+		options.URL = "https://api.honeycomb.io"
+	}
+	if options.MaxFrames <= 0 {
+		options.MaxFrames = 20
+	}
+	return &HoneycombReporter{options: options}
+}
+
+// WithHoneycomb sends every crash report to a Honeycomb dataset
+func WithHoneycomb(options HoneycombOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewHoneycombReporter(options))
+	}
+}
+
+// Event converts a crash report into the columns of a Honeycomb event. Metadata, flags and config
+// are prefixed with "metadata.", "flag." and "config.", and tags of the form key=value become "tag.<key>" columns
+func (h *HoneycombReporter) Event(report CrashReport) map[string]any {
+	event := make(map[string]any, len(h.options.Fields))
+	for key, value := range h.options.Fields {
+		event[key] = value
+	}
+	event["name"] = "crash"
+	event["error"] = report.Error
+	event["error.type"] = report.ErrorType
+	event["handled"] = report.Handled
+	event["fingerprint"] = Fingerprint(report)
+	event["host"] = reportHost(report)
+	event["stack"] = report.Stack
+	if report.ID != "" {
+		event["crash.id"] = report.ID
+	}
+	if report.Category != "" {
+		event["category"] = report.Category
+	}
+	if report.SystemInfo.OS != "" {
+		event["system.os"] = report.SystemInfo.OS
+		event["system.architecture"] = report.SystemInfo.Architecture
+		event["system.go_version"] = report.SystemInfo.GoVersion
+	}
+	if len(report.Tags) > 0 {
+		event["tags"] = strings.Join(report.Tags, ",")
+		for _, tag := range report.Tags {
+			if key, value, ok := strings.Cut(tag, "="); ok {
+				event["tag."+key] = value
+			}
+		}
+	}
+	for key, value := range report.Metadata {
+		event["metadata."+key] = value
+	}
+	for key, value := range report.Flags {
+		event["flag."+key] = value
+	}
+	for key, value := range report.Config {
+		event["config."+key] = value
+	}
+
+	frames := ParseStack(report.Stack)
+	event["frames"] = len(frames)
+	if app := appFrames(frames); len(app) > 0 {
+		event["culprit"] = app[0].Function
+	}
+	if len(frames) > h.options.MaxFrames {
+		frames = frames[:h.options.MaxFrames]
+	}
+	for i, frame := range frames {
+		prefix := "frame." + strconv.Itoa(i) + "."
+		event[prefix+"function"] = frame.Function
+		event[prefix+"file"] = frame.File
+		event[prefix+"line"] = frame.Line
+	}
+	return event
+}
+
+// Name returns the name used in delivery receipts
+func (h *HoneycombReporter) Name() string {
+	return "honeycomb"
+}
+
+// Report sends the crash report to the configured dataset
+func (h *HoneycombReporter) Report(ctx context.Context, report CrashReport) error {
+	endpoint := strings.TrimRight(h.options.URL, "/") + "/1/events/" + url.PathEscape(h.options.Dataset)
+	return postJSON(ctx, h.options.HTTPClient, endpoint, h.Event(report), map[string]string{
+		"X-Honeycomb-Team":       h.options.APIKey,
+		"X-Honeycomb-Event-Time": report.Timestamp.UTC().Format(time.RFC3339Nano),
+	})
+}
@@ -0,0 +1,68 @@
+package adfer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHoneycombReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewHoneycombReporter(HoneycombOptions{
+		APIKey:    "key",
+		Dataset:   "crash reports",
+		URL:       server.URL + "/",
+		Fields:    map[string]any{"service.name": "api"},
+		MaxFrames: 2,
+	})
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	report := CrashReport{
+		ID:         "crash-1",
+		Timestamp:  timestamp,
+		Error:      "nil map",
+		ErrorType:  "runtime.Error",
+		Stack:      testStack,
+		Category:   "runtime",
+		SystemInfo: SystemInfo{OS: "linux", Architecture: "amd64", GoVersion: "go1.22", Hostname: "web-1"},
+		Metadata:   map[string]string{"region": "eu"},
+		Tags:       []string{"queue=email", "retry"},
+	}
+
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	request := requests()[0]
+	if request.Path != "/1/events/crash reports" {
+		t.Errorf("Unexpected path: %s", request.Path)
+	}
+	if request.Headers.Get("X-Honeycomb-Team") != "key" || request.Headers.Get("X-Honeycomb-Event-Time") != "2024-06-01T12:00:00Z" {
+		t.Errorf("Unexpected headers: %v", request.Headers)
+	}
+	expected := map[string]any{
+		"service.name":     "api",
+		"crash.id":         "crash-1",
+		"error":            "nil map",
+		"error.type":       "runtime.Error",
+		"category":         "runtime",
+		"handled":          false,
+		"fingerprint":      Fingerprint(report),
+		"host":             "web-1",
+		"system.os":        "linux",
+		"metadata.region":  "eu",
+		"tags":             "queue=email,retry",
+		"tag.queue":        "email",
+		"frames":           float64(3),
+		"culprit":          "main.inner",
+		"frame.0.function": "runtime/debug.Stack",
+		"frame.1.function": "main.inner",
+		"frame.1.line":     float64(5),
+	}
+	for key, value := range expected {
+		if request.Body[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, request.Body[key])
+		}
+	}
+	if _, ok := request.Body["frame.2.function"]; ok {
+		t.Errorf("Expected frames to be limited to MaxFrames")
+	}
+}
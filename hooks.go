@@ -0,0 +1,53 @@
+package adfer
+
+// FileHooks are callbacks for lifecycle events of the crash file, e.g. to archive rotated files
+// to cold storage or to alert when crash history is destroyed. Hooks of the crash file are
+// called once it is unlocked, so they may use the PanicHandler
+type FileHooks struct {
+	// OnRotate is called with the path of the compressed backup after the crash file was rotated
+	OnRotate func(oldPath string)
+	// OnPrune is called with the number of reports removed by MaxReports, DeleteReportsOlderThan or DeleteCrashReports
+	OnPrune func(removed int)
+	// OnWipe is called after the crash reports were wiped
+	OnWipe func()
+	// OnCorruptDetected is called with the path of the backup of a corrupt crash file, see RepairCrashFile
+	OnCorruptDetected func(path string)
+}
+
+// WithFileHooks sets the callbacks for lifecycle events of the crash file
+func WithFileHooks(hooks FileHooks) Option {
+	return func(o *Options) {
+		o.FileHooks = &hooks
+	}
+}
+
+// emit queues a hook call, made by fireHooks once the crash file is unlocked. Callers hold f.mu
+func (f *fileStorage) emit(hook func()) {
+	f.events = append(f.events, hook)
+}
+
+// fireHooks calls the hooks queued while the crash file was locked
+func (f *fileStorage) fireHooks() {
+	f.mu.Lock()
+	events := f.events
+	f.events = nil
+	f.mu.Unlock()
+	for _, event := range events {
+		event()
+	}
+}
+
+// pruned queues the OnPrune hook if reports were removed. Callers hold f.mu
+func (f *fileStorage) pruned(removed int) {
+	if f.hooks.OnPrune != nil && removed > 0 {
+		f.emit(func() { f.hooks.OnPrune(removed) })
+	}
+}
+
+// fileHooks returns the configured lifecycle hooks
+func (ph *PanicHandler) fileHooks() FileHooks {
+	if ph.options.FileHooks == nil {
+		return FileHooks{}
+	}
+	return *ph.options.FileHooks
+}
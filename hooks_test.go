@@ -0,0 +1,69 @@
+package adfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileHooks(t *testing.T) {
+	dir := t.TempDir()
+	var events []string
+	hooks := FileHooks{
+		OnPrune: func(removed int) { events = append(events, fmt.Sprintf("prune %d", removed)) },
+		OnWipe:  func() { events = append(events, "wipe") },
+		OnCorruptDetected: func(path string) {
+			events = append(events, "corrupt "+filepath.Base(path)[:len("crash.json.corrupt-")])
+		},
+	}
+	var ph *PanicHandler
+	hooks.OnRotate = func(oldPath string) {
+		// The crash file is unlocked when hooks are called
+		if _, err := ph.GetLastNCrashReports(1); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		events = append(events, "rotate "+filepath.Base(oldPath))
+	}
+	ph = New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(dir, "crash.json"),
+	}, WithMaxFileSize(1), WithFileHooks(hooks))
+	ph.Report(os.ErrClosed)
+	ph.Report(os.ErrClosed)
+
+	filePath := filepath.Join(dir, "pruned", "crash.json")
+	ph = New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithMaxReports(2), WithFileHooks(hooks))
+	for i := 0; i < 3; i++ {
+		ph.Report(os.ErrClosed)
+	}
+	os.WriteFile(filePath, []byte("[{"), 0644)
+	ph.Report(os.ErrClosed)
+	ph.DeleteReportsOlderThan(time.Now().Add(time.Hour))
+	ph.WipeCrashFile()
+
+	want := "rotate crash.json.1.gz|prune 1|corrupt crash.json.corrupt-|prune 1|wipe"
+	if got := strings.Join(events, "|"); got != want {
+		t.Errorf("Expected events %s, got %s", want, got)
+	}
+}
+
+func TestFileHooksCrashDir(t *testing.T) {
+	removed := 0
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithCrashDir(t.TempDir()), WithMaxReports(1), WithFileHooks(FileHooks{OnPrune: func(n int) { removed += n }}))
+	ph.Report(os.ErrClosed)
+	ph.Report(os.ErrClosed)
+	if removed != 1 {
+		t.Errorf("Expected 1 pruned report, got %d", removed)
+	}
+}
@@ -0,0 +1,76 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPClient is used by reporters that haven't been given a client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// post sends body to url and returns an error for non-2xx responses
+func post(ctx context.Context, client *http.Client, url string, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return do(client, req)
+}
+
+// postJSON marshals payload and posts it to url
+func postJSON(ctx context.Context, client *http.Client, url string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, client, url, "application/json", body, headers)
+}
+
+// do sends a request and returns an error for non-2xx responses
+func do(client *http.Client, req *http.Request) error {
+	_, err := roundTrip(client, req)
+	return err
+}
+
+// maxResponseSize is the maximum size of a response body read by roundTrip
+const maxResponseSize = 1 << 20
+
+// roundTrip sends a request and returns the response body, or an error for non-2xx responses
+func roundTrip(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &statusError{host: req.URL.Host, code: resp.StatusCode, body: truncate(string(bytes.TrimSpace(respBody)), 1024)}
+	}
+	return respBody, nil
+}
+
+// statusError is returned by roundTrip for non-2xx responses
+type statusError struct {
+	host string
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	if e.body != "" {
+		return fmt.Sprintf("%s returned status %d: %s", e.host, e.code, e.body)
+	}
+	return fmt.Sprintf("%s returned status %d", e.host, e.code)
+}
@@ -0,0 +1,114 @@
+package adfer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithIDGenerator sets the function that generates crash report IDs, e.g. UUIDv7, ULID or
+// an ID scheme required by downstream systems. Defaults to UUIDv4
+func WithIDGenerator(generator func() string) Option {
+	return func(o *Options) {
+		o.IDGenerator = generator
+	}
+}
+
+// newID returns an ID for a new crash report
+func (ph *PanicHandler) newID() string {
+	if ph.options.IDGenerator != nil {
+		return ph.options.IDGenerator()
+	}
+	return newReportID()
+}
+
+// newReportID returns a random (version 4) UUID
+func newReportID() string {
+	return UUIDv4()
+}
+
+// UUIDv4 returns a random (version 4) UUID. This is the default crash report ID
+func UUIDv4() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return formatUUID(id)
+}
+
+// UUIDv7 returns a time-ordered (version 7) UUID, which sorts by creation time
+func UUIDv7() string {
+	var id [16]byte
+	_, _ = rand.Read(id[6:])
+	ms := uint64(time.Now().UnixMilli())
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	id[6] = (id[6] & 0x0f) | 0x70
+	id[8] = (id[8] & 0x3f) | 0x80
+	return formatUUID(id)
+}
+
+func formatUUID(id [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidState struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// ULID returns a Universally Unique Lexicographically Sortable Identifier. IDs created
+// within the same millisecond are monotonically increasing
+func ULID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidState.Lock()
+	if ms == ulidState.ms {
+		// Increment the entropy, as an 80 bit big endian number
+		for i := len(ulidState.entropy) - 1; i >= 0; i-- {
+			ulidState.entropy[i]++
+			if ulidState.entropy[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ulidState.ms = ms
+		_, _ = rand.Read(ulidState.entropy[:])
+	}
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	copy(id[6:], ulidState.entropy[:])
+	ulidState.Unlock()
+
+	// 128 bits are encoded as 26 characters of 5 bits, the first holding only 3 bits
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// SequentialIDs returns a generator of sequential IDs, e.g. "crash-1", "crash-2". Intended for tests
+func SequentialIDs(prefix string) func() string {
+	var counter atomic.Int64
+	return func() string {
+		return prefix + strconv.FormatInt(counter.Add(1), 10)
+	}
+}
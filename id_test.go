@@ -0,0 +1,53 @@
+package adfer
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUUIDv7(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first := UUIDv7()
+	if !pattern.MatchString(first) {
+		t.Errorf("Invalid UUIDv7: %s", first)
+	}
+	if second := UUIDv7(); second == first {
+		t.Error("Expected unique IDs")
+	}
+}
+
+func TestULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	previous := ULID()
+	for i := 0; i < 100; i++ {
+		id := ULID()
+		if !pattern.MatchString(id) {
+			t.Fatalf("Invalid ULID: %s", id)
+		}
+		if id <= previous {
+			t.Fatalf("Expected ULIDs to increase, got %s after %s", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	reports := make(chan CrashReport, 2)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithIDGenerator(SequentialIDs("crash-")),
+		WithReporter(channelReporter(reports)),
+	)
+	for i := 0; i < 2; i++ {
+		func() {
+			defer ph.Recover()
+			panic("sequential panic")
+		}()
+	}
+
+	if id := (<-reports).ID; id != "crash-1" {
+		t.Errorf("Expected crash-1, got %s", id)
+	}
+	if id := (<-reports).ID; id != "crash-2" {
+		t.Errorf("Expected crash-2, got %s", id)
+	}
+}
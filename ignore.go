@@ -0,0 +1,49 @@
+package adfer
+
+import (
+	"errors"
+	"reflect"
+)
+
+// WithIgnorePanics ignores panics with the given values, e.g. http.ErrAbortHandler, which are re-raised
+// untouched instead of being reported. Error values also match panics with errors wrapping them
+func WithIgnorePanics(values ...any) Option {
+	return func(o *Options) {
+		o.IgnorePanics = append(o.IgnorePanics, values...)
+	}
+}
+
+// WithIgnoreFunc ignores panics for which ignore returns true, which are re-raised untouched instead
+// of being reported, e.g. to ignore the control-flow panics of a type
+func WithIgnoreFunc(ignore func(value any) bool) Option {
+	return func(o *Options) {
+		o.IgnoreFuncs = append(o.IgnoreFuncs, ignore)
+	}
+}
+
+// ignores returns true if the panic value r is ignored
+func (ph *PanicHandler) ignores(r any) bool {
+	for _, value := range ph.options.IgnorePanics {
+		if samePanic(r, value) {
+			return true
+		}
+	}
+	for _, ignore := range ph.options.IgnoreFuncs {
+		if ignore(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// samePanic returns true if the panic value r is value, or an error wrapping it
+func samePanic(r, value any) bool {
+	if err, ok := r.(error); ok {
+		if target, ok := value.(error); ok {
+			return errors.Is(err, target)
+		}
+	}
+	// Comparing values of the same uncomparable type would panic
+	typ := reflect.TypeOf(r)
+	return typ != nil && typ == reflect.TypeOf(value) && typ.Comparable() && r == value
+}
@@ -0,0 +1,49 @@
+package adfer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type controlFlow struct{ label string }
+
+func TestIgnorePanics(t *testing.T) {
+	reports := make(chan CrashReport, 4)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	}, WithIgnorePanics(http.ErrAbortHandler, "stop"), WithIgnoreFunc(func(value any) bool {
+		_, ok := value.(controlFlow)
+		return ok
+	}))
+
+	wrapped := fmt.Errorf("aborted: %w", http.ErrAbortHandler)
+	for _, value := range []any{http.ErrAbortHandler, wrapped, "stop", controlFlow{"retry"}} {
+		var repanicked any
+		func() {
+			defer func() {
+				repanicked = recover()
+			}()
+			defer ph.Recover()
+			panic(value)
+		}()
+		if repanicked != value {
+			t.Errorf("Expected %v to be re-raised untouched, got %v", value, repanicked)
+		}
+	}
+	if len(reports) != 0 {
+		t.Errorf("Expected ignored panics not to be reported, got %d reports", len(reports))
+	}
+
+	for _, value := range []any{"other", errors.New("other"), []int{1}} {
+		func() {
+			defer ph.Recover()
+			panic(value)
+		}()
+	}
+	if len(reports) != 3 {
+		t.Errorf("Expected other panics to be reported, got %d reports", len(reports))
+	}
+}
@@ -0,0 +1,192 @@
+package adfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrCrashFileChanged is returned when the crash file doesn't match its index, e.g. because
+// it was truncated or modified by another program
+var ErrCrashFileChanged = errors.New("crash file doesn't match its index")
+
+// crashIndex is the sidecar index of the crash file
+type crashIndex struct {
+	// Count is the number of reports in the crash file
+	Count int `json:"count"`
+	// Size is the size of the crash file in bytes
+	Size int64 `json:"size"`
+	// Checksum is the SHA-256 checksum of the crash file
+	Checksum string `json:"checksum"`
+	// Offsets holds the byte offset of each report in the crash file
+	Offsets []int64 `json:"offsets"`
+}
+
+// indexPath returns the path of the crash file's index
+func (f *fileStorage) indexPath() string {
+	return f.path + ".idx"
+}
+
+// encodeCrashReports encodes reports the same way as json.MarshalIndent(reports, "", "  "),
+// returning the offset of each report in the output
+func encodeCrashReports(reports []CrashReport) ([]byte, []int64, error) {
+	if len(reports) == 0 {
+		return []byte("[]"), nil, nil
+	}
+	var buf bytes.Buffer
+	offsets := make([]int64, len(reports))
+	buf.WriteString("[\n")
+	for i, report := range reports {
+		data, err := json.MarshalIndent(report, "  ", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		buf.WriteString("  ")
+		offsets[i] = int64(buf.Len())
+		buf.Write(data)
+		if i < len(reports)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+	return buf.Bytes(), offsets, nil
+}
+
+// write writes encoded reports to the crash file and updates the index, if enabled.
+// Index failures are reported as diagnostics, as the crash file itself was written
+func (f *fileStorage) write(data []byte, offsets []int64) error {
+	if err := f.writeFile(f.path, f.compressData(data)); err != nil {
+		return err
+	}
+	if !f.index {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	index, err := json.Marshal(crashIndex{
+		Count:    len(offsets),
+		Size:     int64(len(data)),
+		Checksum: hex.EncodeToString(sum[:]),
+		Offsets:  offsets,
+	})
+	if err == nil {
+		err = f.perms.writeFile(f.indexPath(), index)
+	}
+	if err != nil {
+		f.diagnose(OpIndex, f.indexPath(), err)
+	}
+	return nil
+}
+
+// readIndex reads the crash file's index
+func (f *fileStorage) readIndex() (*crashIndex, error) {
+	data, err := os.ReadFile(f.indexPath())
+	if err != nil {
+		return nil, err
+	}
+	var index crashIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// VerifyCrashFile checks the crash file against its index, returning an error wrapping
+// ErrCrashFileChanged if it was truncated or modified since adfer last wrote it.
+// It returns nil if Options.Index is not set, the crash file doesn't exist or a custom Storage is used
+func (s *reportStore) VerifyCrashFile() error {
+	file, ok := s.storage.(*fileStorage)
+	if !ok {
+		return nil
+	}
+	return file.verify()
+}
+
+// verify checks the crash file against its index
+func (f *fileStorage) verify() error {
+	if !f.index || f.path == "" {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock(false)()
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	index, err := f.readIndex()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCrashFileChanged, err)
+	}
+	if int64(len(data)) != index.Size {
+		return fmt.Errorf("%w: size is %d bytes, expected %d", ErrCrashFileChanged, len(data), index.Size)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != index.Checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrCrashFileChanged)
+	}
+	return nil
+}
+
+// checkIndex verifies the crash file on startup, rebuilding the index if it doesn't match
+func (f *fileStorage) checkIndex() {
+	err := f.verify()
+	if err == nil {
+		return
+	}
+	if _, indexErr := os.Stat(f.indexPath()); !os.IsNotExist(indexErr) {
+		f.diagnose(OpIndex, f.path, err)
+	}
+	err = f.modify(func(reports []CrashReport) ([]CrashReport, error) {
+		return reports, nil
+	})
+	if err != nil {
+		f.diagnose(OpIndex, f.indexPath(), err)
+	}
+}
+
+// readLast reads the last n reports using the index, without decoding the rest
+// of the crash file. It returns false if the index is missing or doesn't match the crash file. Callers hold f.mu
+func (f *fileStorage) readLast(n int) ([]CrashReport, bool) {
+	if !f.index {
+		return nil, false
+	}
+	index, err := f.readIndex()
+	if err != nil || index.Count != len(index.Offsets) {
+		return nil, false
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.Size() != index.Size {
+		return nil, false
+	}
+	if index.Count == 0 || n <= 0 {
+		return nil, true
+	}
+	if n > index.Count {
+		n = index.Count
+	}
+	offset := index.Offsets[index.Count-n]
+	tail, err := io.ReadAll(io.NewSectionReader(file, offset, index.Size-offset))
+	if err != nil {
+		return nil, false
+	}
+	var reports []CrashReport
+	if err := json.Unmarshal(append([]byte("["), tail...), &reports); err != nil || len(reports) != n {
+		return nil, false
+	}
+	return reports, true
+}
@@ -0,0 +1,131 @@
+package adfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncodeCrashReports(t *testing.T) {
+	reports := []CrashReport{
+		{ID: "1", Error: "first", Metadata: map[string]string{"key": "value"}, Timestamp: time.Now()},
+		{ID: "2", Error: "second", Tags: []string{"a", "b"}},
+	}
+	for _, reports := range [][]CrashReport{nil, reports[:1], reports} {
+		data, offsets, err := encodeCrashReports(reports)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected, _ := json.MarshalIndent(reports, "", "  ")
+		if len(reports) == 0 {
+			expected = []byte("[]")
+		}
+		if string(data) != string(expected) {
+			t.Errorf("Expected output of json.MarshalIndent, got:\n%s", data)
+		}
+		for i, offset := range offsets {
+			var report CrashReport
+			if err := json.NewDecoder(bytes.NewReader(data[offset:])).Decode(&report); err != nil || report.ID != reports[i].ID {
+				t.Errorf("Offset %d doesn't point at report %d", offset, i)
+			}
+		}
+	}
+}
+
+func TestCrashFileIndex(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	var diagnostics []Diagnostic
+	newHandler := func() *PanicHandler {
+		return New(Options{
+			ErrorHandler: func(error, []byte) {},
+			DumpToFile:   true,
+			FilePath:     filePath,
+			Index:        true,
+			OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		})
+	}
+
+	ph := newHandler()
+	for i := 0; i < 5; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+	if err := ph.VerifyCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, ok := ph.storage.(*fileStorage).readLast(2)
+	if !ok || len(reports) != 2 || reports[0].Error != "3" || reports[1].Error != "4" {
+		t.Fatalf("Expected the last 2 reports from the index, got %v %+v", ok, reports)
+	}
+
+	// Truncation is detected on startup and the index is rebuilt
+	data, _ := os.ReadFile(filePath)
+	var all []CrashReport
+	json.Unmarshal(data, &all)
+	truncated, _ := json.MarshalIndent(all[:3], "", "  ")
+	os.WriteFile(filePath, truncated, 0644)
+	if err := ph.VerifyCrashFile(); !errors.Is(err, ErrCrashFileChanged) {
+		t.Fatalf("Expected ErrCrashFileChanged, got %v", err)
+	}
+	if _, ok := ph.storage.(*fileStorage).readLast(2); ok {
+		t.Error("Expected the stale index not to be used")
+	}
+
+	ph = newHandler()
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpIndex {
+		t.Errorf("Expected an index diagnostic, got %v", diagnostics)
+	}
+	if err := ph.VerifyCrashFile(); err != nil {
+		t.Errorf("Expected the index to be rebuilt, got %v", err)
+	}
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 3 {
+		t.Errorf("Expected 3 reports, got %d (%v)", len(reports), err)
+	}
+}
+
+func TestCrashFileIndexConcurrent(t *testing.T) {
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+		Index:        true,
+	})
+	ph.Report(errors.New("first"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			ph.Report(fmt.Errorf("report %d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := ph.GetLastNCrashReports(3); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out reading the crash file while reports were written")
+	}
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 9 {
+		t.Errorf("Expected 9 reports, got %d (%v)", len(reports), err)
+	}
+}
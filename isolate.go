@@ -0,0 +1,51 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHandlerTimeout is returned when an error handler or reporter didn't return within HandlerTimeout
+var ErrHandlerTimeout = errors.New("handler timed out")
+
+// WithHandlerTimeout limits how long each error handler and reporter may take per crash report, so a
+// hung handler, e.g. a webhook call, can't block Recover. Handlers that time out keep running in the
+// background on their own copy of the report, whose changes are discarded, and the next handler is called
+func WithHandlerTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HandlerTimeout = timeout
+	}
+}
+
+// isolate calls fn, converting its panics into errors. With a timeout, fn runs on its own goroutine
+// with a context that is done after the timeout, and isolate returns ErrHandlerTimeout once it has
+// passed, leaving fn running
+func isolate(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return recovered(ctx, fn)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- recovered(ctx, fn)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %v", ErrHandlerTimeout, timeout)
+	}
+}
+
+// recovered calls fn, converting its panics into errors
+func recovered(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panicked: %v", p)
+		}
+	}()
+	return fn(ctx)
+}
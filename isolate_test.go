@@ -0,0 +1,129 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	exited := make(chan struct{})
+	ph := New(Options{
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+		ExitOnPanic:  true,
+	}, WithHandlerTimeout(20*time.Millisecond),
+		WithErrorHandlerV2(func(ctx context.Context, report *CrashReport) {
+			report.Tags = append(report.Tags, "discarded")
+			<-release
+		}),
+		WithReporter(ReporterFunc(func(ctx context.Context, _ CrashReport) error {
+			<-release
+			return ctx.Err()
+		})),
+		WithExitFunc(func(int) { close(exited) }),
+	)
+
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	select {
+	case <-exited:
+	default:
+		t.Fatal("Expected Recover to exit despite the hung handler and reporter")
+	}
+
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("Expected the report to be stored, got %+v, error %v", reports, err)
+	}
+	report := reports[0]
+	if len(report.HandlerErrors) != 1 || !strings.Contains(report.HandlerErrors[0], ErrHandlerTimeout.Error()) {
+		t.Errorf("Expected the handler timeout to be recorded, got %v", report.HandlerErrors)
+	}
+	if len(report.Tags) != 0 {
+		t.Errorf("Expected the changes of the timed out handler to be discarded, got %v", report.Tags)
+	}
+	for _, delivery := range report.Deliveries {
+		if delivery.Status != DeliveryFailed || !strings.Contains(delivery.Error, ErrHandlerTimeout.Error()) {
+			t.Errorf("Expected a failed delivery, got %+v", delivery)
+		}
+	}
+	if len(report.Deliveries) != 1 {
+		t.Errorf("Expected a delivery receipt, got %+v", report.Deliveries)
+	}
+}
+
+func TestHandlerTimeoutKeepsWriting(t *testing.T) {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	ph := New(Options{
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+		Metadata:     map[string]string{"region": "eu"},
+	}, WithHandlerTimeout(10*time.Millisecond),
+		WithErrorHandlerV2(func(_ context.Context, report *CrashReport) {
+			defer close(stopped)
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				report.Metadata["late"] = strconv.Itoa(i)
+			}
+		}),
+	)
+
+	// The handler outlives its timeout, writing to its report while this one is stored
+	ph.Report(errors.New("boom"))
+	reports, err := ph.GetLastNCrashReports(1)
+	close(stop)
+	<-stopped
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("Expected the report to be stored, got %+v, error %v", reports, err)
+	}
+	if _, ok := reports[0].Metadata["late"]; ok {
+		t.Errorf("Expected the timed out handler's writes to be discarded, got %+v", reports[0])
+	}
+}
+
+func TestReporterPanic(t *testing.T) {
+	var observed error
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, OnDiagnostic: func(Diagnostic) {}},
+		WithReporter(ReporterFunc(func(context.Context, CrashReport) error { panic("reporter bug") })),
+		WithAfterReport(func(_ CrashReport, err error) { observed = err }))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if observed == nil || !strings.Contains(observed.Error(), "panicked: reporter bug") {
+		t.Errorf("Expected the reporter panic to fail the delivery, got %v", observed)
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	want := errors.New("failed")
+	if err := isolate(context.Background(), 0, func(context.Context) error { return want }); err != want {
+		t.Errorf("Expected the error to be returned unchanged, got %v", err)
+	}
+	if err := isolate(context.Background(), time.Second, func(context.Context) error { return want }); err != want {
+		t.Errorf("Expected the error to be returned unchanged with a timeout, got %v", err)
+	}
+	err := isolate(context.Background(), time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if !errors.Is(err, ErrHandlerTimeout) {
+		t.Errorf("Expected ErrHandlerTimeout, got %v", err)
+	}
+}
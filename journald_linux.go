@@ -0,0 +1,144 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journalSocket is the path of the systemd journal's native protocol socket
+const journalSocket = "/run/systemd/journal/socket"
+
+// Journal priorities, as used by syslog
+const (
+	journalPriorityCrit    = 2
+	journalPriorityErr     = 3
+	journalPriorityWarning = 4
+	journalPriorityInfo    = 6
+)
+
+// JournaldReporter writes crash reports to the systemd journal using its native
+// protocol, so the full stack trace and report fields are kept as structured fields
+type JournaldReporter struct {
+	identifier string
+	socket     string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournaldReporter creates a JournaldReporter logging with the given syslog identifier
+func NewJournaldReporter(identifier string) *JournaldReporter {
+	return &JournaldReporter{
+		identifier: identifier,
+		socket:     journalSocket,
+	}
+}
+
+// journalAvailable returns true if the systemd journal socket exists
+func journalAvailable() bool {
+	_, err := os.Stat(journalSocket)
+	return err == nil
+}
+
+// Name returns the name used in delivery receipts
+func (j *JournaldReporter) Name() string {
+	return "journald"
+}
+
+// Report writes the crash report to the journal, with the priority of its severity as by SyslogReporter
+func (j *JournaldReporter) Report(_ context.Context, report CrashReport) error {
+	fallback := journalPriorityCrit
+	if report.Handled {
+		fallback = journalPriorityErr
+	}
+	priority := severityOf(report, map[Severity]int{
+		SeverityFatal:   journalPriorityCrit,
+		SeverityError:   journalPriorityErr,
+		SeverityWarning: journalPriorityWarning,
+		SeverityInfo:    journalPriorityInfo,
+	}, fallback)
+
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", logLine(report))
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(priority))
+	if j.identifier != "" {
+		writeJournalField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	}
+	writeJournalField(&buf, "ADFER_ERROR", report.Error)
+	writeJournalField(&buf, "ADFER_STACK", report.Stack)
+	if report.ID != "" {
+		writeJournalField(&buf, "ADFER_ID", report.ID)
+	}
+	if report.ErrorType != "" {
+		writeJournalField(&buf, "ADFER_ERROR_TYPE", report.ErrorType)
+	}
+	if len(report.Tags) > 0 {
+		writeJournalField(&buf, "ADFER_TAGS", strings.Join(report.Tags, ","))
+	}
+	for key, value := range report.Metadata {
+		writeJournalField(&buf, "ADFER_META_"+journalFieldName(key), value)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: j.socket, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		j.conn = conn
+	}
+	_, err := j.conn.Write(buf.Bytes())
+	if err != nil {
+		j.conn.Close()
+		j.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection to the journal
+func (j *JournaldReporter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// writeJournalField writes a field in the native journal format. Values containing
+// newlines use the binary-safe length-prefixed encoding
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts a metadata key to a valid journal field name
+func journalFieldName(key string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
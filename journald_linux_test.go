@@ -0,0 +1,67 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournaldReporter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	reporter := NewJournaldReporter("myapp")
+	reporter.socket = socket
+	defer reporter.Close()
+
+	err = reporter.Report(context.Background(), CrashReport{
+		ID:       "abc",
+		Error:    "boom",
+		Stack:    testStack,
+		Metadata: map[string]string{"build-id": "42"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read journal message: %v", err)
+	}
+	message := buf[:n]
+
+	for _, expected := range []string{"PRIORITY=2\n", "SYSLOG_IDENTIFIER=myapp\n", "ADFER_ID=abc\n", "ADFER_META_BUILD_ID=42\n", "MESSAGE=Panic recovered: boom"} {
+		if !bytes.Contains(message, []byte(expected)) {
+			t.Errorf("Expected message to contain '%s', got:\n%s", expected, message)
+		}
+	}
+
+	// Multi-line values use the length-prefixed encoding
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(testStack)))
+	expected := append(append([]byte("ADFER_STACK\n"), length[:]...), []byte(testStack+"\n")...)
+	if !bytes.Contains(message, expected) {
+		t.Errorf("Expected length-prefixed stack field, got:\n%q", message)
+	}
+
+	if err := reporter.Report(context.Background(), CrashReport{Error: "slow", Handled: true, Severity: SeverityWarning}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n, err = conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read journal message: %v", err)
+	}
+	if !bytes.Contains(buf[:n], []byte("PRIORITY=4\n")) {
+		t.Errorf("Expected warning priority, got:\n%s", buf[:n])
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+
+package adfer
+
+import (
+	"context"
+	"errors"
+)
+
+// JournaldReporter writes crash reports to the systemd journal.
+// The journal is only available on Linux and every report fails
+type JournaldReporter struct{}
+
+// NewJournaldReporter creates a JournaldReporter logging with the given syslog identifier
+func NewJournaldReporter(identifier string) *JournaldReporter {
+	return &JournaldReporter{}
+}
+
+// journalAvailable returns false as the journal is only available on Linux
+func journalAvailable() bool {
+	return false
+}
+
+// Name returns the name used in delivery receipts
+func (j *JournaldReporter) Name() string {
+	return "journald"
+}
+
+// Report returns an error as the journal is only available on Linux
+func (j *JournaldReporter) Report(context.Context, CrashReport) error {
+	return errors.New("the systemd journal is only available on linux")
+}
+
+// Close does nothing
+func (j *JournaldReporter) Close() error {
+	return nil
+}
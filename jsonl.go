@@ -0,0 +1,192 @@
+package adfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// readChunkSize is the size of the chunks read from the end of a JSON Lines crash file
+const readChunkSize = 64 * 1024
+
+// FileFormat is the format of the crash file
+type FileFormat int
+
+const (
+	// FormatJSON stores the reports as an indented JSON array, which is rewritten for every report
+	FormatJSON FileFormat = iota
+	// FormatJSONLines appends each report as a line of JSON, so storing a report doesn't
+	// read or rewrite the file. Index and StackDiffs don't apply to this format
+	FormatJSONLines
+)
+
+// WithFileFormat sets the format of the crash file
+func WithFileFormat(format FileFormat) Option {
+	return func(o *Options) {
+		o.FileFormat = format
+	}
+}
+
+// appendLine appends a report to a JSON Lines crash file
+func (f *fileStorage) appendLine(report CrashReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		f.diagnose(OpEncode, f.path, err)
+		return err
+	}
+	err = f.convertLines()
+	if err == nil {
+		err = f.appendData(f.compressData(append(data, '\n')))
+	}
+	if err != nil {
+		f.diagnose(OpWrite, f.path, err)
+	}
+	return err
+}
+
+// appendData appends data to the crash file. Files that aren't on the OS file system are rewritten
+func (f *fileStorage) appendData(data []byte) error {
+	if !f.native() {
+		existing, err := f.files().ReadFile(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return f.writeFile(f.path, append(existing, data...))
+	}
+	file, err := f.perms.open(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readLines reads every report of a JSON Lines crash file
+func (f *fileStorage) readLines() ([]CrashReport, error) {
+	if f.path == "" {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	data, err := f.readFile()
+	if err != nil {
+		return nil, err
+	}
+	return f.decodeLines(data), nil
+}
+
+// readLastLines reads the last n reports of a JSON Lines crash file, reading backwards from
+// the end of the file until n complete lines have been read
+func (f *fileStorage) readLastLines(n int) ([]CrashReport, error) {
+	if f.path == "" {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(gzipMagic))
+	if _, err := file.ReadAt(magic, 0); err == nil && isGzip(magic) {
+		// Compressed without compression enabled, e.g. written by another process
+		reports, err := f.readLines()
+		if err != nil || len(reports) <= n {
+			return reports, err
+		}
+		return reports[len(reports)-n:], nil
+	}
+
+	var tail []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(bytes.TrimRight(tail, "\n"), []byte("\n")) < n {
+		size := int64(readChunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+	}
+	if offset > 0 {
+		// Drop the partial line at the start of the chunks
+		tail = tail[bytes.IndexByte(tail, '\n')+1:]
+	}
+	reports := f.decodeLines(tail)
+	if len(reports) > n {
+		reports = reports[len(reports)-n:]
+	}
+	return reports, nil
+}
+
+// decodeLines decodes the lines of a JSON Lines crash file. Lines that can't be decoded, such as
+// a line torn by a crash while it was written, are skipped and reported as diagnostics
+func (f *fileStorage) decodeLines(data []byte) []CrashReport {
+	var reports []CrashReport
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var report CrashReport
+		if err := json.Unmarshal(line, &report); err != nil {
+			f.diagnose(OpDecode, f.path, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// encodeLines encodes reports as JSON Lines
+func encodeLines(reports []CrashReport) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, report := range reports {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// DetectFileFormat returns the format of an existing crash file and whether it is compressed, so a
+// crash file can be opened without knowing the options it was written with. Files that can't be
+// read are reported as uncompressed JSON
+func DetectFileFormat(path string) (format FileFormat, compressed bool) {
+	return detectFormat(path)
+}
+
+// detectFormat returns the format of an existing crash file from its first character, and
+// whether it is compressed
+func detectFormat(path string) (FileFormat, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FormatJSON, false
+	}
+	defer file.Close()
+	data := make([]byte, 512)
+	n, _ := io.ReadFull(file, data)
+	compressed := isGzip(data[:n])
+	if compressed {
+		if reader, err := gzip.NewReader(io.MultiReader(bytes.NewReader(data[:n]), file)); err == nil {
+			n, _ = io.ReadFull(reader, data)
+		}
+	}
+	if trimmed := bytes.TrimSpace(data[:n]); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSONLines, compressed
+	}
+	return FormatJSON, compressed
+}
@@ -0,0 +1,114 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithFileFormat(FormatJSONLines))
+	for i := 0; i < 5; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	data, _ := os.ReadFile(filePath)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 5 || !strings.HasPrefix(lines[0], "{") {
+		t.Fatalf("Expected 5 lines of JSON, got:\n%s", data)
+	}
+	reports, err := ph.GetLastNCrashReports(2)
+	if err != nil || len(reports) != 2 || reports[0].Error != "3" || reports[1].Error != "4" {
+		t.Fatalf("Expected the last 2 reports, got %+v, error %v", reports, err)
+	}
+	if all, _ := ph.GetLastNCrashReports(10); len(all) != 5 {
+		t.Errorf("Expected 5 reports, got %d", len(all))
+	}
+
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last, _ := reader.GetLastNCrashReports(1); len(last) != 1 || last[0].Error != "4" {
+		t.Errorf("Expected the reader to detect JSON Lines, got %+v", last)
+	}
+
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filePath); len(data) != 0 {
+		t.Errorf("Expected an empty file, got %q", data)
+	}
+}
+
+func TestJSONLinesReadBackwards(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	var diagnostics []Diagnostic
+	storage := newFileStorage(Options{FilePath: filePath, FileFormat: FormatJSONLines}, func(op, path string, err error) {
+		diagnostics = append(diagnostics, Diagnostic{Op: op, Path: path, Err: err})
+	})
+	// Reports spanning several chunks
+	large := strings.Repeat("x", readChunkSize/3)
+	for i := 0; i < 10; i++ {
+		storage.Append(CrashReport{ID: strconv.Itoa(i), Error: large})
+	}
+	// A line torn by a crash while it was written
+	file, _ := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"id":"torn","err`)
+	file.Close()
+
+	reports, err := storage.LastN(4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var ids []string
+	for _, report := range reports {
+		ids = append(ids, report.ID)
+	}
+	if strings.Join(ids, ",") != "6,7,8,9" {
+		t.Errorf("Expected the last complete reports, got %v", ids)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpDecode {
+		t.Errorf("Expected a decode diagnostic for the torn line, got %v", diagnostics)
+	}
+}
+
+func TestJSONLinesDeliveries(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+		FileFormat:   FormatJSONLines,
+	}, WithReporter(ReporterFunc(func(context.Context, CrashReport) error {
+		return errors.New("unreachable")
+	})))
+	for i := 0; i < 2; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	pending, err := ph.PendingReports()
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("Expected 2 pending reports, got %d, error %v", len(pending), err)
+	}
+	data, _ := os.ReadFile(filePath)
+	if bytes.Count(data, []byte("\n")) != 2 {
+		t.Errorf("Expected the file to stay in JSON Lines, got:\n%s", data)
+	}
+}
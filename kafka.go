@@ -0,0 +1,104 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KafkaOptions configures a KafkaPublisher
+type KafkaOptions struct {
+	// URL is the base URL of a Kafka REST Proxy (v2 API), e.g. "http://kafka-rest:8082"
+	URL string
+	// Topic is the topic crash reports are published to
+	Topic string
+	// Key returns the record key of a report, which selects its partition. Defaults to the
+	// panic fingerprint, so occurrences of the same panic are kept in order
+	Key func(CrashReport) string
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// HTTPClient is the client used to publish records. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// KafkaPublisher publishes crash reports as JSON records to a Kafka topic through the
+// Kafka REST Proxy
+type KafkaPublisher struct {
+	options KafkaOptions
+}
+
+// NewKafkaPublisher creates a KafkaPublisher from the given options
+func NewKafkaPublisher(options KafkaOptions) *KafkaPublisher {
+	if options.Key == nil {
+		options.Key = Fingerprint
+	}
+	return &KafkaPublisher{options: options}
+}
+
+// WithKafka publishes every crash report to a Kafka topic
+func WithKafka(options KafkaOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewKafkaPublisher(options))
+	}
+}
+
+// kafkaRecord is a record of a REST Proxy produce request
+type kafkaRecord struct {
+	Key   string      `json:"key"`
+	Value CrashReport `json:"value"`
+}
+
+// kafkaProduceResponse is the response to a REST Proxy produce request
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Name returns the name used in delivery receipts
+func (k *KafkaPublisher) Name() string {
+	return "kafka"
+}
+
+// Report publishes the crash report to the topic
+func (k *KafkaPublisher) Report(ctx context.Context, report CrashReport) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: k.options.Key(report), Value: report}},
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(k.options.URL, "/") + "/topics/" + url.PathEscape(k.options.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	for key, value := range k.options.Headers {
+		req.Header.Set(key, value)
+	}
+	respBody, err := roundTrip(k.options.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	// The proxy responds with 200 even if the record was rejected by the broker. Responses
+	// that can't be decoded are accepted, as the proxy did accept the request
+	var resp kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil
+	}
+	for _, offset := range resp.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected record (error code %d): %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package adfer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	publisher := NewKafkaPublisher(KafkaOptions{
+		URL:     server.URL + "/",
+		Topic:   "crashes",
+		Headers: map[string]string{"Authorization": "Basic secret"},
+	})
+
+	report := CrashReport{ID: "abc", Error: "boom", ErrorType: "string", Stack: testStack}
+	if err := publisher.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	request := requests()[0]
+	if request.Path != "/topics/crashes" {
+		t.Errorf("Unexpected path '%s'", request.Path)
+	}
+	if request.Headers.Get("Content-Type") != "application/vnd.kafka.json.v2+json" || request.Headers.Get("Authorization") != "Basic secret" {
+		t.Errorf("Unexpected headers %v", request.Headers)
+	}
+	record := request.Body["records"].([]any)[0].(map[string]any)
+	if record["key"] != Fingerprint(report) {
+		t.Errorf("Expected the fingerprint as key, got %v", record["key"])
+	}
+	if value := record["value"].(map[string]any); value["id"] != "abc" || value["error"] != "boom" {
+		t.Errorf("Unexpected value %v", value)
+	}
+}
+
+func TestKafkaPublisherRecordError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"Topic not found"}]}`))
+	}))
+	defer server.Close()
+
+	publisher := NewKafkaPublisher(KafkaOptions{URL: server.URL, Topic: "missing", Key: func(r CrashReport) string { return r.ID }})
+	err := publisher.Report(context.Background(), CrashReport{ID: "abc"})
+	if err == nil || !strings.Contains(err.Error(), "Topic not found") {
+		t.Errorf("Expected the record error, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+// Package kvstore is a pure-Go embedded key-value store for adfer crash reports.
+//
+// Reports are appended to a log file as checksummed records, with an in-memory index
+// rebuilt when the store is opened, so a panic costs a single append instead of rewriting
+// a growing JSON array. Updates, such as delivery receipts, append a new version of the
+// report; the log is compacted once most of it is superseded versions. A record torn by a
+// crash mid-write is discarded when the store is opened.
+//
+//	store, err := kvstore.Open("crashes.db", kvstore.Options{})
+//	...
+//	defer store.Close()
+//	ph := adfer.New(adfer.Options{}, adfer.WithStorage(store))
+package kvstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/leaanthony/adfer"
+)
+
+// headerSize is the size of a record header: payload length, CRC-32 and sequence number
+const headerSize = 16
+
+// compactMinSize is the log size below which the store is never compacted
+const compactMinSize = 1 << 20
+
+// ErrClosed is returned by the methods of a closed Store
+var ErrClosed = errors.New("kvstore: store is closed")
+
+// Options configures a Store
+type Options struct {
+	// Sync flushes the log to disk after every write, so reports survive a power loss
+	Sync bool
+	// FileMode is the mode of the log file. It is set explicitly, so it isn't reduced by the
+	// umask. Defaults to 0644
+	FileMode os.FileMode
+}
+
+// location is the position of the latest version of a report in the log
+type location struct {
+	offset int64
+	size   int64
+}
+
+// Store is an adfer.Storage that keeps crash reports in an append-only log file. It also
+// implements adfer.ReportUpdater, so delivery receipts are stored
+type Store struct {
+	options Options
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	sequences []uint64
+	locations map[uint64]location
+	ids       map[string]uint64
+	next      uint64
+	live      int64
+}
+
+// Open opens the store at path, creating it if it doesn't exist
+func Open(path string, options Options) (*Store, error) {
+	if options.FileMode == 0 {
+		options.FileMode = 0644
+	}
+	file, err := openLog(path, os.O_RDWR|os.O_CREATE, options.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{options: options, file: file}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openLog opens a log file and sets its mode
+func openLog(path string, flag int, mode os.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// load rebuilds the index from the log, truncating a torn record at its end
+func (s *Store) load() error {
+	s.sequences = nil
+	s.locations = make(map[uint64]location)
+	s.ids = make(map[string]uint64)
+	s.next, s.live, s.size = 1, 0, 0
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerSize)
+	var offset int64
+	for offset+headerSize <= info.Size() {
+		if _, err := s.file.ReadAt(header, offset); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		if offset+headerSize+size > info.Size() {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := s.file.ReadAt(payload, offset+headerSize); err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+		var report adfer.CrashReport
+		if err := json.Unmarshal(payload, &report); err != nil {
+			break
+		}
+		s.index(binary.BigEndian.Uint64(header[8:16]), report.ID, location{offset: offset + headerSize, size: size})
+		offset += headerSize + size
+	}
+	if offset < info.Size() {
+		if err := s.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	s.size = offset
+	return nil
+}
+
+// index records the latest version of a report
+func (s *Store) index(sequence uint64, id string, loc location) {
+	if previous, ok := s.locations[sequence]; ok {
+		s.live -= previous.size
+	} else {
+		s.sequences = append(s.sequences, sequence)
+	}
+	s.locations[sequence] = loc
+	s.live += loc.size
+	if id != "" {
+		s.ids[id] = sequence
+	}
+	if sequence >= s.next {
+		s.next = sequence + 1
+	}
+}
+
+// write appends a version of a report to the log
+func (s *Store) write(sequence uint64, report adfer.CrashReport) error {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.WriteAt(encodeRecord(sequence, payload), s.size); err != nil {
+		return err
+	}
+	if s.options.Sync {
+		if err := s.file.Sync(); err != nil {
+			return err
+		}
+	}
+	s.index(sequence, report.ID, location{offset: s.size + headerSize, size: int64(len(payload))})
+	s.size += headerSize + int64(len(payload))
+	return nil
+}
+
+// encodeRecord returns the record of a version of a report
+func encodeRecord(sequence uint64, payload []byte) []byte {
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	binary.BigEndian.PutUint64(record[8:16], sequence)
+	copy(record[headerSize:], payload)
+	return record
+}
+
+// readPayload reads the encoded latest version of a report
+func (s *Store) readPayload(sequence uint64) ([]byte, error) {
+	loc := s.locations[sequence]
+	payload := make([]byte, loc.size)
+	if _, err := s.file.ReadAt(payload, loc.offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// read reads the latest version of a report
+func (s *Store) read(sequence uint64) (adfer.CrashReport, error) {
+	var report adfer.CrashReport
+	payload, err := s.readPayload(sequence)
+	if err != nil {
+		return report, err
+	}
+	err = json.Unmarshal(payload, &report)
+	return report, err
+}
+
+// Append stores a crash report
+func (s *Store) Append(report adfer.CrashReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	return s.write(s.next, report)
+}
+
+// LastN returns the last n crash reports, oldest first. If n is negative, every report is returned
+func (s *Store) LastN(n int) ([]adfer.CrashReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil, ErrClosed
+	}
+	if n < 0 || n > len(s.sequences) {
+		n = len(s.sequences)
+	}
+	reports := make([]adfer.CrashReport, 0, n)
+	for _, sequence := range s.sequences[len(s.sequences)-n:] {
+		report, err := s.read(sequence)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Wipe removes every crash report
+func (s *Store) Wipe() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// Update calls fn with the stored report with the given ID and stores the result.
+// It returns adfer.ErrReportNotFound if there is no such report
+func (s *Store) Update(id string, fn func(report *adfer.CrashReport)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	sequence, ok := s.ids[id]
+	if !ok {
+		return adfer.ErrReportNotFound
+	}
+	report, err := s.read(sequence)
+	if err != nil {
+		return err
+	}
+	fn(&report)
+	if err := s.write(sequence, report); err != nil {
+		return err
+	}
+	if s.size > compactMinSize && s.size > 2*s.live {
+		return s.compact()
+	}
+	return nil
+}
+
+// Compact rewrites the log without superseded versions of reports
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	return s.compact()
+}
+
+// compact copies the latest version of every report to a new log and renames it over the old one
+func (s *Store) compact() error {
+	path := s.file.Name()
+	compacted, err := openLog(path+".compact", os.O_RDWR|os.O_CREATE|os.O_TRUNC, s.options.FileMode)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	for _, sequence := range s.sequences {
+		payload, err := s.readPayload(sequence)
+		if err == nil {
+			_, err = compacted.WriteAt(encodeRecord(sequence, payload), offset)
+		}
+		if err != nil {
+			compacted.Close()
+			os.Remove(compacted.Name())
+			return err
+		}
+		offset += headerSize + int64(len(payload))
+	}
+	err = compacted.Sync()
+	if closeErr := compacted.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(compacted.Name())
+		return err
+	}
+	// Both files are closed before renaming, as open files can't be replaced on Windows
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(compacted.Name(), path)
+	s.file, err = openLog(path, os.O_RDWR|os.O_CREATE, s.options.FileMode)
+	if err != nil {
+		return err
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	return renameErr
+}
+
+// Close closes the log file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
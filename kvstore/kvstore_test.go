@@ -0,0 +1,126 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/leaanthony/adfer"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crashes.db")
+	store, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ph := adfer.New(adfer.Options{ErrorHandler: func(error, []byte) {}}, adfer.WithStorage(store))
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+	reports, err := ph.GetLastNCrashReports(2)
+	if err != nil || len(reports) != 2 || reports[0].Error != "1" || reports[1].Error != "2" {
+		t.Fatalf("Expected the last 2 reports, got %+v, error %v", reports, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.LastN(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	// The index is rebuilt when the store is reopened
+	store, err = Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+	all, err := store.LastN(-1)
+	if err != nil || len(all) != 3 || all[0].Error != "0" {
+		t.Fatalf("Expected 3 reports after reopening, got %+v, error %v", all, err)
+	}
+	if err := store.Wipe(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if all, _ := store.LastN(-1); len(all) != 0 {
+		t.Errorf("Expected no reports after wiping, got %d", len(all))
+	}
+}
+
+func TestStoreTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crashes.db")
+	store, _ := Open(path, Options{Sync: true})
+	store.Append(adfer.CrashReport{ID: "first", Error: "boom"})
+	store.Append(adfer.CrashReport{ID: "second", Error: "boom"})
+	store.Close()
+
+	// Simulate a crash in the middle of writing the second record
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-5)
+
+	store, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+	all, _ := store.LastN(-1)
+	if len(all) != 1 || all[0].ID != "first" {
+		t.Fatalf("Expected only the intact report, got %+v", all)
+	}
+	if err := store.Append(adfer.CrashReport{ID: "third"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if all, _ := store.LastN(-1); len(all) != 2 || all[1].ID != "third" {
+		t.Errorf("Expected the torn record to be replaced, got %+v", all)
+	}
+}
+
+func TestStoreUpdateAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crashes.db")
+	store, _ := Open(path, Options{})
+	defer store.Close()
+	ph := adfer.New(adfer.Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(adfer.Diagnostic) {},
+	}, adfer.WithStorage(store), adfer.WithReporter(adfer.ReporterFunc(func(context.Context, adfer.CrashReport) error {
+		return errors.New("unreachable")
+	})))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	pending, err := ph.PendingReports()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected 1 pending report, got %d, error %v", len(pending), err)
+	}
+
+	for i := 0; i < 10; i++ {
+		err := store.Update(pending[0].ID, func(report *adfer.CrashReport) {
+			report.Metadata = map[string]string{"attempt": strconv.Itoa(i)}
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	before, _ := os.Stat(path)
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected compaction to shrink the log from %d bytes, got %d", before.Size(), after.Size())
+	}
+	all, _ := store.LastN(-1)
+	if len(all) != 1 || all[0].Metadata["attempt"] != "9" {
+		t.Errorf("Expected the latest version of the report, got %+v", all)
+	}
+	if err := store.Update("missing", func(*adfer.CrashReport) {}); !errors.Is(err, adfer.ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package adfer
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+)
+
+// legacyTimeLayouts are the layouts of the timestamps at the start of legacy log lines,
+// e.g. as written by the log package
+var legacyTimeLayouts = []string{"2006/01/02 15:04:05.999999999", "2006-01-02 15:04:05.999999999"}
+
+// WithLegacyLogImport converts a plain-text panic log found at the crash file path, e.g. raw
+// tracebacks appended by earlier recovery code, into crash reports when the handler is created.
+// The original log is kept as <path>.legacy
+func WithLegacyLogImport() Option {
+	return func(o *Options) {
+		o.ImportLegacyLog = true
+	}
+}
+
+// ParsePanicLog extracts the panics of a plain-text log holding raw tracebacks, such as the output of
+// debug.Stack or of a crashed program. Each goroutine dump becomes a crash report, whose error is the
+// last "panic: " line before it, or the last line before it if there is none. Timestamps are taken
+// from log package prefixes and are zero otherwise. Text that isn't followed by a traceback is ignored
+func ParsePanicLog(data []byte) []CrashReport {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var reports []CrashReport
+	var message []string
+	for i := 0; i < len(lines); {
+		if !isGoroutineHeader(lines[i]) {
+			message = append(message, lines[i])
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && isStackLine(lines, end) {
+			end++
+		}
+		report := legacyReport(message)
+		report.Stack = strings.TrimRight(strings.Join(lines[i:end], "\n"), "\n") + "\n"
+		reports = append(reports, report)
+		message = nil
+		i = end
+	}
+	return reports
+}
+
+// isGoroutineHeader returns true if line starts a goroutine dump, e.g. "goroutine 1 [running]:"
+func isGoroutineHeader(line string) bool {
+	rest, ok := strings.CutPrefix(line, "goroutine ")
+	if !ok {
+		return false
+	}
+	id, _, ok := strings.Cut(rest, " [")
+	return ok && id != "" && strings.Trim(id, "0123456789") == "" && strings.HasSuffix(line, ":")
+}
+
+// isStackLine returns true if lines[i] continues the goroutine dumps before it
+func isStackLine(lines []string, i int) bool {
+	line := lines[i]
+	switch {
+	case isGoroutineHeader(line), strings.HasPrefix(line, "\t"), strings.HasPrefix(line, "created by "),
+		strings.HasPrefix(line, "...") && strings.HasSuffix(line, "frames elided..."):
+		return true
+	case line == "":
+		// Blank lines separate the goroutines of a dump
+		return i+1 < len(lines) && isGoroutineHeader(lines[i+1])
+	}
+	// A function, followed by its location
+	return i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") && strings.HasSuffix(strings.TrimSpace(line), ")")
+}
+
+// legacyReport creates the report of a traceback from the log lines before it
+func legacyReport(message []string) CrashReport {
+	line := ""
+	for i := len(message) - 1; i >= 0; i-- {
+		if strings.Contains(message[i], "panic: ") {
+			line = message[i]
+			break
+		}
+		if line == "" && strings.TrimSpace(message[i]) != "" && !strings.HasPrefix(message[i], "\t") {
+			line = message[i]
+		}
+	}
+	timestamp, text := legacyTimestamp(line)
+	if i := strings.LastIndex(text, "panic: "); i >= 0 {
+		text = text[i+len("panic: "):]
+	}
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "[recovered]"))
+	if text == "" {
+		text = "unknown panic"
+	}
+	return CrashReport{
+		Timestamp: timestamp,
+		Error:     text,
+		Metadata:  map[string]string{"source": "legacy"},
+	}
+}
+
+// legacyTimestamp splits a log line into the timestamp at its start, if any, and the rest of the line
+func legacyTimestamp(line string) (time.Time, string) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) >= 2 {
+		for _, layout := range legacyTimeLayouts {
+			if t, err := time.ParseInLocation(layout, fields[0]+" "+fields[1], time.Local); err == nil {
+				return t, strings.Join(fields[2:], " ")
+			}
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		return t, strings.Join(fields[1:], " ")
+	}
+	return time.Time{}, line
+}
+
+// importLegacyLog converts a plain-text panic log at the crash file path into crash reports,
+// keeping the original as <path>.legacy. Files that are already crash files or hold no
+// tracebacks are left alone
+func (ph *PanicHandler) importLegacyLog() {
+	file, ok := ph.storage.(*fileStorage)
+	if !ok || file.path == "" {
+		return
+	}
+	files := file.files()
+	data, err := files.ReadFile(file.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		ph.diagnose(OpImport, file.path, err)
+		return
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] == '[' || trimmed[0] == '{' {
+		return
+	}
+	reports := ParsePanicLog(data)
+	if len(reports) == 0 {
+		return
+	}
+	modTime := time.Now()
+	if info, err := files.Stat(file.path); err == nil {
+		modTime = info.ModTime()
+	}
+	if err := files.Rename(file.path, file.path+".legacy"); err != nil {
+		ph.diagnose(OpImport, file.path, err)
+		return
+	}
+	for _, report := range reports {
+		report.ID = ph.newID()
+		if report.Timestamp.IsZero() {
+			report.Timestamp = modTime
+		}
+		file.Append(report)
+	}
+}
@@ -0,0 +1,81 @@
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// legacyLog is a panic log written by homegrown recovery code and by a crashed program
+const legacyLog = `2024/06/01 12:00:00 server started
+2024/06/01 12:00:05 recovered panic: assignment to entry in nil map
+goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x5e
+main.handler({0x1, 0x2})
+	/app/main.go:42 +0x1d
+created by main.serve in goroutine 1
+	/app/main.go:30 +0x85
+2024/06/01 12:01:00 request served
+panic: boom [recovered]
+	panic: boom
+
+goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x25
+
+goroutine 5 [chan receive]:
+main.worker()
+	/app/worker.go:5 +0x12
+exit status 2
+`
+
+func TestParsePanicLog(t *testing.T) {
+	reports := ParsePanicLog([]byte(legacyLog))
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d: %+v", len(reports), reports)
+	}
+	first, second := reports[0], reports[1]
+	if first.Error != "assignment to entry in nil map" || !first.Timestamp.Equal(time.Date(2024, 6, 1, 12, 0, 5, 0, time.Local)) {
+		t.Errorf("Unexpected first report: %+v", first)
+	}
+	if frames := appFrames(ParseStack(first.Stack)); len(frames) == 0 || frames[0].Function != "main.handler" || frames[0].Line != 42 {
+		t.Errorf("Unexpected frames %+v", frames)
+	}
+	if second.Error != "boom" || !second.Timestamp.IsZero() || !strings.Contains(second.Stack, "main.worker()") || strings.Contains(second.Stack, "exit status") {
+		t.Errorf("Unexpected second report: %+v", second)
+	}
+	if reports := ParsePanicLog([]byte("just a log line\n")); len(reports) != 0 {
+		t.Errorf("Expected no reports from a log without tracebacks, got %+v", reports)
+	}
+}
+
+func TestLegacyLogImport(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "panic.log")
+	os.WriteFile(filePath, []byte(legacyLog), 0644)
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filePath}, WithLegacyLogImport())
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 2 || reports[0].ID == "" || reports[0].Metadata["source"] != "legacy" || reports[1].Timestamp.IsZero() {
+		t.Fatalf("Expected the legacy panics to be imported, got %+v, error %v", reports, err)
+	}
+	if data, _ := os.ReadFile(filePath + ".legacy"); string(data) != legacyLog {
+		t.Errorf("Expected the legacy log to be kept")
+	}
+
+	// The crash file isn't imported again
+	New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filePath}, WithLegacyLogImport())
+	if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 2 {
+		t.Errorf("Expected the crash file to be left alone, got %d reports", len(reports))
+	}
+
+	other := filepath.Join(dir, "other.log")
+	os.WriteFile(other, []byte("not a panic log\n"), 0644)
+	New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: other}, WithLegacyLogImport())
+	if data, _ := os.ReadFile(other); string(data) != "not a panic log\n" {
+		t.Errorf("Expected a log without tracebacks to be left alone")
+	}
+}
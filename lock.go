@@ -0,0 +1,44 @@
+package adfer
+
+import "os"
+
+// lockPath returns the path of the crash file's lock file
+func (f *fileStorage) lockPath() string {
+	return f.path + ".lock"
+}
+
+// lock takes an advisory lock on the crash file's lock file, so several processes can share the
+// crash file: exclusive for read-modify-write cycles and shared for reads. The lock file is
+// separate from the crash file, as rotation replaces the crash file. Failures are reported as
+// diagnostics and the crash file is used without the lock. It returns a function releasing the lock
+func (f *fileStorage) lock(exclusive bool) func() {
+	if !f.native() {
+		return func() {}
+	}
+	var file *os.File
+	var err error
+	if exclusive {
+		file, err = f.perms.open(f.lockPath(), os.O_RDWR|os.O_CREATE)
+	} else {
+		// Readers may not be allowed to create the lock file, and there is nothing
+		// to protect if no writer created it
+		file, err = os.Open(f.lockPath())
+		if os.IsNotExist(err) {
+			return func() {}
+		}
+	}
+	if err == nil {
+		err = lockFile(file, exclusive)
+		if err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		f.diagnose(OpLock, f.lockPath(), err)
+		return func() {}
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}
+}
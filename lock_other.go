@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !openbsd && !netbsd && !dragonfly && !illumos && !windows
+
+package adfer
+
+import "os"
+
+// lockFile does nothing on platforms without flock or LockFileEx. The crash file is
+// only protected against concurrent writes within the process
+func lockFile(*os.File, bool) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without flock or LockFileEx
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package adfer
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestLockConcurrentWriters(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	var wg sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		// Separate storages don't share a mutex, like separate processes
+		storage := newFileStorage(Options{FilePath: filePath}, func(op, path string, err error) {
+			t.Errorf("Unexpected diagnostic %s %s: %v", op, path, err)
+		})
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				storage.Append(CrashReport{ID: strconv.Itoa(writer) + "-" + strconv.Itoa(i)})
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, err := reader.GetLastNCrashReports(100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 40 {
+		t.Errorf("Expected 40 reports, got %d", len(reports))
+	}
+}
@@ -0,0 +1,94 @@
+package adfer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LokiOptions configures a LokiReporter
+type LokiOptions struct {
+	// URL is the base URL of Loki, e.g. "http://localhost:3100"
+	URL string
+	// Labels are added to every stream, e.g. {"service": "api"}. The job label defaults to "adfer"
+	Labels map[string]string
+	// ComponentKey is the metadata key whose value is used as the component label. Defaults to "component"
+	ComponentKey string
+	// TenantID, if set, is sent in the X-Scope-OrgID header of multi-tenant installations
+	TenantID string
+	// Username and Password authenticate with basic authentication, e.g. for Grafana Cloud
+	Username string
+	Password string
+	// HTTPClient is the client used to push log lines. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// LokiReporter pushes crash reports to Grafana Loki as JSON log lines, labelled with their
+// severity, component and fingerprint
+type LokiReporter struct {
+	options LokiOptions
+}
+
+// NewLokiReporter creates a LokiReporter from the given options
+func NewLokiReporter(options LokiOptions) *LokiReporter {
+	if options.ComponentKey == "" {
+		options.ComponentKey = "component"
+	}
+	options.URL = strings.TrimRight(options.URL, "/")
+	return &LokiReporter{options: options}
+}
+
+// WithLoki pushes every crash report to Grafana Loki
+func WithLoki(options LokiOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewLokiReporter(options))
+	}
+}
+
+// Labels returns the stream labels of a crash report. The severity label is the report's severity,
+// with fatal crashes labelled "critical"
+func (l *LokiReporter) Labels(report CrashReport) map[string]string {
+	labels := map[string]string{"job": "adfer"}
+	for key, value := range l.options.Labels {
+		labels[key] = value
+	}
+	labels["severity"] = severityLevel(report, "critical")
+	labels["fingerprint"] = Fingerprint(report)
+	if component := report.Metadata[l.options.ComponentKey]; component != "" {
+		labels["component"] = component
+	}
+	if report.Category != "" {
+		labels["category"] = report.Category
+	}
+	return labels
+}
+
+// Name returns the name used in delivery receipts
+func (l *LokiReporter) Name() string {
+	return "loki"
+}
+
+// Report pushes the crash report as a JSON log line, so it can be queried with LogQL's json parser
+func (l *LokiReporter) Report(ctx context.Context, report CrashReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"streams": []map[string]any{{
+			"stream": l.Labels(report),
+			"values": [][]string{{strconv.FormatInt(report.Timestamp.UnixNano(), 10), string(line)}},
+		}},
+	}
+	headers := map[string]string{}
+	if l.options.TenantID != "" {
+		headers["X-Scope-OrgID"] = l.options.TenantID
+	}
+	if l.options.Username != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(l.options.Username+":"+l.options.Password))
+	}
+	return postJSON(ctx, l.options.HTTPClient, l.options.URL+"/loki/api/v1/push", payload, headers)
+}
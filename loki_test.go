@@ -0,0 +1,67 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLokiReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewLokiReporter(LokiOptions{
+		URL:      server.URL,
+		Labels:   map[string]string{"service": "api"},
+		TenantID: "tenant-1",
+		Username: "user",
+		Password: "token",
+	})
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	report := CrashReport{
+		ID:        "crash-1",
+		Timestamp: timestamp,
+		Error:     "nil map",
+		Stack:     testStack,
+		Category:  "runtime",
+		Metadata:  map[string]string{"component": "billing"},
+	}
+
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	request := requests()[0]
+	if request.Path != "/loki/api/v1/push" || request.Headers.Get("X-Scope-OrgID") != "tenant-1" {
+		t.Errorf("Unexpected request: %s %v", request.Path, request.Headers)
+	}
+	if user, password, ok := (&http.Request{Header: request.Headers}).BasicAuth(); !ok || user != "user" || password != "token" {
+		t.Errorf("Unexpected authorization: %s", request.Headers.Get("Authorization"))
+	}
+	stream := request.Body["streams"].([]any)[0].(map[string]any)
+	labels := stream["stream"].(map[string]any)
+	expected := map[string]string{
+		"job":         "adfer",
+		"service":     "api",
+		"severity":    "critical",
+		"component":   "billing",
+		"category":    "runtime",
+		"fingerprint": Fingerprint(report),
+	}
+	for key, value := range expected {
+		if labels[key] != value {
+			t.Errorf("Expected label %s to be %s, got %v", key, value, labels[key])
+		}
+	}
+	value := stream["values"].([]any)[0].([]any)
+	if value[0] != "1717243200000000000" {
+		t.Errorf("Unexpected timestamp: %v", value[0])
+	}
+	var line CrashReport
+	if err := json.Unmarshal([]byte(value[1].(string)), &line); err != nil || line.ID != "crash-1" {
+		t.Errorf("Expected the report as a JSON line, got %v, error %v", value[1], err)
+	}
+
+	if labels := reporter.Labels(CrashReport{Error: "boom", Handled: true}); labels["severity"] != "error" || labels["component"] != "" {
+		t.Errorf("Unexpected labels for a handled error: %v", labels)
+	}
+}
@@ -0,0 +1,98 @@
+package adfer
+
+import "sync"
+
+// defaultMemoryCapacity is the capacity of an in-memory store created with a capacity of 0
+const defaultMemoryCapacity = 100
+
+// MemoryStore is a Storage that keeps the last crash reports in a ring buffer in memory, for
+// services that can't write to disk, e.g. in read-only containers. The oldest report is
+// dropped when the store is full. It implements ReportUpdater, so delivery receipts are stored
+type MemoryStore struct {
+	mu      sync.Mutex
+	reports []CrashReport
+	start   int
+	count   int
+}
+
+// NewMemoryStore creates a MemoryStore holding up to capacity reports. Defaults to 100
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = defaultMemoryCapacity
+	}
+	return &MemoryStore{reports: make([]CrashReport, capacity)}
+}
+
+// WithInMemoryStore stores the last capacity crash reports in memory instead of the crash file
+func WithInMemoryStore(capacity int) Option {
+	return WithStorage(NewMemoryStore(capacity))
+}
+
+// Append stores a crash report, dropping the oldest report if the store is full
+func (m *MemoryStore) Append(report CrashReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports[(m.start+m.count)%len(m.reports)] = report
+	if m.count < len(m.reports) {
+		m.count++
+	} else {
+		m.start = (m.start + 1) % len(m.reports)
+	}
+	return nil
+}
+
+// LastN returns the last n crash reports, oldest first. If n is negative, every report is returned
+func (m *MemoryStore) LastN(n int) ([]CrashReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 0 || n > m.count {
+		n = m.count
+	}
+	reports := make([]CrashReport, 0, n)
+	for i := m.count - n; i < m.count; i++ {
+		reports = append(reports, m.reports[(m.start+i)%len(m.reports)])
+	}
+	return reports, nil
+}
+
+// Wipe removes every crash report
+func (m *MemoryStore) Wipe() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports = make([]CrashReport, len(m.reports))
+	m.start, m.count = 0, 0
+	return nil
+}
+
+// Delete removes the reports for which fn returns true
+func (m *MemoryStore) Delete(fn func(report CrashReport) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reports := make([]CrashReport, len(m.reports))
+	kept := 0
+	for i := 0; i < m.count; i++ {
+		report := m.reports[(m.start+i)%len(m.reports)]
+		if !fn(report) {
+			reports[kept] = report
+			kept++
+		}
+	}
+	removed := m.count - kept
+	m.reports, m.start, m.count = reports, 0, kept
+	return removed, nil
+}
+
+// Update calls fn with the stored report with the given ID. It returns ErrReportNotFound
+// if there is no such report, e.g. because it was dropped
+func (m *MemoryStore) Update(id string, fn func(report *CrashReport)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := 0; i < m.count; i++ {
+		report := &m.reports[(m.start+i)%len(m.reports)]
+		if report.ID == id {
+			fn(report)
+			return nil
+		}
+	}
+	return ErrReportNotFound
+}
@@ -0,0 +1,66 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithInMemoryStore(3))
+	for i := 0; i < 5; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Expected the store to keep 3 reports, got %d", len(reports))
+	}
+	for i, report := range reports {
+		if report.Error != strconv.Itoa(i+2) {
+			t.Errorf("Expected report %d to be %d, got %s", i, i+2, report.Error)
+		}
+	}
+	if last, _ := ph.GetLastNCrashReports(1); len(last) != 1 || last[0].Error != "4" {
+		t.Errorf("Expected the last report, got %+v", last)
+	}
+
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 0 {
+		t.Errorf("Expected no reports after wiping, got %d", len(reports))
+	}
+}
+
+func TestMemoryStoreDeliveries(t *testing.T) {
+	store := NewMemoryStore(0)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+	}, WithStorage(store), WithReporter(ReporterFunc(func(context.Context, CrashReport) error {
+		return errors.New("unreachable")
+	})))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	pending, err := ph.PendingReports()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected 1 pending report, got %d, error %v", len(pending), err)
+	}
+	if len(store.reports) != defaultMemoryCapacity {
+		t.Errorf("Expected the default capacity, got %d", len(store.reports))
+	}
+	if err := store.Update("missing", func(*CrashReport) {}); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
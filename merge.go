@@ -0,0 +1,127 @@
+package adfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ImportCrashFile appends the reports of a crash file or crash directory, e.g. collected from another
+// machine, to the handler's storage and returns the number imported. Reports whose ID is already stored
+// are skipped, so a file can be imported again. Reports keep their IDs and delivery receipts, and are
+// encrypted if WithEncryption is set. They aren't sent to the reporters
+func (ph *PanicHandler) ImportCrashFile(path string) (int, error) {
+	if ph.storage == nil {
+		return 0, fmt.Errorf("no file path set for crash reports")
+	}
+	reports, err := readMergeSource(path)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := ph.readCrashReports()
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	seen := reportIDs(existing)
+	imported := 0
+	for _, report := range reports {
+		if report.ID != "" && seen[report.ID] {
+			continue
+		}
+		if ph.options.EncryptionKey != nil && report.Encrypted == "" {
+			if report, err = EncryptCrashReport(report, ph.options.EncryptionKey); err != nil {
+				return imported, err
+			}
+		}
+		if err := ph.storage.Append(report); err != nil {
+			return imported, err
+		}
+		seen[report.ID] = true
+		imported++
+	}
+	return imported, nil
+}
+
+// MergeCrashFiles combines the reports of crash files or crash directories, e.g. collected from several
+// machines, into the crash file dst, ordered by timestamp. Reports already in dst are kept, and reports
+// with the same ID are only included once. dst keeps its format and compression if it exists, and is
+// otherwise written as JSON Lines if its extension is .jsonl and as a JSON array otherwise
+func MergeCrashFiles(dst string, srcs ...string) error {
+	var merged []CrashReport
+	for _, src := range srcs {
+		reports, err := readMergeSource(src)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", src, err)
+		}
+		merged = append(merged, reports...)
+	}
+
+	format := FormatJSON
+	compressed := false
+	if _, err := os.Stat(dst); err == nil {
+		format, compressed = detectFormat(dst)
+	} else if filepath.Ext(dst) == ".jsonl" {
+		format = FormatJSONLines
+	}
+	storage := &fileStorage{
+		path:       dst,
+		format:     format,
+		compressed: compressed,
+		perms:      permsFromOptions(Options{}),
+		diagnose:   func(string, string, error) {},
+	}
+	return storage.modify(func(existing []CrashReport) ([]CrashReport, error) {
+		expandStacks(existing)
+		reports := append(existing, merged...)
+		seen := make(map[string]bool, len(reports))
+		kept := reports[:0]
+		for _, report := range reports {
+			if report.ID != "" && seen[report.ID] {
+				continue
+			}
+			seen[report.ID] = true
+			kept = append(kept, standaloneStack(report))
+		}
+		sort.SliceStable(kept, func(i, j int) bool {
+			return kept[i].Timestamp.Before(kept[j].Timestamp)
+		})
+		return kept, nil
+	})
+}
+
+// readMergeSource reads every report of a crash file or crash directory
+func readMergeSource(path string) ([]CrashReport, error) {
+	reader, err := OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := reader.readCrashReports()
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		reports[i] = standaloneStack(reports[i])
+	}
+	return reports, nil
+}
+
+// standaloneStack drops the stack diff of a report whose stack has been restored, as its
+// base may not be stored alongside it
+func standaloneStack(report CrashReport) CrashReport {
+	if report.StackDiff != nil && report.Stack != "" {
+		report.StackDiff = nil
+	}
+	return report
+}
+
+// reportIDs returns the set of IDs of reports
+func reportIDs(reports []CrashReport) map[string]bool {
+	ids := make(map[string]bool, len(reports))
+	for _, report := range reports {
+		if report.ID != "" {
+			ids[report.ID] = true
+		}
+	}
+	return ids
+}
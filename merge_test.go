@@ -0,0 +1,94 @@
+package adfer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCrashFile writes reports to a new crash file of the given format
+func writeCrashFile(t *testing.T, path string, format FileFormat, reports ...CrashReport) {
+	t.Helper()
+	storage := newFileStorage(Options{FilePath: path, FileFormat: format}, func(string, string, error) {})
+	for _, report := range reports {
+		if err := storage.Append(report); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestImportCrashFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	src := filepath.Join(dir, "other.jsonl")
+	writeCrashFile(t, src, FormatJSONLines,
+		CrashReport{ID: "a", Timestamp: now, Error: "first"},
+		CrashReport{ID: "b", Timestamp: now, Error: "second"},
+	)
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filepath.Join(dir, "crash.json")})
+	func() {
+		defer ph.Recover()
+		panic("local")
+	}()
+	for i, expected := range []int{2, 0} {
+		imported, err := ph.ImportCrashFile(src)
+		if err != nil || imported != expected {
+			t.Fatalf("Import %d: expected %d reports, got %d, error %v", i, expected, imported, err)
+		}
+	}
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 3 || reports[1].ID != "a" || reports[2].Error != "second" {
+		t.Errorf("Expected the local and imported reports, got %+v, error %v", reports, err)
+	}
+}
+
+func TestMergeCrashFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	first := filepath.Join(dir, "host1.json")
+	writeCrashFile(t, first, FormatJSON,
+		CrashReport{ID: "a", Timestamp: now.Add(-3 * time.Hour), Error: "a"},
+		CrashReport{ID: "c", Timestamp: now.Add(-1 * time.Hour), Error: "c"},
+	)
+	second := filepath.Join(dir, "host2.jsonl")
+	writeCrashFile(t, second, FormatJSONLines,
+		CrashReport{ID: "b", Timestamp: now.Add(-2 * time.Hour), Error: "b"},
+		CrashReport{ID: "a", Timestamp: now.Add(-3 * time.Hour), Error: "a"},
+	)
+
+	dst := filepath.Join(dir, "merged.jsonl")
+	if err := MergeCrashFiles(dst, first, second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reader, err := OpenReadOnly(dst)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if format, _ := detectFormat(dst); format != FormatJSONLines {
+		t.Errorf("Expected JSON Lines for a .jsonl destination, got %v", format)
+	}
+	reports, err := reader.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 3 {
+		t.Fatalf("Expected 3 reports, got %+v, error %v", reports, err)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if reports[i].ID != id {
+			t.Errorf("Expected report %s at %d, got %s", id, i, reports[i].ID)
+		}
+	}
+
+	// Merging into an existing file keeps its reports
+	third := filepath.Join(dir, "host3.json")
+	writeCrashFile(t, third, FormatJSON, CrashReport{ID: "d", Timestamp: now, Error: "d"})
+	if err := MergeCrashFiles(dst, third); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports, _ := reader.GetLastNCrashReports(10); len(reports) != 4 || reports[3].ID != "d" {
+		t.Errorf("Expected 4 reports, got %+v", reports)
+	}
+
+	if err := MergeCrashFiles(dst, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing source")
+	}
+}
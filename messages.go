@@ -0,0 +1,153 @@
+package adfer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Messages holds the user-facing strings of a language. Empty fields fall back to English
+type Messages struct {
+	// Banner is printed by the default error handler before the error, e.g. "Recovered from panic:"
+	Banner string
+	// ErrorLabel labels the error in the default error handler, e.g. "Error:"
+	ErrorLabel string
+	// StackLabel labels the stack trace in the default error handler, e.g. "Stack Trace:"
+	StackLabel string
+	// Crashed introduces the summary shown by the consent prompt. %s is replaced with the error
+	Crashed string
+	// CrashID labels the crash ID shown by the default error handler and the consent prompt
+	CrashID string
+	// SubmitQuestion is the question asked by the consent prompt
+	SubmitQuestion string
+	// NoAnswer is printed when the consent prompt times out
+	NoAnswer string
+	// Yes and No are the answers accepted by the consent prompt, e.g. "y" and "n"
+	Yes string
+	No  string
+	// Title and Message are shown to end users by error pages and dialogs
+	Title   string
+	Message string
+}
+
+// englishMessages are the default messages
+var englishMessages = Messages{
+	Banner:         "Recovered from panic:",
+	ErrorLabel:     "Error:",
+	StackLabel:     "Stack Trace:",
+	Crashed:        "The program crashed: %s",
+	CrashID:        "Crash ID:",
+	SubmitQuestion: "Submit this crash report?",
+	NoAnswer:       "No answer, using the default",
+	Yes:            "y",
+	No:             "n",
+	Title:          "Something went wrong",
+	Message:        "The application ran into an unexpected problem. The error has been recorded.",
+}
+
+// WithMessages registers the messages for a language tag, e.g. "de" or "pt-BR"
+func WithMessages(language string, messages Messages) Option {
+	return func(o *Options) {
+		if o.Messages == nil {
+			o.Messages = make(map[string]Messages)
+		}
+		o.Messages[normalizeLanguage(language)] = messages
+	}
+}
+
+// WithLanguage sets the language of user-facing messages, overriding the language of the environment
+func WithLanguage(language string) Option {
+	return func(o *Options) {
+		o.Language = language
+	}
+}
+
+// Messages returns the user-facing messages for the configured language. The messages of
+// the language tag are used if registered, then those of its base language, then English
+func (ph *PanicHandler) Messages() Messages {
+	return ph.messages
+}
+
+// resolveMessages selects the messages for the configured language
+func (ph *PanicHandler) resolveMessages() Messages {
+	language := ph.options.Language
+	if language == "" {
+		language = environmentLanguage()
+	}
+	language = normalizeLanguage(language)
+
+	catalog := make(map[string]Messages, len(ph.options.Messages))
+	for tag, messages := range ph.options.Messages {
+		catalog[normalizeLanguage(tag)] = messages
+	}
+
+	messages := englishMessages
+	base, _, _ := strings.Cut(language, "-")
+	if localized, ok := catalog[base]; ok {
+		messages = mergeMessages(messages, localized)
+	}
+	if localized, ok := catalog[language]; ok && language != base {
+		messages = mergeMessages(messages, localized)
+	}
+	return messages
+}
+
+// mergeMessages returns base with every non-empty field of override applied
+func mergeMessages(base, override Messages) Messages {
+	fields := []struct {
+		dst *string
+		src string
+	}{
+		{&base.Banner, override.Banner},
+		{&base.ErrorLabel, override.ErrorLabel},
+		{&base.StackLabel, override.StackLabel},
+		{&base.Crashed, override.Crashed},
+		{&base.CrashID, override.CrashID},
+		{&base.SubmitQuestion, override.SubmitQuestion},
+		{&base.NoAnswer, override.NoAnswer},
+		{&base.Yes, override.Yes},
+		{&base.No, override.No},
+		{&base.Title, override.Title},
+		{&base.Message, override.Message},
+	}
+	for _, field := range fields {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+	return base
+}
+
+// environmentLanguage returns the language from the POSIX locale environment variables
+func environmentLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" && value != "C" && value != "POSIX" {
+			return value
+		}
+	}
+	return "en"
+}
+
+// normalizeLanguage converts a language tag or POSIX locale, e.g. "pt_BR.UTF-8", to the form "pt-br"
+func normalizeLanguage(language string) string {
+	if idx := strings.IndexAny(language, ".@"); idx >= 0 {
+		language = language[:idx]
+	}
+	return strings.ToLower(strings.ReplaceAll(language, "_", "-"))
+}
+
+// consoleErrorHandler returns the default error handler, printing the given messages
+func consoleErrorHandler(messages Messages) ErrorHandler {
+	return func(err error, stack []byte) {
+		printCrash(messages, "", err, stack)
+	}
+}
+
+// printCrash prints a crash the way the default error handler does, with the crash ID if it is set
+func printCrash(messages Messages, id string, err error, stack []byte) {
+	if id == "" {
+		fmt.Printf("%s\n%s %v\n%s\n%s\n", messages.Banner, messages.ErrorLabel, err, messages.StackLabel, stack)
+		return
+	}
+	fmt.Printf("%s\n%s %s\n%s %v\n%s\n%s\n", messages.Banner, messages.CrashID, id, messages.ErrorLabel, err, messages.StackLabel, stack)
+}
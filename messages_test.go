@@ -0,0 +1,73 @@
+package adfer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMessagesLanguageFallback(t *testing.T) {
+	options := []Option{
+		WithMessages("pt", Messages{Banner: "Recuperado de pânico:", Title: "Algo deu errado"}),
+		WithMessages("pt_BR", Messages{Title: "Ops, algo deu errado"}),
+	}
+	tests := []struct {
+		language string
+		banner   string
+		title    string
+	}{
+		{language: "pt-BR", banner: "Recuperado de pânico:", title: "Ops, algo deu errado"},
+		{language: "pt_PT.UTF-8", banner: "Recuperado de pânico:", title: "Algo deu errado"},
+		{language: "de", banner: "Recovered from panic:", title: "Something went wrong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			ph := New(Options{}, append(options, WithLanguage(tt.language))...)
+			messages := ph.Messages()
+			if messages.Banner != tt.banner || messages.Title != tt.title {
+				t.Errorf("Unexpected messages: %+v", messages)
+			}
+			if messages.StackLabel != "Stack Trace:" {
+				t.Errorf("Expected missing messages to fall back to English, got %q", messages.StackLabel)
+			}
+		})
+	}
+}
+
+func TestMessagesFromEnvironment(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "cy_GB.UTF-8")
+	ph := New(Options{}, WithMessages("cy", Messages{Banner: "Wedi adfer o banig:"}))
+	if ph.Messages().Banner != "Wedi adfer o banig:" {
+		t.Errorf("Unexpected banner: %q", ph.Messages().Banner)
+	}
+}
+
+func TestLocalizedPrompt(t *testing.T) {
+	var output bytes.Buffer
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithLanguage("de"),
+		WithMessages("de", Messages{
+			Crashed:        "Das Programm ist abgestürzt: %s",
+			SubmitQuestion: "Fehlerbericht senden?",
+			Yes:            "j",
+		}),
+		WithReporter(&countingReporter{}),
+		WithConsentPrompt(PromptOptions{Input: strings.NewReader("j\n"), Output: &output}),
+	)
+	func() {
+		defer ph.Recover()
+		panic("kaputt")
+	}()
+
+	if !strings.Contains(output.String(), "Das Programm ist abgestürzt: kaputt") {
+		t.Errorf("Expected localized summary, got %q", output.String())
+	}
+	if !strings.Contains(output.String(), "Fehlerbericht senden? [j/N]") {
+		t.Errorf("Expected localized question, got %q", output.String())
+	}
+	if ph.options.Reporters[0].(*countingReporter).calls != 1 {
+		t.Error("Expected the localized answer to submit the report")
+	}
+}
@@ -0,0 +1,367 @@
+package adfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MQTTOptions configures an MQTTPublisher
+type MQTTOptions struct {
+	// Broker is the MQTT broker, e.g. "tcp://broker.example.com:1883" or "ssl://broker.example.com:8883".
+	// Credentials in the URL are used to authenticate. Defaults to "tcp://127.0.0.1:1883"
+	Broker string
+	// DeviceID identifies the device. It is the value of the {device} placeholder and the
+	// default client ID. Defaults to the host name
+	DeviceID string
+	// ClientID is the MQTT client identifier. Defaults to "adfer-" followed by the device ID
+	ClientID string
+	// TopicTemplate is the topic reports are published to. Supports the placeholders {device}, {app},
+	// {hostname}, {id} and {fingerprint}. Defaults to "devices/{device}/crashes"
+	TopicTemplate string
+	// App is the value of the {app} placeholder. Defaults to the executable name
+	App string
+	// QoS is the quality of service of published reports: 0 (at most once), 1 (at least once)
+	// or 2 (exactly once). Defaults to 0; use 1 or 2 on flaky links
+	QoS byte
+	// Retain asks the broker to keep the last report for new subscribers
+	Retain bool
+	// WillTopic is the topic of the last will and testament, which the broker publishes if the
+	// connection drops before the report is acknowledged. Supports the same placeholders as
+	// TopicTemplate. No will is set if empty
+	WillTopic string
+	// WillMessage is the payload of the last will
+	WillMessage []byte
+	// WillQoS is the quality of service of the last will
+	WillQoS byte
+	// WillRetain asks the broker to retain the last will
+	WillRetain bool
+	// TLSConfig is used for "ssl", "tls" and "mqtts" brokers. Defaults to verifying the broker's host name
+	TLSConfig *tls.Config
+	// Timeout is the timeout of each connection attempt, including publishing. Defaults to 10 seconds
+	Timeout time.Duration
+	// MaxRetries is the number of retries when the connection fails. Retried reports are
+	// marked as duplicates for QoS 1 and 2. Defaults to 3
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, doubled for each subsequent retry. Defaults to 1 second
+	RetryDelay time.Duration
+}
+
+// MQTTPublisher publishes crash reports as JSON messages to an MQTT 3.1.1 broker. A connection
+// is made for each report, as panics should be rare
+type MQTTPublisher struct {
+	options MQTTOptions
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	packetID uint16
+}
+
+// NewMQTTPublisher creates an MQTTPublisher from the given options
+func NewMQTTPublisher(options MQTTOptions) *MQTTPublisher {
+	if options.Broker == "" {
+		options.Broker = "tcp://127.0.0.1:1883"
+	}
+	if options.DeviceID == "" {
+		options.DeviceID = reportHost(CrashReport{})
+	}
+	if options.ClientID == "" {
+		options.ClientID = "adfer-" + options.DeviceID
+	}
+	if options.TopicTemplate == "" {
+		options.TopicTemplate = "devices/{device}/crashes"
+	}
+	if options.App == "" {
+		options.App = filepath.Base(os.Args[0])
+	}
+	if options.QoS > 2 {
+		options.QoS = 2
+	}
+	if options.WillQoS > 2 {
+		options.WillQoS = 2
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = 3
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = time.Second
+	}
+	return &MQTTPublisher{options: options, sleep: sleepContext}
+}
+
+// WithMQTT publishes every crash report to an MQTT broker
+func WithMQTT(options MQTTOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewMQTTPublisher(options))
+	}
+}
+
+// MQTT control packet types
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttPubrec     = 5
+	mqttPubrel     = 6
+	mqttPubcomp    = 7
+	mqttDisconnect = 14
+)
+
+// errMQTTRefused is returned when the broker refuses the connection, which is not retried
+var errMQTTRefused = errors.New("MQTT broker refused connection")
+
+// mqttConnackErrors describes the return codes of a refused connection
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Name returns the name used in delivery receipts
+func (m *MQTTPublisher) Name() string {
+	return "mqtt"
+}
+
+// Report publishes the crash report, retrying with exponential backoff if the connection fails
+func (m *MQTTPublisher) Report(ctx context.Context, report CrashReport) error {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	values := placeholderValues(report, m.options.App)
+	values["device"] = m.options.DeviceID
+	topic := expandPlaceholders(m.options.TopicTemplate, values)
+	willTopic := expandPlaceholders(m.options.WillTopic, values)
+
+	id := m.nextPacketID()
+	delay := m.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err = m.publish(ctx, topic, willTopic, payload, id, attempt > 0)
+		if err == nil || errors.Is(err, errMQTTRefused) || attempt >= m.options.MaxRetries {
+			return err
+		}
+		if err := m.sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// nextPacketID returns the identifier of the next QoS 1 or 2 message
+func (m *MQTTPublisher) nextPacketID() uint16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.packetID++
+	if m.packetID == 0 {
+		m.packetID = 1
+	}
+	return m.packetID
+}
+
+// publish connects to the broker, publishes the payload and waits for the acknowledgement of its QoS
+func (m *MQTTPublisher) publish(ctx context.Context, topic, willTopic string, payload []byte, id uint16, dup bool) error {
+	broker, err := url.Parse(m.options.Broker)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(m.options.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", broker.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	switch broker.Scheme {
+	case "ssl", "tls", "mqtts":
+		config := m.options.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: broker.Hostname()}
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		conn = tlsConn
+	}
+	reader := bufio.NewReader(conn)
+
+	if _, err := conn.Write(m.connectPacket(broker.User, willTopic)); err != nil {
+		return err
+	}
+	packetType, body, err := readMQTTPacket(reader)
+	if err != nil {
+		return err
+	}
+	if packetType != mqttConnack || len(body) != 2 {
+		return fmt.Errorf("unexpected MQTT packet type %d, expected CONNACK", packetType)
+	}
+	if code := body[1]; code != 0 {
+		return fmt.Errorf("%w: %s", errMQTTRefused, mqttConnackErrors[code])
+	}
+
+	qos := m.options.QoS
+	var packet bytes.Buffer
+	writeMQTTString(&packet, topic)
+	if qos > 0 {
+		packet.Write([]byte{byte(id >> 8), byte(id)})
+	}
+	packet.Write(payload)
+	flags := qos << 1
+	if dup && qos > 0 {
+		flags |= 0x08
+	}
+	if m.options.Retain {
+		flags |= 0x01
+	}
+	if _, err := conn.Write(mqttPacket(mqttPublish<<4|flags, packet.Bytes())); err != nil {
+		return err
+	}
+
+	switch qos {
+	case 1:
+		if err := expectMQTTAck(reader, mqttPuback, id); err != nil {
+			return err
+		}
+	case 2:
+		if err := expectMQTTAck(reader, mqttPubrec, id); err != nil {
+			return err
+		}
+		if _, err := conn.Write(mqttPacket(mqttPubrel<<4|0x02, []byte{byte(id >> 8), byte(id)})); err != nil {
+			return err
+		}
+		if err := expectMQTTAck(reader, mqttPubcomp, id); err != nil {
+			return err
+		}
+	}
+	// A clean disconnect tells the broker not to publish the last will
+	_, err = conn.Write([]byte{mqttDisconnect << 4, 0})
+	return err
+}
+
+// connectPacket returns the CONNECT packet, with the last will if configured
+func (m *MQTTPublisher) connectPacket(user *url.Userinfo, willTopic string) []byte {
+	var body bytes.Buffer
+	writeMQTTString(&body, "MQTT")
+	body.WriteByte(4) // protocol level 3.1.1
+
+	flags := byte(0x02) // clean session
+	if willTopic != "" {
+		flags |= 0x04 | m.options.WillQoS<<3
+		if m.options.WillRetain {
+			flags |= 0x20
+		}
+	}
+	password, hasPassword := "", false
+	if user != nil {
+		flags |= 0x80
+		password, hasPassword = user.Password()
+		if hasPassword {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	keepAlive := uint16(m.options.Timeout / time.Second * 2)
+	body.Write([]byte{byte(keepAlive >> 8), byte(keepAlive)})
+
+	writeMQTTString(&body, m.options.ClientID)
+	if willTopic != "" {
+		writeMQTTString(&body, willTopic)
+		writeMQTTString(&body, string(m.options.WillMessage))
+	}
+	if user != nil {
+		writeMQTTString(&body, user.Username())
+		if hasPassword {
+			writeMQTTString(&body, password)
+		}
+	}
+	return mqttPacket(mqttConnect<<4, body.Bytes())
+}
+
+// writeMQTTString writes a length-prefixed string
+func writeMQTTString(buf *bytes.Buffer, s string) {
+	buf.Write([]byte{byte(len(s) >> 8), byte(len(s))})
+	buf.WriteString(s)
+}
+
+// mqttPacket returns a packet with the given first header byte and body
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	// The remaining length is encoded in 7 bits per byte, least significant first
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// readMQTTPacket reads a packet, returning its type and body
+func readMQTTPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+// expectMQTTAck reads an acknowledgement of the given type for packet id
+func expectMQTTAck(reader *bufio.Reader, ackType byte, id uint16) error {
+	packetType, body, err := readMQTTPacket(reader)
+	if err != nil {
+		return err
+	}
+	if packetType != ackType || len(body) < 2 || binary.BigEndian.Uint16(body) != id {
+		return fmt.Errorf("unexpected MQTT packet type %d, expected acknowledgement of message %d", packetType, id)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package adfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// mqttSession is what fakeMQTTBroker received on a connection
+type mqttSession struct {
+	connectFlags byte
+	clientID     string
+	willTopic    string
+	header       byte
+	topic        string
+	payload      []byte
+	released     bool
+}
+
+// readMQTTString reads a length-prefixed string from b
+func readMQTTString(b []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+// fakeMQTTBroker answers connections with the given CONNACK return code. The first drop
+// connections are closed right after CONNECT, to simulate a flaky link
+func fakeMQTTBroker(t *testing.T, returnCode byte, drop int) (string, chan mqttSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	sessions := make(chan mqttSession, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			var session mqttSession
+			_, body, err := readMQTTPacket(reader)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			session.connectFlags = body[7]
+			rest := body[10:]
+			session.clientID, rest = readMQTTString(rest)
+			if session.connectFlags&0x04 != 0 {
+				session.willTopic, _ = readMQTTString(rest)
+			}
+			if drop > 0 {
+				drop--
+				conn.Close()
+				continue
+			}
+			_, _ = conn.Write([]byte{mqttConnack << 4, 2, 0, returnCode})
+			if returnCode != 0 {
+				sessions <- session
+				conn.Close()
+				continue
+			}
+			header, _ := reader.ReadByte()
+			_ = reader.UnreadByte()
+			_, body, _ = readMQTTPacket(reader)
+			session.header = header
+			session.topic, body = readMQTTString(body)
+			qos := header >> 1 & 0x03
+			if qos > 0 {
+				id := body[:2]
+				body = body[2:]
+				if qos == 1 {
+					_, _ = conn.Write([]byte{mqttPuback << 4, 2, id[0], id[1]})
+				} else {
+					_, _ = conn.Write([]byte{mqttPubrec << 4, 2, id[0], id[1]})
+					packetType, _, _ := readMQTTPacket(reader)
+					session.released = packetType == mqttPubrel
+					_, _ = conn.Write([]byte{mqttPubcomp << 4, 2, id[0], id[1]})
+				}
+			}
+			session.payload = body
+			packetType, _, _ := readMQTTPacket(reader)
+			if packetType == mqttDisconnect {
+				sessions <- session
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), sessions
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	for _, qos := range []byte{0, 1, 2} {
+		addr, sessions := fakeMQTTBroker(t, 0, 0)
+		publisher := NewMQTTPublisher(MQTTOptions{
+			Broker:      "tcp://gateway:secret@" + addr,
+			DeviceID:    "gw-17",
+			QoS:         qos,
+			Retain:      true,
+			WillTopic:   "devices/{device}/status",
+			WillMessage: []byte("offline"),
+		})
+
+		if err := publisher.Report(context.Background(), CrashReport{ID: "abc", Error: "boom"}); err != nil {
+			t.Fatalf("QoS %d: unexpected error: %v", qos, err)
+		}
+		session := <-sessions
+		if session.topic != "devices/gw-17/crashes" || session.clientID != "adfer-gw-17" || session.willTopic != "devices/gw-17/status" {
+			t.Errorf("QoS %d: unexpected session %+v", qos, session)
+		}
+		// user name, password, will and clean session
+		if session.connectFlags != 0x80|0x40|0x04|0x02 {
+			t.Errorf("QoS %d: unexpected connect flags %08b", qos, session.connectFlags)
+		}
+		if session.header != mqttPublish<<4|qos<<1|0x01 {
+			t.Errorf("QoS %d: unexpected PUBLISH header %08b", qos, session.header)
+		}
+		if qos == 2 && !session.released {
+			t.Error("Expected PUBREL for QoS 2")
+		}
+		var report CrashReport
+		if err := json.Unmarshal(session.payload, &report); err != nil || report.ID != "abc" {
+			t.Errorf("QoS %d: unexpected payload %s (%v)", qos, session.payload, err)
+		}
+	}
+}
+
+func TestMQTTPublisherRetries(t *testing.T) {
+	addr, sessions := fakeMQTTBroker(t, 0, 2)
+	publisher := NewMQTTPublisher(MQTTOptions{Broker: "tcp://" + addr, QoS: 1, Timeout: time.Second})
+	var delays []time.Duration
+	publisher.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	if err := publisher.Report(context.Background(), CrashReport{ID: "abc"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != 2*time.Second {
+		t.Errorf("Unexpected retry delays %v", delays)
+	}
+	if session := <-sessions; session.header&0x08 == 0 {
+		t.Errorf("Expected the retried report to be marked as duplicate, got header %08b", session.header)
+	}
+}
+
+func TestMQTTPublisherRefused(t *testing.T) {
+	addr, _ := fakeMQTTBroker(t, 5, 0)
+	publisher := NewMQTTPublisher(MQTTOptions{Broker: "tcp://" + addr})
+	publisher.sleep = func(context.Context, time.Duration) error {
+		t.Error("Expected a refused connection not to be retried")
+		return nil
+	}
+	err := publisher.Report(context.Background(), CrashReport{ID: "abc"})
+	if err == nil || err.Error() != "MQTT broker refused connection: not authorized" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
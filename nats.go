@@ -0,0 +1,157 @@
+package adfer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NATSOptions configures a NATSPublisher
+type NATSOptions struct {
+	// URL is the NATS server, e.g. "nats://nats.example.com:4222". Use the "tls" scheme to
+	// require TLS. Defaults to "nats://127.0.0.1:4222". Credentials in the URL are used to authenticate
+	URL string
+	// Subject is the subject crash reports are published to
+	Subject string
+	// Token authenticates with a token instead of a user and password
+	Token string
+	// TLSConfig is used if the server requires TLS. Defaults to verifying the server's host name
+	TLSConfig *tls.Config
+	// Timeout is the timeout of connecting and publishing. Defaults to 5 seconds
+	Timeout time.Duration
+}
+
+// NATSPublisher publishes crash reports as JSON messages to a NATS subject. A connection is
+// made for each report, as panics should be rare
+type NATSPublisher struct {
+	options NATSOptions
+}
+
+// NewNATSPublisher creates a NATSPublisher from the given options
+func NewNATSPublisher(options NATSOptions) *NATSPublisher {
+	if options.URL == "" {
+		options.URL = "nats://127.0.0.1:4222"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	return &NATSPublisher{options: options}
+}
+
+// WithNATS publishes every crash report to a NATS subject
+func WithNATS(options NATSOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewNATSPublisher(options))
+	}
+}
+
+// natsInfo is the part of the server's INFO message the publisher needs
+type natsInfo struct {
+	TLSRequired  bool `json:"tls_required"`
+	AuthRequired bool `json:"auth_required"`
+}
+
+// natsConnect is the CONNECT message sent by the publisher
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Token    string `json:"auth_token,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"pass,omitempty"`
+}
+
+// Name returns the name used in delivery receipts
+func (n *NATSPublisher) Name() string {
+	return "nats"
+}
+
+// Report publishes the crash report to the subject, waiting for the server to acknowledge it
+func (n *NATSPublisher) Report(ctx context.Context, report CrashReport) error {
+	if n.options.Subject == "" || strings.ContainsAny(n.options.Subject, " \t\r\n") {
+		return fmt.Errorf("invalid NATS subject '%s'", n.options.Subject)
+	}
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	server, err := url.Parse(n.options.URL)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(n.options.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", server.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting from NATS server: %s", strings.TrimSpace(line))
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return err
+	}
+	if info.TLSRequired || server.Scheme == "tls" {
+		config := n.options.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: server.Hostname()}
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect := natsConnect{Name: "adfer", Lang: "go", Version: "1", Token: n.options.Token}
+	if server.User != nil {
+		connect.User = server.User.Username()
+		connect.Password, _ = server.User.Password()
+	}
+	connectJSON, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("CONNECT %s\r\nPUB %s %d\r\n%s\r\nPING\r\n", connectJSON, n.options.Subject, len(payload), payload)
+	if _, err := conn.Write([]byte(message)); err != nil {
+		return err
+	}
+	// The server answers PING with PONG once the preceding messages were processed
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("NATS server error: " + strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+	}
+}
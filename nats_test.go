@@ -0,0 +1,101 @@
+package adfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// natsMessage is a message received by fakeNATSServer
+type natsMessage struct {
+	connect natsConnect
+	subject string
+	payload []byte
+}
+
+// fakeNATSServer accepts a single connection and answers it with reply after PING
+func fakeNATSServer(t *testing.T, reply string) (string, chan natsMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan natsMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+		reader := bufio.NewReader(conn)
+		var message natsMessage
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "CONNECT":
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &message.connect)
+			case "PUB":
+				var size int
+				_, _ = fmt.Sscan(fields[2], &size)
+				message.subject = fields[1]
+				message.payload = make([]byte, size+2)
+				_, _ = io.ReadFull(reader, message.payload)
+				message.payload = message.payload[:size]
+			case "PING":
+				messages <- message
+				_, _ = conn.Write([]byte(reply + "\r\n"))
+				return
+			}
+		}
+	}()
+	return "nats://" + listener.Addr().String(), messages
+}
+
+func TestNATSPublisher(t *testing.T) {
+	addr, messages := fakeNATSServer(t, "PONG")
+	publisher := NewNATSPublisher(NATSOptions{
+		URL:     strings.Replace(addr, "nats://", "nats://adfer:secret@", 1),
+		Subject: "crashes.myapp",
+	})
+
+	if err := publisher.Report(context.Background(), CrashReport{ID: "abc", Error: "boom"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message := <-messages
+	if message.subject != "crashes.myapp" {
+		t.Errorf("Unexpected subject '%s'", message.subject)
+	}
+	if message.connect.User != "adfer" || message.connect.Password != "secret" || message.connect.Verbose {
+		t.Errorf("Unexpected CONNECT %+v", message.connect)
+	}
+	var report CrashReport
+	if err := json.Unmarshal(message.payload, &report); err != nil || report.ID != "abc" {
+		t.Errorf("Unexpected payload %s (%v)", message.payload, err)
+	}
+}
+
+func TestNATSPublisherError(t *testing.T) {
+	addr, _ := fakeNATSServer(t, "-ERR 'Authorization Violation'")
+	publisher := NewNATSPublisher(NATSOptions{URL: addr, Subject: "crashes", Token: "wrong"})
+
+	err := publisher.Report(context.Background(), CrashReport{ID: "abc"})
+	if err == nil || err.Error() != "NATS server error: Authorization Violation" {
+		t.Errorf("Expected the server error, got %v", err)
+	}
+
+	if err := NewNATSPublisher(NATSOptions{URL: addr, Subject: "bad subject"}).Report(context.Background(), CrashReport{}); err == nil {
+		t.Error("Expected an error for a subject with whitespace")
+	}
+}
@@ -0,0 +1,333 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxSummaryFrames is the number of stack frames included in chat summaries
+const maxSummaryFrames = 5
+
+// chatFormat is the markup of a chat service. All chat notifiers build their
+// messages with summarize, so panics are formatted consistently
+type chatFormat struct {
+	bold     func(string) string
+	code     func(string) string
+	block    func(string) string
+	escape   func(string) string
+	truncate func(summary string, limit int) string
+}
+
+var (
+	// plainFormat is used for plain text, e.g. emails
+	plainFormat = chatFormat{
+		bold:     func(s string) string { return s },
+		code:     func(s string) string { return "`" + s + "`" },
+		block:    func(s string) string { return "```\n" + s + "\n```" },
+		escape:   func(s string) string { return s },
+		truncate: truncateSummary,
+	}
+	slackFormat = chatFormat{
+		bold:     func(s string) string { return "*" + s + "*" },
+		code:     plainFormat.code,
+		block:    plainFormat.block,
+		escape:   strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+		truncate: truncateSummary,
+	}
+	discordFormat = chatFormat{
+		bold:     func(s string) string { return "**" + s + "**" },
+		code:     plainFormat.code,
+		block:    plainFormat.block,
+		escape:   plainFormat.escape,
+		truncate: truncateSummary,
+	}
+	// teamsFormat is the markdown subset of Adaptive Card text blocks, which has no code formatting
+	teamsFormat = chatFormat{
+		bold:     discordFormat.bold,
+		code:     func(s string) string { return s },
+		block:    func(s string) string { return strings.ReplaceAll(s, "\n", "\n\n") },
+		escape:   plainFormat.escape,
+		truncate: truncateSummary,
+	}
+	// telegramFormat is Telegram's HTML parse mode
+	telegramFormat = chatFormat{
+		bold:     func(s string) string { return "<b>" + s + "</b>" },
+		code:     func(s string) string { return "<code>" + s + "</code>" },
+		block:    func(s string) string { return "<pre>" + s + "</pre>" },
+		escape:   html.EscapeString,
+		truncate: truncateHTML,
+	}
+)
+
+// chatNotifier posts a formatted crash summary to a chat webhook
+type chatNotifier struct {
+	name    string
+	url     string
+	client  *http.Client
+	format  chatFormat
+	limit   int
+	payload func(summary string) any
+}
+
+// NewSlackNotifier creates a Reporter that posts a crash summary to a Slack incoming webhook
+func NewSlackNotifier(webhookURL string) Reporter {
+	return &chatNotifier{
+		name:   "slack",
+		url:    webhookURL,
+		format: slackFormat,
+		limit:  3000,
+		payload: func(summary string) any {
+			return map[string]string{"text": summary}
+		},
+	}
+}
+
+// NewDiscordNotifier creates a Reporter that posts a crash summary to a Discord webhook
+func NewDiscordNotifier(webhookURL string) Reporter {
+	return &chatNotifier{
+		name:   "discord",
+		url:    webhookURL,
+		format: discordFormat,
+		limit:  2000,
+		payload: func(summary string) any {
+			return map[string]string{"content": summary}
+		},
+	}
+}
+
+// NewTeamsNotifier creates a Reporter that posts a crash summary as an Adaptive Card to a Microsoft Teams incoming webhook
+func NewTeamsNotifier(webhookURL string) Reporter {
+	return &chatNotifier{
+		name:   "teams",
+		url:    webhookURL,
+		format: teamsFormat,
+		limit:  20000,
+		payload: func(summary string) any {
+			return map[string]any{
+				"type": "message",
+				"attachments": []map[string]any{{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content": map[string]any{
+						"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+						"type":    "AdaptiveCard",
+						"version": "1.4",
+						"body": []map[string]any{{
+							"type": "TextBlock",
+							"text": summary,
+							"wrap": true,
+						}},
+					},
+				}},
+			}
+		},
+	}
+}
+
+// NewTelegramNotifier creates a Reporter that sends a crash summary to a Telegram chat using the Bot API
+func NewTelegramNotifier(botToken, chatID string) Reporter {
+	return &chatNotifier{
+		name:   "telegram",
+		url:    "https://api.telegram.org/bot" + botToken + "/sendMessage",
+		format: telegramFormat,
+		limit:  4096,
+		payload: func(summary string) any {
+			return map[string]any{
+				"chat_id":                  chatID,
+				"text":                     summary,
+				"parse_mode":               "HTML",
+				"disable_web_page_preview": true,
+			}
+		},
+	}
+}
+
+// WithSlackNotifier posts a crash summary to the given Slack webhook when a panic is recovered
+func WithSlackNotifier(webhookURL string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewSlackNotifier(webhookURL))
+	}
+}
+
+// WithDiscordNotifier posts a crash summary to the given Discord webhook when a panic is recovered
+func WithDiscordNotifier(webhookURL string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewDiscordNotifier(webhookURL))
+	}
+}
+
+// WithTeamsNotifier posts a crash summary to the given Microsoft Teams webhook when a panic is recovered
+func WithTeamsNotifier(webhookURL string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewTeamsNotifier(webhookURL))
+	}
+}
+
+// WithTelegramNotifier sends a crash summary to the given Telegram chat when a panic is recovered
+func WithTelegramNotifier(botToken, chatID string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewTelegramNotifier(botToken, chatID))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (c *chatNotifier) Name() string {
+	return c.name
+}
+
+// Report posts the crash summary to the webhook
+func (c *chatNotifier) Report(ctx context.Context, report CrashReport) error {
+	summary := c.format.truncate(summarize(report, c.format), c.limit)
+	return postJSON(ctx, c.client, c.url, c.payload(summary), nil)
+}
+
+// truncateSummary shortens a summary to at most limit bytes, closing a code block left open by the cut
+// so the rest of the message isn't rendered as code
+func truncateSummary(summary string, limit int) string {
+	const fence = "\n```"
+	if len(summary) <= limit {
+		return summary
+	}
+	if cut := truncate(summary, limit-len(fence)); strings.Count(cut, "```")%2 == 1 {
+		return cut + fence
+	}
+	return truncate(summary, limit)
+}
+
+// truncateHTML shortens an HTML summary to at most limit bytes without splitting a tag or an entity,
+// closing the tags left open by the cut so the message can still be parsed
+func truncateHTML(summary string, limit int) string {
+	if len(summary) <= limit {
+		return summary
+	}
+	for max := limit; max > 0; max-- {
+		cut := strings.TrimSuffix(truncate(summary, max), "...")
+		if i := strings.LastIndexAny(cut, "<&"); i >= 0 && !strings.ContainsAny(cut[i:], ">;") {
+			cut = cut[:i]
+		}
+		if truncated := cut + "..." + closeTags(cut); len(truncated) <= limit {
+			return truncated
+		}
+	}
+	return ""
+}
+
+// closeTags returns the closing tags of the tags left open in s, innermost first
+func closeTags(s string) string {
+	var open []string
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			break
+		}
+		tag := s[start+1 : start+end]
+		s = s[start+end+1:]
+		if name, ok := strings.CutPrefix(tag, "/"); ok {
+			if len(open) > 0 && open[len(open)-1] == name {
+				open = open[:len(open)-1]
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(tag, " ")
+		open = append(open, name)
+	}
+	var closing strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		closing.WriteString("</" + open[i] + ">")
+	}
+	return closing.String()
+}
+
+// maxSummaryError is the length the error is truncated to in summaries, so the
+// message limits of chat services are only reached in exceptional cases
+const maxSummaryError = 1000
+
+// summarize formats a crash report as a short message in the given format
+func summarize(report CrashReport, format chatFormat) string {
+	var sb strings.Builder
+	headline := "Panic recovered"
+	if report.Handled {
+		headline = "Error reported"
+	}
+	fmt.Fprintf(&sb, "%s on %s\n", format.bold(headline), format.code(format.escape(reportHost(report))))
+	fmt.Fprintf(&sb, "%s %s\n", format.bold("Error:"), format.escape(truncate(report.Error, maxSummaryError)))
+	if annotations := annotations(report); annotations != "" {
+		fmt.Fprintf(&sb, "%s %s\n", format.bold("Context:"), format.escape(annotations))
+	}
+
+	frames := appFrames(ParseStack(report.Stack))
+	if len(frames) > maxSummaryFrames {
+		frames = frames[:maxSummaryFrames]
+	}
+	if len(frames) > 0 {
+		lines := make([]string, 0, len(frames))
+		for _, frame := range frames {
+			lines = append(lines, fmt.Sprintf("%s\n    %s:%d", frame.Function, frame.File, frame.Line))
+		}
+		fmt.Fprintf(&sb, "%s\n%s\n", format.bold("Stack:"), format.block(format.escape(strings.Join(lines, "\n"))))
+	}
+
+	if len(report.Metadata) > 0 {
+		keys := make([]string, 0, len(report.Metadata))
+		for key := range report.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + "=" + report.Metadata[key]
+		}
+		fmt.Fprintf(&sb, "%s %s\n", format.bold("Metadata:"), format.escape(strings.Join(pairs, ", ")))
+	}
+	if len(report.Tags) > 0 {
+		fmt.Fprintf(&sb, "%s %s\n", format.bold("Tags:"), format.escape(strings.Join(report.Tags, ", ")))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// reportHost returns the host a crash happened on, falling back to the current host
+func reportHost(report CrashReport) string {
+	if report.SystemInfo.Hostname != "" {
+		return report.SystemInfo.Hostname
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// logLine formats a crash report as a single line suitable for line-based logs
+func logLine(report CrashReport) string {
+	var sb strings.Builder
+	if report.Handled {
+		sb.WriteString("Error reported: ")
+	} else {
+		sb.WriteString("Panic recovered: ")
+	}
+	sb.WriteString(strings.ReplaceAll(report.Error, "\n", " "))
+	if report.ID != "" {
+		fmt.Fprintf(&sb, " [id=%s]", report.ID)
+	}
+	frames := appFrames(ParseStack(report.Stack))
+	if len(frames) > maxSummaryFrames {
+		frames = frames[:maxSummaryFrames]
+	}
+	for i, frame := range frames {
+		if i == 0 {
+			sb.WriteString(" at ")
+		} else {
+			sb.WriteString(" <- ")
+		}
+		fmt.Fprintf(&sb, "%s (%s:%d)", frame.Function, frame.File, frame.Line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,199 @@
+package adfer
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+const testStack = "goroutine 1 [running]:\nruntime/debug.Stack()\n\t/go/src/runtime/debug/stack.go:24 +0x5e\nmain.inner()\n\t/app/main.go:5 +0x1\nmain.main()\n\t/app/main.go:10 +0x1\n"
+
+func TestSummarize(t *testing.T) {
+	summary := summarize(CrashReport{
+		Timestamp:  time.Now(),
+		Error:      "boom",
+		Stack:      testStack,
+		SystemInfo: SystemInfo{Hostname: "web-1"},
+		Metadata:   map[string]string{"version": "1.0.0", "region": "eu"},
+	}, slackFormat)
+
+	for _, expected := range []string{
+		"*Panic recovered* on `web-1`",
+		"*Error:* boom",
+		"main.inner\n    /app/main.go:5",
+		"*Metadata:* region=eu, version=1.0.0",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected summary to contain '%s', got:\n%s", expected, summary)
+		}
+	}
+	if strings.Contains(summary, "runtime/debug") {
+		t.Errorf("Expected runtime frames to be omitted, got:\n%s", summary)
+	}
+}
+
+func TestTruncateSummary(t *testing.T) {
+	summary := summarize(CrashReport{Error: "boom", Stack: testStack, SystemInfo: SystemInfo{Hostname: "web-1"}}, slackFormat)
+	limit := strings.Index(summary, "main.go:5")
+	truncated := truncateSummary(summary, limit)
+	if len(truncated) > limit || strings.Count(truncated, "```")%2 != 0 || !strings.HasSuffix(truncated, "...\n```") {
+		t.Errorf("Expected the code block to be closed after the cut, got:\n%s", truncated)
+	}
+
+	if truncated := truncateSummary(strings.Repeat("é", 10), 8); !utf8.ValidString(truncated) || truncated != "éé..." {
+		t.Errorf("Expected the summary to be cut on a rune boundary, got %q", truncated)
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	var stack strings.Builder
+	stack.WriteString("goroutine 1 [running]:\n")
+	for i := 0; i < maxSummaryFrames; i++ {
+		fmt.Fprintf(&stack, "main.handle[T %s](0x%d)\n\t/app/very/long/path/to/the/handler_%d.go:%d +0x1\n", strings.Repeat("A&B<C>", 60), i, i, i)
+	}
+	summary := summarize(CrashReport{Error: "a & b < c", Stack: stack.String(), SystemInfo: SystemInfo{Hostname: "web-1"}}, telegramFormat)
+	if len(summary) <= 4096 {
+		t.Fatalf("Expected an over-long summary, got %d bytes", len(summary))
+	}
+	for limit := 100; limit < len(summary); limit += 7 {
+		truncated := truncateHTML(summary, limit)
+		if len(truncated) > limit {
+			t.Fatalf("Expected at most %d bytes, got %d", limit, len(truncated))
+		}
+		decoder := xml.NewDecoder(strings.NewReader("<message>" + truncated + "</message>"))
+		decoder.Strict = true
+		for {
+			_, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Expected balanced tags and whole entities at limit %d, got %v:\n%s", limit, err, truncated)
+			}
+		}
+	}
+	if truncated := truncateHTML(summary, 4096); !strings.HasSuffix(truncated, "...</pre>") {
+		t.Errorf("Expected the code block to be closed after the cut, got:\n%s", truncated[len(truncated)-100:])
+	}
+}
+
+func TestChatNotifiers(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(string) Option
+		field  string
+		bold   string
+	}{
+		{name: "Slack", option: WithSlackNotifier, field: "text", bold: "*Error:*"},
+		{name: "Discord", option: WithDiscordNotifier, field: "content", bold: "**Error:**"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var payload map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Unexpected content type '%s'", r.Header.Get("Content-Type"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+			}))
+			defer server.Close()
+
+			ph := New(Options{ErrorHandler: func(error, []byte) {}}, test.option(server.URL))
+			func() {
+				defer ph.Recover()
+				panic("chat panic")
+			}()
+
+			if !strings.Contains(payload[test.field], test.bold+" chat panic") {
+				t.Errorf("Unexpected payload: %+v", payload)
+			}
+		})
+	}
+}
+
+func TestSummarizeEscapesMarkup(t *testing.T) {
+	report := CrashReport{Error: "<nil> & more", Stack: testStack, SystemInfo: SystemInfo{Hostname: "web-1"}, Handled: true}
+
+	summary := summarize(report, telegramFormat)
+	for _, expected := range []string{
+		"<b>Error reported</b> on <code>web-1</code>",
+		"<b>Error:</b> &lt;nil&gt; &amp; more",
+		"<pre>main.inner\n    /app/main.go:5",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected summary to contain '%s', got:\n%s", expected, summary)
+		}
+	}
+	if summary := summarize(report, slackFormat); !strings.Contains(summary, "*Error:* &lt;nil&gt; &amp; more") {
+		t.Errorf("Expected Slack control characters to be escaped, got:\n%s", summary)
+	}
+	if summary := summarize(report, discordFormat); !strings.Contains(summary, "**Error:** <nil> & more") {
+		t.Errorf("Expected Discord summary to be unescaped, got:\n%s", summary)
+	}
+}
+
+func TestTeamsNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithTeamsNotifier(server.URL))
+	func() {
+		defer ph.Recover()
+		panic("teams panic")
+	}()
+
+	if len(requests()) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests()))
+	}
+	request := requests()[0]
+	if request.Body["type"] != "message" {
+		t.Fatalf("Unexpected payload: %+v", request.Body)
+	}
+	attachment := request.Body["attachments"].([]any)[0].(map[string]any)
+	if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("Unexpected content type '%v'", attachment["contentType"])
+	}
+	card := attachment["content"].(map[string]any)
+	text := card["body"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "**Error:** teams panic") {
+		t.Errorf("Unexpected card text:\n%s", text)
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	notifier := NewTelegramNotifier("123:abc", "-10042").(*chatNotifier)
+	if notifier.url != "https://api.telegram.org/bot123:abc/sendMessage" {
+		t.Errorf("Unexpected URL '%s'", notifier.url)
+	}
+	notifier.url = server.URL + "/bot123:abc/sendMessage"
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(notifier))
+	func() {
+		defer ph.Recover()
+		panic("telegram <panic>")
+	}()
+
+	if len(requests()) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests()))
+	}
+	request := requests()[0]
+	if request.Path != "/bot123:abc/sendMessage" {
+		t.Errorf("Unexpected path '%s'", request.Path)
+	}
+	if request.Body["chat_id"] != "-10042" {
+		t.Errorf("Unexpected chat ID '%v'", request.Body["chat_id"])
+	}
+	if request.Body["parse_mode"] != "HTML" {
+		t.Errorf("Unexpected parse mode '%v'", request.Body["parse_mode"])
+	}
+	if text, _ := request.Body["text"].(string); !strings.Contains(text, "<b>Error:</b> telegram &lt;panic&gt;") {
+		t.Errorf("Unexpected text:\n%s", text)
+	}
+}
@@ -0,0 +1,78 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// PanicError is returned by OnceFunc, LazyValue, SafeGoResult and Group when the function panicked
+type PanicError struct {
+	// Value is the recovered panic value
+	Value any
+	// ReportID is the ID of the crash report of the panic
+	ReportID string
+}
+
+// Error returns the panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// OnceFunc returns a function that calls f only once. If f panics, the panic is recovered and
+// reported through ph, and every call returns the same *PanicError instead of silently
+// returning as sync.Once would after a panic
+func OnceFunc(ph *PanicHandler, f func()) func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			ph.call(context.Background(), f, &err)
+		})
+		return err
+	}
+}
+
+// LazyValue is a value initialized on first use. A panic during initialization is recovered,
+// reported and returned as a *PanicError to every caller of Get
+type LazyValue[T any] struct {
+	ph    *PanicHandler
+	init  func() (T, error)
+	once  sync.Once
+	value T
+	err   error
+}
+
+// NewLazyValue creates a LazyValue initialized by init
+func NewLazyValue[T any](ph *PanicHandler, init func() (T, error)) *LazyValue[T] {
+	return &LazyValue[T]{ph: ph, init: init}
+}
+
+// Get initializes the value on the first call and returns it, or the initialization error
+func (l *LazyValue[T]) Get() (T, error) {
+	l.once.Do(func() {
+		l.ph.call(context.Background(), func() {
+			l.value, l.err = l.init()
+		}, &l.err)
+	})
+	return l.value, l.err
+}
+
+// call calls f and stores a *PanicError in err if it panics, handling the panic with ctx. The error is
+// stored before the panic is handled, so later callers of a sync.Once see it even if a policy re-panics
+func (ph *PanicHandler) call(ctx context.Context, f func(), err *error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := &PanicError{Value: r}
+			*err = panicErr
+			panicErr.ReportID = ph.handlePanic(ctx, r).ID
+		}
+	}()
+	f()
+}
@@ -0,0 +1,105 @@
+package adfer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestOnceFunc(t *testing.T) {
+	reports := make(channelReporter, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(reports))
+
+	calls := 0
+	initialize := OnceFunc(ph, func() {
+		calls++
+		panic("config missing")
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = initialize()
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected f to be called once, got %d", calls)
+	}
+	report := <-reports
+	for _, err := range errs {
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("Expected a *PanicError, got %v", err)
+		}
+		if panicErr.Value != "config missing" || panicErr.ReportID != report.ID {
+			t.Errorf("Unexpected error %+v, report ID %s", panicErr, report.ID)
+		}
+	}
+	if len(reports) != 0 {
+		t.Error("Expected the panic to be reported once")
+	}
+}
+
+func TestOnceFuncSuccess(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	calls := 0
+	initialize := OnceFunc(ph, func() { calls++ })
+	for i := 0; i < 3; i++ {
+		if err := initialize(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected f to be called once, got %d", calls)
+	}
+}
+
+func TestOnceFuncRepanic(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithPolicy(CategoryRuntime, Repanic))
+	initialize := OnceFunc(ph, func() {
+		var m map[string]int
+		m["key"] = 1
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the policy to re-panic")
+			}
+		}()
+		_ = initialize()
+	}()
+
+	if err := initialize(); err == nil {
+		t.Error("Expected later calls to return the panic instead of nil")
+	}
+}
+
+func TestLazyValue(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+
+	value := NewLazyValue(ph, func() (int, error) { return 42, nil })
+	if got, err := value.Get(); got != 42 || err != nil {
+		t.Errorf("Expected 42, got %d (%v)", got, err)
+	}
+
+	initErr := errors.New("connection refused")
+	failing := NewLazyValue(ph, func() (string, error) { return "", initErr })
+	if _, err := failing.Get(); err != initErr {
+		t.Errorf("Expected the initialization error, got %v", err)
+	}
+
+	cause := errors.New("nil pointer")
+	panicking := NewLazyValue(ph, func() (*int, error) { panic(cause) })
+	for i := 0; i < 2; i++ {
+		got, err := panicking.Get()
+		if got != nil || !errors.Is(err, cause) {
+			t.Errorf("Expected the panic to be returned as an error, got %v (%v)", got, err)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package adfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ReportPager is implemented by storages that can count and page through their crash reports
+// without reading all of them. Other storages are paged by reading every report
+type ReportPager interface {
+	// Count returns the number of stored reports
+	Count() (int, error)
+	// Page returns up to limit stored reports, oldest first, after skipping the first offset.
+	// If limit is negative, every report after offset is returned
+	Page(offset, limit int) ([]CrashReport, error)
+}
+
+// CountCrashReports returns the number of stored crash reports
+func (s *reportStore) CountCrashReports() (int, error) {
+	if s.storage == nil {
+		return 0, fmt.Errorf("no file path set for crash reports")
+	}
+	if pager, ok := s.storage.(ReportPager); ok {
+		return pager.Count()
+	}
+	reports, err := s.storage.LastN(-1)
+	return len(reports), err
+}
+
+// GetCrashReports retrieves up to limit crash reports, oldest first, after skipping the first offset,
+// so a dashboard can page through a large crash history. If limit is negative, every report after
+// offset is returned
+func (s *reportStore) GetCrashReports(offset, limit int) ([]CrashReport, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if s.storage == nil {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	if limit == 0 {
+		return nil, nil
+	}
+	if pager, ok := s.storage.(ReportPager); ok {
+		return pager.Page(offset, limit)
+	}
+	reports, err := s.storage.LastN(-1)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(reports, offset, limit), nil
+}
+
+// pageOf returns the page of reports starting at offset
+func pageOf(reports []CrashReport, offset, limit int) []CrashReport {
+	if offset >= len(reports) {
+		return nil
+	}
+	reports = reports[offset:]
+	if limit >= 0 && len(reports) > limit {
+		reports = reports[:limit]
+	}
+	return reports
+}
+
+// pageBounds returns the range of the page starting at offset in a store of count reports
+func pageBounds(count, offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > count {
+		offset = count
+	}
+	end := count
+	if limit >= 0 && offset+limit < count {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// Count returns the number of reports in the crash file. The index is used if it matches
+// the crash file, otherwise the file is scanned without decoding the reports
+func (f *fileStorage) Count() (int, error) {
+	if f.path == "" {
+		return 0, fmt.Errorf("no file path set for crash reports")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock(false)()
+	if index, ok := f.validIndex(); ok {
+		return index.Count, nil
+	}
+	count := 0
+	err := f.scan(func(int, []byte) bool {
+		count++
+		return true
+	})
+	return count, err
+}
+
+// Page returns a page of the crash file's reports. Only the reports of the page are decoded,
+// and only they are read if the index matches the crash file
+func (f *fileStorage) Page(offset, limit int) ([]CrashReport, error) {
+	if f.path == "" {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock(false)()
+	if index, ok := f.validIndex(); ok {
+		start, end := pageBounds(index.Count, offset, limit)
+		if reports, ok := f.readRange(index, start, end); ok && !hasStackDiffs(reports) {
+			return reports, nil
+		}
+	}
+	var reports []CrashReport
+	err := f.scan(func(i int, data []byte) bool {
+		if i < offset {
+			return true
+		}
+		if limit >= 0 && i >= offset+limit {
+			return false
+		}
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			f.diagnose(OpDecode, f.path, err)
+			return true
+		}
+		reports = append(reports, report)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hasStackDiffs(reports) {
+		// Stack diffs are expanded from base reports that may be outside the page
+		all, err := f.readAll()
+		if err != nil {
+			return nil, err
+		}
+		return pageOf(all, offset, limit), nil
+	}
+	return reports, nil
+}
+
+// each calls fn with every report of the crash file, oldest first, until fn returns false. The crash file
+// is copied with it locked and the copy is decoded a report at a time, so fn is called with the crash file
+// unlocked, can use the storage and doesn't hold up crash reports written meanwhile, which aren't seen by fn.
+// If a report's stack is stored as a diff, the rest of the copy is read at once so its stack can be expanded
+func (f *fileStorage) each(fn func(report CrashReport) bool) error {
+	if f.path == "" {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	snapshot, err := f.snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	reader, err := decompressReader(snapshot)
+	if err != nil {
+		return err
+	}
+	decoded, expand := 0, false
+	err = f.scanReader(reader, func(_ int, data []byte) bool {
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			f.diagnose(OpDecode, f.path, err)
+			return true
+		}
+		if report.StackDiff != nil && report.Stack == "" {
+			expand = true
+			return false
+		}
+		decoded++
+		return fn(report)
+	})
+	if err != nil || !expand {
+		return err
+	}
+
+	if _, err := snapshot.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(snapshot)
+	if err == nil {
+		data, err = decompress(data)
+	}
+	if err != nil {
+		return err
+	}
+	var all []CrashReport
+	if f.format == FormatJSONLines {
+		all = f.decodeLines(data)
+	} else if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	expandStacks(all)
+	for _, report := range pageOf(all, decoded, -1) {
+		if !fn(report) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// snapshot copies the crash file with it locked. Crash files on the OS file system are copied to
+// a temporary file, removed once the copy is closed, rather than read into memory
+func (f *fileStorage) snapshot() (io.ReadSeekCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock(false)()
+	if !f.native() {
+		data, err := f.files().ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		return memorySnapshot{bytes.NewReader(data)}, nil
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	tmp, err := os.CreateTemp("", "adfer-snapshot-*")
+	if err != nil {
+		return nil, err
+	}
+	snapshot := tempSnapshot{tmp}
+	if _, err := io.Copy(tmp, file); err != nil {
+		snapshot.Close()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		snapshot.Close()
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// memorySnapshot is the copy of a crash file that isn't on the OS file system
+type memorySnapshot struct {
+	*bytes.Reader
+}
+
+// Close does nothing
+func (memorySnapshot) Close() error {
+	return nil
+}
+
+// tempSnapshot is the copy of a crash file in a temporary file
+type tempSnapshot struct {
+	*os.File
+}
+
+// Close closes and removes the temporary file
+func (t tempSnapshot) Close() error {
+	err := t.File.Close()
+	if removeErr := os.Remove(t.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// scan calls fn with the position and encoding of each report of the crash file until fn returns false,
+// holding a single report in memory at a time
+func (f *fileStorage) scan(fn func(i int, data []byte) bool) error {
+	file, err := openCrashFile(f.files(), f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.scanReader(file, fn)
+}
+
+// scanReader calls fn with the position and encoding of each report read from reader until fn returns
+// false. Lines of a JSON Lines crash file that aren't valid JSON are skipped, as they are by decodeLines
+func (f *fileStorage) scanReader(reader io.Reader, fn func(i int, data []byte) bool) error {
+	if f.format == FormatJSONLines {
+		reader := bufio.NewReader(reader)
+		for i := 0; ; {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 && json.Valid(line) {
+				if !fn(i, line) {
+					return nil
+				}
+				i++
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	decoder := json.NewDecoder(reader)
+	if _, err := decoder.Token(); err == io.EOF {
+		// An empty crash file
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := 0; decoder.More(); i++ {
+		var data json.RawMessage
+		if err := decoder.Decode(&data); err != nil {
+			return err
+		}
+		if !fn(i, data) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// validIndex reads the crash file's index, returning false if it is disabled or doesn't match
+// the size of the crash file
+func (f *fileStorage) validIndex() (*crashIndex, bool) {
+	if !f.index || f.format != FormatJSON {
+		return nil, false
+	}
+	index, err := f.readIndex()
+	if err != nil || index.Count != len(index.Offsets) {
+		return nil, false
+	}
+	info, err := os.Stat(f.path)
+	if err != nil || info.Size() != index.Size {
+		return nil, false
+	}
+	return index, true
+}
+
+// readRange reads the reports [start, end) of the crash file using the index, without decoding
+// the rest of the file. It returns false if the crash file doesn't match the index
+func (f *fileStorage) readRange(index *crashIndex, start, end int) ([]CrashReport, bool) {
+	if start >= end {
+		return nil, true
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	from, to := index.Offsets[start], index.Size
+	if end < index.Count {
+		to = index.Offsets[end]
+	}
+	data, err := io.ReadAll(io.NewSectionReader(file, from, to-from))
+	if err != nil {
+		return nil, false
+	}
+	// Reports are separated by ",\n  " and the last one is followed by "\n]"
+	data = bytes.TrimRight(data, " \n]")
+	data = bytes.TrimSuffix(data, []byte(","))
+	var reports []CrashReport
+	if err := json.Unmarshal(append(append([]byte("["), data...), ']'), &reports); err != nil || len(reports) != end-start {
+		return nil, false
+	}
+	return reports, true
+}
+
+// Count returns the number of crash files in the crash directory
+func (d *dirStorage) Count() (int, error) {
+	files, err := d.files()
+	return len(files), err
+}
+
+// Page returns a page of the crash directory's reports, reading only the files of the page.
+// Files that can't be read or decoded are skipped and reported as diagnostics
+func (d *dirStorage) Page(offset, limit int) ([]CrashReport, error) {
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	start, end := pageBounds(len(files), offset, limit)
+	reports := make([]CrashReport, 0, end-start)
+	for _, file := range files[start:end] {
+		path := filepath.Join(d.dir, file.name)
+		report, err := readCrashFile(path)
+		if os.IsNotExist(err) {
+			// Wiped by another process
+			continue
+		}
+		if err != nil {
+			d.diagnose(OpDecode, path, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Count returns the number of stored crash reports
+func (m *MemoryStore) Count() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.count, nil
+}
+
+// Page returns a page of the stored crash reports, oldest first
+func (m *MemoryStore) Page(offset, limit int) ([]CrashReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	start, end := pageBounds(m.count, offset, limit)
+	reports := make([]CrashReport, 0, end-start)
+	for i := start; i < end; i++ {
+		reports = append(reports, m.reports[(m.start+i)%len(m.reports)])
+	}
+	return reports, nil
+}
@@ -0,0 +1,108 @@
+package adfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGetCrashReports(t *testing.T) {
+	for name, opts := range map[string]func(t *testing.T) []Option{
+		"json":       func(*testing.T) []Option { return nil },
+		"index":      func(*testing.T) []Option { return []Option{func(o *Options) { o.Index = true }} },
+		"stackdiffs": func(*testing.T) []Option { return []Option{WithStackDiffs()} },
+		"jsonl":      func(*testing.T) []Option { return []Option{WithFileFormat(FormatJSONLines)} },
+		"compressed": func(*testing.T) []Option { return []Option{WithFileFormat(FormatJSONLines), WithCompression()} },
+		"dir":        func(t *testing.T) []Option { return []Option{WithCrashDir(filepath.Join(t.TempDir(), "crashes"))} },
+		"memory":     func(*testing.T) []Option { return []Option{WithInMemoryStore(10)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			ph := New(Options{
+				ErrorHandler: func(error, []byte) {},
+				OnDiagnostic: func(Diagnostic) {},
+				DumpToFile:   true,
+				FilePath:     filepath.Join(t.TempDir(), "crash"),
+			}, opts(t)...)
+			for i := 0; i < 5; i++ {
+				func() {
+					defer ph.Recover()
+					panic(i)
+				}()
+			}
+
+			if count, err := ph.CountCrashReports(); err != nil || count != 5 {
+				t.Errorf("Expected 5 reports, got %d, error %v", count, err)
+			}
+			page, err := ph.GetCrashReports(1, 2)
+			if err != nil || len(page) != 2 || page[0].Error != "1" || page[1].Error != "2" {
+				t.Fatalf("Expected reports 1 and 2, got %+v, error %v", page, err)
+			}
+			for _, report := range page {
+				if report.Stack == "" {
+					t.Errorf("Expected the stack of report %s to be expanded", report.Error)
+				}
+			}
+			rest, err := ph.GetCrashReports(3, -1)
+			if err != nil || len(rest) != 2 || rest[0].Error != "3" || rest[1].Error != "4" {
+				t.Errorf("Expected reports 3 and 4, got %+v, error %v", rest, err)
+			}
+			if last, _ := ph.GetCrashReports(4, 10); len(last) != 1 || last[0].Error != "4" {
+				t.Errorf("Expected report 4, got %+v", last)
+			}
+			if empty, err := ph.GetCrashReports(5, 10); err != nil || len(empty) != 0 {
+				t.Errorf("Expected no reports past the end, got %+v, error %v", empty, err)
+			}
+		})
+	}
+}
+
+func TestGetCrashReportsTornLine(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	writeCrashFile(t, filePath, FormatJSONLines, CrashReport{ID: "a"}, CrashReport{ID: "b"})
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file.WriteString(`{"id":"torn`)
+	file.Close()
+
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count, err := reader.CountCrashReports(); err != nil || count != 2 {
+		t.Errorf("Expected the torn line not to be counted, got %d, error %v", count, err)
+	}
+	if page, _ := reader.GetCrashReports(1, 5); len(page) != 1 || page[0].ID != "b" {
+		t.Errorf("Expected report b, got %+v", page)
+	}
+}
+
+func TestGetCrashReportsIndex(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	storage := newFileStorage(Options{FilePath: filePath, Index: true}, func(string, string, error) {})
+	for i := 0; i < 3; i++ {
+		storage.Append(CrashReport{ID: strconv.Itoa(i)})
+	}
+	if page, err := storage.Page(1, 1); err != nil || len(page) != 1 || page[0].ID != "1" {
+		t.Fatalf("Expected report 1, got %+v, error %v", page, err)
+	}
+
+	// The index is ignored once the crash file no longer matches it
+	reports := make([]CrashReport, 4)
+	for i := range reports {
+		reports[i] = CrashReport{ID: strconv.Itoa(i + 10)}
+	}
+	data, _ := json.MarshalIndent(reports, "", "  ")
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count, _ := storage.Count(); count != 4 {
+		t.Errorf("Expected 4 reports, got %d", count)
+	}
+	if page, _ := storage.Page(2, 2); len(page) != 2 || page[0].ID != "12" || page[1].ID != "13" {
+		t.Errorf("Expected reports 12 and 13, got %+v", page)
+	}
+}
@@ -0,0 +1,79 @@
+package adfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CompressionGzip compresses request bodies with gzip, sent with "Content-Encoding: gzip"
+const CompressionGzip = "gzip"
+
+// ErrPayloadTooLarge is returned when a crash report doesn't fit the payload size cap, even
+// with its stack removed
+var ErrPayloadTooLarge = errors.New("crash report exceeds the maximum payload size")
+
+// stackTruncated marks a stack that was cut to fit the payload size cap
+const stackTruncated = "\n...stack truncated"
+
+// gzipBody compresses a request body
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(body)
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkCompression returns an error for compression schemes that aren't supported
+func checkCompression(compression string) error {
+	if compression != "" && compression != CompressionGzip {
+		return fmt.Errorf("unsupported compression %q", compression)
+	}
+	return nil
+}
+
+// fitPayload encodes report, truncating its stack if the encoded payload is larger than max bytes.
+// Only the first goroutine of a stack holding several is kept, then the stack is cut to the longest
+// prefix that fits. It returns ErrPayloadTooLarge if the payload doesn't fit without a stack
+func fitPayload(report CrashReport, max int, encode func(CrashReport) ([]byte, error)) ([]byte, error) {
+	body, err := encode(report)
+	if err != nil || max <= 0 || len(body) <= max {
+		return body, err
+	}
+	stack := report.Stack
+	if i := strings.Index(stack, "\n\ngoroutine "); i >= 0 {
+		stack = stack[:i]
+		report.Stack = stack + stackTruncated
+		if body, err = encode(report); err != nil || len(body) <= max {
+			return body, err
+		}
+	}
+	// Binary search for the longest prefix of the stack that fits, as the bytes saved by cutting
+	// the stack depend on escaping and compression
+	var fitted []byte
+	low, high := 0, len(stack)
+	for low <= high {
+		keep := (low + high) / 2
+		report.Stack = ""
+		if keep > 0 {
+			report.Stack = stack[:keep] + stackTruncated
+		}
+		if body, err = encode(report); err != nil {
+			return nil, err
+		}
+		if len(body) <= max {
+			fitted, low = body, keep+1
+		} else {
+			high = keep - 1
+		}
+	}
+	if fitted != nil {
+		return fitted, nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrPayloadTooLarge, len(body), max)
+}
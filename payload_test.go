@@ -0,0 +1,48 @@
+package adfer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFitPayload(t *testing.T) {
+	stack := "goroutine 1 [running]:\nmain.main()\n\nmain.go:10\n\ngoroutine 2 [select]:\n" + strings.Repeat("main.worker()\n", 100)
+	report := CrashReport{Error: "boom", Stack: stack}
+	encodePlain := func(report CrashReport) ([]byte, error) {
+		return json.Marshal(report)
+	}
+	encodeGzip := func(report CrashReport) ([]byte, error) {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		return gzipBody(data)
+	}
+
+	if body, err := fitPayload(report, 0, encodePlain); err != nil || !strings.Contains(string(body), "goroutine 2") {
+		t.Errorf("Expected the report to be sent whole without a cap, got %s, error %v", body, err)
+	}
+	body, err := fitPayload(report, 200, encodePlain)
+	var fitted CrashReport
+	if err != nil || len(body) > 200 || json.Unmarshal(body, &fitted) != nil {
+		t.Fatalf("Expected a payload of at most 200 bytes, got %d bytes, error %v", len(body), err)
+	}
+	if fitted.Stack != "goroutine 1 [running]:\nmain.main()\n\nmain.go:10"+stackTruncated {
+		t.Errorf("Expected only the first goroutine to be kept, got %q", fitted.Stack)
+	}
+
+	report.Stack = strings.Repeat("x", 10000)
+	for name, encode := range map[string]func(CrashReport) ([]byte, error){"plain": encodePlain, "gzip": encodeGzip} {
+		body, err := fitPayload(report, 300, encode)
+		if err != nil || len(body) > 300 {
+			t.Errorf("%s: expected a payload of at most 300 bytes, got %d bytes, error %v", name, len(body), err)
+		}
+	}
+
+	report.Error = strings.Repeat("e", 300)
+	if _, err := fitPayload(report, 300, encodePlain); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge, got %v", err)
+	}
+}
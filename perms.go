@@ -0,0 +1,136 @@
+package adfer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Default permissions of the files and directories written by adfer
+const (
+	defaultFileMode os.FileMode = 0644
+	defaultDirMode  os.FileMode = 0755
+)
+
+// FileOwner is the owner of the files and directories written by adfer
+type FileOwner struct {
+	UID int
+	GID int
+}
+
+// WithFileMode sets the permissions of the files written by adfer: the crash file and its index,
+// spooled reports, execution traces, snapshots and the consent file. Use 0600 if reports may
+// contain sensitive data. Defaults to 0644
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *Options) {
+		o.FileMode = mode
+	}
+}
+
+// WithDirMode sets the permissions of the directories created by adfer. Defaults to 0755
+func WithDirMode(mode os.FileMode) Option {
+	return func(o *Options) {
+		o.DirMode = mode
+	}
+}
+
+// WithFileOwner sets the owner of the files and directories written by adfer, e.g. when running as
+// root on behalf of a service account. It is ignored on Windows
+func WithFileOwner(uid, gid int) Option {
+	return func(o *Options) {
+		o.FileOwner = &FileOwner{UID: uid, GID: gid}
+	}
+}
+
+// filePerms are the permissions and owner applied to the files and directories written by adfer.
+// Modes are set explicitly after creating a file or directory, so they aren't reduced by the
+// umask and also apply to existing files
+type filePerms struct {
+	file  os.FileMode
+	dir   os.FileMode
+	owner *FileOwner
+}
+
+// permsFromOptions returns the configured permissions
+func permsFromOptions(options Options) filePerms {
+	perms := filePerms{file: options.FileMode, dir: options.DirMode, owner: options.FileOwner}
+	if perms.file == 0 {
+		perms.file = defaultFileMode
+	}
+	if perms.dir == 0 {
+		perms.dir = defaultDirMode
+	}
+	return perms
+}
+
+// create creates or truncates a file with the configured mode and owner
+func (p filePerms) create(path string) (*os.File, error) {
+	return p.open(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+}
+
+// open opens a file with the given flags, setting the configured mode and owner
+func (p filePerms) open(path string, flag int) (*os.File, error) {
+	file, err := os.OpenFile(path, flag, p.file)
+	if err != nil {
+		return nil, err
+	}
+	err = file.Chmod(p.file)
+	if err == nil && p.owner != nil && runtime.GOOS != "windows" {
+		err = file.Chown(p.owner.UID, p.owner.GID)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeFile writes data to a file with the configured mode and owner
+func (p filePerms) writeFile(path string, data []byte) error {
+	file, err := p.create(path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// mkdirAll creates a directory and its missing parents with the configured mode and owner.
+// Existing directories are left unchanged
+func (p filePerms) mkdirAll(dir string) error {
+	var created []string
+	for path := filepath.Clean(dir); ; path = filepath.Dir(path) {
+		if _, err := os.Stat(path); err == nil || !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		created = append(created, path)
+		if filepath.Dir(path) == path {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, p.dir); err != nil {
+		return err
+	}
+	for _, path := range created {
+		if err := p.apply(path, p.dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply sets the mode and owner of an existing path
+func (p filePerms) apply(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if p.owner != nil && runtime.GOOS != "windows" {
+		return os.Chown(path, p.owner.UID, p.owner.GID)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package adfer
+
+import "testing"
+
+func TestPermsFromOptions(t *testing.T) {
+	perms := permsFromOptions(Options{})
+	if perms.file != 0644 || perms.dir != 0755 || perms.owner != nil {
+		t.Errorf("Unexpected default permissions %+v", perms)
+	}
+	var options Options
+	WithFileOwner(1000, 1000)(&options)
+	if perms := permsFromOptions(options); perms.owner == nil || perms.owner.UID != 1000 {
+		t.Errorf("Expected the owner to be set, got %+v", perms.owner)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	// The mode is applied regardless of the umask
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "crash.json")
+	os.WriteFile(filePath, []byte("[]"), 0666)
+	os.Chmod(filePath, 0666)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+		Index:        true,
+	}, WithFileMode(0640), WithDirMode(0750), WithWipeSnapshots(filepath.Join(dir, "snapshots", "crash")))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for path, mode := range map[string]os.FileMode{
+		filePath:                                 0640,
+		filePath + ".idx":                        0640,
+		filepath.Join(dir, "snapshots"):          0750,
+		filepath.Join(dir, "snapshots", "crash"): 0750,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to have mode %o, got %o", path, mode, info.Mode().Perm())
+		}
+	}
+	// Existing directories are left unchanged
+	if info, _ := os.Stat(dir); info.Mode().Perm() == 0750 {
+		t.Error("Expected the existing directory to keep its mode")
+	}
+}
@@ -0,0 +1,153 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxItemDump limits the size of the in-flight item captured when a stage fails
+const maxItemDump = 1024
+
+// Stage is a stage of a Pipeline
+type Stage struct {
+	// Name identifies the stage in errors and crash reports
+	Name string
+	// Workers is the number of goroutines processing items concurrently. Defaults to 1
+	Workers int
+	// Process transforms an item. The result is sent to the next stage. Returning an error
+	// stops the pipeline
+	Process func(ctx context.Context, item any) (any, error)
+}
+
+// StageError is returned by a Pipeline when a stage panicked or returned an error
+type StageError struct {
+	// Stage is the name of the stage
+	Stage string
+	// Item is a dump of the item being processed, truncated to 1KB
+	Item string
+	// Err is the error returned by the stage, or a *PanicError if it panicked
+	Err error
+}
+
+// Error returns the stage name and its error
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the error of the stage
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline runs stages connected by channels. A panic in any stage is recovered and reported
+// with the stage name and in-flight item, and stops the pipeline with a *StageError
+type Pipeline struct {
+	ph     *PanicHandler
+	stages []Stage
+}
+
+// NewPipeline creates a Pipeline that runs items through stages in order
+func NewPipeline(ph *PanicHandler, stages ...Stage) *Pipeline {
+	return &Pipeline{ph: ph, stages: stages}
+}
+
+// Run starts the stages, reading items from in. Results of the last stage are sent on the
+// returned channel, which is closed once in is closed and drained, or a stage fails. The
+// returned function waits for all stages to stop and returns the first *StageError, or the
+// context's error if it was cancelled. Producers should stop sending to in once ctx is done
+func (p *Pipeline) Run(parent context.Context, in <-chan any) (<-chan any, func() error) {
+	ctx, cancel := context.WithCancel(parent)
+	var once sync.Once
+	var failure error
+	fail := func(err error) {
+		once.Do(func() {
+			failure = err
+			cancel()
+		})
+	}
+
+	var stopped sync.WaitGroup
+	current := in
+	for _, stage := range p.stages {
+		out := make(chan any)
+		workers := stage.Workers
+		if workers <= 0 {
+			workers = 1
+		}
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go func(stage Stage, in <-chan any) {
+				defer wg.Done()
+				p.work(ctx, stage, in, out, fail)
+			}(stage, current)
+		}
+		// Closing the output once all workers have stopped shuts down the next stage
+		stopped.Add(1)
+		go func(wg *sync.WaitGroup, out chan any) {
+			wg.Wait()
+			close(out)
+			stopped.Done()
+		}(&wg, out)
+		current = out
+	}
+
+	return current, func() error {
+		stopped.Wait()
+		cancel()
+		if failure != nil {
+			return failure
+		}
+		return parent.Err()
+	}
+}
+
+// work processes items from in until it is closed, ctx is done or an item fails
+func (p *Pipeline) work(ctx context.Context, stage Stage, in <-chan any, out chan<- any, fail func(error)) {
+	for {
+		var item any
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case item, ok = <-in:
+			if !ok {
+				return
+			}
+		}
+		result, err := p.process(ctx, stage, item)
+		if err != nil {
+			fail(err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case out <- result:
+		}
+	}
+}
+
+// process runs a stage on an item, converting a panic into a *StageError
+func (p *Pipeline) process(ctx context.Context, stage Stage, item any) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			dump := dumpItem(item)
+			panicErr := &PanicError{Value: r}
+			err = &StageError{Stage: stage.Name, Item: dump, Err: panicErr}
+			report := p.ph.handlePanicWith(ctx, r, map[string]string{"stage": stage.Name, "item": dump})
+			panicErr.ReportID = report.ID
+		}
+	}()
+	result, err = stage.Process(ctx, item)
+	if err != nil {
+		err = &StageError{Stage: stage.Name, Item: dumpItem(item), Err: err}
+	}
+	return result, err
+}
+
+// dumpItem formats an item for a crash report, truncated to maxItemDump
+func dumpItem(item any) string {
+	return truncate(fmt.Sprintf("%+v", item), maxItemDump)
+}
@@ -0,0 +1,122 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// feed returns a channel that sends items and is then closed
+func feed(items ...any) <-chan any {
+	in := make(chan any, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+	return in
+}
+
+func TestPipeline(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	pipeline := NewPipeline(ph,
+		Stage{Name: "double", Workers: 3, Process: func(ctx context.Context, item any) (any, error) {
+			return item.(int) * 2, nil
+		}},
+		Stage{Name: "increment", Process: func(ctx context.Context, item any) (any, error) {
+			return item.(int) + 1, nil
+		}},
+	)
+
+	out, wait := pipeline.Run(context.Background(), feed(1, 2, 3, 4))
+	sum := 0
+	for result := range out {
+		sum += result.(int)
+	}
+	if err := wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sum != 24 {
+		t.Errorf("Expected a sum of 24, got %d", sum)
+	}
+}
+
+func TestPipelinePanic(t *testing.T) {
+	reports := make(channelReporter, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(reports))
+	pipeline := NewPipeline(ph,
+		Stage{Name: "parse", Process: func(ctx context.Context, item any) (any, error) {
+			if item == "bad" {
+				panic("unexpected token")
+			}
+			return item, nil
+		}},
+		Stage{Name: "store", Process: func(ctx context.Context, item any) (any, error) {
+			return item, nil
+		}},
+	)
+
+	out, wait := pipeline.Run(context.Background(), feed("a", "bad", "b", "c"))
+	for range out {
+	}
+	err := wait()
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) {
+		t.Fatalf("Expected a *StageError, got %v", err)
+	}
+	if stageErr.Stage != "parse" || stageErr.Item != "bad" {
+		t.Errorf("Unexpected error %+v", stageErr)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected the error to wrap a *PanicError, got %v", err)
+	}
+	report := <-reports
+	if panicErr.ReportID != report.ID {
+		t.Errorf("Expected report ID %s, got %s", report.ID, panicErr.ReportID)
+	}
+	if report.Metadata["stage"] != "parse" || report.Metadata["item"] != "bad" {
+		t.Errorf("Unexpected metadata %v", report.Metadata)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	errInvalid := errors.New("invalid")
+	pipeline := NewPipeline(ph, Stage{Name: "validate", Process: func(ctx context.Context, item any) (any, error) {
+		return nil, errInvalid
+	}})
+
+	out, wait := pipeline.Run(context.Background(), feed(strings.Repeat("x", 2*maxItemDump)))
+	for range out {
+	}
+	err := wait()
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("Expected the stage error, got %v", err)
+	}
+	var stageErr *StageError
+	errors.As(err, &stageErr)
+	if len(stageErr.Item) != maxItemDump {
+		t.Errorf("Expected the item dump to be truncated to %d bytes, got %d", maxItemDump, len(stageErr.Item))
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	pipeline := NewPipeline(ph, Stage{Name: "identity", Process: func(ctx context.Context, item any) (any, error) {
+		return item, nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan any)
+	out, wait := pipeline.Run(ctx, in)
+	in <- 1
+	<-out
+	cancel()
+	for range out {
+	}
+	if err := wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderValues returns the values available to "{name}" placeholders for a report
+func placeholderValues(report CrashReport, app string) map[string]string {
+	if app == "" {
+		app = filepath.Base(os.Args[0])
+	}
+	timestamp := report.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	id := report.ID
+	if id == "" {
+		id = newReportID()
+	}
+	return map[string]string{
+		"app":         app,
+		"date":        timestamp.UTC().Format("2006-01-02"),
+		"time":        timestamp.UTC().Format("15-04-05"),
+		"timestamp":   timestamp.UTC().Format("2006-01-02T15-04-05"),
+		"uuid":        id,
+		"id":          id,
+		"hostname":    reportHost(report),
+		"pid":         strconv.Itoa(os.Getpid()),
+		"fingerprint": Fingerprint(report),
+	}
+}
+
+// expandPlaceholders replaces "{name}" placeholders in s. Unknown placeholders are left untouched
+func expandPlaceholders(s string, values map[string]string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		sb.WriteString(s[:start])
+		if value, ok := values[s[start+1:end]]; ok {
+			sb.WriteString(value)
+		} else {
+			sb.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// expandFilePath replaces the "{app}", "{pid}", "{hostname}", "{date}", "{time}" and "{timestamp}"
+// placeholders in a crash file or directory path, so concurrent processes and runs get distinct files
+func expandFilePath(path string, now time.Time) string {
+	if !strings.Contains(path, "{") {
+		return path
+	}
+	return expandPlaceholders(path, map[string]string{
+		"app":       strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe"),
+		"pid":       strconv.Itoa(os.Getpid()),
+		"hostname":  reportHost(CrashReport{}),
+		"date":      now.UTC().Format("2006-01-02"),
+		"time":      now.UTC().Format("15-04-05"),
+		"timestamp": now.UTC().Format("2006-01-02T15-04-05"),
+	})
+}
@@ -0,0 +1,31 @@
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilePathPlaceholders(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	hostname, _ := os.Hostname()
+	got := expandFilePath(filepath.Join("crashes", "{app}-{hostname}-{pid}-{date}-{time}-{unknown}.json"), now)
+	want := filepath.Join("crashes", strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")+"-"+hostname+"-"+strconv.Itoa(os.Getpid())+"-2024-06-01-12-30-00-{unknown}.json")
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	dir := t.TempDir()
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(dir, "crash-{pid}.json"),
+	})
+	ph.Report(os.ErrClosed)
+	if _, err := os.Stat(filepath.Join(dir, "crash-"+strconv.Itoa(os.Getpid())+".json")); err != nil {
+		t.Errorf("Expected the crash file to be named after the process: %v", err)
+	}
+}
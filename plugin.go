@@ -0,0 +1,158 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// pluginReportPrefix marks the lines of a plugin's output that carry a forwarded crash report
+const pluginReportPrefix = "adfer-report: "
+
+// maxPluginLine is the longest line of plugin output buffered while waiting for its newline
+const maxPluginLine = 1 << 20
+
+// Plugin identifies a plugin in the crash reports of its panics, with the "plugin" and
+// "plugin_version" metadata entries
+type Plugin struct {
+	Name    string
+	Version string
+}
+
+// metadata returns the metadata entries added to the crash reports of the plugin
+func (p Plugin) metadata() map[string]string {
+	metadata := map[string]string{"plugin": p.Name}
+	if p.Version != "" {
+		metadata["plugin_version"] = p.Version
+	}
+	return metadata
+}
+
+// CallPlugin calls f, which dispatches a call into a plugin, with panic recovery. A panic, e.g. in a
+// plugin loaded with the plugin package or in an RPC client decoding the plugin's reply, is reported
+// with the plugin's name and version and returned as a *PanicError
+func (ph *PanicHandler) CallPlugin(plugin Plugin, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := &PanicError{Value: r}
+			err = panicErr
+			panicErr.ReportID = ph.handlePanicWith(context.Background(), r, plugin.metadata()).ID
+		}
+	}()
+	return f()
+}
+
+// PluginOutput returns a writer for the output of a plugin process, e.g. the Stderr of a go-plugin
+// ClientConfig or an exec.Cmd. Crash reports forwarded by a PluginForwarder in the plugin are handled
+// by ph with the plugin's name and version added, so they keep their ID and are stored and delivered
+// by the host. Other output is written to w, a line at a time. w may be nil to discard it
+func (ph *PanicHandler) PluginOutput(plugin Plugin, w io.Writer) io.Writer {
+	if w == nil {
+		w = io.Discard
+	}
+	return &pluginOutput{ph: ph, plugin: plugin, w: w}
+}
+
+// pluginOutput extracts the crash reports forwarded in the output of a plugin
+type pluginOutput struct {
+	ph     *PanicHandler
+	plugin Plugin
+	w      io.Writer
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write handles the complete lines written so far
+func (o *pluginOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := o.buf[:i+1]
+		o.buf = o.buf[i+1:]
+		if err := o.line(line); err != nil {
+			return len(p), err
+		}
+	}
+	if len(o.buf) > maxPluginLine {
+		// Too long to be a crash report
+		_, err := o.w.Write(o.buf)
+		o.buf = nil
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// line handles a line of output, including its newline
+func (o *pluginOutput) line(line []byte) error {
+	data, ok := bytes.CutPrefix(line, []byte(pluginReportPrefix))
+	if !ok {
+		_, err := o.w.Write(line)
+		return err
+	}
+	var report CrashReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		o.ph.diagnose(OpCollect, o.plugin.Name, err)
+		_, err := o.w.Write(line)
+		return err
+	}
+	metadata := o.plugin.metadata()
+	for key := range metadata {
+		if _, ok := report.Metadata[key]; ok {
+			delete(metadata, key)
+		}
+	}
+	addMetadata(&report, metadata)
+	o.ph.process(context.Background(), errors.New(report.Error), report)
+	return nil
+}
+
+// PluginForwarder is a Reporter for plugin processes that writes crash reports to the plugin's
+// output, where the host extracts them with PanicHandler.PluginOutput
+type PluginForwarder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewPluginForwarder creates a PluginForwarder writing to w. Defaults to os.Stderr, which
+// go-plugin passes to the host
+func NewPluginForwarder(w io.Writer) *PluginForwarder {
+	if w == nil {
+		w = os.Stderr
+	}
+	return &PluginForwarder{w: w}
+}
+
+// WithPluginForwarding forwards every crash report of a plugin process to its host through stderr
+func WithPluginForwarding() Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewPluginForwarder(nil))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (f *PluginForwarder) Name() string {
+	return "plugin"
+}
+
+// Report writes the crash report as a single line
+func (f *PluginForwarder) Report(_ context.Context, report CrashReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	line := append([]byte(pluginReportPrefix), data...)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.w.Write(append(line, '\n'))
+	return err
+}
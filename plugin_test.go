@@ -0,0 +1,77 @@
+package adfer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCallPlugin(t *testing.T) {
+	storage := NewMemoryStore(10)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(storage))
+	plugin := Plugin{Name: "resizer", Version: "1.2.0"}
+
+	if err := ph.CallPlugin(plugin, func() error { return errors.New("bad input") }); err == nil || err.Error() != "bad input" {
+		t.Errorf("Expected the plugin's error to be returned, got %v", err)
+	}
+	err := ph.CallPlugin(plugin, func() error {
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.ReportID == "" {
+		t.Fatalf("Expected a *PanicError, got %v", err)
+	}
+	reports, _ := storage.LastN(-1)
+	if len(reports) != 1 || reports[0].Metadata["plugin"] != "resizer" || reports[0].Metadata["plugin_version"] != "1.2.0" {
+		t.Errorf("Expected a report tagged with the plugin, got %+v", reports)
+	}
+}
+
+func TestPluginForwarding(t *testing.T) {
+	// The plugin process forwards its reports through its output
+	var output bytes.Buffer
+	output.WriteString("plugin starting\n")
+	plugin := New(Options{ErrorHandler: func(error, []byte) {}}, WithReporter(NewPluginForwarder(&output)))
+	func() {
+		defer plugin.Recover()
+		panic("plugin crashed")
+	}()
+	output.WriteString("plugin stopping\n")
+	output.WriteString(pluginReportPrefix + "{not json\n")
+
+	// The host reads the plugin's output in arbitrary chunks
+	storage := NewMemoryStore(10)
+	var diagnostics []Diagnostic
+	host := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithStorage(storage))
+	var logs bytes.Buffer
+	w := host.PluginOutput(Plugin{Name: "resizer", Version: "1.2.0"}, &logs)
+	data := output.Bytes()
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+		w.Write(data[:n])
+		data = data[n:]
+	}
+
+	reports, _ := storage.LastN(-1)
+	if len(reports) != 1 || reports[0].Error != "plugin crashed" || reports[0].Metadata["plugin"] != "resizer" {
+		t.Fatalf("Expected the forwarded report to be stored, got %+v", reports)
+	}
+	if !strings.Contains(reports[0].Stack, "TestPluginForwarding") {
+		t.Errorf("Expected the plugin's stack to be kept")
+	}
+	if logs.String() != "plugin starting\nplugin stopping\n"+pluginReportPrefix+"{not json\n" {
+		t.Errorf("Expected other output to be passed through, got %q", logs.String())
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpCollect {
+		t.Errorf("Expected a diagnostic for the invalid report, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,111 @@
+package adfer
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Category classifies a recovered panic value
+type Category int
+
+const (
+	// CategoryRuntime is a runtime.Error, e.g. a nil pointer dereference or an
+	// index out of range. Program state may be corrupted
+	CategoryRuntime Category = iota
+	// CategoryError is a panic with any other error value
+	CategoryError
+	// CategoryValue is a panic with a non-error value, e.g. panic("oh no")
+	CategoryValue
+)
+
+// String returns the name of the category
+func (c Category) String() string {
+	switch c {
+	case CategoryRuntime:
+		return "runtime"
+	case CategoryError:
+		return "error"
+	case CategoryValue:
+		return "value"
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// Action is what happens after a panic has been handled and reported
+type Action int
+
+const (
+	// Absorb continues execution after the deferred Recover
+	Absorb Action = iota
+	// Repanic re-raises the original panic value
+	Repanic
+	// Exit exits the program
+	Exit
+)
+
+// String returns the name of the action
+func (a Action) String() string {
+	switch a {
+	case Absorb:
+		return "absorb"
+	case Repanic:
+		return "repanic"
+	case Exit:
+		return "exit"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// WithPolicy sets the action taken after handling a panic of the given category,
+// overriding ExitOnPanic for that category
+func WithPolicy(category Category, action Action) Option {
+	return func(o *Options) {
+		if o.Policies == nil {
+			o.Policies = make(map[Category]Action)
+		}
+		o.Policies[category] = action
+	}
+}
+
+// WithRepanic re-raises the original panic value once a panic has been handled, for categories
+// without a policy, so libraries can report panics without swallowing those their callers expect to
+// recover. It takes precedence over ExitOnPanic
+func WithRepanic() Option {
+	return func(o *Options) {
+		o.Repanic = true
+	}
+}
+
+// WithExitFunc sets the function called to exit the program after a panic, instead of os.Exit,
+// e.g. to trigger a supervisor's shutdown or to intercept the exit in tests. Pending deliveries
+// are flushed before it is called
+func WithExitFunc(exit func(code int)) Option {
+	return func(o *Options) {
+		o.ExitFunc = exit
+	}
+}
+
+// categorize returns the category of a recovered panic value
+func categorize(r any) Category {
+	switch r.(type) {
+	case runtime.Error:
+		return CategoryRuntime
+	case error:
+		return CategoryError
+	}
+	return CategoryValue
+}
+
+// action returns the action configured for the given category
+func (ph *PanicHandler) action(category Category) Action {
+	if action, ok := ph.options.Policies[category]; ok {
+		return action
+	}
+	if ph.options.Repanic {
+		return Repanic
+	}
+	if ph.options.ExitOnPanic {
+		return Exit
+	}
+	return Absorb
+}
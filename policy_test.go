@@ -0,0 +1,116 @@
+package adfer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	var runtimeErr error
+	func() {
+		defer func() {
+			runtimeErr = recover().(error)
+		}()
+		var m map[string]int
+		m["x"] = 1
+	}()
+
+	tests := []struct {
+		value    any
+		expected Category
+	}{
+		{value: runtimeErr, expected: CategoryRuntime},
+		{value: errors.New("boom"), expected: CategoryError},
+		{value: "boom", expected: CategoryValue},
+		{value: 42, expected: CategoryValue},
+	}
+	for _, test := range tests {
+		if got := categorize(test.value); got != test.expected {
+			t.Errorf("Expected %v to be categorized as %s, got %s", test.value, test.expected, got)
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	exitCode := -1
+	reports := make(chan CrashReport, 3)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		ExitOnPanic:  true,
+		Reporters:    []Reporter{channelReporter(reports)},
+	},
+		WithPolicy(CategoryRuntime, Repanic),
+		WithPolicy(CategoryValue, Absorb),
+		WithExitFunc(func(code int) { exitCode = code }),
+	)
+
+	t.Run("Repanic", func(t *testing.T) {
+		var repanicked any
+		func() {
+			defer func() {
+				repanicked = recover()
+			}()
+			defer ph.Recover()
+			var p *struct{ x int }
+			_ = p.x
+		}()
+		if _, ok := repanicked.(error); !ok {
+			t.Fatalf("Expected original runtime error to be re-raised, got %v", repanicked)
+		}
+		report := <-reports
+		if report.Category != "runtime" {
+			t.Errorf("Expected runtime category, got '%s'", report.Category)
+		}
+	})
+
+	t.Run("Absorb", func(t *testing.T) {
+		func() {
+			defer ph.Recover()
+			panic("absorbed")
+		}()
+		<-reports
+		if exitCode != -1 {
+			t.Error("Expected value panics to be absorbed")
+		}
+	})
+
+	t.Run("Fallback to ExitOnPanic", func(t *testing.T) {
+		func() {
+			defer ph.Recover()
+			panic(errors.New("exit"))
+		}()
+		<-reports
+		if exitCode != 1 {
+			t.Errorf("Expected exit for categories without a policy, got %d", exitCode)
+		}
+	})
+}
+
+func TestWithRepanic(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	exited := false
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		ExitOnPanic:  true,
+		Reporters:    []Reporter{channelReporter(reports)},
+	}, WithRepanic(), WithExitFunc(func(int) { exited = true }))
+
+	original := errors.New("caller handles this")
+	var repanicked any
+	func() {
+		defer func() {
+			repanicked = recover()
+		}()
+		defer ph.Recover()
+		panic(original)
+	}()
+	if repanicked != original {
+		t.Errorf("Expected the original value to be re-raised, got %v", repanicked)
+	}
+	if report := <-reports; report.Error != "caller handles this" {
+		t.Errorf("Expected the panic to be reported first, got %+v", report)
+	}
+	if exited {
+		t.Error("Expected Repanic to take precedence over ExitOnPanic")
+	}
+}
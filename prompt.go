@@ -0,0 +1,155 @@
+package adfer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PromptOptions configures the interactive consent prompt
+type PromptOptions struct {
+	// Input is where the answer is read from. Defaults to os.Stdin
+	Input io.Reader
+	// Output is where the summary and prompt are written to. Defaults to os.Stderr
+	Output io.Writer
+	// Timeout is how long to wait for an answer. Defaults to 30 seconds
+	Timeout time.Duration
+	// Submit is the answer used when the user just presses enter, the prompt times out
+	// or Input is not a terminal. Defaults to false, so nothing is sent without consent
+	Submit bool
+	// PromptHandled also asks before sending handled errors, submitted with PanicHandler.Report.
+	// By default only crashes are prompted for, and handled errors are sent according to Submit
+	PromptHandled bool
+}
+
+// WithConsentPrompt asks the user on the terminal before a crash report is sent to
+// any reporter. The error handler and crash file are not affected. Intended for CLI tools
+func WithConsentPrompt(options PromptOptions) Option {
+	return func(o *Options) {
+		o.Prompt = &options
+	}
+}
+
+// prompter asks the user for consent to submit crash reports
+type prompter struct {
+	options  PromptOptions
+	messages Messages
+
+	// mu serialises prompts from concurrent panics
+	mu    sync.Mutex
+	once  sync.Once
+	lines chan answer
+}
+
+// answer is a line read from the input, with the time it was read
+type answer struct {
+	line string
+	at   time.Time
+}
+
+// newPrompter creates a prompter, applying defaults
+func newPrompter(options PromptOptions, messages Messages) *prompter {
+	if options.Input == nil {
+		options.Input = os.Stdin
+	}
+	if options.Output == nil {
+		options.Output = os.Stderr
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 30 * time.Second
+	}
+	return &prompter{
+		options:  options,
+		messages: messages,
+		lines:    make(chan answer),
+	}
+}
+
+// interactive returns false if the input is a file that isn't a terminal, e.g. a pipe or /dev/null
+func (p *prompter) interactive() bool {
+	file, ok := p.options.Input.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// readLines reads answers in the background, so a prompt can time out.
+// The goroutine lives as long as the input
+func (p *prompter) readLines() {
+	scanner := bufio.NewScanner(p.options.Input)
+	for scanner.Scan() {
+		p.lines <- answer{line: scanner.Text(), at: time.Now()}
+	}
+	close(p.lines)
+}
+
+// confirm prints a summary of the report and asks whether to submit it. Answers typed before
+// the question was asked, e.g. after an earlier prompt timed out, are discarded
+func (p *prompter) confirm(report CrashReport) bool {
+	if report.Handled && !p.options.PromptHandled || !p.interactive() {
+		return p.options.Submit
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	asked := time.Now()
+	p.once.Do(func() { go p.readLines() })
+
+	yes, no := strings.ToLower(p.messages.Yes), strings.ToLower(p.messages.No)
+	choices := "[" + yes + "/" + strings.ToUpper(no) + "]"
+	if p.options.Submit {
+		choices = "[" + strings.ToUpper(yes) + "/" + no + "]"
+	}
+	fmt.Fprintf(p.options.Output, "\n%s\n%s %s ", p.summary(report), p.messages.SubmitQuestion, choices)
+
+	timer := time.NewTimer(p.options.Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case answer, ok := <-p.lines:
+			if !ok {
+				fmt.Fprintln(p.options.Output)
+				return p.options.Submit
+			}
+			if answer.at.Before(asked) {
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(answer.line)) {
+			case yes, "y", "yes":
+				return true
+			case no, "n", "no":
+				return false
+			}
+			return p.options.Submit
+		case <-timer.C:
+			fmt.Fprintln(p.options.Output)
+			fmt.Fprintln(p.options.Output, p.messages.NoAnswer)
+			return p.options.Submit
+		}
+	}
+}
+
+// summary returns a short plain text summary of a report for the terminal
+func (p *prompter) summary(report CrashReport) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(p.messages.Crashed, firstLine(report.Error)) + "\n")
+	frames := appFrames(ParseStack(report.Stack))
+	if len(frames) > maxSummaryFrames {
+		frames = frames[:maxSummaryFrames]
+	}
+	for _, frame := range frames {
+		fmt.Fprintf(&sb, "    at %s (%s:%d)\n", frame.Function, frame.File, frame.Line)
+	}
+	if report.ID != "" {
+		fmt.Fprintf(&sb, "%s %s\n", p.messages.CrashID, report.ID)
+	}
+	return sb.String()
+}
@@ -0,0 +1,172 @@
+package adfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsentPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		submit   bool
+		expected bool
+	}{
+		{name: "yes", input: "y\n", expected: true},
+		{name: "full word", input: "Yes\n", expected: true},
+		{name: "no", input: "n\n", submit: true, expected: false},
+		{name: "enter uses default", input: "\n", expected: false},
+		{name: "enter uses submit default", input: "\n", submit: true, expected: true},
+		{name: "end of input", input: "", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			reports := make(chan CrashReport, 1)
+			ph := New(Options{ErrorHandler: func(error, []byte) {}},
+				WithReporter(channelReporter(reports)),
+				WithConsentPrompt(PromptOptions{
+					Input:  strings.NewReader(tt.input),
+					Output: &output,
+					Submit: tt.submit,
+				}),
+			)
+
+			func() {
+				defer ph.Recover()
+				panic("prompt panic")
+			}()
+
+			if !strings.Contains(output.String(), "The program crashed: prompt panic") {
+				t.Errorf("Expected summary in output, got %q", output.String())
+			}
+			if !strings.Contains(output.String(), "Submit this crash report?") {
+				t.Errorf("Expected prompt in output, got %q", output.String())
+			}
+			if sent := len(reports) == 1; sent != tt.expected {
+				t.Errorf("Expected report sent to be %v", tt.expected)
+			}
+		})
+	}
+}
+
+func TestConsentPromptTimeout(t *testing.T) {
+	input, writer := io.Pipe()
+	defer writer.Close()
+	var output bytes.Buffer
+	p := newPrompter(PromptOptions{Input: input, Output: &output, Timeout: 10 * time.Millisecond}, englishMessages)
+
+	if p.confirm(CrashReport{Error: "boom"}) {
+		t.Error("Expected timeout to decline")
+	}
+	if !strings.Contains(output.String(), "No answer") {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+}
+
+func TestConsentPromptLateAnswer(t *testing.T) {
+	input, writer := io.Pipe()
+	defer writer.Close()
+	p := newPrompter(PromptOptions{Input: input, Output: io.Discard, Timeout: 20 * time.Millisecond}, englishMessages)
+	if p.confirm(CrashReport{Error: "first"}) {
+		t.Fatal("Expected timeout to decline")
+	}
+
+	// Answered after the first prompt timed out
+	writer.Write([]byte("y\n"))
+	time.Sleep(10 * time.Millisecond)
+	if p.confirm(CrashReport{Error: "second"}) {
+		t.Error("Expected the late answer not to be applied to the next prompt")
+	}
+
+	answered := make(chan bool)
+	p.options.Timeout = 5 * time.Second
+	go func() { answered <- p.confirm(CrashReport{Error: "third"}) }()
+	time.Sleep(10 * time.Millisecond)
+	writer.Write([]byte("y\n"))
+	if !<-answered {
+		t.Error("Expected an answer given after the prompt to be used")
+	}
+}
+
+func TestConsentPromptHandled(t *testing.T) {
+	for _, promptHandled := range []bool{false, true} {
+		var output bytes.Buffer
+		reports := make(chan CrashReport, 1)
+		ph := New(Options{ErrorHandler: func(error, []byte) {}},
+			WithReporter(channelReporter(reports)),
+			WithConsentPrompt(PromptOptions{
+				Input:         strings.NewReader("y\n"),
+				Output:        &output,
+				PromptHandled: promptHandled,
+			}),
+		)
+		ph.Report(errors.New("handled"))
+
+		if prompted := output.Len() > 0; prompted != promptHandled {
+			t.Errorf("Expected prompted to be %v for PromptHandled %v, got %q", promptHandled, promptHandled, output.String())
+		}
+		if sent := len(reports) == 1; sent != promptHandled {
+			t.Errorf("Expected the handled error to be sent by consent only, got sent %v", sent)
+		}
+	}
+}
+
+func TestConsentPromptNonInteractive(t *testing.T) {
+	input, err := os.CreateTemp("", "input_*.txt")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(input.Name())
+	defer input.Close()
+	input.WriteString("y\n")
+	input.Seek(0, io.SeekStart)
+
+	var output bytes.Buffer
+	p := newPrompter(PromptOptions{Input: input, Output: &output}, englishMessages)
+	if p.confirm(CrashReport{Error: "boom"}) {
+		t.Error("Expected non-terminal input to use the default")
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no prompt, got %q", output.String())
+	}
+}
+
+func TestConsentPromptDeclinedReceipts(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "crash_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     tempFile.Name(),
+	},
+		WithReporter(&namedReporter{name: "webhook"}),
+		WithConsentPrompt(PromptOptions{Input: strings.NewReader("n\n"), Output: io.Discard}),
+	)
+
+	func() {
+		defer ph.Recover()
+		panic("declined panic")
+	}()
+
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Deliveries["webhook"].Status != DeliveryDeclined {
+		t.Fatalf("Expected declined receipt, got %+v", reports)
+	}
+	pending, _ := ph.PendingReports()
+	if len(pending) != 0 {
+		t.Errorf("Expected declined reports not to be pending, got %d", len(pending))
+	}
+}
@@ -0,0 +1,152 @@
+package adfer
+
+import (
+	"fmt"
+	"time"
+)
+
+// eachPageSize is the number of reports read at a time by EachCrashReport from a ReportPager
+const eachPageSize = 100
+
+// reportStore provides the query methods shared by PanicHandler and CrashReader
+type reportStore struct {
+	storage Storage
+	perms   filePerms
+}
+
+// GetLastNCrashReports retrieves the last N crash reports from the storage
+func (s *reportStore) GetLastNCrashReports(n int) ([]CrashReport, error) {
+	if n < 0 {
+		n = 0
+	}
+	if s.storage == nil {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	return s.storage.LastN(n)
+}
+
+// GetCrashReportsWithTags retrieves all crash reports that have every one of the given tags
+func (s *reportStore) GetCrashReportsWithTags(tags ...string) ([]CrashReport, error) {
+	return s.QueryCrashReports(func(report CrashReport) bool {
+		return report.HasTags(tags...)
+	})
+}
+
+// GetCrashReportsBetween retrieves the crash reports created in the time range [from, to), e.g. a deploy window
+func (s *reportStore) GetCrashReportsBetween(from, to time.Time) ([]CrashReport, error) {
+	return s.QueryCrashReports(func(report CrashReport) bool {
+		return !report.Timestamp.Before(from) && report.Timestamp.Before(to)
+	})
+}
+
+// QueryCrashReports retrieves the crash reports for which match returns true, oldest first
+func (s *reportStore) QueryCrashReports(match func(report CrashReport) bool) ([]CrashReport, error) {
+	if s.storage == nil {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	var result []CrashReport
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		if match(report) {
+			result = append(result, report)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetCrashReportByID retrieves the crash report with the given ID, as printed by the default error
+// handler, so a support ticket can reference a specific crash. It returns ErrReportNotFound if no
+// stored report has the ID
+func (s *reportStore) GetCrashReportByID(id string) (CrashReport, error) {
+	if s.storage == nil {
+		return CrashReport{}, fmt.Errorf("no file path set for crash reports")
+	}
+	var result CrashReport
+	found := false
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		if report.ID == id {
+			result, found = report, true
+		}
+		return !found
+	})
+	if err != nil {
+		return CrashReport{}, err
+	}
+	if !found {
+		return CrashReport{}, ErrReportNotFound
+	}
+	return result, nil
+}
+
+// readCrashReports reads all crash reports from the storage
+func (s *reportStore) readCrashReports() ([]CrashReport, error) {
+	if s.storage == nil {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	return s.storage.LastN(-1)
+}
+
+// EachCrashReport calls fn with every stored crash report, oldest first, until fn returns false, so
+// analysis tools can process a crash history too large to hold in memory. The crash file is copied and the
+// copy is read a report at a time, so reports written or trimmed meanwhile don't shift the iteration. Storages
+// that implement ReportPager, such as the crash directory, are read a page at a time. Other storages are read
+// at once. fn is called without any storage lock held, so it may query the crash reports itself
+func (s *reportStore) EachCrashReport(fn func(report CrashReport) bool) error {
+	if s.storage == nil {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	switch storage := s.storage.(type) {
+	case *fileStorage:
+		return storage.each(fn)
+	case ReportPager:
+		// Pages may hold fewer reports than requested, e.g. if files of a crash directory can't be read
+		count, err := storage.Count()
+		if err != nil {
+			return err
+		}
+		for offset := 0; offset < count; offset += eachPageSize {
+			reports, err := storage.Page(offset, eachPageSize)
+			if err != nil {
+				return err
+			}
+			for _, report := range reports {
+				if !fn(report) {
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+	reports, err := s.readCrashReports()
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if !fn(report) {
+			break
+		}
+	}
+	return nil
+}
+
+// PendingReports returns the crash reports that have not been delivered to every reporter.
+// Reports the user declined to submit are not pending
+func (s *reportStore) PendingReports() ([]CrashReport, error) {
+	reports, err := s.readCrashReports()
+	if err != nil {
+		return nil, err
+	}
+	var pending []CrashReport
+	for _, report := range reports {
+		for _, delivery := range report.Deliveries {
+			if delivery.Status != DeliverySent && delivery.Status != DeliveryDeclined {
+				pending = append(pending, report)
+				break
+			}
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,173 @@
+package adfer
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestQueryCrashReports(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	writeCrashFile(t, filePath, FormatJSON,
+		CrashReport{ID: "a", Timestamp: start, Metadata: map[string]string{"version": "1.0"}},
+		CrashReport{ID: "b", Timestamp: start.Add(time.Hour), Metadata: map[string]string{"version": "1.1"}},
+		CrashReport{ID: "c", Timestamp: start.Add(2 * time.Hour), Metadata: map[string]string{"version": "1.1"}},
+	)
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	between, err := reader.GetCrashReportsBetween(start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil || len(between) != 1 || between[0].ID != "b" {
+		t.Errorf("Expected report b, got %+v, error %v", between, err)
+	}
+	if all, _ := reader.GetCrashReportsBetween(start, start.Add(3*time.Hour)); len(all) != 3 {
+		t.Errorf("Expected 3 reports, got %d", len(all))
+	}
+
+	matched, err := reader.QueryCrashReports(func(report CrashReport) bool {
+		return report.Metadata["version"] == "1.1"
+	})
+	if err != nil || len(matched) != 2 || matched[0].ID != "b" || matched[1].ID != "c" {
+		t.Errorf("Expected reports b and c, got %+v, error %v", matched, err)
+	}
+}
+
+func TestEachCrashReport(t *testing.T) {
+	const count = 2*eachPageSize + 5
+	storages := map[string]Storage{
+		"json":   newFileStorage(Options{FilePath: filepath.Join(t.TempDir(), "crash.json")}, func(string, string, error) {}),
+		"jsonl":  newFileStorage(Options{FilePath: filepath.Join(t.TempDir(), "crash.jsonl"), FileFormat: FormatJSONLines}, func(string, string, error) {}),
+		"dir":    &dirStorage{dir: t.TempDir(), diagnose: func(string, string, error) {}},
+		"memory": NewMemoryStore(count),
+		"custom": &memoryStorage{},
+	}
+	start := time.Now()
+	for name, storage := range storages {
+		for i := 0; i < count; i++ {
+			storage.Append(CrashReport{ID: strconv.Itoa(i), Timestamp: start.Add(time.Duration(i) * time.Second)})
+		}
+		ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(storage))
+
+		next := 0
+		err := ph.EachCrashReport(func(report CrashReport) bool {
+			if report.ID != strconv.Itoa(next) {
+				t.Errorf("%s: expected report %d, got %s", name, next, report.ID)
+			}
+			next++
+			return true
+		})
+		if err != nil || next != count {
+			t.Errorf("%s: expected %d reports, got %d, error %v", name, count, next, err)
+		}
+
+		seen := 0
+		ph.EachCrashReport(func(CrashReport) bool {
+			seen++
+			return seen < 3
+		})
+		if seen != 3 {
+			t.Errorf("%s: expected the iteration to stop after 3 reports, got %d", name, seen)
+		}
+	}
+}
+
+func TestEachCrashReportReentrant(t *testing.T) {
+	for _, format := range []FileFormat{FormatJSON, FormatJSONLines} {
+		ph := New(Options{
+			ErrorHandler: func(error, []byte) {},
+			DumpToFile:   true,
+			FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+			FileFormat:   format,
+		})
+		for i := 0; i < eachPageSize+1; i++ {
+			ph.Report(errors.New("boom"))
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			seen := 0
+			done <- ph.EachCrashReport(func(CrashReport) bool {
+				if _, err := ph.CountCrashReports(); err != nil {
+					t.Errorf("Unexpected error counting reports: %v", err)
+				}
+				if seen++; seen == 1 {
+					ph.Report(errors.New("reported while iterating"))
+				}
+				return true
+			})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the storage to be usable from the callback")
+		}
+	}
+}
+
+func TestEachCrashReportTrimmed(t *testing.T) {
+	const count = eachPageSize + 5
+	for _, compress := range []bool{false, true} {
+		for _, format := range []FileFormat{FormatJSON, FormatJSONLines} {
+			ph := New(Options{
+				ErrorHandler: func(error, []byte) {},
+				DumpToFile:   true,
+				FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+				FileFormat:   format,
+				MaxReports:   count,
+				Compress:     compress,
+			})
+			for i := 0; i < count; i++ {
+				ph.Report(errors.New(strconv.Itoa(i)))
+			}
+
+			// The report appended while iterating trims the oldest one, which must not shift the later batches
+			next := 0
+			err := ph.EachCrashReport(func(report CrashReport) bool {
+				if report.Error != strconv.Itoa(next) {
+					t.Errorf("format %v compress %v: expected report %d, got %s", format, compress, next, report.Error)
+				}
+				if next++; next == 1 {
+					ph.Report(errors.New("reported while iterating"))
+				}
+				return true
+			})
+			if err != nil || next != count {
+				t.Errorf("format %v compress %v: expected %d reports, got %d, error %v", format, compress, count, next, err)
+			}
+		}
+	}
+}
+
+func TestGetCrashReportByID(t *testing.T) {
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	})
+	var ids []string
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+		last, _ := ph.GetLastNCrashReports(1)
+		ids = append(ids, last[0].ID)
+	}
+
+	report, err := ph.GetCrashReportByID(ids[1])
+	if err != nil || report.ID != ids[1] || report.Error != "1" {
+		t.Errorf("Expected report 1, got %+v, error %v", report, err)
+	}
+	if _, err := ph.GetCrashReportByID("missing"); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package adfer
+
+import (
+	"os"
+)
+
+// CrashReader reads the crash reports of a crash file without being able to modify it, so
+// analysis tools and dashboards can safely share a production crash file. It has the same
+// query methods as PanicHandler, but no methods that append, update or wipe reports
+type CrashReader struct {
+	reportStore
+}
+
+// OpenReadOnly opens a crash file, or a crash directory written with WithCrashDir, for reading.
+// The format of a crash file is detected from its contents, and the sidecar index is used if it exists
+func OpenReadOnly(path string) (*CrashReader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		storage := &dirStorage{dir: path, diagnose: func(string, string, error) {}}
+		return &CrashReader{reportStore{storage: storage, perms: permsFromOptions(Options{})}}, nil
+	}
+	format, compressed := detectFormat(path)
+	storage := &fileStorage{path: path, format: format, compressed: compressed, diagnose: func(string, string, error) {}}
+	if _, err := os.Stat(storage.indexPath()); err == nil && storage.format == FormatJSON && !compressed {
+		storage.index = true
+	}
+	return &CrashReader{reportStore{storage: storage, perms: permsFromOptions(Options{})}}, nil
+}
@@ -0,0 +1,52 @@
+package adfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+		Index:        true,
+	})
+	for _, tag := range []string{"api", "worker", "api"} {
+		func() {
+			defer ph.RecoverCtx(WithTags(context.Background(), tag))
+			panic(tag)
+		}()
+	}
+	before, _ := os.ReadFile(filePath)
+
+	reader, err := OpenReadOnly(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, err := reader.GetLastNCrashReports(2)
+	if err != nil || len(reports) != 2 || reports[0].Error != "worker" {
+		t.Fatalf("Unexpected reports %+v, error %v", reports, err)
+	}
+	tagged, err := reader.GetCrashReportsWithTags("api")
+	if err != nil || len(tagged) != 2 {
+		t.Fatalf("Expected 2 reports tagged api, got %d, error %v", len(tagged), err)
+	}
+	if err := reader.VerifyCrashFile(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	after, _ := os.ReadFile(filePath)
+	if string(before) != string(after) {
+		t.Error("Expected the crash file not to be modified")
+	}
+}
+
+func TestOpenReadOnlyMissingFile(t *testing.T) {
+	if _, err := OpenReadOnly(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
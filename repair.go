@@ -0,0 +1,158 @@
+package adfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// corruptTime is the layout of the timestamp in the name of the backup of a corrupt crash file
+const corruptTime = "20060102T150405Z"
+
+// RepairCrashFile checks that every report of the crash file can be decoded. If not, the corrupt
+// file is backed up to <path>.corrupt-<timestamp> and rewritten with the reports that could be
+// recovered. It returns the path of the backup, empty if the crash file was intact, and the number
+// of reports recovered. Corrupt JSON array crash files are also repaired when a report is written
+func (ph *PanicHandler) RepairCrashFile() (backup string, recovered int, err error) {
+	storage, ok := ph.storage.(*fileStorage)
+	if !ok {
+		return "", 0, fmt.Errorf("storage %T has no crash file to repair", ph.storage)
+	}
+	return storage.repair()
+}
+
+// repair backs up and rewrites the crash file if any of its reports can't be decoded
+func (f *fileStorage) repair() (string, int, error) {
+	if f.path == "" {
+		return "", 0, fmt.Errorf("no file path set for crash reports")
+	}
+	defer f.fireHooks()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock(true)()
+	data, err := f.readFile()
+	if os.IsNotExist(err) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if f.format == FormatJSONLines {
+		reports, corrupt := recoverLines(data)
+		if corrupt == nil {
+			return "", 0, nil
+		}
+		backup, err := f.backupCorrupt(data, len(reports), corrupt)
+		if err != nil {
+			return "", 0, err
+		}
+		encoded, err := encodeLines(reports)
+		if err == nil {
+			err = f.writeFile(f.path, f.compressData(encoded))
+		}
+		f.lines, f.counted = len(reports), true
+		return backup, len(reports), err
+	}
+
+	var reports []CrashReport
+	decodeErr := json.Unmarshal(data, &reports)
+	if decodeErr == nil || len(bytes.TrimSpace(data)) == 0 {
+		return "", 0, nil
+	}
+	reports = recoverReports(data)
+	backup, err := f.backupCorrupt(data, len(reports), decodeErr)
+	if err != nil {
+		return "", 0, err
+	}
+	encoded, offsets, err := encodeCrashReports(reports)
+	if err == nil {
+		err = f.write(encoded, offsets)
+	}
+	return backup, len(reports), err
+}
+
+// decodeArray decodes a JSON array crash file. A corrupt file is backed up, and the reports
+// that could be recovered are returned so they are written to a fresh crash file
+func (f *fileStorage) decodeArray(data []byte) []CrashReport {
+	if plain, err := decompress(data); err == nil {
+		data = plain
+	}
+	var reports []CrashReport
+	err := json.Unmarshal(data, &reports)
+	if err == nil || len(bytes.TrimSpace(data)) == 0 {
+		return reports
+	}
+	reports = recoverReports(data)
+	if _, backupErr := f.backupCorrupt(data, len(reports), err); backupErr != nil {
+		f.diagnose(OpWrite, f.path, backupErr)
+	}
+	return reports
+}
+
+// backupCorrupt writes the contents of a corrupt crash file to <path>.corrupt-<timestamp>
+// and reports the repair as a diagnostic
+func (f *fileStorage) backupCorrupt(data []byte, recovered int, cause error) (string, error) {
+	backup := f.path + ".corrupt-" + time.Now().UTC().Format(corruptTime)
+	if err := f.writeFile(backup, data); err != nil {
+		return "", err
+	}
+	f.diagnose(OpRepair, f.path, fmt.Errorf("backed up to %s, recovered %d report(s): %w", backup, recovered, cause))
+	if f.hooks.OnCorruptDetected != nil {
+		f.emit(func() { f.hooks.OnCorruptDetected(backup) })
+	}
+	return backup, nil
+}
+
+// recoverReports decodes the intact reports of a corrupt JSON array crash file. After a report
+// that can't be decoded, decoding resumes at the next report starting on its own line
+func recoverReports(data []byte) []CrashReport {
+	var reports []CrashReport
+	pos := 0
+	if i := bytes.IndexByte(data, '['); i >= 0 {
+		pos = i + 1
+	}
+	for {
+		for pos < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[pos]) >= 0 {
+			pos++
+		}
+		if pos >= len(data) || data[pos] == ']' {
+			return reports
+		}
+		var report CrashReport
+		decoder := json.NewDecoder(bytes.NewReader(data[pos:]))
+		if data[pos] == '{' && decoder.Decode(&report) == nil {
+			reports = append(reports, report)
+			pos += int(decoder.InputOffset())
+			continue
+		}
+		next := bytes.Index(data[pos+1:], []byte("\n  {"))
+		if next < 0 {
+			return reports
+		}
+		pos += next + len("\n  {")
+	}
+}
+
+// recoverLines decodes the lines of a JSON Lines crash file, returning the error of the
+// first line that can't be decoded
+func recoverLines(data []byte) ([]CrashReport, error) {
+	var reports []CrashReport
+	var corrupt error
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var report CrashReport
+		if err := json.Unmarshal(line, &report); err != nil {
+			if corrupt == nil {
+				corrupt = err
+			}
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, corrupt
+}
@@ -0,0 +1,106 @@
+package adfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecoverReports(t *testing.T) {
+	reports := []CrashReport{{ID: "1", Stack: testStack}, {ID: "2"}, {ID: "3"}, {ID: "4"}}
+	data, _, _ := encodeCrashReports(reports)
+	compact, _ := json.Marshal(reports)
+	second := strings.Index(string(data), `"id": "2"`)
+
+	tests := map[string]struct {
+		data string
+		want string
+	}{
+		"truncated":         {string(data[:len(data)-20]), "1,2,3"},
+		"corrupt report":    {string(data[:second]) + "garbage" + string(data[second+3:]), "1,3,4"},
+		"truncated compact": {string(compact[:len(compact)-5]), "1,2,3"},
+		"garbage":           {"not json", ""},
+	}
+	for name, test := range tests {
+		var ids []string
+		for _, report := range recoverReports([]byte(test.data)) {
+			ids = append(ids, report.ID)
+		}
+		if got := strings.Join(ids, ","); got != test.want {
+			t.Errorf("%s: expected reports %s, got %s", name, test.want, got)
+		}
+	}
+}
+
+func TestCorruptCrashFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		DumpToFile:   true,
+		FilePath:     filePath,
+	})
+	data, _, _ := encodeCrashReports([]CrashReport{{ID: "old-1"}, {ID: "old-2"}})
+	corrupt := data[:len(data)-10]
+	os.WriteFile(filePath, corrupt, 0644)
+
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 2 || reports[0].ID != "old-1" || reports[1].Error != "boom" {
+		t.Fatalf("Expected the recovered report to be kept, got %+v, error %v", reports, err)
+	}
+	backups, _ := filepath.Glob(filePath + ".corrupt-*")
+	if len(backups) != 1 {
+		t.Fatalf("Expected a backup of the corrupt file, got %v", backups)
+	}
+	if backup, _ := os.ReadFile(backups[0]); string(backup) != string(corrupt) {
+		t.Errorf("Expected the backup to keep the corrupt contents")
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpRepair || !strings.Contains(diagnostics[0].Err.Error(), "recovered 1 report(s)") {
+		t.Errorf("Expected a repair diagnostic, got %v", diagnostics)
+	}
+}
+
+func TestRepairCrashFile(t *testing.T) {
+	for _, format := range []FileFormat{FormatJSON, FormatJSONLines} {
+		filePath := filepath.Join(t.TempDir(), "crash.json")
+		ph := New(Options{
+			ErrorHandler: func(error, []byte) {},
+			OnDiagnostic: func(Diagnostic) {},
+			DumpToFile:   true,
+			FilePath:     filePath,
+			FileFormat:   format,
+		})
+		for i := 0; i < 3; i++ {
+			func() {
+				defer ph.Recover()
+				panic(i)
+			}()
+		}
+		if backup, _, err := ph.RepairCrashFile(); backup != "" || err != nil {
+			t.Fatalf("Format %d: expected an intact crash file to be left alone, got %s, error %v", format, backup, err)
+		}
+
+		data, _ := os.ReadFile(filePath)
+		os.WriteFile(filePath, data[:len(data)-10], 0644)
+		backup, recovered, err := ph.RepairCrashFile()
+		if err != nil || recovered != 2 || !strings.HasPrefix(backup, filePath+".corrupt-") {
+			t.Fatalf("Format %d: expected 2 reports to be recovered, got %d, backup %s, error %v", format, recovered, backup, err)
+		}
+		if reports, err := ph.GetLastNCrashReports(10); err != nil || len(reports) != 2 {
+			t.Errorf("Format %d: expected the repaired file to hold 2 reports, got %d, error %v", format, len(reports), err)
+		}
+	}
+
+	ph := New(Options{}, WithStorage(NewMemoryStore(10)))
+	if _, _, err := ph.RepairCrashFile(); err == nil {
+		t.Errorf("Expected an error for a storage without a crash file")
+	}
+}
@@ -0,0 +1,34 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Replay passes a stored crash report through the handler again, marked as replayed, so new reporters
+// and alert rules can be developed and verified against real historical crashes. The report is passed
+// to the error handler and delivered to every configured reporter, synchronously, but isn't stored
+// again, counted or spooled. It returns the errors of the reporters that failed, and ErrNoConsent
+// unless the consent level is ConsentFull. Encrypted reports must be decrypted first, see DecryptCrashReport
+func (ph *PanicHandler) Replay(report CrashReport) error {
+	if ph.Consent() < ConsentFull {
+		return ErrNoConsent
+	}
+	if report.Encrypted != "" {
+		return errors.New("can't replay an encrypted crash report")
+	}
+	report.Replayed = true
+	report.Deliveries = nil
+	ctx := context.Background()
+	ph.console.Report(ctx, report)
+
+	var errs []error
+	for i := range ph.options.Reporters {
+		delivery := ph.deliver(ctx, i, report, Delivery{})
+		if delivery.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", ph.reporterNames[i], delivery.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
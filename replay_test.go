@@ -0,0 +1,57 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	reports := make(chan CrashReport, 2)
+	var handled []string
+	ph := New(Options{
+		ErrorHandler: func(err error, _ []byte) { handled = append(handled, err.Error()) },
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	}, WithReporter(channelReporter(reports)), WithReporter(ReporterFunc(func(context.Context, CrashReport) error {
+		return errors.New("sink down")
+	})))
+	func() {
+		defer ph.Recover()
+		panic("historical")
+	}()
+	original := <-reports
+
+	stored, _ := ph.GetLastNCrashReports(1)
+	err := ph.Replay(stored[0])
+	if err == nil || !strings.Contains(err.Error(), "sink down") {
+		t.Errorf("Expected the failing reporter's error, got %v", err)
+	}
+	replayed := <-reports
+	if !replayed.Replayed || replayed.ID != original.ID || replayed.Error != "historical" || replayed.Deliveries != nil {
+		t.Errorf("Expected the stored report marked as replayed, got %+v", replayed)
+	}
+	if original.Replayed {
+		t.Error("Expected the original report not to be marked as replayed")
+	}
+	if len(handled) != 2 || handled[1] != "historical" {
+		t.Errorf("Expected the error handler to be called again, got %v", handled)
+	}
+	if all, _ := ph.GetLastNCrashReports(10); len(all) != 1 {
+		t.Errorf("Expected the replayed report not to be stored again, got %d reports", len(all))
+	}
+	if stats := ph.Stats(); stats.Panics != 1 {
+		t.Errorf("Expected the replayed report not to be counted, got %d panics", stats.Panics)
+	}
+
+	if err := ph.Replay(CrashReport{ID: "x", Encrypted: "..."}); err == nil {
+		t.Error("Expected an error for an encrypted report")
+	}
+	ph.SetConsent(ConsentLocal)
+	if err := ph.Replay(stored[0]); !errors.Is(err, ErrNoConsent) {
+		t.Errorf("Expected ErrNoConsent, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// ReportOption configures a report submitted with PanicHandler.Report
+type ReportOption func(*reportConfig)
+
+type reportConfig struct {
+	ctx      context.Context
+	metadata map[string]string
+	tags     []string
+}
+
+// WithReportContext sets the context passed to reporters. Tags stored in ctx are added to the report
+func WithReportContext(ctx context.Context) ReportOption {
+	return func(c *reportConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithReportMetadata adds a metadata entry to the report, overriding configured metadata with the same key
+func WithReportMetadata(key, value string) ReportOption {
+	return func(c *reportConfig) {
+		if c.metadata == nil {
+			c.metadata = make(map[string]string)
+		}
+		c.metadata[key] = value
+	}
+}
+
+// WithReportTags adds tags to the report
+func WithReportTags(tags ...string) ReportOption {
+	return func(c *reportConfig) {
+		c.tags = append(c.tags, tags...)
+	}
+}
+
+// Report records a crash report for a handled error, capturing the current stack.
+// The report flows through the same error handler, crash file and reporters as a
+// recovered panic, but never causes the program to exit. Nil errors are ignored.
+func (ph *PanicHandler) Report(err error, opts ...ReportOption) {
+	if err == nil {
+		return
+	}
+	config := reportConfig{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	stack := debug.Stack()
+	ph.mu.Lock()
+	ph.stats.Reports++
+	ph.mu.Unlock()
+
+	report := ph.newCrashReport(config.ctx, err, fmt.Sprintf("%T", err), stack)
+	report.Handled = true
+	if len(config.tags) > 0 {
+		report.Tags = append(append([]string{}, report.Tags...), config.tags...)
+	}
+	addMetadata(&report, config.metadata)
+	ph.process(config.ctx, err, report)
+}
+
+// addMetadata adds metadata to a report, overriding the configured metadata
+func addMetadata(report *CrashReport, metadata map[string]string) {
+	if len(metadata) == 0 {
+		return
+	}
+	if report.Metadata == nil {
+		report.Metadata = make(map[string]string, len(metadata))
+	}
+	for key, value := range metadata {
+		report.Metadata[key] = value
+	}
+}
@@ -0,0 +1,60 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	var handled error
+	exitCalled := false
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(err error, stack []byte) {
+			handled = err
+		},
+		ExitOnPanic: true,
+		ExitFunc:    func(int) { exitCalled = true },
+		Metadata:    map[string]string{"version": "1.0.0", "component": "default"},
+		Reporters:   []Reporter{channelReporter(reports)},
+	})
+
+	ctx := WithTags(context.Background(), "queue=email")
+	reportErr := errors.New("payment failed")
+	ph.Report(reportErr,
+		WithReportContext(ctx),
+		WithReportTags("severe"),
+		WithReportMetadata("component", "billing"),
+	)
+
+	if handled != reportErr {
+		t.Errorf("Expected error handler to receive the error, got %v", handled)
+	}
+	if exitCalled {
+		t.Error("Expected Report not to exit")
+	}
+	report := <-reports
+	if !report.Handled || report.Error != "payment failed" || report.ErrorType != "*errors.errorString" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if !strings.Contains(report.Stack, "TestReport") {
+		t.Error("Expected stack to contain the caller")
+	}
+	if !report.HasTags("queue=email", "severe") {
+		t.Errorf("Unexpected tags: %v", report.Tags)
+	}
+	if report.Metadata["component"] != "billing" || report.Metadata["version"] != "1.0.0" {
+		t.Errorf("Unexpected metadata: %v", report.Metadata)
+	}
+	if ph.options.Metadata["component"] != "default" {
+		t.Error("Expected configured metadata to be left untouched")
+	}
+
+	ph.Report(nil)
+	stats := ph.Stats()
+	if stats.Reports != 1 || stats.Panics != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
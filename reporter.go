@@ -0,0 +1,59 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+)
+
+// Reporter delivers crash reports to a destination such as a remote service.
+// Reporters may implement Name() string to set the name used in delivery receipts
+type Reporter interface {
+	Report(ctx context.Context, report CrashReport) error
+}
+
+// ReporterFunc is an adapter to allow the use of ordinary functions as Reporters
+type ReporterFunc func(ctx context.Context, report CrashReport) error
+
+// Report calls f(ctx, report)
+func (f ReporterFunc) Report(ctx context.Context, report CrashReport) error {
+	return f(ctx, report)
+}
+
+// WithReporter adds a reporter that receives every crash report
+func WithReporter(r Reporter) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, r)
+	}
+}
+
+type panicErrorKey struct{}
+
+// contextWithError stores the original error of a crash in ctx, so the console
+// reporter can pass it to the ErrorHandler unchanged
+func contextWithError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, panicErrorKey{}, err)
+}
+
+// errorFromContext returns the original error of a crash, falling back to the report's error message
+func errorFromContext(ctx context.Context, report CrashReport) error {
+	if err, ok := ctx.Value(panicErrorKey{}).(error); ok {
+		return err
+	}
+	return errors.New(report.Error)
+}
+
+// consoleReporter passes crash reports to the error handler
+type consoleReporter struct {
+	handler ErrorHandlerV2
+}
+
+// Report calls the error handler with the crash report
+func (c consoleReporter) Report(ctx context.Context, report CrashReport) error {
+	c.handle(ctx, &report)
+	return nil
+}
+
+// handle calls the error handler with the crash report, which it may modify
+func (c consoleReporter) handle(ctx context.Context, report *CrashReport) {
+	c.handler(ctx, report)
+}
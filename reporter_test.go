@@ -0,0 +1,90 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithReporter(t *testing.T) {
+	var order []string
+	var handled error
+	ph := New(Options{
+		ErrorHandler: func(err error, stack []byte) {
+			handled = err
+			order = append(order, "console")
+		},
+	},
+		WithReporter(ReporterFunc(func(ctx context.Context, report CrashReport) error {
+			order = append(order, "first:"+report.Error)
+			return nil
+		})),
+		WithReporter(ReporterFunc(func(ctx context.Context, report CrashReport) error {
+			order = append(order, "second:"+report.Error)
+			return nil
+		})),
+	)
+
+	panicErr := errors.New("reporter panic")
+	func() {
+		defer ph.Recover()
+		panic(panicErr)
+	}()
+
+	if handled != panicErr {
+		t.Errorf("Expected the console reporter to receive the original error, got %v", handled)
+	}
+	expected := []string{"console", "first:reporter panic", "second:reporter panic"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestBuiltInReporters(t *testing.T) {
+	ph := New(Options{DumpToFile: true, FilePath: "unused.json"})
+	if len(ph.reporters) != 1 {
+		t.Fatalf("Expected the file reporter, got %d reporters", len(ph.reporters))
+	}
+	if _, ok := ph.reporters[0].(storageReporter); !ok {
+		t.Errorf("Expected storage reporter, got %T", ph.reporters[0])
+	}
+
+	err := consoleReporter{handler: AdaptErrorHandler(func(err error, _ []byte) {
+		if err.Error() != "from report" {
+			t.Errorf("Expected error from the report, got %v", err)
+		}
+	})}.Report(context.Background(), CrashReport{Error: "from report"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestConsoleReporterCrashID(t *testing.T) {
+	ph := New(Options{IDGenerator: func() string { return "ab12" }})
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if output := buf.String(); !strings.Contains(output, "Crash ID: ab12\nError: boom\n") {
+		t.Errorf("Expected the crash ID before the error, got %q", output)
+	}
+}
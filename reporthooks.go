@@ -0,0 +1,72 @@
+package adfer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WithBeforeReport adds a hook called with each crash report before it is passed to the error handlers,
+// stored and delivered, e.g. to scrub or enrich it. Returning false vetoes the report, which is then
+// dropped. Hooks are called in the order they were added and see the changes of the previous ones
+func WithBeforeReport(hook func(report *CrashReport) bool) Option {
+	return func(o *Options) {
+		o.BeforeReport = append(o.BeforeReport, hook)
+	}
+}
+
+// WithAfterReport adds a hook called with each crash report once it has been stored and delivered,
+// with its delivery receipts, e.g. for auditing. err joins the errors of the crash file and of the
+// reporters that failed, and is ErrNoConsent if the report wasn't sent for lack of consent. With
+// asynchronous delivery, the hook is called on the background worker
+func WithAfterReport(hook func(report CrashReport, err error)) Option {
+	return func(o *Options) {
+		o.AfterReport = append(o.AfterReport, hook)
+	}
+}
+
+// beforeReport calls the BeforeReport hooks and returns false if one of them vetoed the report.
+// A hook that panics is skipped and doesn't veto the report
+func (ph *PanicHandler) beforeReport(report *CrashReport) bool {
+	for i, hook := range ph.options.BeforeReport {
+		keep := true
+		ph.callHook("before report", i, func() { keep = hook(report) })
+		if !keep {
+			return false
+		}
+	}
+	return true
+}
+
+// afterReport calls the AfterReport hooks
+func (ph *PanicHandler) afterReport(report CrashReport, err error) {
+	for i, hook := range ph.options.AfterReport {
+		ph.callHook("after report", i, func() { hook(report, err) })
+	}
+}
+
+// callHook calls a report hook, recovering from its panics
+func (ph *PanicHandler) callHook(kind string, i int, call func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			ph.diagnose(OpHook, "", fmt.Errorf("%s hook %d panicked: %v", kind, i, p))
+		}
+	}()
+	call()
+}
+
+// deliveryErrors returns the errors of the failed deliveries, ordered by reporter name
+func deliveryErrors(deliveries map[string]Delivery) error {
+	names := make([]string, 0, len(deliveries))
+	for name, delivery := range deliveries {
+		if delivery.Error != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = fmt.Errorf("%s: %s", name, deliveries[name].Error)
+	}
+	return errors.Join(errs...)
+}
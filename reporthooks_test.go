@@ -0,0 +1,108 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBeforeReport(t *testing.T) {
+	handled := 0
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) { handled++ },
+		OnDiagnostic: func(Diagnostic) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	}, WithBeforeReport(func(report *CrashReport) bool {
+		report.Error = strings.ReplaceAll(report.Error, "hunter2", "[redacted]")
+		return true
+	}), WithBeforeReport(func(report *CrashReport) bool {
+		return !strings.Contains(report.Error, "ignored")
+	}))
+	for _, value := range []string{"password hunter2 rejected", "ignored: client went away"} {
+		func() {
+			defer ph.Recover()
+			panic(value)
+		}()
+	}
+
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("Expected the vetoed report to be dropped, got %+v, error %v", reports, err)
+	}
+	if reports[0].Error != "password [redacted] rejected" {
+		t.Errorf("Expected the scrubbed error to be stored, got %q", reports[0].Error)
+	}
+	if handled != 1 {
+		t.Errorf("Expected the error handler to skip the vetoed report, got %d calls", handled)
+	}
+}
+
+func TestAfterReport(t *testing.T) {
+	var observed []CrashReport
+	var errs []error
+	failing := ReporterFunc(func(context.Context, CrashReport) error { return errors.New("unavailable") })
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+	}, WithReporter(failing), WithAfterReport(func(report CrashReport, err error) {
+		observed = append(observed, report)
+		errs = append(errs, err)
+	}))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	if len(observed) != 1 || observed[0].Error != "boom" {
+		t.Fatalf("Expected the report to be observed once, got %+v", observed)
+	}
+	if len(observed[0].Deliveries) != 1 {
+		t.Errorf("Expected the delivery receipts, got %+v", observed[0].Deliveries)
+	}
+	if errs[0] == nil || !strings.Contains(errs[0].Error(), "unavailable") {
+		t.Errorf("Expected the delivery error, got %v", errs[0])
+	}
+}
+
+func TestAfterReportAsync(t *testing.T) {
+	done := make(chan error, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithReporter(ReporterFunc(func(context.Context, CrashReport) error { return nil })),
+		WithAsync(AsyncOptions{}),
+		WithAfterReport(func(report CrashReport, err error) { done <- err }))
+	defer ph.Close()
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a successful delivery, got %v", err)
+	}
+}
+
+func TestReportHookPanics(t *testing.T) {
+	var diagnostics []Diagnostic
+	after := 0
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithBeforeReport(func(*CrashReport) bool {
+		panic("broken hook")
+	}), WithAfterReport(func(CrashReport, error) {
+		after++
+	}))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	if after != 1 {
+		t.Errorf("Expected a panicking BeforeReport hook not to veto the report")
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpHook {
+		t.Errorf("Expected a hook diagnostic, got %+v", diagnostics)
+	}
+}
@@ -0,0 +1,87 @@
+package adfer
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// RestartPolicy configures how SafeGoRestart restarts a goroutine after a recovered panic
+type RestartPolicy struct {
+	// MaxAttempts is the number of restarts after which the goroutine gives up. Unlimited if zero
+	MaxAttempts int
+	// InitialBackoff is the delay before the first restart. Defaults to 1 second
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between restarts. Defaults to 1 minute
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay grows by after each restart. Defaults to 2
+	Multiplier float64
+	// ResetAfter resets the delay and the attempts if the goroutine ran at least this long before
+	// panicking, so a long-lived consumer isn't given up on for rare panics. Disabled if zero
+	ResetAfter time.Duration
+	// OnGiveUp, if set, is called with the crash report of the last panic once MaxAttempts restarts were used
+	OnGiveUp func(report CrashReport)
+}
+
+// withDefaults returns the policy with defaults for unset fields
+func (p RestartPolicy) withDefaults() RestartPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Minute
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	return p
+}
+
+// SafeGoRestart runs f in a goroutine with panic recovery, like SafeGo, and restarts it with exponential
+// backoff after each recovered panic, e.g. for long-lived consumers that must keep running. The crash
+// reports carry the number of restarts in the "restarts" metadata entry. The goroutine stops once f
+// returns, MaxAttempts restarts were used or the handler shuts down with Close. Panics whose policy
+// re-panics or exits aren't restarted
+func (ph *PanicHandler) SafeGoRestart(f func(), policy RestartPolicy) {
+	policy = policy.withDefaults()
+	ctx, release := ph.contexts.derive(context.Background())
+	go func() {
+		defer release()
+		delay := policy.InitialBackoff
+		for restarts := 0; ; restarts++ {
+			start := time.Now()
+			report, panicked := ph.runRestartable(f, restarts)
+			if !panicked {
+				return
+			}
+			if policy.ResetAfter > 0 && time.Since(start) >= policy.ResetAfter {
+				restarts, delay = 0, policy.InitialBackoff
+			}
+			if policy.MaxAttempts > 0 && restarts >= policy.MaxAttempts {
+				if policy.OnGiveUp != nil {
+					ph.callHook("give up", 0, func() { policy.OnGiveUp(report) })
+				}
+				return
+			}
+			if sleepContext(ctx, delay) != nil {
+				return
+			}
+			delay = time.Duration(float64(delay) * policy.Multiplier)
+			if delay > policy.MaxBackoff {
+				delay = policy.MaxBackoff
+			}
+		}
+	}()
+}
+
+// runRestartable calls f, handling its panic. It returns the crash report and true if f panicked
+func (ph *PanicHandler) runRestartable(f func(), restarts int) (report CrashReport, panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			report = ph.handlePanicWith(context.Background(), r, map[string]string{"restarts": strconv.Itoa(restarts)})
+		}
+	}()
+	f()
+	return CrashReport{}, false
+}
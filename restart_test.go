@@ -0,0 +1,84 @@
+package adfer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSafeGoRestart(t *testing.T) {
+	reports := make(chan CrashReport, 10)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	})
+
+	gaveUp := make(chan CrashReport, 1)
+	var runs atomic.Int32
+	ph.SafeGoRestart(func() {
+		runs.Add(1)
+		panic("consumer failed")
+	}, RestartPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, OnGiveUp: func(report CrashReport) { gaveUp <- report }})
+
+	select {
+	case report := <-gaveUp:
+		if report.Metadata["restarts"] != "2" {
+			t.Errorf("Expected the last report to carry 2 restarts, got %v", report.Metadata)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the goroutine to give up")
+	}
+	if runs.Load() != 3 {
+		t.Errorf("Expected 3 runs, got %d", runs.Load())
+	}
+	if len(reports) != 3 {
+		t.Errorf("Expected a report per panic, got %d", len(reports))
+	}
+}
+
+func TestSafeGoRestartRecovers(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	done := make(chan struct{})
+	var runs atomic.Int32
+	ph.SafeGoRestart(func() {
+		if runs.Add(1) < 3 {
+			panic("transient")
+		}
+		close(done)
+	}, RestartPolicy{InitialBackoff: time.Millisecond})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the goroutine to be restarted until it returned")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != 3 {
+		t.Errorf("Expected no restart after f returned, got %d runs", runs.Load())
+	}
+}
+
+func TestSafeGoRestartStopsOnClose(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	var runs atomic.Int32
+	panicked := make(chan struct{}, 1)
+	ph.SafeGoRestart(func() {
+		runs.Add(1)
+		panicked <- struct{}{}
+		panic("boom")
+	}, RestartPolicy{InitialBackoff: 50 * time.Millisecond})
+
+	<-panicked
+	ph.Close()
+	time.Sleep(100 * time.Millisecond)
+	if runs.Load() != 1 {
+		t.Errorf("Expected Close to stop the restarts, got %d runs", runs.Load())
+	}
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	policy := RestartPolicy{}.withDefaults()
+	if policy.InitialBackoff != time.Second || policy.MaxBackoff != time.Minute || policy.Multiplier != 2 {
+		t.Errorf("Unexpected defaults %+v", policy)
+	}
+}
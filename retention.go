@@ -0,0 +1,128 @@
+package adfer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ReportDeleter is implemented by storages that can delete stored crash reports, needed by
+// DeleteReportsOlderThan
+type ReportDeleter interface {
+	// Delete removes the stored reports for which fn returns true and returns the number removed
+	Delete(fn func(report CrashReport) bool) (int, error)
+}
+
+// WithMaxReports keeps only the newest n reports in the crash file or crash directory, so crash
+// history doesn't accumulate on customer machines. JSON Lines crash files are trimmed in batches,
+// so they may briefly hold up to twice n reports
+func WithMaxReports(n int) Option {
+	return func(o *Options) {
+		o.MaxReports = n
+	}
+}
+
+// DeleteReportsOlderThan removes the stored crash reports created before t and returns the number
+// removed. The storage must implement ReportDeleter, as the crash file, crash directory and MemoryStore do
+func (ph *PanicHandler) DeleteReportsOlderThan(t time.Time) (int, error) {
+	return ph.DeleteCrashReports(func(report CrashReport) bool {
+		return report.Timestamp.Before(t)
+	})
+}
+
+// DeleteCrashReports removes the stored crash reports for which match returns true, e.g. the reports
+// of a resolved issue, and returns the number removed. The storage must implement ReportDeleter
+func (ph *PanicHandler) DeleteCrashReports(match func(report CrashReport) bool) (int, error) {
+	if ph.storage == nil {
+		return 0, fmt.Errorf("no file path set for crash reports")
+	}
+	deleter, ok := ph.storage.(ReportDeleter)
+	if !ok {
+		return 0, fmt.Errorf("storage %T can't delete crash reports", ph.storage)
+	}
+	removed, err := deleter.Delete(match)
+	if hooks := ph.fileHooks(); hooks.OnPrune != nil && removed > 0 {
+		hooks.OnPrune(removed)
+	}
+	return removed, err
+}
+
+// DeleteCrashReportsWithFingerprint removes the stored crash reports with the given fingerprint,
+// see Fingerprint, and returns the number removed
+func (ph *PanicHandler) DeleteCrashReportsWithFingerprint(fingerprint string) (int, error) {
+	return ph.DeleteCrashReports(func(report CrashReport) bool {
+		return Fingerprint(report) == fingerprint
+	})
+}
+
+// dropReports returns reports without those for which remove returns true. Kept reports stored
+// as a diff against a removed report have their full stack restored
+func dropReports(reports []CrashReport, remove func(i int, report CrashReport) bool) []CrashReport {
+	removed := make(map[string]string)
+	kept := make([]CrashReport, 0, len(reports))
+	for i, report := range reports {
+		if remove(i, report) {
+			if report.StackDiff == nil && report.ID != "" {
+				removed[report.ID] = report.Stack
+			}
+			continue
+		}
+		kept = append(kept, report)
+	}
+	for i := range kept {
+		diff := kept[i].StackDiff
+		if diff == nil || kept[i].Stack != "" {
+			continue
+		}
+		if base, ok := removed[diff.Base]; ok {
+			kept[i].Stack = diff.apply(base)
+			kept[i].StackDiff = nil
+		}
+	}
+	return kept
+}
+
+// Delete removes the reports of the crash file for which fn returns true
+func (f *fileStorage) Delete(fn func(report CrashReport) bool) (int, error) {
+	if f.path == "" {
+		return 0, fmt.Errorf("no file path set for crash reports")
+	}
+	if _, err := f.files().Stat(f.path); os.IsNotExist(err) {
+		return 0, nil
+	}
+	count := 0
+	err := f.modify(func(reports []CrashReport) ([]CrashReport, error) {
+		kept := dropReports(reports, func(_ int, report CrashReport) bool { return fn(report) })
+		count = len(reports) - len(kept)
+		return kept, nil
+	})
+	return count, err
+}
+
+// trimLines trims a JSON Lines crash file to the newest maxReports reports once it holds twice
+// as many, so the file isn't rewritten for every report. The reports are counted on first use
+func (f *fileStorage) trimLines() {
+	if !f.counted {
+		data, err := f.readFile()
+		if err != nil {
+			return
+		}
+		f.lines, f.counted = bytes.Count(data, []byte("\n")), true
+	} else {
+		f.lines++
+	}
+	if f.lines < 2*f.maxReports {
+		return
+	}
+	err := f.modifyLocked(func(reports []CrashReport) ([]CrashReport, error) {
+		if len(reports) > f.maxReports {
+			f.pruned(len(reports) - f.maxReports)
+			reports = reports[len(reports)-f.maxReports:]
+		}
+		return reports, nil
+	})
+	if err != nil {
+		f.diagnose(OpWrite, f.path, err)
+	}
+}
@@ -0,0 +1,139 @@
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxReports(t *testing.T) {
+	for _, format := range []FileFormat{FormatJSON, FormatJSONLines} {
+		filePath := filepath.Join(t.TempDir(), "crash.json")
+		ph := New(Options{
+			ErrorHandler: func(error, []byte) {},
+			DumpToFile:   true,
+			FilePath:     filePath,
+			FileFormat:   format,
+		}, WithMaxReports(3))
+		for i := 0; i < 8; i++ {
+			func() {
+				defer ph.Recover()
+				panic(i)
+			}()
+		}
+
+		reports, err := ph.GetLastNCrashReports(10)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if format == FormatJSON && len(reports) != 3 || format == FormatJSONLines && (len(reports) < 3 || len(reports) >= 6) {
+			t.Errorf("Format %d: unexpected number of reports %d", format, len(reports))
+		}
+		if last := reports[len(reports)-1]; last.Error != "7" {
+			t.Errorf("Format %d: expected the newest report to be kept, got %s", format, last.Error)
+		}
+	}
+}
+
+func TestMaxReportsCrashDir(t *testing.T) {
+	dir := t.TempDir()
+	storage := &dirStorage{dir: dir, maxReports: 2, diagnose: func(string, string, error) {}}
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		storage.Append(CrashReport{ID: strconv.Itoa(i), Timestamp: start.Add(time.Duration(i) * time.Second)})
+	}
+	reports, _ := storage.LastN(-1)
+	if len(reports) != 2 || reports[0].ID != "2" || reports[1].ID != "3" {
+		t.Errorf("Expected the newest 2 reports, got %+v", reports)
+	}
+}
+
+func TestDeleteReportsOlderThan(t *testing.T) {
+	now := time.Now()
+	reports := []CrashReport{
+		{ID: "1", Timestamp: now.Add(-3 * time.Hour)},
+		{ID: "2", Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "3", Timestamp: now},
+	}
+	storages := map[string]Storage{
+		"file":   newFileStorage(Options{FilePath: filepath.Join(t.TempDir(), "crash.json")}, func(string, string, error) {}),
+		"dir":    &dirStorage{dir: t.TempDir(), diagnose: func(string, string, error) {}},
+		"memory": NewMemoryStore(10),
+	}
+	for name, storage := range storages {
+		ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(storage))
+		for _, report := range reports {
+			storage.Append(report)
+		}
+		count, err := ph.DeleteReportsOlderThan(now.Add(-time.Hour))
+		if err != nil || count != 2 {
+			t.Errorf("%s: expected 2 reports to be deleted, got %d, error %v", name, count, err)
+		}
+		if kept, _ := ph.GetLastNCrashReports(10); len(kept) != 1 || kept[0].ID != "3" {
+			t.Errorf("%s: expected report 3 to be kept, got %+v", name, kept)
+		}
+	}
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(&memoryStorage{}))
+	if _, err := ph.DeleteReportsOlderThan(now); err == nil || !strings.Contains(err.Error(), "can't delete") {
+		t.Errorf("Expected an error for a storage without Delete, got %v", err)
+	}
+}
+
+func TestDeleteCrashReports(t *testing.T) {
+	var pruned []int
+	filePath := filepath.Join(t.TempDir(), "crash.jsonl")
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filePath},
+		WithFileFormat(FormatJSONLines), WithFileHooks(FileHooks{OnPrune: func(removed int) { pruned = append(pruned, removed) }}))
+	for _, report := range []CrashReport{
+		{ID: "1", Fingerprint: "resolved", Metadata: map[string]string{"version": "1.0"}},
+		{ID: "2", Fingerprint: "open", Metadata: map[string]string{"version": "1.0"}},
+		{ID: "3", Fingerprint: "resolved", Metadata: map[string]string{"version": "1.1"}},
+		{ID: "4", Fingerprint: "open", Metadata: map[string]string{"version": "1.1"}},
+	} {
+		ph.storage.Append(report)
+	}
+
+	count, err := ph.DeleteCrashReportsWithFingerprint("resolved")
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 reports to be deleted, got %d, error %v", count, err)
+	}
+	count, err = ph.DeleteCrashReports(func(report CrashReport) bool {
+		return report.Metadata["version"] == "1.0"
+	})
+	if err != nil || count != 1 {
+		t.Errorf("Expected 1 report to be deleted, got %d, error %v", count, err)
+	}
+	if kept, _ := ph.GetLastNCrashReports(10); len(kept) != 1 || kept[0].ID != "4" {
+		t.Errorf("Expected report 4 to be kept, got %+v", kept)
+	}
+	if count, err := ph.DeleteCrashReportsWithFingerprint("unknown"); err != nil || count != 0 {
+		t.Errorf("Expected no reports to be deleted, got %d, error %v", count, err)
+	}
+	if len(pruned) != 2 || pruned[0] != 2 || pruned[1] != 1 {
+		t.Errorf("Expected the prune hook to be called for each deletion, got %v", pruned)
+	}
+}
+
+func TestDeleteKeepsStackDiffs(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	storage := newFileStorage(Options{FilePath: filePath, StackDiffs: true}, func(string, string, error) {})
+	old := CrashReport{ID: "base", Timestamp: time.Now().Add(-time.Hour), ErrorType: "string", Stack: testStack}
+	recent := CrashReport{ID: "recent", Timestamp: time.Now(), ErrorType: "string", Stack: strings.Replace(testStack, "main.go:10", "main.go:11", 1)}
+	storage.Append(old)
+	storage.Append(recent)
+	if data, _ := os.ReadFile(filePath); !strings.Contains(string(data), "stack_diff") {
+		t.Fatalf("Expected the second report to be stored as a diff")
+	}
+
+	if _, err := storage.Delete(func(report CrashReport) bool { return report.ID == "base" }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reports, _ := storage.LastN(-1)
+	if len(reports) != 1 || reports[0].Stack != recent.Stack || reports[0].StackDiff != nil {
+		t.Errorf("Expected the full stack to be restored, got %+v", reports)
+	}
+}
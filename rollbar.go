@@ -0,0 +1,120 @@
+package adfer
+
+import (
+	"context"
+	"net/http"
+	"os"
+)
+
+// RollbarOptions configures a RollbarReporter
+type RollbarOptions struct {
+	// AccessToken is a project access token with the "post_server_item" scope
+	AccessToken string
+	// Environment is the environment the program runs in, e.g. "production". Defaults to "production"
+	Environment string
+	// CodeVersion is the version of the program, e.g. a git SHA or release tag
+	CodeVersion string
+	// URL is the item endpoint. Defaults to "https://api.rollbar.com/api/1/item/"
+	URL string
+	// HTTPClient is the client used to send items. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// RollbarReporter sends crash reports to Rollbar
+type RollbarReporter struct {
+	options RollbarOptions
+}
+
+// NewRollbarReporter creates a RollbarReporter from the given options
+func NewRollbarReporter(options RollbarOptions) *RollbarReporter {
+	if options.Environment == "" {
+		options.Environment = "production"
+	}
+	if options.URL == "" {
+		options.URL = "https://api.rollbar.com/api/1/item/"
+	}
+	return &RollbarReporter{options: options}
+}
+
+// WithRollbar sends every crash report to Rollbar
+func WithRollbar(options RollbarOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewRollbarReporter(options))
+	}
+}
+
+// Payload converts a crash report into a Rollbar item payload, with the report's severity as its
+// level and fatal crashes reported as critical
+func (r *RollbarReporter) Payload(report CrashReport) map[string]any {
+	level := severityLevel(report, "critical")
+	errorType := report.ErrorType
+	if errorType == "" {
+		errorType = "panic"
+	}
+
+	// Rollbar expects the most recent call last
+	parsed := ParseStack(report.Stack)
+	frames := make([]map[string]any, 0, len(parsed))
+	for i := len(parsed) - 1; i >= 0; i-- {
+		frames = append(frames, map[string]any{
+			"filename": parsed[i].File,
+			"lineno":   parsed[i].Line,
+			"method":   parsed[i].Function,
+		})
+	}
+
+	custom := map[string]any{}
+	for key, value := range report.Metadata {
+		custom[key] = value
+	}
+	if len(report.Tags) > 0 {
+		custom["tags"] = report.Tags
+	}
+	if report.Category != "" {
+		custom["category"] = report.Category
+	}
+
+	data := map[string]any{
+		"environment": r.options.Environment,
+		"level":       level,
+		"timestamp":   report.Timestamp.Unix(),
+		"platform":    "go",
+		"language":    "go",
+		"framework":   "adfer",
+		"fingerprint": Fingerprint(report),
+		"body": map[string]any{
+			"trace": map[string]any{
+				"frames": frames,
+				"exception": map[string]any{
+					"class":   errorType,
+					"message": report.Error,
+				},
+			},
+		},
+		"server": map[string]any{
+			"host": reportHost(report),
+			"pid":  os.Getpid(),
+		},
+		"notifier": map[string]any{"name": "adfer"},
+		"custom":   custom,
+	}
+	if report.ID != "" {
+		data["uuid"] = report.ID
+	}
+	if r.options.CodeVersion != "" {
+		data["code_version"] = r.options.CodeVersion
+	}
+	return map[string]any{"data": data}
+}
+
+// Name returns the name used in delivery receipts
+func (r *RollbarReporter) Name() string {
+	return "rollbar"
+}
+
+// Report sends the crash report to Rollbar
+func (r *RollbarReporter) Report(ctx context.Context, report CrashReport) error {
+	return postJSON(ctx, r.options.HTTPClient, r.options.URL, r.Payload(report), map[string]string{
+		"X-Rollbar-Access-Token": r.options.AccessToken,
+	})
+}
@@ -0,0 +1,55 @@
+package adfer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRollbarReporter(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	reporter := NewRollbarReporter(RollbarOptions{
+		AccessToken: "token",
+		CodeVersion: "abc123",
+		URL:         server.URL,
+	})
+	report := CrashReport{
+		ID:        "crash-1",
+		Timestamp: time.Unix(1700000000, 0),
+		Error:     "nil map",
+		ErrorType: "runtime.Error",
+		Stack:     testStack,
+		Metadata:  map[string]string{"region": "eu"},
+	}
+
+	if err := reporter.Report(context.Background(), report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	request := requests()[0]
+	if request.Headers.Get("X-Rollbar-Access-Token") != "token" {
+		t.Errorf("Unexpected token header: %s", request.Headers.Get("X-Rollbar-Access-Token"))
+	}
+	data := request.Body["data"].(map[string]any)
+	if data["level"] != "critical" || data["environment"] != "production" || data["uuid"] != "crash-1" ||
+		data["code_version"] != "abc123" || data["timestamp"] != float64(1700000000) {
+		t.Errorf("Unexpected data: %v", data)
+	}
+	trace := data["body"].(map[string]any)["trace"].(map[string]any)
+	exception := trace["exception"].(map[string]any)
+	if exception["class"] != "runtime.Error" || exception["message"] != "nil map" {
+		t.Errorf("Unexpected exception: %v", exception)
+	}
+	frames := trace["frames"].([]any)
+	if len(frames) != 3 || frames[2].(map[string]any)["method"] != "runtime/debug.Stack" ||
+		frames[0].(map[string]any)["method"] != "main.main" {
+		t.Errorf("Expected the most recent call last, got %v", frames)
+	}
+	if data["custom"].(map[string]any)["region"] != "eu" {
+		t.Errorf("Unexpected custom data: %v", data["custom"])
+	}
+
+	handled := reporter.Payload(CrashReport{Error: "boom", Handled: true})["data"].(map[string]any)
+	if handled["level"] != "error" {
+		t.Errorf("Expected handled errors to have level error, got %v", handled["level"])
+	}
+}
@@ -0,0 +1,155 @@
+package adfer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultMaxFileBackups is the number of rotated crash files kept if rotation is enabled
+const defaultMaxFileBackups = 5
+
+// WithMaxFileSize rotates the crash file once it reaches size bytes. The file is compressed to
+// <path>.1.gz, and earlier rotated files are renamed to <path>.2.gz and so on
+func WithMaxFileSize(size int64) Option {
+	return func(o *Options) {
+		o.MaxFileSize = size
+	}
+}
+
+// WithMaxFileAge rotates the crash file once its first report is older than age
+func WithMaxFileAge(age time.Duration) Option {
+	return func(o *Options) {
+		o.MaxFileAge = age
+	}
+}
+
+// WithMaxFileBackups sets the number of rotated crash files kept. Older files are removed. Defaults to 5
+func WithMaxFileBackups(count int) Option {
+	return func(o *Options) {
+		o.MaxFileBackups = count
+	}
+}
+
+// rotates returns true if crash file rotation is enabled
+func (f *fileStorage) rotates() bool {
+	return f.maxSize > 0 || f.maxAge > 0
+}
+
+// shouldRotate returns true if a crash file of the given size, whose first report
+// was created at first, has reached the maximum size or age
+func (f *fileStorage) shouldRotate(size int64, first time.Time) bool {
+	if size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && size >= f.maxSize {
+		return true
+	}
+	return f.maxAge > 0 && !first.IsZero() && time.Since(first) >= f.maxAge
+}
+
+// rotateLines rotates a JSON Lines crash file if it has reached the maximum size or age.
+// Failures are reported as diagnostics and the file is kept
+func (f *fileStorage) rotateLines() {
+	info, err := f.files().Stat(f.path)
+	if err != nil {
+		return
+	}
+	var first time.Time
+	if f.maxAge > 0 {
+		first = f.firstLineTimestamp()
+	}
+	if f.shouldRotate(info.Size(), first) {
+		if err := f.rotate(); err != nil {
+			f.diagnose(OpRotate, f.path, err)
+		}
+	}
+}
+
+// firstLineTimestamp returns the timestamp of the first report of a JSON Lines crash file
+func (f *fileStorage) firstLineTimestamp() time.Time {
+	file, err := openCrashFile(f.files(), f.path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return time.Time{}
+	}
+	var report CrashReport
+	if json.Unmarshal(line, &report) != nil {
+		return time.Time{}
+	}
+	return report.Timestamp
+}
+
+// rotate compresses the crash file to <path>.1.gz, shifting earlier rotated files up and
+// removing the oldest, then removes the crash file and its index
+func (f *fileStorage) rotate() error {
+	backups := f.maxBackups
+	if backups <= 0 {
+		backups = defaultMaxFileBackups
+	}
+	files := f.files()
+	if err := files.Remove(f.backupPath(backups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := backups - 1; i >= 1; i-- {
+		if err := files.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := f.compress(f.backupPath(1)); err != nil {
+		return err
+	}
+	if err := files.Remove(f.path); err != nil {
+		return err
+	}
+	if err := files.Remove(f.indexPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f.lines, f.counted = 0, true
+	if f.hooks.OnRotate != nil {
+		backup := f.backupPath(1)
+		f.emit(func() { f.hooks.OnRotate(backup) })
+	}
+	return nil
+}
+
+// backupPath returns the path of the nth rotated crash file
+func (f *fileStorage) backupPath(n int) string {
+	return f.path + "." + strconv.Itoa(n) + ".gz"
+}
+
+// compress writes the gzip compressed crash file to path
+func (f *fileStorage) compress(path string) error {
+	files := f.files()
+	data, err := files.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	if !isGzip(data) {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	tmp := path + ".tmp"
+	err = f.writeFile(tmp, data)
+	if err == nil {
+		err = files.Rename(tmp, path)
+	}
+	if err != nil {
+		files.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package adfer
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readRotated decompresses a rotated JSON crash file
+func readRotated(t *testing.T, path string) []CrashReport {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected rotated file %s: %v", path, err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	var reports []CrashReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return reports
+}
+
+func TestRotateBySize(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "panic.log")
+	storage := newFileStorage(Options{
+		FilePath:       filePath,
+		Index:          true,
+		MaxFileSize:    1,
+		MaxFileBackups: 2,
+	}, func(op, path string, err error) { t.Errorf("Unexpected diagnostic %s %s: %v", op, path, err) })
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := storage.Append(CrashReport{ID: id, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	current, err := storage.LastN(-1)
+	if err != nil || len(current) != 1 || current[0].ID != "4" {
+		t.Fatalf("Expected the crash file to hold the last report, got %+v, error %v", current, err)
+	}
+	if rotated := readRotated(t, filePath+".1.gz"); len(rotated) != 1 || rotated[0].ID != "3" {
+		t.Errorf("Expected report 3 in the first rotated file, got %+v", rotated)
+	}
+	if rotated := readRotated(t, filePath+".2.gz"); len(rotated) != 1 || rotated[0].ID != "2" {
+		t.Errorf("Expected report 2 in the second rotated file, got %+v", rotated)
+	}
+	if _, err := os.Stat(filePath + ".3.gz"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 rotated files to be kept")
+	}
+	if err := storage.verify(); err != nil {
+		t.Errorf("Expected the index to match the new crash file: %v", err)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "panic.log")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+	}, WithFileFormat(FormatJSONLines), WithMaxFileAge(time.Hour))
+	storage := ph.storage.(*fileStorage)
+	storage.Append(CrashReport{ID: "old", Timestamp: time.Now().Add(-2 * time.Hour)})
+	func() {
+		defer ph.Recover()
+		panic("new")
+	}()
+
+	reports, err := ph.GetLastNCrashReports(10)
+	if err != nil || len(reports) != 1 || reports[0].Error != "new" {
+		t.Fatalf("Expected the crash file to be rotated, got %+v, error %v", reports, err)
+	}
+	if _, err := os.Stat(filePath + ".1.gz"); err != nil {
+		t.Errorf("Expected a rotated file: %v", err)
+	}
+	func() {
+		defer ph.Recover()
+		panic("newer")
+	}()
+	if reports, _ := ph.GetLastNCrashReports(10); len(reports) != 2 {
+		t.Errorf("Expected no rotation for a recent file, got %d reports", len(reports))
+	}
+}
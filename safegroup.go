@@ -0,0 +1,60 @@
+package adfer
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs functions in goroutines with panic recovery and returns the first error, like
+// errgroup.Group. Panics are reported through the handler and returned as a *PanicError. The group's
+// context is cancelled once a function fails, Wait returns or the handler shuts down with Close
+type Group struct {
+	ph     *PanicHandler
+	parent context.Context
+	ctx    context.Context
+	cancel func()
+
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+// Group returns a Group whose context is derived from ctx. Tags stored in ctx are added to the crash
+// reports of its panics
+func (ph *PanicHandler) Group(ctx context.Context) *Group {
+	groupCtx, cancel := ph.contexts.derive(ctx)
+	return &Group{ph: ph, parent: ctx, ctx: groupCtx, cancel: cancel}
+}
+
+// Context returns the group's context, cancelled once a function fails or Wait returns
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go runs f in a goroutine with panic recovery. The first function to return an error or panic
+// cancels the group's context, and its error is returned by Wait
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		var err error
+		// Panics are handled with the parent context, as the group's context may be cancelled
+		// by then and reporters would fail with it
+		g.ph.call(g.parent, func() {
+			err = f()
+		}, &err)
+		if err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait waits for every function started with Go, cancels the group's context and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
@@ -0,0 +1,58 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	})
+
+	g := ph.Group(WithTags(context.Background(), "job=import"))
+	g.Go(func() error {
+		panic("worker failed")
+	})
+	g.Go(func() error {
+		select {
+		case <-g.Context().Done():
+			return g.Context().Err()
+		case <-time.After(time.Second):
+			return errors.New("expected the group context to be cancelled")
+		}
+	})
+
+	err := g.Wait()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "worker failed" {
+		t.Fatalf("Expected the panic as the first error, got %v", err)
+	}
+	report := <-reports
+	if panicErr.ReportID != report.ID || len(report.Tags) != 1 || report.Tags[0] != "job=import" {
+		t.Errorf("Expected the panic to be reported with the group's tags, got %+v", report)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	ph := New(Options{ErrorHandler: func(error, []byte) {}})
+	g := ph.Group(context.Background())
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected no error for an empty group, got %v", err)
+	}
+
+	g = ph.Group(context.Background())
+	want := errors.New("failed")
+	g.Go(func() error { return nil })
+	g.Go(func() error { return want })
+	if err := g.Wait(); err != want {
+		t.Errorf("Expected the returned error, got %v", err)
+	}
+	if g.Context().Err() == nil {
+		t.Error("Expected Wait to cancel the group's context")
+	}
+}
@@ -0,0 +1,42 @@
+package adfer
+
+import "context"
+
+type scopeKey struct{}
+
+// scope is an entry of the scope stack stored in a context
+type scope struct {
+	name   string
+	parent *scope
+	depth  int
+}
+
+// PushScope returns a copy of ctx with name pushed onto its scope stack, e.g.
+// adfer.PushScope(ctx, "parsing invoice 42"). The scope stack is recorded in crash reports
+// recovered by RecoverCtx and SafeGoCtx, or reported with WithReportContext, as a breadcrumb
+// trail of the logical operations that led to the panic. The scope ends with the context, so
+// it doesn't need to be popped
+func PushScope(ctx context.Context, name string) context.Context {
+	parent, _ := ctx.Value(scopeKey{}).(*scope)
+	depth := 1
+	if parent != nil {
+		depth = parent.depth + 1
+	}
+	return context.WithValue(ctx, scopeKey{}, &scope{name: name, parent: parent, depth: depth})
+}
+
+// ScopesFromContext returns the scope stack stored in ctx, outermost first
+func ScopesFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	top, _ := ctx.Value(scopeKey{}).(*scope)
+	if top == nil {
+		return nil
+	}
+	scopes := make([]string, top.depth)
+	for s := top; s != nil; s = s.parent {
+		scopes[s.depth-1] = s.name
+	}
+	return scopes
+}
@@ -0,0 +1,47 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPushScope(t *testing.T) {
+	ctx := PushScope(context.Background(), "handling request 7")
+	invoice := PushScope(ctx, "parsing invoice 42")
+	line := PushScope(invoice, "reading line 3")
+	sibling := PushScope(ctx, "sending receipt")
+
+	if got := strings.Join(ScopesFromContext(line), " > "); got != "handling request 7 > parsing invoice 42 > reading line 3" {
+		t.Errorf("Unexpected scopes %s", got)
+	}
+	if got := strings.Join(ScopesFromContext(sibling), " > "); got != "handling request 7 > sending receipt" {
+		t.Errorf("Unexpected scopes %s", got)
+	}
+	if scopes := ScopesFromContext(context.Background()); scopes != nil {
+		t.Errorf("Expected no scopes, got %v", scopes)
+	}
+}
+
+func TestScopesInReports(t *testing.T) {
+	storage := NewMemoryStore(10)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(storage))
+	ctx := PushScope(PushScope(context.Background(), "importing batch"), "parsing invoice 42")
+
+	func() {
+		defer ph.RecoverCtx(ctx)
+		panic("bad total")
+	}()
+	ph.Report(errors.New("slow parse"), WithReportContext(ctx))
+
+	reports, _ := storage.LastN(-1)
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+	for _, report := range reports {
+		if strings.Join(report.Scopes, ",") != "importing batch,parsing invoice 42" {
+			t.Errorf("Expected the scope stack in %q, got %v", report.Error, report.Scopes)
+		}
+	}
+}
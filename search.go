@@ -0,0 +1,65 @@
+package adfer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SearchQuery selects crash reports for SearchCrashReports. A report matches if it matches every
+// filter that is set
+type SearchQuery struct {
+	// Error matches reports whose error contains it
+	Error string
+	// ErrorPattern matches reports whose error matches it
+	ErrorPattern *regexp.Regexp
+	// Stack matches reports whose stack contains it, e.g. a function or file name
+	Stack string
+	// StackPattern matches reports whose stack matches it
+	StackPattern *regexp.Regexp
+	// Metadata matches reports with each of its keys set to its value
+	Metadata map[string]string
+	// Limit is the maximum number of reports returned, oldest first. No limit if 0
+	Limit int
+}
+
+// SearchCrashReports retrieves the crash reports matching query, oldest first. The crash file is
+// searched one report at a time, so only the matching reports are kept in memory
+func (s *reportStore) SearchCrashReports(query SearchQuery) ([]CrashReport, error) {
+	if s.storage == nil {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	var result []CrashReport
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		if query.matches(report) {
+			result = append(result, report)
+		}
+		return query.Limit <= 0 || len(result) < query.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// matches reports whether a report matches every filter of the query
+func (q SearchQuery) matches(report CrashReport) bool {
+	if q.Error != "" && !strings.Contains(report.Error, q.Error) {
+		return false
+	}
+	if q.ErrorPattern != nil && !q.ErrorPattern.MatchString(report.Error) {
+		return false
+	}
+	if q.Stack != "" && !strings.Contains(report.Stack, q.Stack) {
+		return false
+	}
+	if q.StackPattern != nil && !q.StackPattern.MatchString(report.Stack) {
+		return false
+	}
+	for key, value := range q.Metadata {
+		if actual, ok := report.Metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
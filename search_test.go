@@ -0,0 +1,54 @@
+package adfer
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSearchCrashReports(t *testing.T) {
+	for name, format := range map[string]FileFormat{"json": FormatJSON, "jsonl": FormatJSONLines} {
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "crash")
+			writeCrashFile(t, filePath, format,
+				CrashReport{ID: "a", Error: "dial tcp: connection refused", Stack: "main.connect()", Metadata: map[string]string{"region": "eu"}},
+				CrashReport{ID: "b", Error: "index out of range [5] with length 3", Stack: "main.parse()", Metadata: map[string]string{"region": "us"}},
+				CrashReport{ID: "c", Error: "index out of range [7] with length 2", Stack: "main.parse()", Metadata: map[string]string{"region": "eu"}},
+			)
+			reader, err := OpenReadOnly(filePath)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			tests := []struct {
+				name     string
+				query    SearchQuery
+				expected []string
+			}{
+				{name: "all", query: SearchQuery{}, expected: []string{"a", "b", "c"}},
+				{name: "error", query: SearchQuery{Error: "refused"}, expected: []string{"a"}},
+				{name: "error pattern", query: SearchQuery{ErrorPattern: regexp.MustCompile(`range \[\d+\] with length 2`)}, expected: []string{"c"}},
+				{name: "stack", query: SearchQuery{Stack: "main.parse"}, expected: []string{"b", "c"}},
+				{name: "stack pattern", query: SearchQuery{StackPattern: regexp.MustCompile(`^main\.conn`)}, expected: []string{"a"}},
+				{name: "metadata", query: SearchQuery{Metadata: map[string]string{"region": "eu"}}, expected: []string{"a", "c"}},
+				{name: "combined", query: SearchQuery{Stack: "parse", Metadata: map[string]string{"region": "eu"}}, expected: []string{"c"}},
+				{name: "missing metadata", query: SearchQuery{Metadata: map[string]string{"version": ""}}, expected: nil},
+				{name: "limit", query: SearchQuery{Error: "index", Limit: 1}, expected: []string{"b"}},
+			}
+			for _, test := range tests {
+				reports, err := reader.SearchCrashReports(test.query)
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", test.name, err)
+				}
+				var ids []string
+				for _, report := range reports {
+					ids = append(ids, report.ID)
+				}
+				if !reflect.DeepEqual(ids, test.expected) {
+					t.Errorf("%s: expected %v, got %v", test.name, test.expected, ids)
+				}
+			}
+		})
+	}
+}
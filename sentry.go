@@ -0,0 +1,258 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SentryOptions configures a SentryReporter
+type SentryOptions struct {
+	// DSN is the Sentry project DSN, e.g. "https://key@o1.ingest.sentry.io/42"
+	DSN string
+	// Release is the application release the crash happened in
+	Release string
+	// Environment is the deployment environment, e.g. "production"
+	Environment string
+	// ServerName identifies the host the crash happened on
+	ServerName string
+	// HTTPClient is the client used to send events. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// SentryDSN is a parsed Sentry DSN
+type SentryDSN struct {
+	Scheme    string
+	PublicKey string
+	Host      string
+	Path      string
+	ProjectID string
+}
+
+// ParseSentryDSN parses a DSN of the form "scheme://publickey@host[/path]/projectid"
+func ParseSentryDSN(dsn string) (*SentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid sentry dsn: unsupported scheme '%s'", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing public key")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing host")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 || path[idx+1:] == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing project id")
+	}
+	return &SentryDSN{
+		Scheme:    u.Scheme,
+		PublicKey: u.User.Username(),
+		Host:      u.Host,
+		Path:      path[:idx],
+		ProjectID: path[idx+1:],
+	}, nil
+}
+
+// EnvelopeURL returns the URL envelopes are posted to
+func (d *SentryDSN) EnvelopeURL() string {
+	return fmt.Sprintf("%s://%s%s/api/%s/envelope/", d.Scheme, d.Host, d.Path, d.ProjectID)
+}
+
+// String returns the DSN in its original form
+func (d *SentryDSN) String() string {
+	return fmt.Sprintf("%s://%s@%s%s/%s", d.Scheme, d.PublicKey, d.Host, d.Path, d.ProjectID)
+}
+
+// SentryEvent is the subset of the Sentry event payload produced by adfer
+type SentryEvent struct {
+	EventID     string                    `json:"event_id"`
+	Timestamp   string                    `json:"timestamp"`
+	Platform    string                    `json:"platform"`
+	Level       string                    `json:"level"`
+	Logger      string                    `json:"logger,omitempty"`
+	Release     string                    `json:"release,omitempty"`
+	Environment string                    `json:"environment,omitempty"`
+	ServerName  string                    `json:"server_name,omitempty"`
+	Message     string                    `json:"message,omitempty"`
+	Exception   *SentryExceptions         `json:"exception,omitempty"`
+	Contexts    map[string]map[string]any `json:"contexts,omitempty"`
+	Tags        map[string]string         `json:"tags,omitempty"`
+	Extra       map[string]any            `json:"extra,omitempty"`
+}
+
+// SentryExceptions is the Sentry exception interface
+type SentryExceptions struct {
+	Values []SentryException `json:"values"`
+}
+
+// SentryException is a single exception within a Sentry event
+type SentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Module     string            `json:"module,omitempty"`
+	Stacktrace *SentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+// SentryStacktrace is the Sentry stacktrace interface. Frames are ordered oldest call first
+type SentryStacktrace struct {
+	Frames []SentryFrame `json:"frames"`
+}
+
+// SentryFrame is a single frame within a Sentry stacktrace
+type SentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// SentryReporter sends crash reports to Sentry using the envelope endpoint
+type SentryReporter struct {
+	dsn     *SentryDSN
+	options SentryOptions
+}
+
+// NewSentryReporter creates a SentryReporter from the given options
+func NewSentryReporter(options SentryOptions) (*SentryReporter, error) {
+	dsn, err := ParseSentryDSN(options.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &SentryReporter{
+		dsn:     dsn,
+		options: options,
+	}, nil
+}
+
+// Event converts a crash report into a Sentry event, with the report's severity as its level
+func (s *SentryReporter) Event(report CrashReport) SentryEvent {
+	event := SentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   report.Timestamp.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       severityLevel(report, "fatal"),
+		Logger:      "adfer",
+		Release:     s.options.Release,
+		Environment: s.options.Environment,
+		ServerName:  s.options.ServerName,
+	}
+
+	errorType := report.ErrorType
+	if errorType == "" {
+		errorType = "panic"
+	}
+	exception := SentryException{
+		Type:  errorType,
+		Value: report.Error,
+	}
+	frames := ParseStack(report.Stack)
+	if len(frames) > 0 {
+		stacktrace := &SentryStacktrace{}
+		// Sentry expects the oldest frame first
+		for i := len(frames) - 1; i >= 0; i-- {
+			frame := frames[i]
+			stacktrace.Frames = append(stacktrace.Frames, SentryFrame{
+				Function: frame.Function,
+				Module:   frame.Package(),
+				Filename: frame.File[strings.LastIndex(frame.File, "/")+1:],
+				AbsPath:  frame.File,
+				Lineno:   frame.Line,
+				InApp:    !frame.isRuntime(),
+			})
+		}
+		exception.Stacktrace = stacktrace
+	}
+	event.Exception = &SentryExceptions{Values: []SentryException{exception}}
+
+	if report.SystemInfo.OS != "" {
+		event.Contexts = map[string]map[string]any{
+			"os":      {"name": report.SystemInfo.OS},
+			"device":  {"arch": report.SystemInfo.Architecture},
+			"runtime": {"name": "go", "version": report.SystemInfo.GoVersion},
+		}
+	}
+	if len(report.Metadata) > 0 || len(report.Tags) > 0 {
+		event.Tags = make(map[string]string, len(report.Metadata)+len(report.Tags))
+		for key, value := range report.Metadata {
+			event.Tags[key] = value
+		}
+		for _, tag := range report.Tags {
+			key, value, found := strings.Cut(tag, "=")
+			if !found {
+				value = "true"
+			}
+			event.Tags[key] = value
+		}
+	}
+	return event
+}
+
+// Envelope converts a crash report into a Sentry envelope containing a single event
+func (s *SentryReporter) Envelope(report CrashReport) ([]byte, error) {
+	event := s.Event(report)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"dsn":      s.dsn.String(),
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	itemHeader, err := json.Marshal(map[string]any{
+		"type":   "event",
+		"length": len(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(itemHeader)
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Name returns the name used in delivery receipts
+func (s *SentryReporter) Name() string {
+	return "sentry"
+}
+
+// Report sends the crash report to Sentry
+func (s *SentryReporter) Report(ctx context.Context, report CrashReport) error {
+	envelope, err := s.Envelope(report)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.options.HTTPClient, s.dsn.EnvelopeURL(), "application/x-sentry-envelope", envelope, map[string]string{
+		"X-Sentry-Auth": fmt.Sprintf("Sentry sentry_version=7, sentry_client=adfer/1.0, sentry_key=%s", s.dsn.PublicKey),
+	})
+}
+
+// newEventID returns a random 32 character hex string
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
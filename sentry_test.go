@@ -0,0 +1,147 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSentryDSN(t *testing.T) {
+	dsn, err := ParseSentryDSN("https://abc123@o1.ingest.sentry.io/prefix/42")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dsn.PublicKey != "abc123" || dsn.Host != "o1.ingest.sentry.io" || dsn.Path != "/prefix" || dsn.ProjectID != "42" {
+		t.Errorf("Unexpected DSN: %+v", dsn)
+	}
+	if dsn.EnvelopeURL() != "https://o1.ingest.sentry.io/prefix/api/42/envelope/" {
+		t.Errorf("Unexpected envelope URL '%s'", dsn.EnvelopeURL())
+	}
+
+	invalid := []string{
+		"",
+		"ftp://key@host/1",
+		"https://host/1",
+		"https://key@host/",
+	}
+	for _, value := range invalid {
+		if _, err := ParseSentryDSN(value); err == nil {
+			t.Errorf("Expected error for DSN '%s'", value)
+		}
+	}
+}
+
+func TestSentryEvent(t *testing.T) {
+	reporter, err := NewSentryReporter(SentryOptions{
+		DSN:         "https://key@sentry.example.com/1",
+		Release:     "1.0.0",
+		Environment: "production",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	event := reporter.Event(CrashReport{
+		Timestamp: time.Now(),
+		Error:     "boom",
+		ErrorType: "string",
+		Stack:     "goroutine 1 [running]:\nmain.inner()\n\t/app/main.go:5 +0x1\nmain.main()\n\t/app/main.go:10 +0x1\n",
+		SystemInfo: SystemInfo{
+			OS:           "linux",
+			Architecture: "amd64",
+			GoVersion:    "go1.22.0",
+		},
+		Metadata: map[string]string{"version": "1.0.0"},
+	})
+
+	if len(event.EventID) != 32 {
+		t.Errorf("Expected 32 character event id, got '%s'", event.EventID)
+	}
+	if event.Release != "1.0.0" || event.Environment != "production" {
+		t.Errorf("Unexpected release/environment: %s/%s", event.Release, event.Environment)
+	}
+	exception := event.Exception.Values[0]
+	if exception.Type != "string" || exception.Value != "boom" {
+		t.Errorf("Unexpected exception: %+v", exception)
+	}
+	frames := exception.Stacktrace.Frames
+	if len(frames) != 2 || frames[0].Function != "main.main" || frames[1].Function != "main.inner" {
+		t.Errorf("Expected frames oldest first, got %+v", frames)
+	}
+	if event.Contexts["os"]["name"] != "linux" {
+		t.Errorf("Expected os context, got %+v", event.Contexts)
+	}
+	if event.Tags["version"] != "1.0.0" {
+		t.Errorf("Expected metadata as tags, got %+v", event.Tags)
+	}
+}
+
+func TestSentryReporterReport(t *testing.T) {
+	var received []byte
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/7/envelope/" {
+			t.Errorf("Unexpected path '%s'", r.URL.Path)
+		}
+		auth = r.Header.Get("X-Sentry-Auth")
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/7"
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters: []Reporter{
+			mustSentryReporter(t, SentryOptions{DSN: dsn}),
+		},
+	})
+
+	func() {
+		defer ph.Recover()
+		panic("sentry panic")
+	}()
+
+	if !strings.Contains(auth, "sentry_key=public") {
+		t.Errorf("Unexpected auth header '%s'", auth)
+	}
+	lines := bytes.Split(bytes.TrimSpace(received), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 envelope lines, got %d", len(lines))
+	}
+	var event SentryEvent
+	if err := json.Unmarshal(lines[2], &event); err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	if event.Exception.Values[0].Value != "sentry panic" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestSentryReporterErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/7"
+	reporter := mustSentryReporter(t, SentryOptions{DSN: dsn})
+	err := reporter.Report(context.Background(), CrashReport{Timestamp: time.Now(), Error: "boom"})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
+
+func mustSentryReporter(t *testing.T, options SentryOptions) *SentryReporter {
+	t.Helper()
+	reporter, err := NewSentryReporter(options)
+	if err != nil {
+		t.Fatalf("Failed to create sentry reporter: %v", err)
+	}
+	return reporter
+}
@@ -0,0 +1,74 @@
+package adfer
+
+import "fmt"
+
+// Severity is the severity of a crash, e.g. to route fatal crashes to PagerDuty and warnings to Slack
+type Severity string
+
+const (
+	// SeverityFatal is the default severity of recovered panics
+	SeverityFatal Severity = "fatal"
+	// SeverityError is the default severity of handled errors, submitted with Report
+	SeverityError Severity = "error"
+	// SeverityWarning and SeverityInfo are set by classifiers, see WithClassifier
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// WithClassifier sets a function classifying the severity of crash reports. It receives the error,
+// or the formatted panic value, and the stack. If it returns an empty severity or panics, the default
+// severity is used: SeverityFatal for panics and SeverityError for handled errors
+func WithClassifier(classify func(err error, stack []byte) Severity) Option {
+	return func(o *Options) {
+		o.Classifier = classify
+	}
+}
+
+// severity returns the severity of a new crash report
+func (ph *PanicHandler) severity(err error, report CrashReport) Severity {
+	if ph.options.Classifier != nil {
+		if severity := ph.classify(err, []byte(report.Stack)); severity != "" {
+			return severity
+		}
+	}
+	if report.Handled {
+		return SeverityError
+	}
+	return SeverityFatal
+}
+
+// classify calls the classifier, recovering from its panics
+func (ph *PanicHandler) classify(err error, stack []byte) (severity Severity) {
+	defer func() {
+		if p := recover(); p != nil {
+			ph.diagnose(OpHook, "", fmt.Errorf("classifying severity: %v", p))
+			severity = ""
+		}
+	}()
+	return ph.options.Classifier(err, stack)
+}
+
+// severityLevel returns the report's severity as the level of a sink, using fatal for fatal crashes.
+// Reports without a severity, e.g. stored by an older version, are fatal if they were recovered from
+// a panic and "error" if they were handled
+func severityLevel(report CrashReport, fatal string) string {
+	switch report.Severity {
+	case "":
+		if report.Handled {
+			return string(SeverityError)
+		}
+		return fatal
+	case SeverityFatal:
+		return fatal
+	}
+	return string(report.Severity)
+}
+
+// severityOf maps the report's severity to a level of a sink. Reports without a severity, e.g. stored
+// by an older version, or with a severity the sink has no level for get fallback
+func severityOf[T any](report CrashReport, levels map[Severity]T, fallback T) T {
+	if level, ok := levels[report.Severity]; ok {
+		return level
+	}
+	return fallback
+}
@@ -0,0 +1,109 @@
+package adfer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSeverity(t *testing.T) {
+	reports := make(chan CrashReport, 2)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	})
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	ph.Report(errors.New("handled"))
+	if report := <-reports; report.Severity != SeverityFatal {
+		t.Errorf("Expected panics to be fatal, got %q", report.Severity)
+	}
+	if report := <-reports; report.Severity != SeverityError {
+		t.Errorf("Expected handled errors to be errors, got %q", report.Severity)
+	}
+}
+
+func TestWithClassifier(t *testing.T) {
+	reports := make(chan CrashReport, 3)
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+		Reporters:    []Reporter{channelReporter(reports)},
+	}, WithClassifier(func(err error, stack []byte) Severity {
+		switch {
+		case strings.Contains(err.Error(), "timeout"):
+			return SeverityWarning
+		case strings.Contains(err.Error(), "classifier bug"):
+			panic("classifier bug")
+		}
+		return ""
+	}))
+	for _, value := range []string{"upstream timeout", "classifier bug", "other"} {
+		func() {
+			defer ph.Recover()
+			panic(value)
+		}()
+	}
+
+	if report := <-reports; report.Severity != SeverityWarning {
+		t.Errorf("Expected the classified severity, got %q", report.Severity)
+	}
+	if report := <-reports; report.Severity != SeverityFatal {
+		t.Errorf("Expected the default severity when the classifier panics, got %q", report.Severity)
+	}
+	if report := <-reports; report.Severity != SeverityFatal {
+		t.Errorf("Expected the default severity for an empty classification, got %q", report.Severity)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpHook {
+		t.Errorf("Expected a diagnostic for the panicking classifier, got %+v", diagnostics)
+	}
+}
+
+func TestSeverityLevels(t *testing.T) {
+	reports := make(chan CrashReport, 3)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	}, WithClassifier(func(err error, stack []byte) Severity {
+		if strings.Contains(err.Error(), "timeout") {
+			return SeverityWarning
+		}
+		return ""
+	}))
+	func() {
+		defer ph.Recover()
+		panic("upstream timeout")
+	}()
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	ph.Report(errors.New("handled"))
+
+	loki := NewLokiReporter(LokiOptions{})
+	sentry, err := NewSentryReporter(SentryOptions{DSN: "https://key@sentry.example.com/1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rollbar := NewRollbarReporter(RollbarOptions{})
+	for _, expected := range []struct{ loki, sentry string }{{"warning", "warning"}, {"critical", "fatal"}, {"error", "error"}} {
+		report := <-reports
+		if level := loki.Labels(report)["severity"]; level != expected.loki {
+			t.Errorf("Expected the Loki severity %q for %q, got %q", expected.loki, report.Error, level)
+		}
+		if level := sentry.Event(report).Level; level != expected.sentry {
+			t.Errorf("Expected the Sentry level %q for %q, got %q", expected.sentry, report.Error, level)
+		}
+		if level := rollbar.Payload(report)["data"].(map[string]any)["level"]; level != expected.loki {
+			t.Errorf("Expected the Rollbar level %q for %q, got %v", expected.loki, report.Error, level)
+		}
+	}
+
+	// Reports stored without a severity fall back to whether they were handled
+	if level := sentry.Event(CrashReport{Error: "old"}).Level; level != "fatal" {
+		t.Errorf("Expected reports without a severity to be fatal, got %q", level)
+	}
+}
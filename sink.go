@@ -0,0 +1,126 @@
+package adfer
+
+import (
+	"context"
+	"fmt"
+)
+
+// FailurePolicy decides what happens when a sink fails to deliver a crash report
+type FailurePolicy int
+
+const (
+	// LogFailure reports the failure as a diagnostic. This is the default
+	LogFailure FailurePolicy = iota
+	// DropFailure silently drops the report. The delivery receipt is marked as dropped
+	DropFailure
+	// FallbackOnFailure delivers the report to the fallback reporter instead,
+	// and reports the original failure as a diagnostic
+	FallbackOnFailure
+)
+
+// String returns the name of the policy
+func (p FailurePolicy) String() string {
+	switch p {
+	case LogFailure:
+		return "log"
+	case DropFailure:
+		return "drop"
+	case FallbackOnFailure:
+		return "fallback"
+	}
+	return fmt.Sprintf("FailurePolicy(%d)", int(p))
+}
+
+// SinkOptions configures a Sink
+type SinkOptions struct {
+	// OnFailure is the policy applied when the reporter fails
+	OnFailure FailurePolicy
+	// Fallback receives the report when the reporter fails and OnFailure is FallbackOnFailure
+	Fallback Reporter
+}
+
+// Sink wraps a Reporter with a policy for handling its failures
+type Sink struct {
+	reporter Reporter
+	options  SinkOptions
+}
+
+// NewSink wraps the given reporter with a failure policy
+func NewSink(reporter Reporter, options SinkOptions) *Sink {
+	return &Sink{
+		reporter: reporter,
+		options:  options,
+	}
+}
+
+// WithSink adds a reporter with a failure policy
+func WithSink(reporter Reporter, options SinkOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewSink(reporter, options))
+	}
+}
+
+// WithReporters adds several reporters that each receive every crash report
+func WithReporters(reporters ...Reporter) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, reporters...)
+	}
+}
+
+// Unwrap returns the wrapped reporter
+func (s *Sink) Unwrap() Reporter {
+	return s.reporter
+}
+
+// Name returns the name of the wrapped reporter
+func (s *Sink) Name() string {
+	return reporterName(s.reporter)
+}
+
+// Report passes the report to the wrapped reporter, applying the failure policy if it fails
+func (s *Sink) Report(ctx context.Context, report CrashReport) error {
+	err := s.reporter.Report(ctx, report)
+	if err == nil {
+		return nil
+	}
+	switch s.options.OnFailure {
+	case DropFailure:
+		return &sinkError{err: err, policy: DropFailure}
+	case FallbackOnFailure:
+		if s.options.Fallback == nil {
+			return err
+		}
+		if fallbackErr := s.options.Fallback.Report(ctx, report); fallbackErr != nil {
+			return fmt.Errorf("%w (fallback failed: %v)", err, fallbackErr)
+		}
+		return &sinkError{err: err, policy: FallbackOnFailure}
+	}
+	return err
+}
+
+// withReporter returns a copy of the sink wrapping a different reporter
+func (s *Sink) withReporter(reporter Reporter) *Sink {
+	return &Sink{
+		reporter: reporter,
+		options:  s.options,
+	}
+}
+
+// sinkError is a failure that has been handled by a sink's failure policy
+type sinkError struct {
+	err    error
+	policy FailurePolicy
+}
+
+// Error implements the error interface
+func (e *sinkError) Error() string {
+	if e.policy == FallbackOnFailure {
+		return e.err.Error() + " (delivered to fallback)"
+	}
+	return e.err.Error()
+}
+
+// Unwrap returns the reporter's error
+func (e *sinkError) Unwrap() error {
+	return e.err
+}
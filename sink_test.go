@@ -0,0 +1,110 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSinkFailurePolicies(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "crash_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	var diagnostics []Diagnostic
+	metrics := &countingReporter{}
+	fallback := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     tempFile.Name(),
+		OnDiagnostic: func(d Diagnostic) {
+			if d.Op == OpReport {
+				diagnostics = append(diagnostics, d)
+			}
+		},
+	},
+		WithReporters(&namedReporter{name: "webhook"}, &namedReporter{name: "metrics"}),
+		WithSink(&namedReporter{name: "statsd", err: errors.New("no route")}, SinkOptions{OnFailure: DropFailure}),
+		WithSink(&namedReporter{name: "sentry", err: errors.New("timeout")}, SinkOptions{
+			OnFailure: FallbackOnFailure,
+			Fallback:  channelReporter(fallback),
+		}),
+		WithSink(&namedReporter{name: "pagerduty", err: errors.New("unauthorized")}, SinkOptions{}),
+		WithReporter(metrics),
+	)
+
+	func() {
+		defer ph.Recover()
+		panic("fan-out panic")
+	}()
+
+	if metrics.calls != 1 {
+		t.Errorf("Expected every reporter to receive the report, got %d calls", metrics.calls)
+	}
+	if len(fallback) != 1 {
+		t.Error("Expected the fallback reporter to receive the report")
+	}
+	if len(diagnostics) != 2 || diagnostics[0].Path != "sentry" || diagnostics[1].Path != "pagerduty" {
+		t.Errorf("Expected diagnostics for sentry and pagerduty only, got %+v", diagnostics)
+	}
+
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]DeliveryStatus{
+		"webhook":   DeliverySent,
+		"metrics":   DeliverySent,
+		"statsd":    DeliveryDropped,
+		"sentry":    DeliveryFallback,
+		"pagerduty": DeliveryFailed,
+	}
+	for name, status := range expected {
+		if reports[0].Deliveries[name].Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", name, status, reports[0].Deliveries[name])
+		}
+	}
+}
+
+func TestSinkFallbackFailure(t *testing.T) {
+	sink := NewSink(failingReporter{err: errors.New("primary down")}, SinkOptions{
+		OnFailure: FallbackOnFailure,
+		Fallback:  failingReporter{err: errors.New("disk full")},
+	})
+	err := sink.Report(context.Background(), CrashReport{})
+	var handled *sinkError
+	if err == nil || errors.As(err, &handled) {
+		t.Fatalf("Expected unhandled error, got %v", err)
+	}
+	if err.Error() != "primary down (fallback failed: disk full)" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSinkWithCircuitBreaker(t *testing.T) {
+	primary := &countingReporter{err: errors.New("down")}
+	fallback := &countingReporter{}
+	ph := New(Options{
+		ErrorHandler:   func(error, []byte) {},
+		OnDiagnostic:   func(Diagnostic) {},
+		CircuitBreaker: &CircuitBreakerOptions{FailureThreshold: 1},
+	}, WithSink(primary, SinkOptions{OnFailure: FallbackOnFailure, Fallback: fallback}))
+
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic("breaker panic")
+		}()
+	}
+
+	if primary.calls != 1 {
+		t.Errorf("Expected the open circuit to stop calls to the primary, got %d", primary.calls)
+	}
+	if fallback.calls != 3 {
+		t.Errorf("Expected the fallback to receive every report, got %d", fallback.calls)
+	}
+}
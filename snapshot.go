@@ -0,0 +1,150 @@
+package adfer
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the values of sensitive flags and config fields
+const redacted = "[REDACTED]"
+
+// maxSnapshotDepth limits how deep nested config values are captured
+const maxSnapshotDepth = 8
+
+// sensitiveNames are parts of flag names whose values are redacted
+var sensitiveNames = []string{"password", "passwd", "secret", "token", "key", "credential", "dsn"}
+
+// WithFlagSnapshot captures the flags set on the command line of fs into each crash report.
+// If fs is nil, flag.CommandLine is used. Values of flags whose names contain "password",
+// "secret", "token", "key", "credential" or "dsn" are redacted
+func WithFlagSnapshot(fs *flag.FlagSet) Option {
+	return func(o *Options) {
+		if fs == nil {
+			fs = flag.CommandLine
+		}
+		o.Flags = fs
+	}
+}
+
+// WithConfigSnapshot captures config into each crash report. Config is usually a pointer to the
+// program's config struct, so the values at crash time are captured. Fields tagged
+// `adfer:"redact"` are redacted and fields tagged `adfer:"-"` are omitted
+func WithConfigSnapshot(config any) Option {
+	return func(o *Options) {
+		o.Config = config
+	}
+}
+
+// snapshotFlags returns the values of the flags set on the command line
+func snapshotFlags(fs *flag.FlagSet) map[string]string {
+	if !fs.Parsed() {
+		return nil
+	}
+	values := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+		if sensitiveName(f.Name) {
+			values[f.Name] = redacted
+		}
+	})
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// sensitiveName reports whether the name of a flag suggests its value is a secret
+func sensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotConfig flattens config into dotted keys, e.g. "Database.Host"
+func snapshotConfig(config any) map[string]string {
+	values := map[string]string{}
+	flattenConfig(values, "", reflect.ValueOf(config), 0)
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// flattenConfig adds the leaf values of v to values
+func flattenConfig(values map[string]string, prefix string, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if prefix != "" {
+				values[prefix] = "<nil>"
+			}
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return
+	}
+	if depth >= maxSnapshotDepth || isLeaf(v) {
+		if prefix != "" {
+			values[prefix] = fmt.Sprint(v.Interface())
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := joinKey(prefix, field.Name)
+			switch field.Tag.Get("adfer") {
+			case "-":
+				continue
+			case "redact":
+				values[name] = redacted
+				continue
+			}
+			flattenConfig(values, name, v.Field(i), depth+1)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			flattenConfig(values, joinKey(prefix, fmt.Sprint(key.Interface())), v.MapIndex(key), depth+1)
+		}
+	}
+}
+
+// isLeaf reports whether v is captured as a single value rather than by its fields
+func isLeaf(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		_, stringer := v.Interface().(fmt.Stringer)
+		return stringer
+	case reflect.Map:
+		return false
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// joinKey joins a prefix and a key with a dot
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
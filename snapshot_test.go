@@ -0,0 +1,102 @@
+package adfer
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDatabaseConfig struct {
+	Host     string
+	Port     int
+	Password string `adfer:"redact"`
+}
+
+type testConfig struct {
+	Listen   string
+	Timeout  time.Duration
+	Started  time.Time
+	Database *testDatabaseConfig
+	Limits   map[string]int
+	Internal string `adfer:"-"`
+	Hosts    []string
+	OnReload func()
+	secret   string
+}
+
+func TestSnapshotConfig(t *testing.T) {
+	config := &testConfig{
+		Listen:   ":8080",
+		Timeout:  5 * time.Second,
+		Database: &testDatabaseConfig{Host: "db", Port: 5432, Password: "hunter2"},
+		Limits:   map[string]int{"upload": 10, "api": 100},
+		Internal: "omitted",
+		Hosts:    []string{"a", "b"},
+		secret:   "unexported",
+	}
+
+	values := snapshotConfig(config)
+	expected := map[string]string{
+		"Listen":            ":8080",
+		"Timeout":           "5s",
+		"Started":           time.Time{}.String(),
+		"Database.Host":     "db",
+		"Database.Port":     "5432",
+		"Database.Password": "[REDACTED]",
+		"Limits.api":        "100",
+		"Limits.upload":     "10",
+		"Hosts":             "[a b]",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+
+	config.Database = nil
+	if values := snapshotConfig(config); values["Database"] != "<nil>" {
+		t.Errorf("Expected a nil pointer to be captured, got %v", values)
+	}
+}
+
+func TestSnapshotFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 8080, "")
+	fs.String("api-key", "", "")
+	fs.Bool("verbose", false, "")
+	if values := snapshotFlags(fs); values != nil {
+		t.Errorf("Expected no values before parsing, got %v", values)
+	}
+	if err := fs.Parse([]string{"-port", "9090", "-api-key", "abc123"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	expected := map[string]string{"port": "9090", "api-key": "[REDACTED]"}
+	if values := snapshotFlags(fs); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+}
+
+func TestConfigSnapshotInReport(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("region", "", "")
+	_ = fs.Parse([]string{"-region", "eu-west-1"})
+	config := &testDatabaseConfig{Host: "db"}
+
+	reports := make(channelReporter, 1)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithFlagSnapshot(fs),
+		WithConfigSnapshot(config),
+		WithReporter(reports),
+	)
+	// The values at crash time are captured
+	config.Host = "replica"
+	func() {
+		defer ph.Recover()
+		panic("config panic")
+	}()
+
+	report := <-reports
+	if report.Flags["region"] != "eu-west-1" || report.Config["Host"] != "replica" {
+		t.Errorf("Unexpected snapshot: flags %v, config %v", report.Flags, report.Config)
+	}
+}
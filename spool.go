@@ -0,0 +1,208 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpoolOptions configures the on-disk spool for failed deliveries
+type SpoolOptions struct {
+	// Dir is the directory spooled crash reports are written to. It is created if it doesn't exist.
+	// Defaults to a "spool" directory next to FilePath, or in the system temp directory if FilePath is not set
+	Dir string
+	// RetryInterval is how often spooled reports are retried in the background. Defaults to 5 minutes
+	RetryInterval time.Duration
+	// MaxAge discards spooled reports older than this. Zero keeps them until they are delivered
+	MaxAge time.Duration
+}
+
+// WithSpool writes crash reports that failed to reach a reporter to a spool directory, and
+// retries them when the program starts and then periodically until they are delivered
+func WithSpool(options SpoolOptions) Option {
+	return func(o *Options) {
+		o.Spool = &options
+	}
+}
+
+// startSpool retries the spooled reports left by previous runs and starts the retry timer
+func (ph *PanicHandler) startSpool() {
+	options := ph.options.Spool
+	if options == nil {
+		return
+	}
+	// Uploaders would retry failed reports from their own queue as well
+	for _, reporter := range ph.options.Reporters {
+		if uploader, ok := findReporter[*Uploader](reporter); ok {
+			uploader.useSpool()
+		}
+	}
+	if options.Dir == "" {
+		options.Dir = filepath.Join(os.TempDir(), "adfer-spool")
+		if ph.options.FilePath != "" {
+			options.Dir = filepath.Join(filepath.Dir(ph.options.FilePath), "spool")
+		}
+	}
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = 5 * time.Minute
+	}
+	ph.spoolStop = make(chan struct{})
+	ph.spoolDone = make(chan struct{})
+	go func() {
+		defer close(ph.spoolDone)
+		ticker := time.NewTicker(options.RetryInterval)
+		defer ticker.Stop()
+		for {
+			// Failed deliveries have already been reported as diagnostics
+			if _, err := ph.retrySpool(context.Background()); err != nil {
+				ph.diagnose(OpSpool, options.Dir, err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ph.spoolStop:
+				return
+			}
+		}
+	}()
+}
+
+// stopSpool stops the retry timer
+func (ph *PanicHandler) stopSpool() {
+	if ph.spoolStop == nil {
+		return
+	}
+	ph.spoolOnce.Do(func() { close(ph.spoolStop) })
+	<-ph.spoolDone
+}
+
+// spoolPath returns the path of the spool file of a report
+func (ph *PanicHandler) spoolPath(id string) string {
+	return filepath.Join(ph.options.Spool.Dir, "crash-"+id+".json")
+}
+
+// spool writes a report with failed deliveries to the spool directory
+func (ph *PanicHandler) spool(report CrashReport, deliveries map[string]Delivery) {
+	if ph.options.Spool == nil {
+		return
+	}
+	failed := false
+	for _, delivery := range deliveries {
+		if delivery.Status == DeliveryFailed {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return
+	}
+	report.Deliveries = deliveries
+	path := ph.spoolPath(report.ID)
+	ph.spoolMu.Lock()
+	defer ph.spoolMu.Unlock()
+	if err := ph.writeSpoolFile(path, report); err != nil {
+		ph.diagnose(OpSpool, path, err)
+	}
+}
+
+// writeSpoolFile writes a spooled report
+func (ph *PanicHandler) writeSpoolFile(path string, report CrashReport) error {
+	if err := ph.perms.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ph.perms.writeFile(path, data)
+}
+
+// SpooledReports returns the crash reports waiting in the spool directory, oldest first
+func (ph *PanicHandler) SpooledReports() ([]CrashReport, error) {
+	if ph.options.Spool == nil {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(ph.options.Spool.Dir, "crash-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var reports []CrashReport
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			ph.diagnose(OpSpool, path, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Timestamp.Before(reports[j].Timestamp)
+	})
+	return reports, nil
+}
+
+// RetrySpool retries every spooled crash report. Reports that reach all their reporters are
+// removed from the spool, and their receipts in the crash file are updated
+func (ph *PanicHandler) RetrySpool(ctx context.Context) error {
+	failed, err := ph.retrySpool(ctx)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return errors.New("failed to deliver spooled crash reports " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// retrySpool retries every spooled crash report and returns the IDs of those that failed again
+func (ph *PanicHandler) retrySpool(ctx context.Context) ([]string, error) {
+	if ph.options.Spool == nil || ph.Consent() < ConsentFull {
+		return nil, nil
+	}
+	ph.spoolMu.Lock()
+	defer ph.spoolMu.Unlock()
+
+	reports, err := ph.SpooledReports()
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, report := range reports {
+		if err := ctx.Err(); err != nil {
+			return failed, err
+		}
+		path := ph.spoolPath(report.ID)
+		if ph.options.Spool.MaxAge > 0 && time.Since(report.Timestamp) > ph.options.Spool.MaxAge {
+			if err := os.Remove(path); err != nil {
+				ph.diagnose(OpSpool, path, err)
+			}
+			continue
+		}
+
+		deliveries, failedReporters := ph.redeliver(ctx, report)
+		if ph.tracksDeliveries() {
+			if err := ph.updateDeliveries(report.ID, deliveries); err != nil && !errors.Is(err, ErrReportNotFound) {
+				ph.diagnose(OpWrite, ph.options.FilePath, err)
+			}
+		}
+		if len(failedReporters) > 0 {
+			failed = append(failed, report.ID)
+			report.Deliveries = deliveries
+			err = ph.writeSpoolFile(path, report)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			ph.diagnose(OpSpool, path, err)
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,94 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	crashFile := filepath.Join(dir, "crash.json")
+	spoolDir := filepath.Join(dir, "spool")
+
+	webhook := &namedReporter{name: "webhook", err: errors.New("offline")}
+	newHandler := func() *PanicHandler {
+		return New(Options{
+			ErrorHandler: func(error, []byte) {},
+			DumpToFile:   true,
+			FilePath:     crashFile,
+			OnDiagnostic: func(Diagnostic) {},
+		},
+			WithReporter(&namedReporter{name: "metrics"}),
+			WithReporter(webhook),
+			WithSpool(SpoolOptions{Dir: spoolDir, RetryInterval: time.Hour}),
+		)
+	}
+
+	ph := newHandler()
+	// Wait for the startup retry, so it doesn't race with the test
+	ph.Close()
+	func() {
+		defer ph.Recover()
+		panic("offline panic")
+	}()
+
+	spooled, err := ph.SpooledReports()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(spooled) != 1 || spooled[0].Deliveries["webhook"].Status != DeliveryFailed {
+		t.Fatalf("Expected the report to be spooled, got %+v", spooled)
+	}
+	if err := ph.RetrySpool(context.Background()); err == nil {
+		t.Error("Expected error while still offline")
+	}
+
+	// The next run retries the spooled report on startup
+	webhook.err = nil
+	ph = newHandler()
+	ph.Close()
+
+	spooled, _ = ph.SpooledReports()
+	if len(spooled) != 0 {
+		t.Errorf("Expected the spool to be empty, got %d reports", len(spooled))
+	}
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	webhookDelivery := reports[0].Deliveries["webhook"]
+	if webhookDelivery.Status != DeliverySent || webhookDelivery.Attempts != 3 {
+		t.Errorf("Expected the receipt to be updated, got %+v", webhookDelivery)
+	}
+	if reports[0].Deliveries["metrics"].Attempts != 1 {
+		t.Errorf("Expected metrics not to be retried, got %+v", reports[0].Deliveries["metrics"])
+	}
+}
+
+func TestSpoolMaxAge(t *testing.T) {
+	spoolDir := t.TempDir()
+	reporter := &countingReporter{err: errors.New("offline")}
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(Diagnostic) {},
+	}, WithReporter(reporter), WithSpool(SpoolOptions{Dir: spoolDir, RetryInterval: time.Hour, MaxAge: time.Hour}))
+	ph.Close()
+
+	old := CrashReport{ID: "old", Timestamp: time.Now().Add(-2 * time.Hour)}
+	if err := ph.writeSpoolFile(ph.spoolPath(old.ID), old); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ph.RetrySpool(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reporter.calls != 0 {
+		t.Error("Expected expired reports not to be retried")
+	}
+	if _, err := os.Stat(ph.spoolPath(old.ID)); !os.IsNotExist(err) {
+		t.Error("Expected expired report to be removed")
+	}
+}
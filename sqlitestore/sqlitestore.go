@@ -0,0 +1,161 @@
+// Package sqlitestore stores adfer crash reports in a SQLite table, with indexed timestamp
+// and fingerprint columns so hosts with thousands of crashes can be queried efficiently.
+//
+// The package doesn't depend on a SQLite driver. Open the database with the driver of your
+// choice, e.g. modernc.org/sqlite or github.com/mattn/go-sqlite3, and pass it to New:
+//
+//	db, err := sql.Open("sqlite", "crashes.db")
+//	...
+//	store, err := sqlitestore.New(db, sqlitestore.Options{})
+//	...
+//	ph := adfer.New(adfer.Options{}, adfer.WithStorage(store))
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+// Options configures a Store
+type Options struct {
+	// Table is the name of the table reports are stored in. Defaults to "crash_reports"
+	Table string
+}
+
+// Store is an adfer.Storage that stores crash reports in a SQLite table. It also implements
+// adfer.ReportUpdater, so delivery receipts are stored
+type Store struct {
+	db    *sql.DB
+	table string
+}
+
+// New creates a Store, creating its table and indexes if they don't exist
+func New(db *sql.DB, options Options) (*Store, error) {
+	if options.Table == "" {
+		options.Table = "crash_reports"
+	}
+	s := &Store{db: db, table: quoteIdentifier(options.Table)}
+	schema := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL,
+	timestamp INTEGER NOT NULL,
+	fingerprint TEXT NOT NULL,
+	report TEXT NOT NULL
+)`, s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (id)", quoteIdentifier(options.Table+"_id"), s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (timestamp)", quoteIdentifier(options.Table+"_timestamp"), s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (fingerprint)", quoteIdentifier(options.Table+"_fingerprint"), s.table),
+	}
+	for _, statement := range schema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Append stores a crash report
+func (s *Store) Append(report adfer.CrashReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		fmt.Sprintf("INSERT INTO %s (id, timestamp, fingerprint, report) VALUES (?, ?, ?, ?)", s.table),
+		report.ID, report.Timestamp.UnixNano(), adfer.Fingerprint(report), string(data),
+	)
+	return err
+}
+
+// LastN returns the last n crash reports, oldest first. If n is negative, every report is returned
+func (s *Store) LastN(n int) ([]adfer.CrashReport, error) {
+	return s.query(fmt.Sprintf("SELECT report FROM (SELECT seq, report FROM %s ORDER BY seq DESC LIMIT ?) ORDER BY seq", s.table), n)
+}
+
+// Wipe removes every crash report
+func (s *Store) Wipe() error {
+	_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s", s.table))
+	return err
+}
+
+// Update calls fn with the stored report with the given ID and stores the result.
+// It returns adfer.ErrReportNotFound if there is no such report
+func (s *Store) Update(id string, fn func(report *adfer.CrashReport)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var seq int64
+	var data string
+	err = tx.QueryRow(fmt.Sprintf("SELECT seq, report FROM %s WHERE id = ? ORDER BY seq DESC LIMIT 1", s.table), id).Scan(&seq, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return adfer.ErrReportNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var report adfer.CrashReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return err
+	}
+	fn(&report)
+	updated, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		fmt.Sprintf("UPDATE %s SET report = ?, fingerprint = ? WHERE seq = ?", s.table),
+		string(updated), adfer.Fingerprint(report), seq,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ByFingerprint returns the crash reports with the given fingerprint, oldest first
+func (s *Store) ByFingerprint(fingerprint string) ([]adfer.CrashReport, error) {
+	return s.query(fmt.Sprintf("SELECT report FROM %s WHERE fingerprint = ? ORDER BY seq", s.table), fingerprint)
+}
+
+// Between returns the crash reports with a timestamp in [from, to), oldest first
+func (s *Store) Between(from, to time.Time) ([]adfer.CrashReport, error) {
+	return s.query(fmt.Sprintf("SELECT report FROM %s WHERE timestamp >= ? AND timestamp < ? ORDER BY seq", s.table), from.UnixNano(), to.UnixNano())
+}
+
+// query returns the reports selected by a query of the report column
+func (s *Store) query(query string, args ...any) ([]adfer.CrashReport, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []adfer.CrashReport
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var report adfer.CrashReport
+		if err := json.Unmarshal([]byte(data), &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// quoteIdentifier quotes a table or index name
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
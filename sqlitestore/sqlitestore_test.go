@@ -0,0 +1,234 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leaanthony/adfer"
+)
+
+// fakeRow is a row of the fake crash reports table
+type fakeRow struct {
+	seq         int64
+	id          string
+	timestamp   int64
+	fingerprint string
+	report      string
+}
+
+// fakeDB is an in-memory stand-in for SQLite that understands the statements of Store
+type fakeDB struct {
+	mu         sync.Mutex
+	rows       []fakeRow
+	seq        int64
+	statements []string
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, s.query)
+	switch {
+	case strings.HasPrefix(s.query, "CREATE"):
+	case strings.HasPrefix(s.query, "INSERT"):
+		d.seq++
+		d.rows = append(d.rows, fakeRow{d.seq, args[0].(string), args[1].(int64), args[2].(string), args[3].(string)})
+	case strings.HasPrefix(s.query, "UPDATE"):
+		for i := range d.rows {
+			if d.rows[i].seq == args[2].(int64) {
+				d.rows[i].report, d.rows[i].fingerprint = args[0].(string), args[1].(string)
+			}
+		}
+	case strings.HasPrefix(s.query, "DELETE"):
+		d.rows = nil
+	default:
+		return nil, fmt.Errorf("unexpected statement %q", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var selected []fakeRow
+	for _, row := range d.rows {
+		switch {
+		case strings.Contains(s.query, "WHERE id"):
+			if row.id == args[0].(string) {
+				selected = append(selected, row)
+			}
+		case strings.Contains(s.query, "WHERE fingerprint"):
+			if row.fingerprint == args[0].(string) {
+				selected = append(selected, row)
+			}
+		case strings.Contains(s.query, "WHERE timestamp"):
+			if row.timestamp >= args[0].(int64) && row.timestamp < args[1].(int64) {
+				selected = append(selected, row)
+			}
+		default:
+			selected = append(selected, row)
+		}
+	}
+	if strings.Contains(s.query, "LIMIT ?") {
+		if n := int(args[0].(int64)); n >= 0 && n < len(selected) {
+			selected = selected[len(selected)-n:]
+		}
+	}
+	if strings.HasPrefix(s.query, "SELECT seq") {
+		sort.Slice(selected, func(i, j int) bool { return selected[i].seq > selected[j].seq })
+		return &fakeRows{rows: selected, columns: []string{"seq", "report"}}, nil
+	}
+	return &fakeRows{rows: selected, columns: []string{"report"}}, nil
+}
+
+type fakeRows struct {
+	rows    []fakeRow
+	columns []string
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	if len(dest) == 2 {
+		dest[0], dest[1] = row.seq, row.report
+		return nil
+	}
+	dest[0] = row.report
+	return nil
+}
+
+var driverCount int
+
+// openFake opens a database backed by a new fakeDB
+func openFake(t *testing.T) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	driverCount++
+	name := fmt.Sprintf("fake-sqlite-%d", driverCount)
+	sql.Register(name, fake)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+func TestStore(t *testing.T) {
+	db, fake := openFake(t)
+	store, err := New(db, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fake.statements) != 4 || !strings.Contains(fake.statements[2], `ON "crash_reports" (timestamp)`) ||
+		!strings.Contains(fake.statements[3], `ON "crash_reports" (fingerprint)`) {
+		t.Errorf("Expected the table and its indexes to be created, got %q", fake.statements)
+	}
+
+	ph := adfer.New(adfer.Options{ErrorHandler: func(error, []byte) {}}, adfer.WithStorage(store))
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+	reports, err := ph.GetLastNCrashReports(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 2 || reports[0].Error != "1" || reports[1].Error != "2" {
+		t.Fatalf("Expected the last 2 reports, got %+v", reports)
+	}
+	if fake.rows[0].fingerprint != adfer.Fingerprint(reports[0]) {
+		t.Errorf("Expected the fingerprint column to be set")
+	}
+
+	all, _ := store.LastN(-1)
+	if len(all) != 3 {
+		t.Errorf("Expected 3 reports, got %d", len(all))
+	}
+	byFingerprint, _ := store.ByFingerprint(fake.rows[0].fingerprint)
+	if len(byFingerprint) != 3 {
+		t.Errorf("Expected 3 reports with the same fingerprint, got %d", len(byFingerprint))
+	}
+	between, _ := store.Between(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(between) != 3 {
+		t.Errorf("Expected 3 reports in the last minute, got %d", len(between))
+	}
+
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if all, _ := store.LastN(-1); len(all) != 0 {
+		t.Errorf("Expected no reports after wiping, got %d", len(all))
+	}
+}
+
+func TestStoreDeliveries(t *testing.T) {
+	db, _ := openFake(t)
+	store, err := New(db, Options{Table: "crashes"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ph := adfer.New(adfer.Options{
+		ErrorHandler: func(error, []byte) {},
+		OnDiagnostic: func(adfer.Diagnostic) {},
+	}, adfer.WithStorage(store), adfer.WithReporter(adfer.ReporterFunc(func(context.Context, adfer.CrashReport) error {
+		return errors.New("unreachable")
+	})))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	pending, err := ph.PendingReports()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected 1 pending report, got %d, error %v", len(pending), err)
+	}
+	for _, delivery := range pending[0].Deliveries {
+		if delivery.Status != adfer.DeliveryFailed || delivery.Error != "unreachable" {
+			t.Errorf("Unexpected delivery %+v", delivery)
+		}
+	}
+	if err := store.Update("missing", func(*adfer.CrashReport) {}); !errors.Is(err, adfer.ErrReportNotFound) {
+		t.Errorf("Expected ErrReportNotFound, got %v", err)
+	}
+}
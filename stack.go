@@ -0,0 +1,104 @@
+package adfer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// StackFrame represents a single frame parsed from a stack trace
+type StackFrame struct {
+	// Function is the fully qualified function name, e.g. "main.main"
+	Function string `json:"function"`
+	// File is the absolute path of the source file
+	File string `json:"file"`
+	// Line is the line number within File
+	Line int `json:"line"`
+}
+
+// Package returns the import path of the package the frame's function belongs to
+func (f StackFrame) Package() string {
+	name := f.Function
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// isRuntime returns true if the frame belongs to the Go runtime or to adfer itself
+func (f StackFrame) isRuntime() bool {
+	if f.Function == "panic" {
+		return true
+	}
+	pkg := f.Package()
+	return pkg == "runtime" || strings.HasPrefix(pkg, "runtime/") || pkg == "github.com/leaanthony/adfer"
+}
+
+// ParseStack parses the output of debug.Stack into frames, innermost call first.
+// Only the first goroutine in the trace is parsed.
+func ParseStack(stack string) []StackFrame {
+	var frames []StackFrame
+	lines := strings.Split(stack, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if strings.HasPrefix(line, "goroutine ") {
+			if len(frames) > 0 {
+				break
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "\t") || i+1 >= len(lines) {
+			continue
+		}
+		next := strings.TrimRight(lines[i+1], "\r")
+		if !strings.HasPrefix(next, "\t") {
+			continue
+		}
+		frame := StackFrame{Function: trimArgs(line)}
+		frame.File, frame.Line = parseLocation(strings.TrimPrefix(next, "\t"))
+		frames = append(frames, frame)
+		i++
+	}
+	return frames
+}
+
+// appFrames returns the frames that do not belong to the runtime or to adfer
+func appFrames(frames []StackFrame) []StackFrame {
+	var result []StackFrame
+	for _, frame := range frames {
+		if !frame.isRuntime() {
+			result = append(result, frame)
+		}
+	}
+	return result
+}
+
+// trimArgs removes the argument list from a function line, e.g. "main.f(0x1, 0x2)" becomes "main.f"
+func trimArgs(line string) string {
+	line = strings.TrimPrefix(line, "created by ")
+	if idx := strings.Index(line, " in goroutine "); idx > 0 {
+		line = line[:idx]
+	}
+	if strings.HasSuffix(line, ")") {
+		if idx := strings.LastIndex(line, "("); idx > 0 {
+			line = line[:idx]
+		}
+	}
+	return line
+}
+
+// parseLocation parses "/path/file.go:12 +0x1d" into its file and line
+func parseLocation(location string) (string, int) {
+	if idx := strings.LastIndex(location, " +0x"); idx > 0 {
+		location = location[:idx]
+	}
+	idx := strings.LastIndex(location, ":")
+	if idx < 0 {
+		return location, 0
+	}
+	line, err := strconv.Atoi(location[idx+1:])
+	if err != nil {
+		return location, 0
+	}
+	return location[:idx], line
+}
@@ -0,0 +1,62 @@
+package adfer
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestParseStack(t *testing.T) {
+	stack := `goroutine 1 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x5e
+github.com/leaanthony/adfer.(*PanicHandler).Recover(0xc000010000)
+	/src/adfer/adfer.go:84 +0x125
+panic({0x5d6a20?, 0x6a7b50?})
+	/usr/local/go/src/runtime/panic.go:770 +0x132
+main.(*worker).run(...)
+	/src/app/worker.go:42
+main.main()
+	/src/app/main.go:10 +0x25
+created by main.start in goroutine 1
+	/src/app/main.go:5 +0x30
+
+goroutine 2 [chan receive]:
+main.other()
+	/src/app/other.go:1 +0x1
+`
+	frames := ParseStack(stack)
+	if len(frames) != 6 {
+		t.Fatalf("Expected 6 frames, got %d: %+v", len(frames), frames)
+	}
+	if frames[3].Function != "main.(*worker).run" || frames[3].File != "/src/app/worker.go" || frames[3].Line != 42 {
+		t.Errorf("Unexpected frame: %+v", frames[3])
+	}
+	if frames[5].Function != "main.start" {
+		t.Errorf("Expected 'created by' frame to be parsed, got %+v", frames[5])
+	}
+	if frames[1].Package() != "github.com/leaanthony/adfer" {
+		t.Errorf("Unexpected package '%s'", frames[1].Package())
+	}
+
+	app := appFrames(frames)
+	if len(app) != 3 || app[0].Function != "main.(*worker).run" {
+		t.Errorf("Unexpected app frames: %+v", app)
+	}
+}
+
+func TestParseStackRealTrace(t *testing.T) {
+	frames := ParseStack(string(debug.Stack()))
+	if len(frames) == 0 {
+		t.Fatal("Expected frames from a real stack trace")
+	}
+	found := false
+	for _, frame := range frames {
+		if strings.HasSuffix(frame.Function, "TestParseStackRealTrace") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected test function in frames: %+v", frames)
+	}
+}
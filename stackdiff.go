@@ -0,0 +1,156 @@
+package adfer
+
+import (
+	"strings"
+)
+
+// StackDiff is stored in the crash file instead of the stack trace of a report whose panic
+// recurred with a similar stack. It holds only the frames that differ from the first-seen stack
+type StackDiff struct {
+	// Base is the ID of the report with the first-seen stack
+	Base string `json:"base"`
+	// Header is the goroutine header line, if it differs from the base
+	Header string `json:"header,omitempty"`
+	// Frames is the number of frames of the stack
+	Frames int `json:"frames"`
+	// Changed holds the frames that differ from the base, by index
+	Changed map[int]string `json:"changed,omitempty"`
+}
+
+// WithStackDiffs stores recurring panics in the crash file as a diff against the first-seen
+// stack of the same fingerprint, instead of a full duplicate. Reports read back through the
+// PanicHandler have their full stack restored, with StackDiff showing what varied
+func WithStackDiffs() Option {
+	return func(o *Options) {
+		o.StackDiffs = true
+	}
+}
+
+// splitStack splits a stack trace into its goroutine header and frames. Each frame is the
+// function line followed by its file line
+func splitStack(stack string) (string, []string) {
+	lines := strings.Split(strings.TrimSuffix(stack, "\n"), "\n")
+	header := ""
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "goroutine ") {
+		header, lines = lines[0], lines[1:]
+	}
+	frames := make([]string, 0, (len(lines)+1)/2)
+	for i := 0; i < len(lines); i += 2 {
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			frames = append(frames, lines[i]+"\n"+lines[i+1])
+			continue
+		}
+		// Lines such as "...additional frames elided..." have no file line
+		frames = append(frames, lines[i])
+		i--
+	}
+	return header, frames
+}
+
+// joinStack is the inverse of splitStack
+func joinStack(header string, frames []string) string {
+	var sb strings.Builder
+	if header != "" {
+		sb.WriteString(header)
+		sb.WriteString("\n")
+	}
+	for _, frame := range frames {
+		sb.WriteString(frame)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// diffStack returns the diff of stack against base, or nil if the stacks differ in more than
+// half of their frames or the stack can't be restored exactly from the diff
+func diffStack(baseID, base, stack string) *StackDiff {
+	baseHeader, baseFrames := splitStack(base)
+	header, frames := splitStack(stack)
+	diff := &StackDiff{Base: baseID, Frames: len(frames)}
+	if header != baseHeader {
+		diff.Header = header
+	}
+	for i, frame := range frames {
+		if i < len(baseFrames) && baseFrames[i] == frame {
+			continue
+		}
+		if diff.Changed == nil {
+			diff.Changed = make(map[int]string)
+		}
+		diff.Changed[i] = frame
+	}
+	if len(diff.Changed)*2 > len(frames) || diff.apply(base) != stack {
+		return nil
+	}
+	return diff
+}
+
+// apply restores the stack trace from the base stack
+func (d *StackDiff) apply(base string) string {
+	header, baseFrames := splitStack(base)
+	if d.Header != "" {
+		header = d.Header
+	}
+	frames := make([]string, d.Frames)
+	for i := range frames {
+		if frame, ok := d.Changed[i]; ok {
+			frames[i] = frame
+		} else if i < len(baseFrames) {
+			frames[i] = baseFrames[i]
+		}
+	}
+	return joinStack(header, frames)
+}
+
+// compactStack replaces the stack of report with a diff against the first report in reports
+// with the same fingerprint and a full stack, if the stacks are similar
+func compactStack(reports []CrashReport, report CrashReport) CrashReport {
+	if report.Stack == "" || report.StackDiff != nil {
+		return report
+	}
+	key := Fingerprint(report)
+	for _, base := range reports {
+		if base.StackDiff != nil || base.Stack == "" || base.ID == "" || Fingerprint(base) != key {
+			continue
+		}
+		if diff := diffStack(base.ID, base.Stack, report.Stack); diff != nil {
+			report.StackDiff = diff
+			report.Stack = ""
+		}
+		return report
+	}
+	return report
+}
+
+// expandStacks restores the stacks of reports stored as a diff. Reports whose base is
+// missing keep an empty stack
+func expandStacks(reports []CrashReport) {
+	var stacks map[string]string
+	for i := range reports {
+		diff := reports[i].StackDiff
+		if diff == nil || reports[i].Stack != "" {
+			continue
+		}
+		if stacks == nil {
+			stacks = make(map[string]string)
+			for _, report := range reports {
+				if report.StackDiff == nil && report.ID != "" {
+					stacks[report.ID] = report.Stack
+				}
+			}
+		}
+		if base, ok := stacks[diff.Base]; ok {
+			reports[i].Stack = diff.apply(base)
+		}
+	}
+}
+
+// hasStackDiffs reports whether any of reports was stored as a diff, so its base must be read too
+func hasStackDiffs(reports []CrashReport) bool {
+	for _, report := range reports {
+		if report.StackDiff != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package adfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testStackRecurrence = "goroutine 34 [running]:\nruntime/debug.Stack()\n\t/go/src/runtime/debug/stack.go:24 +0x5e\nmain.inner()\n\t/app/main.go:6 +0x1\nmain.main()\n\t/app/main.go:10 +0x1\n"
+
+func TestDiffStack(t *testing.T) {
+	diff := diffStack("first", testStack, testStackRecurrence)
+	if diff == nil {
+		t.Fatal("Expected a diff for similar stacks")
+	}
+	if diff.Header != "goroutine 34 [running]:" || diff.Frames != 3 || len(diff.Changed) != 1 {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if diff.Changed[1] != "main.inner()\n\t/app/main.go:6 +0x1" {
+		t.Errorf("Expected only the changed frame, got %q", diff.Changed)
+	}
+	if stack := diff.apply(testStack); stack != testStackRecurrence {
+		t.Errorf("Expected the stack to be restored, got:\n%s", stack)
+	}
+
+	different := "goroutine 1 [running]:\nmain.a()\n\t/app/a.go:1 +0x1\nmain.b()\n\t/app/b.go:1 +0x1\n"
+	if diff := diffStack("first", testStack, different); diff != nil {
+		t.Errorf("Expected no diff for different stacks, got %+v", diff)
+	}
+}
+
+func TestStackDiffs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crashes.json")
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     path,
+	}, WithStackDiffs())
+
+	first := CrashReport{ID: "first", Error: "boom", ErrorType: "string", Stack: testStack}
+	second := CrashReport{ID: "second", Error: "boom", ErrorType: "string", Stack: testStackRecurrence}
+	for _, report := range []CrashReport{first, second} {
+		if err := ph.storage.Append(report); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	var stored []CrashReport
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored[1].Stack != "" || stored[1].StackDiff == nil || stored[1].StackDiff.Base != "first" {
+		t.Errorf("Expected the recurrence to be stored as a diff, got %+v", stored[1])
+	}
+	if strings.Count(string(data), "runtime/debug.Stack()") != 1 {
+		t.Error("Expected the unchanged frames to be stored once")
+	}
+
+	reports, err := ph.GetLastNCrashReports(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reports[0].Stack != testStackRecurrence || reports[0].StackDiff == nil {
+		t.Errorf("Expected the full stack to be restored, got %+v", reports[0])
+	}
+
+	var stacks []string
+	if err := ph.EachCrashReport(func(report CrashReport) bool {
+		stacks = append(stacks, report.Stack)
+		return true
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(stacks) != 2 || stacks[0] != testStack || stacks[1] != testStackRecurrence {
+		t.Errorf("Expected the full stacks when iterating, got %q", stacks)
+	}
+}
@@ -0,0 +1,185 @@
+package adfer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stats holds counters describing the activity of a PanicHandler
+type Stats struct {
+	// Panics is the number of panics recovered
+	Panics int `json:"panics"`
+	// Reports is the number of handled errors submitted with Report
+	Reports int `json:"reports"`
+	// Diagnostics is the number of operational failures of the crash reporter itself
+	Diagnostics int `json:"diagnostics"`
+	// DiagnosticsByOp is the number of operational failures per operation
+	DiagnosticsByOp map[string]int `json:"diagnostics_by_op,omitempty"`
+	// Lifetime holds the counters persisted in the storage, if enabled with WithPersistentStats
+	Lifetime *LifetimeStats `json:"lifetime,omitempty"`
+}
+
+// LifetimeStats holds counters of every crash report stored, across restarts and the processes
+// sharing the storage
+type LifetimeStats struct {
+	// Panics is the number of panics stored
+	Panics int `json:"panics"`
+	// Reports is the number of handled errors stored
+	Reports int `json:"reports"`
+	// ByFingerprint is the number of panics and handled errors stored per fingerprint, see Fingerprint
+	ByFingerprint map[string]int `json:"by_fingerprint,omitempty"`
+	// LastCrash is the time of the last panic stored
+	LastCrash time.Time `json:"last_crash,omitempty"`
+}
+
+// StatsStore is implemented by storages that persist lifetime counters, needed by WithPersistentStats
+type StatsStore interface {
+	// LoadStats returns the stored counters
+	LoadStats() (LifetimeStats, error)
+	// UpdateStats calls fn with the stored counters and stores the result
+	UpdateStats(fn func(stats *LifetimeStats)) error
+}
+
+// WithPersistentStats keeps lifetime counters of the stored crash reports in the storage, so Stats
+// reflects every crash across restarts and the processes sharing a crash file or crash directory,
+// which both implement StatsStore. The counters survive wipes and rotation
+func WithPersistentStats() Option {
+	return func(o *Options) {
+		o.PersistentStats = true
+	}
+}
+
+// Stats returns a snapshot of the handler's counters
+func (ph *PanicHandler) Stats() Stats {
+	var lifetime *LifetimeStats
+	if store, ok := ph.storage.(StatsStore); ok && ph.options.PersistentStats {
+		stats, err := store.LoadStats()
+		if err != nil {
+			ph.diagnose(OpRead, "", err)
+		} else {
+			lifetime = &stats
+		}
+	}
+
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	stats := ph.stats
+	if ph.stats.DiagnosticsByOp != nil {
+		stats.DiagnosticsByOp = make(map[string]int, len(ph.stats.DiagnosticsByOp))
+		for op, count := range ph.stats.DiagnosticsByOp {
+			stats.DiagnosticsByOp[op] = count
+		}
+	}
+	stats.Lifetime = lifetime
+	return stats
+}
+
+// add counts a stored report
+func (s *LifetimeStats) add(report CrashReport) {
+	if report.Handled {
+		s.Reports++
+	} else {
+		s.Panics++
+		if report.Timestamp.After(s.LastCrash) {
+			s.LastCrash = report.Timestamp
+		}
+	}
+	if s.ByFingerprint == nil {
+		s.ByFingerprint = make(map[string]int)
+	}
+	s.ByFingerprint[Fingerprint(report)]++
+}
+
+// statsPath returns the path of the crash file's lifetime counters
+func (f *fileStorage) statsPath() string {
+	return f.path + ".stats"
+}
+
+// LoadStats returns the lifetime counters stored next to the crash file
+func (f *fileStorage) LoadStats() (LifetimeStats, error) {
+	defer f.lock(false)()
+	return readStatsFile(f.files(), f.statsPath())
+}
+
+// UpdateStats updates the lifetime counters stored next to the crash file
+func (f *fileStorage) UpdateStats(fn func(stats *LifetimeStats)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.mkdir(); err != nil {
+		return err
+	}
+	defer f.lock(true)()
+	return updateStatsFile(f.files(), f.perms.file, f.statsPath(), fn)
+}
+
+// statsPath returns the path of the crash directory's lifetime counters
+func (d *dirStorage) statsPath() string {
+	return filepath.Join(d.dir, "stats.json")
+}
+
+// LoadStats returns the lifetime counters stored in the crash directory
+func (d *dirStorage) LoadStats() (LifetimeStats, error) {
+	return readStatsFile(osFileSystem{}, d.statsPath())
+}
+
+// UpdateStats updates the lifetime counters stored in the crash directory, holding a lock
+// on .stats.lock so processes sharing the directory don't lose updates
+func (d *dirStorage) UpdateStats(fn func(stats *LifetimeStats)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.perms.mkdirAll(d.dir); err != nil {
+		return err
+	}
+	lock, err := d.perms.open(filepath.Join(d.dir, ".stats.lock"), os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := lockFile(lock, true); err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+	return updateStatsFile(osFileSystem{perms: d.perms}, d.perms.file, d.statsPath(), fn)
+}
+
+// readStatsFile reads lifetime counters. A missing file holds no counters
+func readStatsFile(files FileSystem, path string) (LifetimeStats, error) {
+	var stats LifetimeStats
+	data, err := files.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal(data, &stats)
+	return stats, err
+}
+
+// updateStatsFile updates lifetime counters, replacing the file so readers never see a partial update
+func updateStatsFile(files FileSystem, mode fs.FileMode, path string, fn func(stats *LifetimeStats)) error {
+	stats, err := readStatsFile(files, path)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		// Start over rather than failing every update
+		stats = LifetimeStats{}
+	} else if err != nil {
+		return err
+	}
+	fn(&stats)
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := files.WriteFile(tmp, data, mode); err != nil {
+		files.Remove(tmp)
+		return err
+	}
+	return files.Rename(tmp, path)
+}
@@ -0,0 +1,88 @@
+package adfer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPersistentStats(t *testing.T) {
+	dir := t.TempDir()
+	for name, option := range map[string]Option{
+		"file": func(o *Options) { o.DumpToFile, o.FilePath = true, filepath.Join(dir, "crash.json") },
+		"dir":  WithCrashDir(filepath.Join(dir, "crashes")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var fingerprint string
+			// Each handler stands for a restart of the application
+			for i := 0; i < 2; i++ {
+				ph := New(Options{ErrorHandler: func(error, []byte) {}}, option, WithPersistentStats())
+				func() {
+					defer ph.Recover()
+					panic("boom")
+				}()
+				if i == 0 {
+					reports, _ := ph.GetLastNCrashReports(1)
+					fingerprint = Fingerprint(reports[0])
+					ph.Report(errors.New("handled"))
+					if err := ph.WipeCrashFile(); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+				}
+			}
+
+			ph := New(Options{ErrorHandler: func(error, []byte) {}}, option, WithPersistentStats())
+			stats := ph.Stats()
+			if stats.Panics != 0 || stats.Lifetime == nil {
+				t.Fatalf("Expected only lifetime counters, got %+v", stats)
+			}
+			lifetime := stats.Lifetime
+			if lifetime.Panics != 2 || lifetime.Reports != 1 || lifetime.LastCrash.IsZero() {
+				t.Errorf("Expected 2 panics and 1 report, got %+v", lifetime)
+			}
+			if lifetime.ByFingerprint[fingerprint] != 2 || len(lifetime.ByFingerprint) != 2 {
+				t.Errorf("Expected 2 panics with fingerprint %s, got %v", fingerprint, lifetime.ByFingerprint)
+			}
+		})
+	}
+}
+
+func TestPersistentStatsDisabled(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	ph := New(Options{ErrorHandler: func(error, []byte) {}, DumpToFile: true, FilePath: filePath})
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if stats := ph.Stats(); stats.Panics != 1 || stats.Lifetime != nil {
+		t.Errorf("Expected no lifetime counters, got %+v", stats)
+	}
+	if _, err := os.Stat(filePath + ".stats"); !os.IsNotExist(err) {
+		t.Errorf("Expected no stats file, got %v", err)
+	}
+}
+
+func TestPersistentStatsCorrupt(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crash.json")
+	if err := os.WriteFile(filePath+".stats", []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filePath,
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithPersistentStats())
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if stats := ph.Stats(); stats.Lifetime == nil || stats.Lifetime.Panics != 1 {
+		t.Errorf("Expected the counters to start over, got %+v", stats.Lifetime)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Unexpected diagnostics %v", diagnostics)
+	}
+}
@@ -0,0 +1,108 @@
+package adfer
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsdOptions configures the metrics sent to a statsd or DogStatsD server
+type StatsdOptions struct {
+	// Addr is the UDP address of the server. Defaults to "127.0.0.1:8125"
+	Addr string
+	// Prefix is prepended to metric names. Defaults to "adfer"
+	Prefix string
+	// DogStatsD sends the fingerprint and category as DogStatsD tags. Plain statsd has no tags,
+	// so the category is appended to the metric name instead and the fingerprint is dropped
+	DogStatsD bool
+	// Tags are added to every metric, e.g. "env:prod". They are only sent with DogStatsD
+	Tags []string
+}
+
+// WithStatsd sends metrics to a statsd or DogStatsD server: the <prefix>.panics and <prefix>.errors
+// counters for panics and handled errors, and the <prefix>.report_write timing of storing a report
+func WithStatsd(options StatsdOptions) Option {
+	return func(o *Options) {
+		o.Statsd = &options
+	}
+}
+
+// statsd sends metrics over UDP in the statsd line format
+type statsd struct {
+	options  StatsdOptions
+	diagnose func(op string, path string, err error)
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newStatsd creates a statsd client. The connection is made when the first metric is sent
+func newStatsd(options StatsdOptions, diagnose func(op string, path string, err error)) *statsd {
+	if options.Addr == "" {
+		options.Addr = "127.0.0.1:8125"
+	}
+	if options.Prefix == "" {
+		options.Prefix = "adfer"
+	}
+	return &statsd{options: options, diagnose: diagnose}
+}
+
+// reportCount counts a crash report, tagged with its fingerprint and category
+func (s *statsd) reportCount(report CrashReport) {
+	name := "panics"
+	if report.Handled {
+		name = "errors"
+	}
+	tags := []string{"fingerprint:" + Fingerprint(report)}
+	if report.Category != "" {
+		tags = append(tags, "category:"+report.Category)
+		if !s.options.DogStatsD {
+			name += "." + report.Category
+		}
+	}
+	s.send(name, "1|c", tags)
+}
+
+// timing sends the duration of an operation in milliseconds
+func (s *statsd) timing(name string, d time.Duration) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	s.send(name, ms+"|ms", nil)
+}
+
+// send writes a metric line. Failures are reported as diagnostics
+func (s *statsd) send(name, value string, tags []string) {
+	var line strings.Builder
+	line.WriteString(s.options.Prefix + "." + name + ":" + value)
+	if s.options.DogStatsD {
+		tags = append(append([]string{}, s.options.Tags...), tags...)
+		if len(tags) > 0 {
+			line.WriteString("|#" + strings.Join(tags, ","))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := net.Dial("udp", s.options.Addr)
+		if err != nil {
+			s.diagnose(OpMetrics, s.options.Addr, err)
+			return
+		}
+		s.conn = conn
+	}
+	if _, err := s.conn.Write([]byte(line.String())); err != nil {
+		s.diagnose(OpMetrics, s.options.Addr, err)
+	}
+}
+
+// close closes the connection to the server
+func (s *statsd) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,73 @@
+package adfer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenStatsd starts a UDP server and returns its address and a function returning the next metric
+func listenStatsd(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), func() string {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Expected a metric: %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestStatsdDogStatsD(t *testing.T) {
+	addr, next := listenStatsd(t)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	}, WithStatsd(StatsdOptions{Addr: addr, DogStatsD: true, Tags: []string{"env:test"}}))
+	defer ph.Close()
+
+	var report CrashReport
+	func() {
+		defer func() { report = ph.handlePanic(context.Background(), recover()) }()
+		panic("boom")
+	}()
+	want := "adfer.panics:1|c|#env:test,fingerprint:" + Fingerprint(report) + ",category:value"
+	if metric := next(); metric != want {
+		t.Errorf("Expected %q, got %q", want, metric)
+	}
+	if metric := next(); !strings.HasPrefix(metric, "adfer.report_write:") || !strings.HasSuffix(metric, "|ms|#env:test") {
+		t.Errorf("Expected the report write timing, got %q", metric)
+	}
+
+	ph.Report(errors.New("handled"))
+	if metric := next(); !strings.HasPrefix(metric, "adfer.errors:1|c|#env:test,fingerprint:") {
+		t.Errorf("Expected the errors counter, got %q", metric)
+	}
+}
+
+func TestStatsdPlain(t *testing.T) {
+	addr, next := listenStatsd(t)
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStatsd(StatsdOptions{Addr: addr, Prefix: "app", Tags: []string{"env:test"}}))
+	defer ph.Close()
+
+	func() {
+		defer ph.Recover()
+		var m map[string]int
+		m["boom"]++
+	}()
+	if metric := next(); metric != "app.panics.runtime:1|c" {
+		t.Errorf("Expected the category in the metric name, got %q", metric)
+	}
+}
@@ -0,0 +1,323 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Storage stores crash reports. The JSON crash file at Options.FilePath is used unless
+// Options.Storage is set, e.g. to store reports in a database, in memory or remotely
+type Storage interface {
+	// Append stores a crash report
+	Append(report CrashReport) error
+	// LastN returns the last n crash reports, oldest first. If n is negative, every report is returned
+	LastN(n int) ([]CrashReport, error)
+	// Wipe removes every crash report
+	Wipe() error
+}
+
+// ReportUpdater is implemented by storages that can update a stored crash report. Delivery
+// receipts are only stored by storages that implement it
+type ReportUpdater interface {
+	// Update calls fn with the stored report with the given ID and stores the result.
+	// It returns ErrReportNotFound if there is no such report
+	Update(id string, fn func(report *CrashReport)) error
+}
+
+// WithStorage stores crash reports in storage instead of the crash file
+func WithStorage(storage Storage) Option {
+	return func(o *Options) {
+		o.Storage = storage
+	}
+}
+
+// storageReporter appends crash reports to the storage
+type storageReporter struct {
+	storage       Storage
+	statsd        *statsd
+	encryptionKey []byte
+	persistStats  bool
+	diagnose      func(op string, path string, err error)
+}
+
+// Report appends the report to the storage, encrypting it if a key is set, timing
+// the write if metrics are enabled and counting it if lifetime counters are persisted
+func (s storageReporter) Report(_ context.Context, report CrashReport) error {
+	stored := report
+	if s.encryptionKey != nil {
+		encrypted, err := EncryptCrashReport(report, s.encryptionKey)
+		if err != nil {
+			s.diagnose(OpEncode, "", err)
+			return err
+		}
+		stored = encrypted
+	}
+	start := time.Now()
+	err := s.storage.Append(stored)
+	if s.statsd != nil {
+		s.statsd.timing("report_write", time.Since(start))
+	}
+	if store, ok := s.storage.(StatsStore); ok && s.persistStats && err == nil {
+		if err := store.UpdateStats(func(stats *LifetimeStats) { stats.add(report) }); err != nil {
+			s.diagnose(OpWrite, "", err)
+		}
+	}
+	return err
+}
+
+// fileStorage stores crash reports in a file, as a JSON array optionally with a sidecar index, or as JSON Lines
+type fileStorage struct {
+	path       string
+	format     FileFormat
+	index      bool
+	compressed bool
+	stackDiffs bool
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	maxReports int
+	perms      filePerms
+	fsys       FileSystem
+	hooks      FileHooks
+	diagnose   func(op string, path string, err error)
+
+	// mu serialises read-modify-write cycles of the crash file
+	mu sync.Mutex
+	// events are the hook calls queued while the crash file is locked
+	events []func()
+	// lines is the number of reports in a JSON Lines crash file, if counted is set
+	lines   int
+	counted bool
+}
+
+// newFileStorage creates the storage of the crash file configured in options
+func newFileStorage(options Options, diagnose func(op string, path string, err error)) *fileStorage {
+	array := options.FileFormat == FormatJSON
+	// Offsets of the index point into the uncompressed file, which is read directly from the OS
+	indexed := options.Index && array && !options.Compress && options.FileSystem == nil
+	var hooks FileHooks
+	if options.FileHooks != nil {
+		hooks = *options.FileHooks
+	}
+	return &fileStorage{
+		path:       options.FilePath,
+		format:     options.FileFormat,
+		index:      indexed,
+		compressed: options.Compress,
+		stackDiffs: options.StackDiffs && array,
+		maxSize:    options.MaxFileSize,
+		maxAge:     options.MaxFileAge,
+		maxBackups: options.MaxFileBackups,
+		maxReports: options.MaxReports,
+		perms:      permsFromOptions(options),
+		fsys:       options.FileSystem,
+		hooks:      hooks,
+		diagnose:   diagnose,
+	}
+}
+
+// Append appends a report to the crash file. Failures are reported as diagnostics and returned
+func (f *fileStorage) Append(report CrashReport) error {
+	defer f.fireHooks()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.mkdir(); err != nil {
+		f.diagnose(OpWrite, f.path, err)
+		return err
+	}
+	defer f.lock(true)()
+	if f.format == FormatJSONLines {
+		if f.rotates() {
+			f.rotateLines()
+		}
+		if err := f.appendLine(report); err != nil {
+			return err
+		}
+		if f.maxReports > 0 {
+			f.trimLines()
+		}
+		return nil
+	}
+
+	var reports []CrashReport
+
+	// The size of the file on disk decides rotation
+	data, err := f.files().ReadFile(f.path)
+	if err == nil {
+		reports = f.decodeArray(data)
+	} else if !os.IsNotExist(err) {
+		f.diagnose(OpRead, f.path, err)
+	}
+	if f.rotates() && len(reports) > 0 && f.shouldRotate(int64(len(data)), reports[0].Timestamp) {
+		if err := f.rotate(); err != nil {
+			f.diagnose(OpRotate, f.path, err)
+		} else {
+			reports = nil
+		}
+	}
+
+	if f.stackDiffs {
+		report = compactStack(reports, report)
+	}
+	reports = append(reports, report)
+	if f.maxReports > 0 && len(reports) > f.maxReports {
+		drop := len(reports) - f.maxReports
+		reports = dropReports(reports, func(i int, _ CrashReport) bool { return i < drop })
+		f.pruned(drop)
+	}
+
+	data, offsets, err := encodeCrashReports(reports)
+	if err != nil {
+		f.diagnose(OpEncode, f.path, err)
+		return err
+	}
+	err = f.write(data, offsets)
+	if err != nil {
+		f.diagnose(OpWrite, f.path, err)
+	}
+	return err
+}
+
+// LastN returns the last n reports of the crash file, using the index if it is enabled
+func (f *fileStorage) LastN(n int) ([]CrashReport, error) {
+	// f.mu is taken before the crash file lock, as by every other reader and writer
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.path != "" {
+		defer f.lock(false)()
+	}
+	if f.format == FormatJSONLines {
+		if n < 0 || f.compressed || !f.native() {
+			// Compressed files can't be read backwards
+			reports, err := f.readLines()
+			if err != nil || n < 0 || len(reports) <= n {
+				return reports, err
+			}
+			return reports[len(reports)-n:], nil
+		}
+		return f.readLastLines(n)
+	}
+	if n >= 0 {
+		if reports, ok := f.readLast(n); ok && !hasStackDiffs(reports) {
+			return reports, nil
+		}
+	}
+	reports, err := f.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || len(reports) <= n {
+		return reports, nil
+	}
+	return reports[len(reports)-n:], nil
+}
+
+// readAll reads all crash reports from the crash file
+func (f *fileStorage) readAll() ([]CrashReport, error) {
+	if f.path == "" {
+		return nil, fmt.Errorf("no file path set for crash reports")
+	}
+	data, err := f.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []CrashReport
+	err = json.Unmarshal(data, &reports)
+	if err != nil {
+		return nil, err
+	}
+	expandStacks(reports)
+	return reports, nil
+}
+
+// Wipe clears all crash reports from the crash file
+func (f *fileStorage) Wipe() error {
+	if f.path == "" {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.mkdir(); err != nil {
+		return err
+	}
+	defer f.lock(true)()
+	if f.format == FormatJSONLines {
+		f.lines, f.counted = 0, true
+		return f.writeFile(f.path, nil)
+	}
+	return f.write([]byte("[]"), nil)
+}
+
+// Update updates the report with the given ID in the crash file
+func (f *fileStorage) Update(id string, fn func(report *CrashReport)) error {
+	return f.modify(func(reports []CrashReport) ([]CrashReport, error) {
+		for i := range reports {
+			if reports[i].ID == id {
+				fn(&reports[i])
+				return reports, nil
+			}
+		}
+		return nil, ErrReportNotFound
+	})
+}
+
+// modify reads the crash file, applies fn and writes the result back
+func (f *fileStorage) modify(fn func([]CrashReport) ([]CrashReport, error)) error {
+	if f.path == "" {
+		return fmt.Errorf("no file path set for crash reports")
+	}
+	defer f.fireHooks()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.mkdir(); err != nil {
+		return err
+	}
+	defer f.lock(true)()
+	return f.modifyLocked(fn)
+}
+
+// mkdir creates the missing parent directories of the crash file, e.g. for ~/.myapp/crashes/panic.json
+func (f *fileStorage) mkdir() error {
+	return f.files().MkdirAll(filepath.Dir(f.path), f.perms.dir)
+}
+
+// modifyLocked is modify for callers holding the lock
+func (f *fileStorage) modifyLocked(fn func([]CrashReport) ([]CrashReport, error)) error {
+	var reports []CrashReport
+	data, err := f.files().ReadFile(f.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && f.format == FormatJSONLines {
+		if data, err = decompress(data); err != nil {
+			return err
+		}
+		reports = f.decodeLines(data)
+	} else if err == nil {
+		reports = f.decodeArray(data)
+	}
+	reports, err = fn(reports)
+	if err != nil {
+		return err
+	}
+	if f.format == FormatJSONLines {
+		data, err := encodeLines(reports)
+		if err != nil {
+			return err
+		}
+		f.lines, f.counted = len(reports), true
+		return f.writeFile(f.path, f.compressData(data))
+	}
+	data, offsets, err := encodeCrashReports(reports)
+	if err != nil {
+		return err
+	}
+	return f.write(data, offsets)
+}
@@ -0,0 +1,136 @@
+package adfer
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// memoryStorage stores crash reports in memory
+type memoryStorage struct {
+	mu      sync.Mutex
+	reports []CrashReport
+}
+
+func (m *memoryStorage) Append(report CrashReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+func (m *memoryStorage) LastN(n int) ([]CrashReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 0 || n > len(m.reports) {
+		n = len(m.reports)
+	}
+	return append([]CrashReport{}, m.reports[len(m.reports)-n:]...), nil
+}
+
+func (m *memoryStorage) Wipe() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports = nil
+	return nil
+}
+
+// updatableStorage is a memoryStorage that can store delivery receipts
+type updatableStorage struct {
+	memoryStorage
+}
+
+func (u *updatableStorage) Update(id string, fn func(report *CrashReport)) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := range u.reports {
+		if u.reports[i].ID == id {
+			fn(&u.reports[i])
+			return nil
+		}
+	}
+	return ErrReportNotFound
+}
+
+func TestCustomStorage(t *testing.T) {
+	storage := &memoryStorage{}
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithStorage(storage))
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic(i)
+		}()
+	}
+
+	reports, err := ph.GetLastNCrashReports(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 2 || reports[0].Error != "1" || reports[1].Error != "2" {
+		t.Fatalf("Expected the last 2 reports, got %+v", reports)
+	}
+	if err := ph.WipeCrashFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(storage.reports) != 0 {
+		t.Errorf("Expected the storage to be wiped, got %d reports", len(storage.reports))
+	}
+}
+
+func TestCustomStorageDeliveries(t *testing.T) {
+	storage := &updatableStorage{}
+	ph := New(Options{ErrorHandler: func(error, []byte) {}},
+		WithStorage(storage),
+		WithReporter(ReporterFunc(func(context.Context, CrashReport) error { return nil })))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	reports, _ := ph.GetLastNCrashReports(1)
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	for name, delivery := range reports[0].Deliveries {
+		if delivery.Status != DeliverySent {
+			t.Errorf("Expected delivery to %s to be sent, got %s", name, delivery.Status)
+		}
+	}
+	if len(reports[0].Deliveries) != 1 {
+		t.Errorf("Expected 1 delivery receipt, got %v", reports[0].Deliveries)
+	}
+
+	// Storages that can't update reports don't get receipts
+	plain := &memoryStorage{}
+	ph = New(Options{ErrorHandler: func(error, []byte) {}},
+		WithStorage(plain),
+		WithReporter(ReporterFunc(func(context.Context, CrashReport) error { return nil })))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	if len(plain.reports) != 1 || plain.reports[0].Deliveries != nil {
+		t.Errorf("Expected 1 report without receipts, got %+v", plain.reports)
+	}
+}
+
+func TestCrashFileDirectoryCreated(t *testing.T) {
+	for _, format := range []FileFormat{FormatJSON, FormatJSONLines} {
+		filePath := filepath.Join(t.TempDir(), ".myapp", "crashes", "panic.json")
+		ph := New(Options{
+			ErrorHandler: func(error, []byte) {},
+			OnDiagnostic: func(d Diagnostic) { t.Errorf("Format %d: unexpected diagnostic: %v", format, d) },
+			DumpToFile:   true,
+			FilePath:     filePath,
+			FileFormat:   format,
+		})
+		func() {
+			defer ph.Recover()
+			panic("boom")
+		}()
+		if reports, err := ph.GetLastNCrashReports(1); err != nil || len(reports) != 1 {
+			t.Errorf("Format %d: expected the report to be written, got %d, error %v", format, len(reports), err)
+		}
+	}
+}
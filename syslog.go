@@ -0,0 +1,84 @@
+//go:build !windows && !plan9
+
+package adfer
+
+import (
+	"context"
+	"log/syslog"
+	"sync"
+)
+
+// SyslogReporter writes crash reports to a local or remote syslog daemon
+type SyslogReporter struct {
+	network string
+	addr    string
+	tag     string
+
+	mu     sync.Mutex
+	writer *syslog.Writer
+}
+
+// NewSyslogReporter creates a SyslogReporter. If network and addr are empty, the
+// local syslog daemon is used. The connection is made when the first report is written
+func NewSyslogReporter(network, addr, tag string) *SyslogReporter {
+	return &SyslogReporter{
+		network: network,
+		addr:    addr,
+		tag:     tag,
+	}
+}
+
+// WithSyslog writes recovered panics to syslog. On Linux, if network and addr are
+// empty and the systemd journal is available, reports are written to the journal instead
+func WithSyslog(network, addr, tag string) Option {
+	return func(o *Options) {
+		if network == "" && addr == "" && journalAvailable() {
+			o.Reporters = append(o.Reporters, NewJournaldReporter(tag))
+			return
+		}
+		o.Reporters = append(o.Reporters, NewSyslogReporter(network, addr, tag))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (s *SyslogReporter) Name() string {
+	return "syslog"
+}
+
+// Report writes the crash report to syslog at the level of its severity: LOG_CRIT for fatal crashes, LOG_ERR
+// for errors, LOG_WARNING for warnings and LOG_INFO for info. Reports without a severity are logged at
+// LOG_CRIT if they were recovered from a panic and LOG_ERR if they were handled
+func (s *SyslogReporter) Report(_ context.Context, report CrashReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		writer, err := syslog.Dial(s.network, s.addr, syslog.LOG_USER|syslog.LOG_CRIT, s.tag)
+		if err != nil {
+			return err
+		}
+		s.writer = writer
+	}
+	fallback := s.writer.Crit
+	if report.Handled {
+		fallback = s.writer.Err
+	}
+	write := severityOf(report, map[Severity]func(string) error{
+		SeverityFatal:   s.writer.Crit,
+		SeverityError:   s.writer.Err,
+		SeverityWarning: s.writer.Warning,
+		SeverityInfo:    s.writer.Info,
+	}, fallback)
+	return write(logLine(report))
+}
+
+// Close closes the connection to the syslog daemon
+func (s *SyslogReporter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		return nil
+	}
+	err := s.writer.Close()
+	s.writer = nil
+	return err
+}
@@ -0,0 +1,42 @@
+//go:build windows || plan9
+
+package adfer
+
+import (
+	"context"
+	"errors"
+)
+
+// errSyslogUnsupported is returned when syslog is not available on the platform
+var errSyslogUnsupported = errors.New("syslog is not supported on this platform")
+
+// SyslogReporter writes crash reports to a local or remote syslog daemon.
+// It is not supported on this platform and every report fails
+type SyslogReporter struct{}
+
+// NewSyslogReporter creates a SyslogReporter
+func NewSyslogReporter(network, addr, tag string) *SyslogReporter {
+	return &SyslogReporter{}
+}
+
+// WithSyslog writes recovered panics to syslog
+func WithSyslog(network, addr, tag string) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewSyslogReporter(network, addr, tag))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (s *SyslogReporter) Name() string {
+	return "syslog"
+}
+
+// Report returns an error as syslog is not supported on this platform
+func (s *SyslogReporter) Report(context.Context, CrashReport) error {
+	return errSyslogUnsupported
+}
+
+// Close does nothing
+func (s *SyslogReporter) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build !windows && !plan9
+
+package adfer
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogReporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	reporter := NewSyslogReporter("udp", conn.LocalAddr().String(), "myapp")
+	defer reporter.Close()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read syslog message: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	if err := reporter.Report(context.Background(), CrashReport{ID: "abc", Error: "boom", Stack: testStack}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	message := read()
+	// LOG_USER|LOG_CRIT
+	if !strings.HasPrefix(message, "<10>") {
+		t.Errorf("Expected critical priority, got '%s'", message)
+	}
+	for _, expected := range []string{"myapp", "Panic recovered: boom [id=abc] at main.inner (/app/main.go:5) <- main.main"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected message to contain '%s', got '%s'", expected, message)
+		}
+	}
+
+	if err := reporter.Report(context.Background(), CrashReport{Error: "handled", Handled: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// LOG_USER|LOG_ERR
+	if message := read(); !strings.HasPrefix(message, "<11>") || !strings.Contains(message, "Error reported: handled") {
+		t.Errorf("Expected error priority, got '%s'", message)
+	}
+
+	if err := reporter.Report(context.Background(), CrashReport{Error: "slow", Handled: true, Severity: SeverityWarning}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// LOG_USER|LOG_WARNING
+	if message := read(); !strings.HasPrefix(message, "<12>") {
+		t.Errorf("Expected warning priority, got '%s'", message)
+	}
+}
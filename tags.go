@@ -0,0 +1,42 @@
+package adfer
+
+import "context"
+
+type tagsKey struct{}
+
+// WithTags returns a copy of ctx carrying the given tags in addition to any
+// tags already stored in ctx. Tags are added to crash reports recovered by
+// RecoverCtx and SafeGoCtx, e.g. adfer.WithTags(ctx, "queue=email", "shard=7")
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	existing := TagsFromContext(ctx)
+	merged := make([]string, 0, len(existing)+len(tags))
+	merged = append(merged, existing...)
+	merged = append(merged, tags...)
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// TagsFromContext returns the tags stored in ctx
+func TagsFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags
+}
+
+// HasTags returns true if the report has every one of the given tags
+func (r CrashReport) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, reportTag := range r.Tags {
+			if reportTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
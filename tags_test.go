@@ -0,0 +1,82 @@
+package adfer
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithTags(t *testing.T) {
+	ctx := WithTags(context.Background(), "queue=email")
+	child := WithTags(ctx, "shard=7")
+
+	if !reflect.DeepEqual(TagsFromContext(ctx), []string{"queue=email"}) {
+		t.Errorf("Expected parent tags to be unchanged, got %v", TagsFromContext(ctx))
+	}
+	if !reflect.DeepEqual(TagsFromContext(child), []string{"queue=email", "shard=7"}) {
+		t.Errorf("Unexpected child tags %v", TagsFromContext(child))
+	}
+	if TagsFromContext(context.Background()) != nil {
+		t.Error("Expected no tags in background context")
+	}
+}
+
+func TestSafeGoCtxTags(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "crash_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	done := make(chan CrashReport, 1)
+	ph := New(Options{
+		DumpToFile:   true,
+		FilePath:     tempFile.Name(),
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(done)},
+	})
+
+	ctx := WithTags(context.Background(), "queue=email", "shard=7")
+	ph.SafeGoCtx(ctx, func(ctx context.Context) {
+		panic("tagged panic")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for panic to be handled")
+	}
+	func() {
+		defer ph.RecoverCtx(WithTags(context.Background(), "queue=sms"))
+		panic("second panic")
+	}()
+
+	reports, err := ph.GetCrashReportsWithTags("queue=email")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Error != "tagged panic" {
+		t.Fatalf("Expected tagged report, got %+v", reports)
+	}
+	if !reports[0].HasTags("queue=email", "shard=7") || reports[0].HasTags("shard=8") {
+		t.Errorf("Unexpected tags %v", reports[0].Tags)
+	}
+
+	reports, err = ph.GetCrashReportsWithTags("queue=sms")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Error != "second panic" {
+		t.Errorf("Expected report from RecoverCtx, got %+v", reports)
+	}
+}
+
+// channelReporter sends every report to the channel, after it has been written to file
+type channelReporter chan CrashReport
+
+func (c channelReporter) Report(_ context.Context, report CrashReport) error {
+	c <- report
+	return nil
+}
@@ -0,0 +1,97 @@
+package adfer
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data available to metadata templates
+type TemplateData struct {
+	// ReportID is the ID of the crash report being created
+	ReportID string
+	// Hostname is the name of the host the crash happened on
+	Hostname string
+	// PID is the process ID
+	PID int
+	// Timestamp is when the crash happened
+	Timestamp time.Time
+	// Error is the error message of the crash
+	Error string
+	// OS is the operating system, as reported by runtime.GOOS
+	OS string
+	// Architecture is the architecture, as reported by runtime.GOARCH
+	Architecture string
+	// GoVersion is the Go version the program was built with
+	GoVersion string
+}
+
+// orDefault returns value, or fallback if value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// templateFuncs are the functions available to metadata templates
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+}
+
+// parseMetadataTemplates parses metadata values that contain template actions.
+// Values that fail to parse are reported as diagnostics and used verbatim.
+func (ph *PanicHandler) parseMetadataTemplates() {
+	for key, value := range ph.options.Metadata {
+		if !strings.Contains(value, "{{") {
+			continue
+		}
+		tmpl, err := template.New(key).Funcs(templateFuncs).Option("missingkey=zero").Parse(value)
+		if err != nil {
+			ph.diagnose(OpTemplate, key, err)
+			continue
+		}
+		if ph.templates == nil {
+			ph.templates = make(map[string]*template.Template)
+		}
+		ph.templates[key] = tmpl
+	}
+}
+
+// resolveMetadata returns a copy of the configured metadata with templates
+// resolved against the given report
+func (ph *PanicHandler) resolveMetadata(report CrashReport) map[string]string {
+	if len(ph.options.Metadata) == 0 {
+		return nil
+	}
+	metadata := make(map[string]string, len(ph.options.Metadata))
+	for key, value := range ph.options.Metadata {
+		metadata[key] = value
+	}
+	if len(ph.templates) == 0 {
+		return metadata
+	}
+
+	// The runtime's values are used if system information isn't included in the report
+	data := TemplateData{
+		ReportID:     report.ID,
+		Hostname:     reportHost(report),
+		PID:          os.Getpid(),
+		Timestamp:    report.Timestamp,
+		Error:        report.Error,
+		OS:           orDefault(report.SystemInfo.OS, runtime.GOOS),
+		Architecture: orDefault(report.SystemInfo.Architecture, runtime.GOARCH),
+		GoVersion:    orDefault(report.SystemInfo.GoVersion, runtime.Version()),
+	}
+	for key, tmpl := range ph.templates {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			ph.diagnose(OpTemplate, key, err)
+			continue
+		}
+		metadata[key] = sb.String()
+	}
+	return metadata
+}
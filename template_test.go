@@ -0,0 +1,71 @@
+package adfer
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestMetadataTemplates(t *testing.T) {
+	t.Setenv("ADFER_REGION", "eu-west-1")
+	hostname, _ := os.Hostname()
+
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		Metadata: map[string]string{
+			"static":   "value",
+			"host":     "{{.Hostname}}",
+			"region":   `{{env "ADFER_REGION"}}`,
+			"report":   "{{.ReportID}}",
+			"combined": "{{.OS}}-{{.PID}}",
+			"invalid":  "{{.Unclosed",
+		},
+		IncludeSystemInfo: true,
+		OnDiagnostic: func(d Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		},
+	})
+
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpTemplate || diagnostics[0].Path != "invalid" {
+		t.Fatalf("Expected template diagnostic for invalid value, got %+v", diagnostics)
+	}
+
+	report := CrashReport{ID: newReportID(), SystemInfo: SystemInfo{OS: "linux", Hostname: hostname}}
+	metadata := ph.resolveMetadata(report)
+
+	expected := map[string]string{
+		"static":   "value",
+		"host":     hostname,
+		"region":   "eu-west-1",
+		"report":   report.ID,
+		"combined": "linux-" + strconv.Itoa(os.Getpid()),
+		"invalid":  "{{.Unclosed",
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("Expected metadata '%s' to be '%s', got '%s'", key, value, metadata[key])
+		}
+	}
+	if ph.options.Metadata["host"] != "{{.Hostname}}" {
+		t.Error("Expected configured metadata to be left untouched")
+	}
+}
+
+func TestMetadataTemplatesRuntime(t *testing.T) {
+	ph := New(Options{Metadata: map[string]string{"platform": "{{.OS}}/{{.Architecture}} {{.GoVersion}}"}})
+	metadata := ph.resolveMetadata(CrashReport{})
+	if expected := runtime.GOOS + "/" + runtime.GOARCH + " " + runtime.Version(); metadata["platform"] != expected {
+		t.Errorf("Expected the runtime's values without system information, got '%s'", metadata["platform"])
+	}
+}
+
+func TestReportIDs(t *testing.T) {
+	id := newReportID()
+	if len(id) != 36 || id[14] != '4' {
+		t.Errorf("Expected version 4 UUID, got '%s'", id)
+	}
+	if id == newReportID() {
+		t.Error("Expected unique report IDs")
+	}
+}
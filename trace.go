@@ -0,0 +1,115 @@
+package adfer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// errTraceUnsupported is returned when flight recording is not available in the Go version used to build the program
+var errTraceUnsupported = errors.New("execution trace capture requires Go 1.25 or later")
+
+// TraceOptions configures execution trace capture
+type TraceOptions struct {
+	// MinAge is how much recent execution trace to keep. Defaults to 5 seconds
+	MinAge time.Duration
+	// MaxBytes is an upper bound on the size of the kept trace. Takes precedence over MinAge. Defaults to 10MB
+	MaxBytes uint64
+	// Dir is the directory trace files are written to. Defaults to the directory of
+	// FilePath, or the system temp directory if FilePath is not set
+	Dir string
+}
+
+// traceRecorder keeps a moving window of the execution trace
+type traceRecorder interface {
+	WriteTo(w io.Writer) (int64, error)
+	Stop()
+}
+
+// WithTraceCapture keeps the last few seconds of the runtime execution trace in
+// memory and writes it to a file next to each crash report. The file can be
+// inspected with "go tool trace". Requires Go 1.25 or later
+func WithTraceCapture(options TraceOptions) Option {
+	return func(o *Options) {
+		o.TraceCapture = &options
+	}
+}
+
+// startTraceCapture starts the flight recorder if trace capture is enabled
+func (ph *PanicHandler) startTraceCapture() {
+	options := ph.options.TraceCapture
+	if options == nil {
+		return
+	}
+	if options.MinAge <= 0 {
+		options.MinAge = 5 * time.Second
+	}
+	if options.MaxBytes == 0 {
+		options.MaxBytes = 10 << 20
+	}
+	if options.Dir == "" {
+		options.Dir = os.TempDir()
+		if ph.options.FilePath != "" {
+			options.Dir = filepath.Dir(ph.options.FilePath)
+		}
+	}
+	recorder, err := startFlightRecorder(*options)
+	if err != nil {
+		ph.diagnose(OpTrace, "", err)
+		return
+	}
+	ph.tracer = recorder
+}
+
+// tracing returns true if the flight recorder is running
+func (ph *PanicHandler) tracing() bool {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return ph.tracer != nil
+}
+
+// captureTrace writes the execution trace to a file, creating its directory if needed, and records
+// its path in the report
+func (ph *PanicHandler) captureTrace(report *CrashReport) {
+	ph.mu.Lock()
+	recorder := ph.tracer
+	ph.mu.Unlock()
+	if recorder == nil {
+		return
+	}
+
+	dir := ph.options.TraceCapture.Dir
+	path := filepath.Join(dir, "trace-"+report.ID+".out")
+	if err := ph.perms.mkdirAll(dir); err != nil {
+		ph.diagnose(OpTrace, dir, err)
+		return
+	}
+	file, err := ph.perms.create(path)
+	if err != nil {
+		ph.diagnose(OpTrace, path, err)
+		return
+	}
+	_, err = recorder.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		ph.diagnose(OpTrace, path, err)
+		_ = os.Remove(path)
+		return
+	}
+	report.TraceFile = path
+}
+
+// StopTraceCapture stops the execution trace flight recorder, if running
+func (ph *PanicHandler) StopTraceCapture() {
+	ph.mu.Lock()
+	recorder := ph.tracer
+	ph.tracer = nil
+	ph.mu.Unlock()
+	if recorder != nil {
+		recorder.Stop()
+	}
+}
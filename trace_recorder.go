@@ -0,0 +1,17 @@
+//go:build go1.25
+
+package adfer
+
+import "runtime/trace"
+
+// startFlightRecorder starts a runtime/trace flight recorder
+func startFlightRecorder(options TraceOptions) (traceRecorder, error) {
+	recorder := trace.NewFlightRecorder(trace.FlightRecorderConfig{
+		MinAge:   options.MinAge,
+		MaxBytes: options.MaxBytes,
+	})
+	if err := recorder.Start(); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
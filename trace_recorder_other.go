@@ -0,0 +1,8 @@
+//go:build !go1.25
+
+package adfer
+
+// startFlightRecorder returns an error as flight recording requires Go 1.25
+func startFlightRecorder(TraceOptions) (traceRecorder, error) {
+	return nil, errTraceUnsupported
+}
@@ -0,0 +1,48 @@
+//go:build go1.25
+
+package adfer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTraceCapture(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "traces")
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+		OnDiagnostic: func(d Diagnostic) {
+			t.Errorf("Unexpected diagnostic: %v", d)
+		},
+	}, WithTraceCapture(TraceOptions{Dir: dir}))
+	defer ph.StopTraceCapture()
+
+	func() {
+		defer ph.Recover()
+		panic("traced panic")
+	}()
+
+	report := <-reports
+	if report.TraceFile != filepath.Join(dir, "trace-"+report.ID+".out") {
+		t.Fatalf("Unexpected trace file '%s'", report.TraceFile)
+	}
+	info, err := os.Stat(report.TraceFile)
+	if err != nil {
+		t.Fatalf("Expected trace file to exist: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Error("Expected non-empty trace file")
+	}
+
+	ph.StopTraceCapture()
+	func() {
+		defer ph.Recover()
+		panic("untraced panic")
+	}()
+	if report := <-reports; report.TraceFile != "" {
+		t.Errorf("Expected no trace after stopping capture, got '%s'", report.TraceFile)
+	}
+}
@@ -0,0 +1,95 @@
+package adfer
+
+import (
+	"fmt"
+	"time"
+)
+
+// CrashStats aggregates the stored crash reports, e.g. for a crash trends view
+type CrashStats struct {
+	// Total is the number of stored reports
+	Total int `json:"total"`
+	// Panics is the number of stored panics
+	Panics int `json:"panics"`
+	// Handled is the number of stored handled errors, submitted with Report
+	Handled int `json:"handled"`
+	// FirstSeen and LastSeen are the times of the oldest and the newest report
+	FirstSeen time.Time `json:"first_seen,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	// ByFingerprint holds the statistics of each fingerprint, see Fingerprint
+	ByFingerprint map[string]FingerprintStats `json:"by_fingerprint,omitempty"`
+	// Daily is the number of reports per day in UTC, from the day of the oldest report to the day
+	// of the newest, including days without reports
+	Daily []DayCount `json:"daily,omitempty"`
+}
+
+// FingerprintStats aggregates the stored crash reports of a fingerprint
+type FingerprintStats struct {
+	// Count is the number of reports with the fingerprint
+	Count int `json:"count"`
+	// FirstSeen and LastSeen are the times of the oldest and the newest report with the fingerprint
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// DayCount is the number of crash reports created on a day
+type DayCount struct {
+	// Day is midnight UTC of the day
+	Day   time.Time `json:"day"`
+	Count int       `json:"count"`
+}
+
+// CrashStats aggregates the stored crash reports into totals, statistics per fingerprint and a
+// per-day histogram. It isn't named Stats because PanicHandler.Stats already returns the counters
+// of the current process, whereas CrashStats covers every stored report. The crash file is read
+// a page at a time
+func (s *reportStore) CrashStats() (CrashStats, error) {
+	if s.storage == nil {
+		return CrashStats{}, fmt.Errorf("no file path set for crash reports")
+	}
+	stats := CrashStats{ByFingerprint: make(map[string]FingerprintStats)}
+	days := make(map[time.Time]int)
+	err := s.EachCrashReport(func(report CrashReport) bool {
+		stats.Total++
+		if report.Handled {
+			stats.Handled++
+		} else {
+			stats.Panics++
+		}
+		if stats.FirstSeen.IsZero() || report.Timestamp.Before(stats.FirstSeen) {
+			stats.FirstSeen = report.Timestamp
+		}
+		if report.Timestamp.After(stats.LastSeen) {
+			stats.LastSeen = report.Timestamp
+		}
+
+		fingerprint := Fingerprint(report)
+		group, ok := stats.ByFingerprint[fingerprint]
+		if !ok || report.Timestamp.Before(group.FirstSeen) {
+			group.FirstSeen = report.Timestamp
+		}
+		if report.Timestamp.After(group.LastSeen) {
+			group.LastSeen = report.Timestamp
+		}
+		group.Count++
+		stats.ByFingerprint[fingerprint] = group
+
+		days[startOfDay(report.Timestamp)]++
+		return true
+	})
+	if err != nil {
+		return CrashStats{}, err
+	}
+	if stats.Total > 0 {
+		for day := startOfDay(stats.FirstSeen); !day.After(stats.LastSeen); day = day.AddDate(0, 0, 1) {
+			stats.Daily = append(stats.Daily, DayCount{Day: day, Count: days[day]})
+		}
+	}
+	return stats, nil
+}
+
+// startOfDay returns midnight UTC of the day of t
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
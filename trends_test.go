@@ -0,0 +1,77 @@
+package adfer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCrashStats(t *testing.T) {
+	for name, format := range map[string]FileFormat{"json": FormatJSON, "jsonl": FormatJSONLines} {
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "crash")
+			day := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+			writeCrashFile(t, filePath, format,
+				CrashReport{ID: "a", Timestamp: day, Fingerprint: "x"},
+				CrashReport{ID: "b", Timestamp: day.Add(time.Hour), Fingerprint: "y", Handled: true},
+				CrashReport{ID: "c", Timestamp: day.Add(50 * time.Hour), Fingerprint: "x"},
+			)
+			reader, err := OpenReadOnly(filePath)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			stats, err := reader.CrashStats()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if stats.Total != 3 || stats.Panics != 2 || stats.Handled != 1 {
+				t.Errorf("Expected 2 panics and 1 handled error, got %+v", stats)
+			}
+			if !stats.FirstSeen.Equal(day) || !stats.LastSeen.Equal(day.Add(50*time.Hour)) {
+				t.Errorf("Unexpected first and last seen times %v, %v", stats.FirstSeen, stats.LastSeen)
+			}
+			x := stats.ByFingerprint["x"]
+			if len(stats.ByFingerprint) != 2 || x.Count != 2 || !x.FirstSeen.Equal(day) || !x.LastSeen.Equal(day.Add(50*time.Hour)) {
+				t.Errorf("Unexpected fingerprint statistics %+v", stats.ByFingerprint)
+			}
+			expected := []int{2, 0, 0, 1}
+			if len(stats.Daily) != len(expected) {
+				t.Fatalf("Expected %d days, got %+v", len(expected), stats.Daily)
+			}
+			for i, count := range expected {
+				if stats.Daily[i].Count != count || !stats.Daily[i].Day.Equal(time.Date(2024, 6, 1+i, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("Expected %d reports on day %d, got %+v", count, i, stats.Daily[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCrashStatsStackDiffs(t *testing.T) {
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		DumpToFile:   true,
+		FilePath:     filepath.Join(t.TempDir(), "crash.json"),
+	}, WithStackDiffs())
+	for i := 0; i < 3; i++ {
+		func() {
+			defer ph.Recover()
+			panic("recurring")
+		}()
+	}
+
+	count := 0
+	err := ph.EachCrashReport(func(report CrashReport) bool {
+		if report.Stack == "" {
+			t.Errorf("Expected the stack of report %s to be expanded", report.ID)
+		}
+		count++
+		return true
+	})
+	if err != nil || count != 3 {
+		t.Errorf("Expected 3 reports, got %d, error %v", count, err)
+	}
+	if stats, _ := ph.CrashStats(); stats.Total != 3 || len(stats.ByFingerprint) != 1 || len(stats.Daily) < 1 {
+		t.Errorf("Expected 3 reports with the same fingerprint, got %+v", stats)
+	}
+}
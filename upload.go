@@ -0,0 +1,483 @@
+package adfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectStore stores objects in a bucket
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// UploaderOptions configures an Uploader
+type UploaderOptions struct {
+	// KeyTemplate is the object key for each report. Supports the placeholders {app}, {date},
+	// {time}, {timestamp}, {uuid}, {hostname}, {pid} and {fingerprint}.
+	// Defaults to "crashes/{app}/{date}/{uuid}.json"
+	KeyTemplate string
+	// App is the value of the {app} placeholder. Defaults to the executable name
+	App string
+	// MaxRetries is the number of retries for a failed upload. Defaults to 3
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, doubled for each subsequent retry. Defaults to 1 second
+	RetryDelay time.Duration
+	// Timeout limits how long uploading a report may take, including its retries, so an unreachable
+	// store can't hold up the crash. Defaults to 10 seconds
+	Timeout time.Duration
+	// QueueSize is the number of failed reports kept for a later attempt. The oldest
+	// report is dropped when the queue is full. Defaults to 100. Failed reports aren't queued
+	// when the PanicHandler spools them, see WithSpool
+	QueueSize int
+	// QueueDir, if set, is the directory the queue is kept in, so queued reports survive a restart
+	// and are uploaded by the next run. It is created if it doesn't exist
+	QueueDir string
+}
+
+// Uploader is a Reporter that uploads each crash report as a JSON object to an object store.
+// Reports that can't be uploaded are queued and uploaded in the background after the next
+// successful upload, or on Flush. If the PanicHandler spools failed deliveries, they are left
+// to the spool instead, so each report is retried once
+type Uploader struct {
+	store   ObjectStore
+	options UploaderOptions
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	queue []CrashReport
+	// spooled is set if failed reports are retried by the PanicHandler's spool
+	spooled bool
+	// flushing is held while the queue is flushed, so queued reports are uploaded once
+	flushing sync.Mutex
+}
+
+// NewUploader creates an Uploader for the given store
+func NewUploader(store ObjectStore, options UploaderOptions) *Uploader {
+	if options.KeyTemplate == "" {
+		options.KeyTemplate = "crashes/{app}/{date}/{uuid}.json"
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = 3
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 100
+	}
+	uploader := &Uploader{
+		store:   store,
+		options: options,
+		sleep:   sleepContext,
+	}
+	uploader.loadQueue()
+	return uploader
+}
+
+// WithUploader uploads every crash report to the given object store
+func WithUploader(store ObjectStore, options UploaderOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewUploader(store, options))
+	}
+}
+
+// Key returns the object key for a report
+func (u *Uploader) Key(report CrashReport) string {
+	return expandPlaceholders(u.options.KeyTemplate, placeholderValues(report, u.options.App))
+}
+
+// Name returns the name used in delivery receipts
+func (u *Uploader) Name() string {
+	return "uploader"
+}
+
+// Report uploads the report within Timeout. If it can't be uploaded, it is queued unless it is
+// spooled. Once a report is uploaded, the queued reports are uploaded in the background
+func (u *Uploader) Report(ctx context.Context, report CrashReport) error {
+	if report.ID == "" {
+		report.ID = newReportID()
+	}
+	ctx, cancel := context.WithTimeout(ctx, u.options.Timeout)
+	defer cancel()
+	if err := u.upload(ctx, report); err != nil {
+		if u.spooling() {
+			return fmt.Errorf("upload failed: %w", err)
+		}
+		u.enqueue(report)
+		return fmt.Errorf("upload failed, report queued: %w", err)
+	}
+	if u.Pending() > 0 {
+		go u.flushBackground()
+	}
+	return nil
+}
+
+// flushBackground uploads the queued reports unless they are already being flushed
+func (u *Uploader) flushBackground() {
+	if !u.flushing.TryLock() {
+		return
+	}
+	defer u.flushing.Unlock()
+	// Reports that fail again stay queued for the next attempt
+	_ = u.flush(context.Background())
+}
+
+// Flush uploads the queued reports, stopping at the first failure
+func (u *Uploader) Flush(ctx context.Context) error {
+	u.flushing.Lock()
+	defer u.flushing.Unlock()
+	return u.flush(ctx)
+}
+
+// flush uploads the queued reports with the queue flush held
+func (u *Uploader) flush(ctx context.Context) error {
+	for {
+		u.mu.Lock()
+		if len(u.queue) == 0 {
+			u.mu.Unlock()
+			return nil
+		}
+		report := u.queue[0]
+		u.mu.Unlock()
+
+		if err := u.upload(ctx, report); err != nil {
+			return err
+		}
+
+		u.mu.Lock()
+		if len(u.queue) > 0 && u.queue[0].ID == report.ID {
+			u.queue = u.queue[1:]
+			u.removeQueued(report.ID)
+		}
+		u.mu.Unlock()
+	}
+}
+
+// useSpool leaves failed reports to the PanicHandler's spool rather than queueing them
+func (u *Uploader) useSpool() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.spooled = true
+}
+
+// spooling returns true if failed reports are left to the spool
+func (u *Uploader) spooling() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.spooled
+}
+
+// Pending returns the number of queued reports
+func (u *Uploader) Pending() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.queue)
+}
+
+// enqueue adds a report to the queue, dropping the oldest report if it is full
+func (u *Uploader) enqueue(report CrashReport) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.queue) >= u.options.QueueSize {
+		u.removeQueued(u.queue[0].ID)
+		u.queue = u.queue[1:]
+	}
+	u.queue = append(u.queue, report)
+	if u.options.QueueDir == "" {
+		return
+	}
+	// The report is still queued in memory if it can't be written
+	if data, err := json.Marshal(report); err == nil && os.MkdirAll(u.options.QueueDir, 0o700) == nil {
+		_ = os.WriteFile(u.queuePath(report.ID), data, 0o600)
+	}
+}
+
+// queuePath returns the path of a queued report in QueueDir
+func (u *Uploader) queuePath(id string) string {
+	return filepath.Join(u.options.QueueDir, "crash-"+id+".json")
+}
+
+// removeQueued removes a report from QueueDir
+func (u *Uploader) removeQueued(id string) {
+	if u.options.QueueDir != "" {
+		_ = os.Remove(u.queuePath(id))
+	}
+}
+
+// loadQueue queues the reports left in QueueDir by a previous run, oldest first. Files that
+// can't be read are left in place
+func (u *Uploader) loadQueue() {
+	if u.options.QueueDir == "" {
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(u.options.QueueDir, "crash-*.json"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil || report.ID == "" {
+			continue
+		}
+		u.queue = append(u.queue, report)
+	}
+	sort.SliceStable(u.queue, func(i, j int) bool {
+		return u.queue[i].Timestamp.Before(u.queue[j].Timestamp)
+	})
+	if excess := len(u.queue) - u.options.QueueSize; excess > 0 {
+		for _, report := range u.queue[:excess] {
+			u.removeQueued(report.ID)
+		}
+		u.queue = u.queue[excess:]
+	}
+}
+
+// upload puts a single report, retrying with exponential backoff
+func (u *Uploader) upload(ctx context.Context, report CrashReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	key := u.Key(report)
+	delay := u.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err = u.store.Put(ctx, key, data, "application/json")
+		if err == nil || attempt >= u.options.MaxRetries {
+			return err
+		}
+		if sleepErr := u.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// S3Config configures an S3 compatible object store
+type S3Config struct {
+	// Bucket is the name of the bucket
+	Bucket string
+	// Region is the bucket's region, e.g. "eu-west-1"
+	Region string
+	// AccessKeyID is the AWS access key ID
+	AccessKeyID string
+	// SecretAccessKey is the AWS secret access key
+	SecretAccessKey string
+	// SessionToken is the session token for temporary credentials
+	SessionToken string
+	// Endpoint overrides the endpoint for S3 compatible services such as MinIO, e.g. "http://localhost:9000".
+	// Path-style addressing is used when set
+	Endpoint string
+	// HTTPClient is the client used for uploads. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// S3Store stores objects in Amazon S3 or an S3 compatible service, using AWS Signature Version 4
+type S3Store struct {
+	config S3Config
+	now    func() time.Time
+}
+
+// NewS3Store creates an S3Store
+func NewS3Store(config S3Config) *S3Store {
+	return &S3Store{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Put uploads an object
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	var endpoint string
+	if s.config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(s.config.Endpoint, "/") + "/" + s.config.Bucket + "/" + escapeKey(key)
+	} else {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.config.Bucket, s.config.Region, escapeKey(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+	signV4(req, data, s.config.AccessKeyID, s.config.SecretAccessKey, s.config.Region, "s3", s.now())
+	return do(s.config.HTTPClient, req)
+}
+
+// escapeKey escapes each segment of an object key as required by Signature Version 4
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// signV4 signs a request with AWS Signature Version 4. Every header already set on the request is signed
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	queryKeys := make([]string, 0, len(query))
+	for key := range query {
+		queryKeys = append(queryKeys, key)
+	}
+	sort.Strings(queryKeys)
+	var canonicalQuery []string
+	for _, key := range queryKeys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// awsEscape escapes a URI component as required by Signature Version 4, escaping every byte
+// except the unreserved characters A-Z, a-z, 0-9, '-', '.', '_' and '~'
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// TokenSource returns an OAuth2 access token
+type TokenSource func(ctx context.Context) (string, error)
+
+// GCSConfig configures a Google Cloud Storage object store
+type GCSConfig struct {
+	// Bucket is the name of the bucket
+	Bucket string
+	// TokenSource returns the OAuth2 access token used to authorize uploads
+	TokenSource TokenSource
+	// Endpoint overrides the API endpoint. Defaults to "https://storage.googleapis.com"
+	Endpoint string
+	// HTTPClient is the client used for uploads. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// GCSStore stores objects in Google Cloud Storage using the JSON API
+type GCSStore struct {
+	config GCSConfig
+}
+
+// NewGCSStore creates a GCSStore
+func NewGCSStore(config GCSConfig) *GCSStore {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://storage.googleapis.com"
+	}
+	return &GCSStore{config: config}
+}
+
+// Put uploads an object
+func (g *GCSStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	endpoint := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		strings.TrimSuffix(g.config.Endpoint, "/"), url.PathEscape(g.config.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if g.config.TokenSource != nil {
+		token, err := g.config.TokenSource(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return do(g.config.HTTPClient, req)
+}
@@ -0,0 +1,48 @@
+package adfer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// processStart approximates the start of the process with the initialization of the package
+var processStart = time.Now()
+
+// WithDeployTime sets a hook returning the time the running build was deployed, e.g. read from
+// a build flag, a file written by the deploy pipeline or the start of the container. Crash reports
+// record the time since, so reviewers can see crashes clustering right after deploys. A zero time
+// is ignored
+func WithDeployTime(deployTime func() time.Time) Option {
+	return func(o *Options) {
+		o.DeployTime = deployTime
+	}
+}
+
+// annotate records the process uptime and the time since the last deploy in a report
+func (ph *PanicHandler) annotate(report *CrashReport) {
+	if ph.options.IncludeSystemInfo {
+		report.Uptime = report.Timestamp.Sub(processStart)
+	}
+	if ph.options.DeployTime != nil {
+		if deployed := ph.options.DeployTime(); !deployed.IsZero() {
+			report.SinceDeploy = report.Timestamp.Sub(deployed)
+		}
+	}
+}
+
+// annotations formats the GOMAXPROCS, uptime and time since the last deploy of a report, e.g.
+// "GOMAXPROCS 8, uptime 2m3s, deployed 5m0s ago". It returns an empty string if none is known
+func annotations(report CrashReport) string {
+	var parts []string
+	if report.SystemInfo.GOMAXPROCS > 0 {
+		parts = append(parts, fmt.Sprintf("GOMAXPROCS %d", report.SystemInfo.GOMAXPROCS))
+	}
+	if report.Uptime > 0 {
+		parts = append(parts, "uptime "+report.Uptime.Round(time.Second).String())
+	}
+	if report.SinceDeploy > 0 {
+		parts = append(parts, "deployed "+report.SinceDeploy.Round(time.Second).String()+" ago")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,57 @@
+package adfer
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnotations(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	deployed := time.Now().Add(-5 * time.Minute)
+	ph := New(Options{
+		ErrorHandler:      func(error, []byte) {},
+		IncludeSystemInfo: true,
+		Reporters:         []Reporter{channelReporter(reports)},
+	}, WithDeployTime(func() time.Time { return deployed }))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	report := <-reports
+	if report.SystemInfo.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected GOMAXPROCS %d, got %d", runtime.GOMAXPROCS(0), report.SystemInfo.GOMAXPROCS)
+	}
+	if report.Uptime <= 0 || report.Uptime > time.Since(processStart) {
+		t.Errorf("Unexpected uptime %v", report.Uptime)
+	}
+	if report.SinceDeploy < 5*time.Minute || report.SinceDeploy > 6*time.Minute {
+		t.Errorf("Expected the time since the deploy to be about 5m, got %v", report.SinceDeploy)
+	}
+	summary := summarize(report, plainFormat)
+	if !strings.Contains(summary, "Context: GOMAXPROCS") || !strings.Contains(summary, "deployed 5m0s ago") {
+		t.Errorf("Expected the annotations in the summary, got:\n%s", summary)
+	}
+}
+
+func TestAnnotationsDisabled(t *testing.T) {
+	reports := make(chan CrashReport, 1)
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+	}, WithDeployTime(func() time.Time { return time.Time{} }))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+
+	report := <-reports
+	if report.Uptime != 0 || report.SinceDeploy != 0 {
+		t.Errorf("Expected no annotations, got uptime %v, since deploy %v", report.Uptime, report.SinceDeploy)
+	}
+	if summary := summarize(report, plainFormat); strings.Contains(summary, "Context:") {
+		t.Errorf("Expected no annotations in the summary, got:\n%s", summary)
+	}
+}
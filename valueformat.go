@@ -0,0 +1,37 @@
+package adfer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithPanicValueFormatter sets a function formatting the values of panics that aren't errors,
+// such as strings, ints or domain types, into the error of their crash reports, e.g. to render
+// a failing AST node instead of its %v form. It receives the raw panic value. If it returns an
+// empty string or panics, the value is formatted with %v
+func WithPanicValueFormatter(format func(value any) string) Option {
+	return func(o *Options) {
+		o.PanicValueFormatter = format
+	}
+}
+
+// panicValueError returns the error of a panic value that isn't an error
+func (ph *PanicHandler) panicValueError(r any) error {
+	if ph.options.PanicValueFormatter != nil {
+		if text := ph.formatPanicValue(r); text != "" {
+			return errors.New(text)
+		}
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// formatPanicValue calls the panic value formatter, recovering from its panics
+func (ph *PanicHandler) formatPanicValue(r any) (text string) {
+	defer func() {
+		if p := recover(); p != nil {
+			ph.diagnose(OpEncode, "", fmt.Errorf("formatting panic value of type %T: %v", r, p))
+			text = ""
+		}
+	}()
+	return ph.options.PanicValueFormatter(r)
+}
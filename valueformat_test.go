@@ -0,0 +1,50 @@
+package adfer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testNode struct {
+	Kind string
+	Line int
+}
+
+func TestPanicValueFormatter(t *testing.T) {
+	reports := make(chan CrashReport, 3)
+	var diagnostics []Diagnostic
+	ph := New(Options{
+		ErrorHandler: func(error, []byte) {},
+		Reporters:    []Reporter{channelReporter(reports)},
+		OnDiagnostic: func(d Diagnostic) { diagnostics = append(diagnostics, d) },
+	}, WithPanicValueFormatter(func(value any) string {
+		switch v := value.(type) {
+		case testNode:
+			return fmt.Sprintf("unexpected %s node at line %d", v.Kind, v.Line)
+		case int:
+			panic("formatter bug")
+		}
+		return ""
+	}))
+
+	for _, value := range []any{testNode{Kind: "call", Line: 12}, "plain", 42} {
+		func() {
+			defer ph.Recover()
+			panic(value)
+		}()
+	}
+
+	if report := <-reports; report.Error != "unexpected call node at line 12" || report.ErrorType != "adfer.testNode" {
+		t.Errorf("Expected the formatted node, got %q of type %s", report.Error, report.ErrorType)
+	}
+	if report := <-reports; report.Error != "plain" {
+		t.Errorf("Expected %%v formatting for unhandled values, got %q", report.Error)
+	}
+	if report := <-reports; report.Error != "42" {
+		t.Errorf("Expected %%v formatting if the formatter panics, got %q", report.Error)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Op != OpEncode || !strings.Contains(diagnostics[0].Err.Error(), "formatter bug") {
+		t.Errorf("Expected a diagnostic for the formatter's panic, got %v", diagnostics)
+	}
+}
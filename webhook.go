@@ -0,0 +1,117 @@
+package adfer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// SignatureHeader is the header holding the HMAC signature of a webhook request body
+const SignatureHeader = "X-Adfer-Signature"
+
+// WebhookOptions configures a WebhookReporter
+type WebhookOptions struct {
+	// URL is the endpoint crash reports are posted to
+	URL string
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// Secret signs the request body with HMAC-SHA256. The signature is sent in the
+	// X-Adfer-Signature header as "sha256=<hex digest>". Requests are not signed if empty
+	Secret string
+	// HTTPClient is the client used to send reports. Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+	// Compression compresses request bodies, e.g. with CompressionGzip. If the endpoint rejects a
+	// compressed body with 415 Unsupported Media Type, the report is resent uncompressed and
+	// later reports aren't compressed. The signature is computed over the compressed body
+	Compression string
+	// MaxPayloadSize caps the size of request bodies in bytes, after compression. The stack of a
+	// larger report is truncated to its first goroutine, then cut until the report fits. No cap if 0
+	MaxPayloadSize int
+}
+
+// WebhookReporter posts crash reports as JSON to an HTTP endpoint
+type WebhookReporter struct {
+	options WebhookOptions
+	// uncompressed is set once the endpoint has rejected a compressed body
+	uncompressed atomic.Bool
+}
+
+// NewWebhookReporter creates a WebhookReporter from the given options
+func NewWebhookReporter(options WebhookOptions) *WebhookReporter {
+	return &WebhookReporter{options: options}
+}
+
+// WithWebhook posts every crash report to the given endpoint
+func WithWebhook(options WebhookOptions) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewWebhookReporter(options))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (w *WebhookReporter) Name() string {
+	return "webhook"
+}
+
+// Report posts the crash report to the endpoint
+func (w *WebhookReporter) Report(ctx context.Context, report CrashReport) error {
+	if err := checkCompression(w.options.Compression); err != nil {
+		return err
+	}
+	compress := w.options.Compression != "" && !w.uncompressed.Load()
+	err := w.send(ctx, report, compress)
+	var status *statusError
+	if compress && errors.As(err, &status) && status.code == http.StatusUnsupportedMediaType {
+		w.uncompressed.Store(true)
+		err = w.send(ctx, report, false)
+	}
+	return err
+}
+
+// send posts the crash report, compressed with the configured compression or not
+func (w *WebhookReporter) send(ctx context.Context, report CrashReport, compress bool) error {
+	body, err := fitPayload(report, w.options.MaxPayloadSize, func(report CrashReport) ([]byte, error) {
+		body, err := json.Marshal(report)
+		if err != nil || !compress {
+			return body, err
+		}
+		return gzipBody(body)
+	})
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(w.options.Headers)+2)
+	for key, value := range w.options.Headers {
+		headers[key] = value
+	}
+	if compress {
+		headers["Content-Encoding"] = w.options.Compression
+	}
+	if w.options.Secret != "" {
+		headers[SignatureHeader] = SignPayload(w.options.Secret, body)
+	}
+	return post(ctx, w.options.HTTPClient, w.options.URL, "application/json", body, headers)
+}
+
+// SignPayload returns the signature of a webhook request body, in the format of the
+// X-Adfer-Signature header
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature, the value of the X-Adfer-Signature header,
+// is a valid signature of body. Receivers should verify the raw request body before decoding it
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(SignPayload(secret, body)), []byte(signature))
+}
@@ -0,0 +1,156 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookReporter(t *testing.T) {
+	type request struct {
+		body      []byte
+		signature string
+		token     string
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{body: body, signature: r.Header.Get(SignatureHeader), token: r.Header.Get("Authorization")}
+	}))
+	defer server.Close()
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithWebhook(WebhookOptions{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Secret:  "shared-secret",
+	}))
+	func() {
+		defer ph.Recover()
+		panic("webhook panic")
+	}()
+
+	got := <-requests
+	var report CrashReport
+	if err := json.Unmarshal(got.body, &report); err != nil || report.Error != "webhook panic" {
+		t.Fatalf("Unexpected body %s (%v)", got.body, err)
+	}
+	if got.token != "Bearer token" {
+		t.Errorf("Expected custom headers to be sent, got '%s'", got.token)
+	}
+	if !VerifySignature("shared-secret", got.body, got.signature) {
+		t.Errorf("Expected a valid signature, got '%s'", got.signature)
+	}
+	if VerifySignature("other-secret", got.body, got.signature) {
+		t.Error("Expected the signature to be invalid with another secret")
+	}
+	if VerifySignature("shared-secret", append(got.body, ' '), got.signature) {
+		t.Error("Expected the signature to be invalid for a modified body")
+	}
+}
+
+func TestWebhookReporterUnsigned(t *testing.T) {
+	signatures := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures <- r.Header.Get(SignatureHeader)
+	}))
+	defer server.Close()
+
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithWebhook(WebhookOptions{URL: server.URL}))
+	func() {
+		defer ph.Recover()
+		panic("webhook panic")
+	}()
+
+	if signature := <-signatures; signature != "" {
+		t.Errorf("Expected no signature without a secret, got '%s'", signature)
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	// Computed with: printf 'payload' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4"
+	if signature := SignPayload("secret", []byte("payload")); signature != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, signature)
+	}
+	if VerifySignature("secret", []byte("payload"), "b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4") {
+		t.Error("Expected signatures without the algorithm prefix to be rejected")
+	}
+}
+
+func TestWebhookReporterCompression(t *testing.T) {
+	type request struct {
+		encoding string
+		body     []byte
+	}
+	requests := make(chan request, 3)
+	reject := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if reject && r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+		requests <- request{encoding: r.Header.Get("Content-Encoding"), body: body}
+	}))
+	defer server.Close()
+
+	reporter := NewWebhookReporter(WebhookOptions{URL: server.URL, Compression: CompressionGzip, Secret: "shared-secret"})
+	if err := reporter.Report(context.Background(), CrashReport{Error: "compressed"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := <-requests
+	if got.encoding != "gzip" {
+		t.Fatalf("Expected a gzip encoded body, got %q", got.encoding)
+	}
+	plain, err := decompress(got.body)
+	var report CrashReport
+	if err != nil || json.Unmarshal(plain, &report) != nil || report.Error != "compressed" {
+		t.Errorf("Unexpected body %q (%v)", plain, err)
+	}
+
+	// The report is resent uncompressed if the endpoint doesn't accept compressed bodies
+	reject = true
+	if err := reporter.Report(context.Background(), CrashReport{Error: "rejected"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := <-requests; got.encoding != "gzip" {
+		t.Errorf("Expected a gzip encoded body first, got %q", got.encoding)
+	}
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			if err := reporter.Report(context.Background(), CrashReport{Error: "later"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if got := <-requests; got.encoding != "" || json.Unmarshal(got.body, &report) != nil {
+			t.Errorf("Expected an uncompressed body, got %q encoded %q", got.body, got.encoding)
+		}
+	}
+
+	if err := NewWebhookReporter(WebhookOptions{URL: server.URL, Compression: "zstd"}).Report(context.Background(), CrashReport{}); err == nil {
+		t.Error("Expected an error for an unsupported compression")
+	}
+}
+
+func TestWebhookReporterMaxPayloadSize(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	reporter := NewWebhookReporter(WebhookOptions{URL: server.URL, MaxPayloadSize: 1024})
+	err := reporter.Report(context.Background(), CrashReport{Error: "large", Stack: strings.Repeat("main.main()\n", 1000)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body := <-bodies
+	var report CrashReport
+	if len(body) > 1024 || json.Unmarshal(body, &report) != nil || !strings.HasSuffix(report.Stack, stackTruncated) {
+		t.Errorf("Expected a truncated report of at most 1024 bytes, got %d bytes: %s", len(body), body)
+	}
+}
@@ -0,0 +1,50 @@
+package adfer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// WriterReporter is a Reporter that writes each crash report as a line of JSON to an io.Writer,
+// e.g. a pipe, a network connection, a log rotation library or a buffer in tests
+type WriterReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterReporter creates a WriterReporter writing to w. Writers with a Flush method, such as
+// bufio.Writer, are flushed after each report
+func NewWriterReporter(w io.Writer) *WriterReporter {
+	return &WriterReporter{w: w}
+}
+
+// WithWriter streams every crash report to w as JSON Lines, in addition to or instead of the crash file
+func WithWriter(w io.Writer) Option {
+	return func(o *Options) {
+		o.Reporters = append(o.Reporters, NewWriterReporter(w))
+	}
+}
+
+// Name returns the name used in delivery receipts
+func (r *WriterReporter) Name() string {
+	return "writer"
+}
+
+// Report writes the crash report as a single line in one call to Write
+func (r *WriterReporter) Report(_ context.Context, report CrashReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if flusher, ok := r.w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
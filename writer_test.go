@@ -0,0 +1,45 @@
+package adfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	ph := New(Options{ErrorHandler: func(error, []byte) {}}, WithWriter(&buf))
+	func() {
+		defer ph.Recover()
+		panic("boom")
+	}()
+	ph.Report(errors.New("handled"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	var report CrashReport
+	if err := json.Unmarshal([]byte(lines[0]), &report); err != nil || report.Error != "boom" || report.Stack == "" {
+		t.Errorf("Unexpected report %+v, error %v", report, err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &report); err != nil || !report.Handled {
+		t.Errorf("Expected a handled error, got %+v, error %v", report, err)
+	}
+}
+
+func TestWriterReporterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	reporter := NewWriterReporter(writer)
+	if err := reporter.Report(context.Background(), CrashReport{ID: "1", Error: "boom"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"id":"1"`) {
+		t.Errorf("Expected the report to be flushed, got %q", buf.String())
+	}
+}